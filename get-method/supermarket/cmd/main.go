@@ -1,16 +1,55 @@
 package main
 
 import (
+	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"supermarket/internal/application"
 )
 
+// The file backend works on a copy of the seed products, kept out of the
+// tracked docs.
+const (
+	seedFilePath = "./get-method/supermarket/docs/db/products.json"
+	dataFilePath = "./get-method/supermarket/data/products.json"
+)
+
 func main() {
 	os.Setenv("PRODUCT_KEY", "123")
-	os.Setenv("DB_FILE_PATH", "./get-method/supermarket/docs/db/products.json")
+	if os.Getenv("DB_FILE_PATH") == "" {
+		if err := seed(dataFilePath, seedFilePath); err != nil {
+			panic(err)
+		}
+		os.Setenv("DB_FILE_PATH", dataFilePath)
+	}
+	if os.Getenv("DB_BACKEND") == "" {
+		os.Setenv("DB_BACKEND", application.BackendFile)
+	}
 
-	server := application.NewServer("8080")
+	cfg, err := application.ConfigFromEnv("8080")
+	if err != nil {
+		panic(err)
+	}
+	server := application.NewServer(cfg)
 	if err := server.Run(); err != nil {
 		panic(err)
 	}
 }
+
+// seed copies the seed file to path on the first run, when there is nothing
+// there yet.
+func seed(path, seedPath string) error {
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	data, err := os.ReadFile(seedPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"supermarket/internal/application"
+
+	"supermarket/platform/database/migration"
+)
+
+// migrate manages the schema of the configured SQL backend, see
+// application.ConfigFromEnv for the environment variables it reads.
+//
+//	go run ./cmd/migrate up|down|status
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: migrate up|down|status")
+		os.Exit(2)
+	}
+
+	if err := run(os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(command string) error {
+	cfg, err := application.ConfigFromEnv("")
+	if err != nil {
+		return err
+	}
+	db, dialect, err := application.OpenDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := application.NewMigrator(db, dialect)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		reverted, err := migrator.Down()
+		if err != nil {
+			if errors.As(err, &migration.NoMigrationError{}) {
+				fmt.Println("no migration to revert")
+				return nil
+			}
+			return err
+		}
+		fmt.Printf("reverted %04d_%s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			switch {
+			case s.Missing:
+				fmt.Printf("%04d %-30s applied %s (file missing)\n", s.Version, s.Name, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			case s.Applied:
+				fmt.Printf("%04d %-30s applied %s\n", s.Version, s.Name, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			default:
+				fmt.Printf("%04d %-30s pending\n", s.Version, s.Name)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+	return nil
+}
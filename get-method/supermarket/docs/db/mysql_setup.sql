@@ -0,0 +1,12 @@
+-- Creates the database and the user the server connects with. The schema
+-- itself is managed by the migrations in internal/repository/migrations:
+--   DB_MYSQL_DSN='user1:secret_password@/supermarket_db' go run ./cmd/migrate up
+
+-- Delete user if already exists
+DROP USER IF EXISTS 'user1'@'localhost';
+
+-- Create user with all privileges
+CREATE USER 'user1'@'localhost' IDENTIFIED BY 'secret_password';
+GRANT ALL PRIVILEGES ON *.* TO 'user1'@'localhost';
+
+CREATE DATABASE IF NOT EXISTS `supermarket_db` DEFAULT CHARACTER SET utf8mb4;
@@ -0,0 +1,205 @@
+-- Sample catalog, load it once the schema is migrated:
+--   go run ./cmd/migrate up
+--   mysql -u user1 -p supermarket_db < docs/db/seed_products.sql
+-- Prices are in cents of the currency column default, USD.
+INSERT INTO products (id, name, quantity, code_value, is_published, expiration, price_minor) VALUES
+(1,'Corn Shoots',244,'0009-1111',0,'2022-01-08',2327),
+(2,'Shrimp - Baby, Cold Water',174,'49288-0877',0,'2022-08-04',5212),
+(3,'Sprouts - Onion',136,'0268-6518',1,'2021-12-27',9195),
+(4,'Triple Sec - Mcguinness',107,'13537-457',0,'2021-06-23',7260),
+(5,'Chervil - Fresh',81,'49430-046',0,'2022-05-28',3446),
+(6,'Wine - German Riesling',212,'24385-804',0,'2022-07-07',519),
+(7,'Oil - Sunflower',169,'59779-590',0,'2022-07-03',724),
+(8,'Persimmons',238,'45802-327',0,'2021-04-14',6065),
+(9,'Beer - Labatt Blue',23,'48951-1215',1,'2022-06-23',3299),
+(10,'Ranchero - Primerba, Paste',59,'0268-1173',1,'2021-04-02',1702),
+(11,'Tomatillo',95,'0264-7730',0,'2021-10-28',9221),
+(12,'Pasta - Rotini, Colour, Dry',61,'68151-3826',1,'2021-03-19',1066),
+(13,'Sherry - Dry',86,'51079-485',1,'2021-12-19',6391),
+(14,'Chocolate - Unsweetened',67,'68788-9799',1,'2022-02-22',3601),
+(15,'Soupfoamcont12oz 112con',224,'11084-050',1,'2022-06-27',9566),
+(16,'Pasta - Gnocchi, Potato',7,'42507-340',1,'2022-09-10',3975),
+(17,'Beef - Sushi Flat Iron Steak',88,'42254-206',1,'2021-12-14',3850),
+(18,'Steamers White',217,'51386-737',0,'2022-07-05',5785),
+(19,'Pasta - Angel Hair',171,'0378-2017',1,'2021-03-18',2037),
+(20,'Parsley - Dried',197,'21695-130',0,'2022-04-23',4202),
+(21,'Maintenance Removal Charge',150,'42549-549',1,'2021-10-09',7780),
+(22,'Dome Lid Clear P92008h',52,'0023-4964',1,'2021-09-15',5449),
+(23,'Cookies - Oreo, 4 Pack',37,'54868-6276',0,'2021-05-20',2672),
+(24,'Nantucket - Kiwi Berry Cktl.',15,'65841-777',0,'2021-09-18',1105),
+(25,'Cookie - Oatmeal',75,'68151-0314',1,'2022-05-09',2562),
+(26,'Soho Lychee Liqueur',22,'68258-3016',1,'2022-08-05',6207),
+(27,'Bread - Bistro Sour',131,'0121-0671',1,'2021-08-27',9256),
+(28,'Chocolate - Pistoles, Lactee, Milk',171,'76181-002',1,'2022-07-01',5898),
+(29,'Grapefruit - White',22,'36987-1530',1,'2022-01-26',7336),
+(30,'Passion Fruit',236,'49288-0781',0,'2022-03-12',6336),
+(31,'Cookies - Englishbay Oatmeal',197,'42291-169',1,'2021-12-16',9437),
+(32,'Lettuce - Belgian Endive',179,'0378-0152',0,'2022-04-02',4829),
+(33,'Vaccum Bag 10x13',39,'0406-9907',0,'2021-03-18',2029),
+(34,'Chocolate - Dark',75,'54575-463',1,'2021-04-04',8837),
+(35,'Raspberries - Fresh',27,'31722-545',0,'2021-12-23',3882),
+(36,'Cattail Hearts',98,'45802-472',1,'2022-03-26',1584),
+(37,'Salt - Sea',34,'66116-360',0,'2022-07-30',5039),
+(38,'Cheese - Swiss',12,'43493-0001',0,'2021-08-30',3827),
+(39,'Pasta - Cheese / Spinach Bauletti',86,'48102-102',0,'2021-08-31',6417),
+(40,'Wine - Sake',216,'0603-0839',1,'2021-09-25',3918),
+(41,'Tea - Black Currant',19,'59011-458',1,'2021-06-17',7263),
+(42,'Cheese - Mozzarella, Shredded',243,'36800-099',0,'2021-06-05',8292),
+(43,'Gooseberry',112,'68788-9834',0,'2021-12-10',1738),
+(44,'Glass Clear 8 Oz',52,'69244-1001',0,'2021-10-09',3513),
+(45,'Bread - White, Unsliced',62,'60512-1005',0,'2021-05-31',3507),
+(46,'Syrup - Monin, Amaretta',139,'49348-559',1,'2022-06-03',9003),
+(47,'Temperature Recording Station',5,'0942-9395',0,'2022-07-19',403),
+(48,'Cheese - Brie, Triple Creme',145,'10702-040',1,'2022-06-11',3663),
+(49,'Beer - Maudite',204,'15127-738',0,'2022-08-22',805),
+(50,'Sesame Seed Black',217,'49884-835',0,'2022-01-30',8225),
+(51,'Pomegranates',200,'68026-528',0,'2021-12-29',5004),
+(52,'Wine - Placido Pinot Grigo',18,'52959-991',1,'2021-10-02',1448),
+(53,'Muffin Mix - Oatmeal',161,'49349-139',0,'2021-04-08',9121),
+(54,'Beans - Black Bean, Preserved',21,'11410-564',0,'2021-05-04',5326),
+(55,'Orange - Canned, Mandarin',162,'49738-078',1,'2021-10-02',913),
+(56,'Towel - Roll White',3,'52125-232',1,'2022-09-10',8407),
+(57,'Pail With Metal Handle 16l White',99,'64578-0087',1,'2021-07-05',3405),
+(58,'Wine - Black Tower Qr',228,'0187-0798',0,'2021-12-20',5298),
+(59,'Duck - Whole',192,'0409-1755',1,'2022-07-27',781),
+(60,'Bag Stand',131,'24470-913',1,'2021-11-30',7423),
+(61,'Cardamon Seed / Pod',203,'67877-220',1,'2022-05-13',9499),
+(62,'Vermacelli - Sprinkles, Assorted',110,'43547-254',0,'2021-03-20',8761),
+(63,'Rolled Oats',124,'49825-128',0,'2022-04-21',5318),
+(64,'Salad Dressing',243,'36987-2644',0,'2021-10-23',5024),
+(65,'Crab Meat Claw Pasteurise',101,'55910-402',0,'2022-03-19',9580),
+(66,'Soup - French Can Pea',96,'10019-955',1,'2022-09-01',3585),
+(67,'Trout - Rainbow, Frozen',23,'0591-3560',1,'2021-05-30',5511),
+(68,'Swordfish Loin Portions',40,'55670-122',0,'2022-06-12',4739),
+(69,'Wine - Red, Wolf Blass, Yellow',87,'43269-648',0,'2022-05-13',3479),
+(70,'Bread Base - Toscano',64,'36987-3086',0,'2021-11-02',8805),
+(71,'Cloves - Ground',213,'55319-140',0,'2021-07-14',6520),
+(72,'Egg - Salad Premix',98,'63777-165',0,'2022-02-27',8682),
+(73,'Sage Derby',114,'0338-1055',1,'2022-09-10',8812),
+(74,'Plasticknivesblack',98,'51655-501',0,'2022-07-30',2827),
+(75,'Kaffir Lime Leaves',27,'36987-2370',1,'2021-09-16',5642),
+(76,'Breakfast Quesadillas',194,'49348-405',0,'2022-01-24',122),
+(77,'Chips - Potato Jalapeno',41,'60232-2582',0,'2021-07-28',7056),
+(78,'Soap - Pine Sol Floor Cleaner',171,'36987-1854',0,'2021-05-17',4912),
+(79,'Wine - Casillero Deldiablo',200,'57664-441',1,'2021-05-14',1082),
+(80,'Lentils - Red, Dry',156,'55154-6970',1,'2022-06-05',864),
+(81,'Beer - True North Strong Ale',61,'0206-2405',1,'2022-06-12',2921),
+(82,'Gingerale - Schweppes, 355 Ml',83,'65862-526',1,'2022-09-05',3188),
+(83,'Capers - Ox Eye Daisy',154,'11822-3300',0,'2022-07-08',1498),
+(84,'Tomato - Tricolor Cherry',147,'33342-057',1,'2021-07-19',5413),
+(85,'Jam - Raspberry,jar',158,'49288-0249',1,'2021-06-19',1298),
+(86,'Chevril',207,'36987-2164',0,'2022-08-27',3115),
+(87,'Pastry - Cheese Baked Scones',168,'51630-004',1,'2022-06-03',6545),
+(88,'Butter - Unsalted',82,'59779-180',0,'2022-05-16',3547),
+(89,'Cookie Dough - Peanut Butter',129,'68599-6110',0,'2021-04-24',8936),
+(90,'Fudge - Chocolate Fudge',188,'11523-0259',1,'2022-09-02',7188),
+(91,'Truffle Shells - White Chocolate',110,'52125-304',1,'2022-02-26',216),
+(92,'Dish Towel',214,'0603-2483',1,'2021-11-03',7251),
+(93,'Molasses - Fancy',68,'65044-1216',1,'2021-12-03',1116),
+(94,'Peppercorns - Pink',98,'11410-007',1,'2022-02-09',9084),
+(95,'Cake Circle, Foil, Scallop',200,'46122-027',0,'2021-03-27',5534),
+(96,'Bread - Mini Hamburger Bun',28,'68026-105',0,'2021-06-16',1291),
+(97,'Beer - Tetleys',37,'54868-4379',1,'2022-09-08',1052),
+(98,'Iced Tea - Lemon, 460 Ml',35,'48951-1199',0,'2022-06-25',5669),
+(99,'Beans - Yellow',36,'60681-0102',1,'2022-06-12',4565),
+(100,'Peppercorns - Green',34,'64117-115',1,'2021-07-24',9231),
+(101,'Steam Pan Full Lid',70,'43538-191',0,'2022-09-05',5055),
+(102,'Nantucket - Carrot Orange',187,'63148-164',0,'2021-12-09',1367),
+(103,'Flour - Teff',132,'55154-4378',1,'2021-08-08',3680),
+(104,'Venison - Striploin',176,'68084-692',0,'2022-01-12',651),
+(105,'Lamb - Sausage Casings',72,'59667-0103',1,'2021-04-28',798),
+(106,'Dates',79,'59779-974',1,'2021-09-16',9100),
+(107,'Oil - Safflower',63,'66129-101',1,'2022-05-11',2801),
+(108,'Clams - Canned',19,'68180-236',0,'2021-11-01',9311),
+(109,'Pastry - Choclate Baked',170,'0093-1006',1,'2021-12-20',9291),
+(110,'Poppy Seed',97,'0054-8084',0,'2021-12-13',3203),
+(111,'Longos - Greek Salad',111,'60760-911',0,'2021-05-08',6921),
+(112,'Bag Stand',13,'42023-136',0,'2022-03-26',3973),
+(113,'Veal - Provimi Inside',50,'63629-2573',1,'2021-06-10',8279),
+(114,'Wine - White, Lindemans Bin 95',152,'57955-5080',0,'2022-06-30',6505),
+(115,'Sprouts - Corn',88,'16714-041',0,'2021-05-05',2141),
+(116,'Snapple - Mango Maddness',126,'68016-125',1,'2022-08-15',8535),
+(117,'Table Cloth 54x54 Colour',65,'0615-7521',1,'2022-02-19',7552),
+(118,'Juice - Orange 1.89l',25,'76329-8261',0,'2022-02-15',6593),
+(119,'Skirt - 24 Foot',70,'24236-995',0,'2021-03-23',7910),
+(120,'Lemonade - Mandarin, 591 Ml',172,'64117-714',0,'2021-12-09',2093),
+(121,'Cod - Black Whole Fillet',244,'58668-4101',1,'2021-12-20',7945),
+(122,'Sugar Thermometer',29,'0527-1301',0,'2021-10-29',3983),
+(123,'Compound - Raspberry',152,'68382-179',1,'2022-01-16',9195),
+(124,'Cookie Trail Mix',36,'51346-257',0,'2022-08-22',7566),
+(125,'Beef - Top Sirloin',91,'64376-132',0,'2021-06-15',7927),
+(126,'Bread - Ciabatta Buns',84,'43598-225',1,'2021-06-24',5998),
+(127,'Soup Campbells - Tomato Bisque',26,'21695-969',0,'2021-05-03',9624),
+(128,'Radish - Pickled',208,'52959-398',1,'2021-08-03',8716),
+(129,'Butter Sweet',185,'67510-0085',1,'2022-01-01',941),
+(130,'Jam - Raspberry',227,'68400-706',0,'2021-06-16',5071),
+(131,'Pork - Backfat',92,'39822-3015',1,'2021-08-20',3258),
+(132,'Yoplait Drink',140,'68788-9165',0,'2021-07-27',1439),
+(133,'Pastry - Cheese Baked Scones',236,'50181-0016',0,'2021-09-29',8477),
+(134,'Bread - Pumpernickle, Rounds',61,'63629-2949',0,'2021-12-20',3597),
+(135,'Veal - Chops, Split, Frenched',223,'68180-655',1,'2021-03-16',5588),
+(136,'Wine - Red, Marechal Foch',94,'51285-595',0,'2022-02-27',9104),
+(137,'Beets - Candy Cane, Organic',37,'67296-0538',0,'2022-06-03',2390),
+(138,'Juice - Clam, 46 Oz',66,'53329-938',0,'2022-06-25',5704),
+(139,'Chocolate Bar - Smarties',51,'42957-002',0,'2022-08-03',5982),
+(140,'Mushroom - King Eryingii',156,'0268-1094',0,'2022-06-25',2250),
+(141,'Gingerale - Schweppes, 355 Ml',132,'54868-5841',1,'2022-05-27',860),
+(142,'Wine - Hardys Bankside Shiraz',219,'49349-626',1,'2021-10-13',9157),
+(143,'Kaffir Lime Leaves',249,'49288-0146',1,'2022-07-12',1702),
+(144,'Kellogs Special K Cereal',12,'33261-591',1,'2021-05-31',522),
+(145,'Cup - 3.5oz, Foam',223,'54868-1173',0,'2021-09-28',3576),
+(146,'Beef Cheek Fresh',30,'53942-311',1,'2021-05-11',3212),
+(147,'Beef - Tenderloin',1,'43068-106',0,'2022-03-10',3906),
+(148,'Paper Cocktail Umberlla 80 - 180',147,'57520-0324',1,'2021-05-15',8287),
+(149,'Pineapple - Canned, Rings',81,'35000-608',1,'2022-04-12',3478),
+(150,'Veal Inside - Provimi',124,'49643-460',0,'2022-01-08',6426),
+(151,'Goulash Seasoning',110,'55910-199',0,'2021-08-10',5924),
+(152,'Juice - Cranberry, 341 Ml',159,'57955-0065',1,'2022-07-27',9105),
+(153,'Pastry - Chocolate Marble Tea',6,'68428-037',1,'2021-07-24',6994),
+(154,'Quinoa',39,'59667-0096',1,'2021-03-13',6515),
+(155,'Island Oasis - Ice Cream Mix',79,'62011-0006',0,'2021-05-30',2023),
+(156,'Basil - Dry, Rubbed',225,'51607-001',0,'2021-07-25',1353),
+(157,'Beef Cheek Fresh',43,'51389-204',0,'2021-08-19',7996),
+(158,'Scrubbie - Scotchbrite Hand Pad',175,'54569-6100',1,'2021-05-06',7255),
+(159,'Cookie - Oreo 100x2',211,'41442-150',0,'2022-01-23',1343),
+(160,'Appetizer - Shrimp Puff',177,'50563-155',0,'2022-08-23',2983),
+(161,'Island Oasis - Pina Colada',77,'50241-141',0,'2021-03-15',8332),
+(162,'Graham Cracker Mix',29,'55154-0536',0,'2022-09-02',792),
+(163,'Poppy Seed',7,'0407-0690',1,'2022-05-21',2727),
+(164,'Anchovy Paste - 56 G Tube',118,'55301-007',1,'2022-04-21',269),
+(165,'Bread - Sticks, Thin, Plain',213,'62175-129',1,'2021-12-04',6029),
+(166,'Coffee - Flavoured',1,'0409-4857',0,'2022-07-21',3188),
+(167,'Macaroons - Homestyle Two Bit',202,'63629-1494',0,'2022-05-09',967),
+(168,'Energy - Boo - Koo',89,'50436-0922',1,'2021-08-20',1354),
+(169,'Sage - Fresh',85,'10424-161',0,'2021-11-27',8957),
+(170,'Nantucket Pine Orangebanana',237,'0268-1505',0,'2022-08-30',5165),
+(171,'Sauce - Hp',228,'60793-851',0,'2021-10-17',7407),
+(172,'Pork - Tenderloin, Frozen',144,'63629-4492',1,'2022-08-14',8939),
+(173,'Glucose',201,'21695-125',1,'2021-10-24',9099),
+(174,'Raisin - Golden',86,'57520-0642',1,'2021-11-03',1211),
+(175,'Brandy Apricot',146,'36800-422',0,'2022-05-11',4027),
+(176,'Capers - Ox Eye Daisy',90,'54868-5662',1,'2022-03-24',4233),
+(177,'Puff Pastry - Slab',16,'21695-365',0,'2021-05-16',4879),
+(178,'Dome Lid Clear P92008h',29,'52685-324',0,'2022-01-28',1569),
+(179,'Salt And Pepper Mix - Black',219,'0440-1771',1,'2021-03-21',7926),
+(180,'Artichoke - Bottom, Canned',196,'0093-7658',1,'2021-08-30',2328),
+(181,'Pasta - Angel Hair',228,'0093-3010',0,'2022-06-19',1791),
+(182,'Muffin Batt - Choc Chk',141,'13537-447',0,'2021-07-08',3189),
+(183,'Beef Flat Iron Steak',14,'0054-0003',0,'2022-03-03',9449),
+(184,'Puree - Mango',147,'60589-005',1,'2021-11-10',8189),
+(185,'Beer - Camerons Cream Ale',206,'0268-6676',1,'2021-11-04',6632),
+(186,'Spinach - Frozen',121,'41250-105',0,'2021-08-21',7936),
+(187,'Bagel - Everything Presliced',153,'49672-100',1,'2022-01-11',2044),
+(188,'Absolut Citron',121,'55154-4623',0,'2021-03-11',6581),
+(189,'Honey - Liquid',176,'41520-300',0,'2021-06-02',5505),
+(190,'Pork - Suckling Pig',187,'61957-1018',0,'2021-03-15',1954),
+(191,'Beef Striploin Aaa',245,'53645-1021',0,'2021-07-26',7387),
+(192,'Pepper - Jalapeno',137,'0007-3260',1,'2022-07-01',7785),
+(193,'Glass - Juice Clear 5oz 55005',126,'53499-5571',1,'2021-10-16',4982),
+(194,'Devonshire Cream',150,'0363-6230',1,'2022-04-03',851),
+(195,'Lobster - Baby, Boiled',21,'0074-6624',1,'2021-04-14',3733),
+(196,'Soupcontfoam16oz 116con',45,'11673-599',1,'2022-05-12',6519),
+(197,'French Pastry - Mini Chocolate',240,'0615-1556',0,'2022-01-25',719),
+(198,'Sobe - Berry Energy',205,'0069-0122',1,'2022-02-12',9199),
+(199,'Tea - Jasmin Green',238,'43269-720',0,'2022-02-03',3463),
+(200,'Scallops - 20/30',229,'68788-9100',1,'2022-03-06',3075);
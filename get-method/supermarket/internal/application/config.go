@@ -0,0 +1,168 @@
+package application
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"supermarket/internal"
+)
+
+// Product storage backends
+const (
+	BackendMemory = "memory"
+	BackendFile   = "file"
+	BackendSQLite = "sqlite"
+	BackendMySQL  = "mysql"
+)
+
+// DefaultExpirationScanInterval is how often expired products are looked
+// for unless configured otherwise.
+const DefaultExpirationScanInterval = time.Hour
+
+// DefaultPriceScanInterval is how often scheduled price changes are looked
+// for unless configured otherwise.
+const DefaultPriceScanInterval = time.Minute
+
+// Config holds the settings the server is started with.
+type Config struct {
+	Port string
+	// Backend is where products are stored, one of the Backend constants.
+	Backend string
+	// FilePath is the JSON snapshot of the file backend, its write-ahead
+	// log and its categories live next to it.
+	FilePath string
+	// SQLitePath is the database file of the sqlite backend.
+	SQLitePath string
+	// MySQLDSN is the data source name of the mysql backend.
+	MySQLDSN string
+	// ExchangeRatesPath is an optional JSON file of the exchange rates the
+	// server starts with, see repository.LoadExchangeRates.
+	ExchangeRatesPath string
+	// TaxJurisdiction is the code of the tax jurisdiction the store is in,
+	// carts naming none are taxed as in it. Without it they are untaxed.
+	TaxJurisdiction string
+	// ExpirationScanInterval is how often the products are scanned to
+	// unpublish the expired ones, DefaultExpirationScanInterval when it is
+	// zero.
+	ExpirationScanInterval time.Duration
+	// PriceScanInterval is how often the scheduled price changes due are
+	// applied, DefaultPriceScanInterval when it is zero.
+	PriceScanInterval time.Duration
+	// ReorderWindows are the windows sales velocities are measured over for
+	// reorder suggestions, those of internal.DefaultReorderPolicy when there
+	// are none.
+	ReorderWindows []time.Duration
+	// ReorderSafetyDays is how many days of sales the stock is kept above
+	// the lead time for, as in internal.DefaultReorderPolicy when it is
+	// nil.
+	ReorderSafetyDays *int
+}
+
+// ConfigFromEnv reads the configuration from DB_BACKEND, DB_FILE_PATH,
+// DB_SQLITE_PATH, DB_MYSQL_DSN, EXCHANGE_RATES_PATH, TAX_JURISDICTION,
+// EXPIRATION_SCAN_INTERVAL and PRICE_SCAN_INTERVAL, durations such as 30m,
+// REORDER_WINDOWS, a comma separated list of periods such as 7d,30d, and
+// REORDER_SAFETY_DAYS. It fails naming the variable that does not parse.
+// Without DB_BACKEND the backend is guessed from which path is set, falling
+// back to memory.
+func ConfigFromEnv(port string) (Config, error) {
+	cfg := Config{
+		Port:              port,
+		Backend:           os.Getenv("DB_BACKEND"),
+		FilePath:          os.Getenv("DB_FILE_PATH"),
+		SQLitePath:        os.Getenv("DB_SQLITE_PATH"),
+		MySQLDSN:          os.Getenv("DB_MYSQL_DSN"),
+		ExchangeRatesPath: os.Getenv("EXCHANGE_RATES_PATH"),
+		TaxJurisdiction:   os.Getenv("TAX_JURISDICTION"),
+	}
+	for _, interval := range []struct {
+		name   string
+		target *time.Duration
+	}{
+		{"EXPIRATION_SCAN_INTERVAL", &cfg.ExpirationScanInterval},
+		{"PRICE_SCAN_INTERVAL", &cfg.PriceScanInterval},
+	} {
+		if value := os.Getenv(interval.name); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid %s: %w", interval.name, err)
+			}
+			*interval.target = d
+		}
+	}
+	if windows := os.Getenv("REORDER_WINDOWS"); windows != "" {
+		periods, err := internal.ParsePeriods(windows)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REORDER_WINDOWS: %w", err)
+		}
+		cfg.ReorderWindows = periods
+	}
+	if days := os.Getenv("REORDER_SAFETY_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REORDER_SAFETY_DAYS: %w", err)
+		}
+		cfg.ReorderSafetyDays = &n
+	}
+
+	if cfg.Backend == "" {
+		switch {
+		case cfg.MySQLDSN != "":
+			cfg.Backend = BackendMySQL
+		case cfg.SQLitePath != "":
+			cfg.Backend = BackendSQLite
+		case cfg.FilePath != "":
+			cfg.Backend = BackendFile
+		default:
+			cfg.Backend = BackendMemory
+		}
+	}
+	return cfg, nil
+}
+
+// Validate checks the selected backend has what it needs and the settings
+// are in range.
+func (c Config) Validate() error {
+	switch c.Backend {
+	case BackendMemory:
+	case BackendFile:
+		if c.FilePath == "" {
+			return fmt.Errorf("backend %q requires DB_FILE_PATH", c.Backend)
+		}
+	case BackendSQLite:
+		if c.SQLitePath == "" {
+			return fmt.Errorf("backend %q requires DB_SQLITE_PATH", c.Backend)
+		}
+	case BackendMySQL:
+		if c.MySQLDSN == "" {
+			return fmt.Errorf("backend %q requires DB_MYSQL_DSN", c.Backend)
+		}
+	default:
+		return fmt.Errorf("unknown backend %q", c.Backend)
+	}
+	if c.ExpirationScanInterval < 0 {
+		return fmt.Errorf("invalid EXPIRATION_SCAN_INTERVAL, want a positive duration")
+	}
+	if c.PriceScanInterval < 0 {
+		return fmt.Errorf("invalid PRICE_SCAN_INTERVAL, want a positive duration")
+	}
+	if err := c.ReorderPolicy().Validate(); err != nil {
+		return fmt.Errorf("invalid REORDER_WINDOWS or REORDER_SAFETY_DAYS: %w", err)
+	}
+	return nil
+}
+
+// ReorderPolicy returns internal.DefaultReorderPolicy with the reorder
+// settings applied.
+func (c Config) ReorderPolicy() internal.ReorderPolicy {
+	policy := internal.DefaultReorderPolicy()
+	if len(c.ReorderWindows) > 0 {
+		policy.Windows = c.ReorderWindows
+	}
+	if c.ReorderSafetyDays != nil {
+		policy.SafetyDays = *c.ReorderSafetyDays
+	}
+	return policy
+}
@@ -0,0 +1,111 @@
+package application_test
+
+import (
+	"testing"
+	"time"
+
+	"supermarket/internal/application"
+
+	"github.com/stretchr/testify/require"
+)
+
+// clearEnv unsets every variable ConfigFromEnv reads for the test.
+func clearEnv(t *testing.T) {
+	for _, name := range []string{
+		"DB_BACKEND", "DB_FILE_PATH", "DB_SQLITE_PATH", "DB_MYSQL_DSN",
+		"EXCHANGE_RATES_PATH", "TAX_JURISDICTION",
+		"EXPIRATION_SCAN_INTERVAL", "PRICE_SCAN_INTERVAL",
+		"REORDER_WINDOWS", "REORDER_SAFETY_DAYS",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestConfigFromEnvBackend(t *testing.T) {
+	for name, tc := range map[string]struct {
+		env     map[string]string
+		backend string
+	}{
+		"default":        {backend: application.BackendMemory},
+		"file path":      {env: map[string]string{"DB_FILE_PATH": "products.json"}, backend: application.BackendFile},
+		"sqlite path":    {env: map[string]string{"DB_FILE_PATH": "products.json", "DB_SQLITE_PATH": "supermarket.db"}, backend: application.BackendSQLite},
+		"mysql dsn":      {env: map[string]string{"DB_SQLITE_PATH": "supermarket.db", "DB_MYSQL_DSN": "user@/supermarket"}, backend: application.BackendMySQL},
+		"explicit":       {env: map[string]string{"DB_BACKEND": "memory", "DB_MYSQL_DSN": "user@/supermarket"}, backend: application.BackendMemory},
+		"explicit other": {env: map[string]string{"DB_BACKEND": "file", "DB_FILE_PATH": "products.json"}, backend: application.BackendFile},
+	} {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := application.ConfigFromEnv(":8080")
+			require.NoError(t, err)
+			require.Equal(t, tc.backend, cfg.Backend)
+			require.NoError(t, cfg.Validate())
+		})
+	}
+}
+
+func TestConfigFromEnvSettings(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("EXPIRATION_SCAN_INTERVAL", "30m")
+	t.Setenv("PRICE_SCAN_INTERVAL", "10s")
+	t.Setenv("REORDER_WINDOWS", "7d,30d")
+	t.Setenv("REORDER_SAFETY_DAYS", "3")
+
+	cfg, err := application.ConfigFromEnv(":8080")
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	require.Equal(t, 30*time.Minute, cfg.ExpirationScanInterval)
+	require.Equal(t, 10*time.Second, cfg.PriceScanInterval)
+
+	policy := cfg.ReorderPolicy()
+	require.Equal(t, []time.Duration{7 * 24 * time.Hour, 30 * 24 * time.Hour}, policy.Windows)
+	require.Equal(t, 3, policy.SafetyDays)
+}
+
+func TestConfigFromEnvParseErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		env map[string]string
+		err string
+	}{
+		"expiration interval": {env: map[string]string{"EXPIRATION_SCAN_INTERVAL": "soon"}, err: `invalid EXPIRATION_SCAN_INTERVAL: time: invalid duration "soon"`},
+		"price interval":      {env: map[string]string{"PRICE_SCAN_INTERVAL": "1x"}, err: `invalid PRICE_SCAN_INTERVAL: time: unknown unit "x" in duration "1x"`},
+		"reorder windows":     {env: map[string]string{"REORDER_WINDOWS": "7d,7x"}, err: `invalid REORDER_WINDOWS: time: unknown unit "x" in duration "7x"`},
+		"safety days":         {env: map[string]string{"REORDER_SAFETY_DAYS": "three"}, err: `invalid REORDER_SAFETY_DAYS: strconv.Atoi: parsing "three": invalid syntax`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := application.ConfigFromEnv(":8080")
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, env := range map[string]map[string]string{
+		"unknown backend":     {"DB_BACKEND": "postgres"},
+		"file without path":   {"DB_BACKEND": "file"},
+		"sqlite without path": {"DB_BACKEND": "sqlite"},
+		"mysql without dsn":   {"DB_BACKEND": "mysql"},
+		"negative interval":   {"PRICE_SCAN_INTERVAL": "-1m"},
+		"negative safety":     {"REORDER_SAFETY_DAYS": "-2"},
+		"too many windows":    {"REORDER_WINDOWS": "1d,2d,3d,4d,5d,6d,7d,8d,9d,10d,11d"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := application.ConfigFromEnv(":8080")
+			require.NoError(t, err)
+			require.Error(t, cfg.Validate())
+		})
+	}
+}
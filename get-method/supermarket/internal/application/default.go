@@ -1,11 +1,16 @@
 package application
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
+	"time"
 
+	"supermarket/internal"
 	"supermarket/internal/handler"
 	"supermarket/internal/repository"
+	"supermarket/internal/search"
 	"supermarket/internal/service"
 
 	"supermarket/platform/web/middleware"
@@ -14,34 +19,331 @@ import (
 )
 
 type Server struct {
-	port string
+	cfg Config
 }
 
-func NewServer(port string) *Server {
-	return &Server{port: port}
+func NewServer(cfg Config) *Server {
+	return &Server{cfg: cfg}
+}
+
+// stores are the repositories of the configured backend.
+type stores struct {
+	products   internal.ProductRepository
+	categories internal.CategoryRepository
+	ledger     internal.StockRepository
+	locations  internal.LocationRepository
+	orders     internal.OrderRepository
+	suppliers  internal.SupplierRepository
+	purchases  internal.PurchaseOrderRepository
+	history    internal.PriceHistoryRepository
+	promotions internal.PromotionRepository
+	taxes      internal.TaxRepository
+}
+
+// repositories builds the repositories of the configured backend. The file
+// backend keeps each store in a JSON file next to the products, the SQL ones
+// in the database of the products.
+func (s *Server) repositories(ctx context.Context) (stores, error) {
+	if err := s.cfg.Validate(); err != nil {
+		return stores{}, err
+	}
+
+	switch s.cfg.Backend {
+	case BackendFile:
+		categories, err := repository.NewCategoryFileDB(s.cfg.FilePath + ".categories")
+		if err != nil {
+			return stores{}, err
+		}
+		ledger, err := repository.NewStockFileDB(s.cfg.FilePath + ".stock")
+		if err != nil {
+			return stores{}, err
+		}
+		locations, err := repository.NewLocationFileDB(s.cfg.FilePath + ".locations")
+		if err != nil {
+			return stores{}, err
+		}
+		orders, err := repository.NewOrderFileDB(s.cfg.FilePath + ".orders")
+		if err != nil {
+			return stores{}, err
+		}
+		suppliers, err := repository.NewSupplierFileDB(s.cfg.FilePath + ".suppliers")
+		if err != nil {
+			return stores{}, err
+		}
+		purchases, err := repository.NewPurchaseOrderFileDB(s.cfg.FilePath + ".purchase-orders")
+		if err != nil {
+			return stores{}, err
+		}
+		history, err := repository.NewPriceHistoryFileDB(s.cfg.FilePath + ".price-history")
+		if err != nil {
+			return stores{}, err
+		}
+		promotions, err := repository.NewPromotionFileDB(s.cfg.FilePath + ".promotions")
+		if err != nil {
+			return stores{}, err
+		}
+		taxes, err := repository.NewTaxFileDB(s.cfg.FilePath + ".taxes")
+		if err != nil {
+			return stores{}, err
+		}
+		products, err := repository.NewProductFileRepository(repository.NewStorage(s.cfg.FilePath), s.cfg.FilePath+".wal", repository.DefaultCompactAfter)
+		if err != nil {
+			return stores{}, err
+		}
+		return stores{
+			products:   products,
+			categories: categories,
+			ledger:     ledger,
+			locations:  locations,
+			orders:     orders,
+			suppliers:  suppliers,
+			purchases:  purchases,
+			history:    history,
+			promotions: promotions,
+			taxes:      taxes,
+		}, nil
+	case BackendSQLite:
+		db, err := repository.OpenSQLite(s.cfg.SQLitePath)
+		if err != nil {
+			return stores{}, err
+		}
+		// the embedded database is kept up to date automatically, the
+		// mysql one is migrated explicitly with cmd/migrate
+		if err := Migrate(db, repository.DialectSQLite); err != nil {
+			db.Close()
+			return stores{}, err
+		}
+		products, err := startSQLRepository(ctx, db, repository.NewProductSQLite(db))
+		if err != nil {
+			return stores{}, err
+		}
+		return sqlStores(db, products), nil
+	case BackendMySQL:
+		db, err := repository.OpenMySQL(s.cfg.MySQLDSN)
+		if err != nil {
+			return stores{}, err
+		}
+		products, err := startSQLRepository(ctx, db, repository.NewProductDB(db))
+		if err != nil {
+			return stores{}, err
+		}
+		return sqlStores(db, products), nil
+	default:
+		products, err := repository.NewProductRepository()
+		if err != nil {
+			return stores{}, err
+		}
+		return stores{
+			products:   products,
+			categories: repository.NewCategoryMapDB(),
+			ledger:     repository.NewStockMapDB(),
+			locations:  repository.NewLocationMapDB(),
+			orders:     repository.NewOrderMapDB(),
+			suppliers:  repository.NewSupplierMapDB(),
+			purchases:  repository.NewPurchaseOrderMapDB(),
+			history:    repository.NewPriceHistoryMapDB(),
+			promotions: repository.NewPromotionMapDB(),
+			taxes:      repository.NewTaxMapDB(),
+		}, nil
+	}
+}
+
+// sqlStores returns the stores kept in db along with products.
+func sqlStores(db *sql.DB, products internal.ProductRepository) stores {
+	return stores{
+		products:   products,
+		categories: repository.NewCategorySQL(db),
+		ledger:     repository.NewStockSQL(db),
+		locations:  repository.NewLocationSQL(db),
+		orders:     repository.NewOrderSQL(db),
+		suppliers:  repository.NewSupplierSQL(db),
+		purchases:  repository.NewPurchaseOrderSQL(db),
+		history:    repository.NewPriceHistorySQL(db),
+		promotions: repository.NewPromotionSQL(db),
+		taxes:      repository.NewTaxSQL(db),
+	}
+}
+
+func startSQLRepository(ctx context.Context, db *sql.DB, rp internal.ProductRepository) (internal.ProductRepository, error) {
+	if _, err := rp.Start(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return rp, nil
+}
+
+// exchangeRates builds the exchange rate repository, loaded with the rates
+// of the configured file if any.
+func (s *Server) exchangeRates(ctx context.Context) (*repository.ExchangeRateMapDB, error) {
+	rates := repository.NewExchangeRateMapDB()
+	if s.cfg.ExchangeRatesPath == "" {
+		return rates, nil
+	}
+
+	loaded, err := repository.LoadExchangeRates(s.cfg.ExchangeRatesPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, rate := range loaded {
+		if err := rates.Put(ctx, rate); err != nil {
+			return nil, err
+		}
+	}
+	return rates, nil
 }
 
 func (s *Server) Run() error {
-	rp, _ := repository.NewProductRepository()
-	sv := service.NewProductDefault(rp)
-	hd := handler.NewDefaultProducts(sv)
+	repos, err := s.repositories(context.Background())
+	if err != nil {
+		return err
+	}
+	categories, ledger, locations := repos.categories, repos.ledger, repos.locations
+	// name searches are served from an index of the products kept in memory
+	indexed, err := search.NewIndexedRepository(context.Background(), repos.products)
+	if err != nil {
+		return err
+	}
+	// exchange rates are kept in memory whatever the product backend
+	rates, err := s.exchangeRates(context.Background())
+	if err != nil {
+		return err
+	}
+	exchange := service.NewExchangeDefault(rates, nil)
+	promotions := repos.promotions
+	taxes := service.NewTaxDefault(repos.taxes, s.cfg.TaxJurisdiction)
+	stock := service.NewStockDefault(indexed, ledger, locations, nil)
+	suppliers, purchaseOrders := repos.suppliers, repos.purchases
+	history := repos.history
+	prices := service.NewPriceHistoryDefault(indexed, history, nil)
+	carts := service.NewCartDefault(indexed, service.CartOptions{Promotions: promotions, Exchange: exchange, Taxes: taxes, Stock: stock, Locations: locations, Prices: prices})
+	expirations := service.NewExpirationDefault(indexed, nil)
+
+	interval := s.cfg.ExpirationScanInterval
+	if interval == 0 {
+		interval = DefaultExpirationScanInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewExpirationScheduler(expirations, ticker.C).Run(ctx)
+
+	interval = s.cfg.PriceScanInterval
+	if interval == 0 {
+		interval = DefaultPriceScanInterval
+	}
+	priceTicker := time.NewTicker(interval)
+	defer priceTicker.Stop()
+	go NewPriceScheduler(prices, priceTicker.C).Run(ctx)
+
+	cs := service.NewCategoryDefault(categories, indexed)
+	sv := service.NewProductDefault(indexed, service.ProductOptions{Categories: cs, History: history, Ledger: ledger})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{Exchange: exchange, Stock: stock})
+	pr := handler.NewDefaultPromotions(service.NewPromotionDefault(promotions))
+	ex := handler.NewDefaultExchangeRates(exchange)
+	tx := handler.NewDefaultTaxes(taxes)
+	st := handler.NewDefaultStock(stock)
+	ph := handler.NewDefaultPriceHistory(prices)
+	lc := handler.NewDefaultLocations(service.NewLocationDefault(locations, ledger))
+	exp := handler.NewDefaultExpirations(expirations)
+	ct := handler.NewDefaultCategories(cs)
+	cart := handler.NewDefaultCart(carts)
+	od := handler.NewDefaultOrders(service.NewOrderDefault(carts, stock, repos.orders, nil))
+	sp := handler.NewDefaultSuppliers(service.NewSupplierDefault(suppliers, indexed))
+	po := handler.NewDefaultPurchaseOrders(service.NewPurchaseOrderDefault(suppliers, stock, purchaseOrders, nil))
+	ro := handler.NewDefaultReorders(service.NewReorderDefault(indexed, ledger, suppliers, purchaseOrders, nil), s.cfg.ReorderPolicy())
 
 	router := chi.NewRouter()
 
+	router.Use(middleware.RequestID)
 	router.Use(middleware.ResponseData)
 	router.Get("/ping", handler.Ping)
 	router.Route("/products", func(r chi.Router) {
 		r.Get("/", hd.GetAllProducts())
 		r.Get("/{id}", hd.GetProductById())
 		r.Get("/search", hd.GetProductsFiltered())
+		r.Get("/expiring", exp.GetExpiringProducts())
 		r.With(middleware.Auth).Post("/", hd.AddProduct())
 		r.With(middleware.Auth).Put("/", hd.UpdateOrCreateProduct())
 		r.With(middleware.Auth).Patch("/{id}", hd.PartialProductUpdate())
 		r.With(middleware.Auth).Delete("/{id}", hd.DeleteProduct())
 		r.Get("/consumer_price", hd.GetCartPrice())
+		r.Get("/{id}/prices", ph.GetPriceHistory())
+		r.With(middleware.Auth).Post("/{id}/prices", ph.SchedulePriceChange())
+		r.With(middleware.Auth).Delete("/{id}/prices/{change_id}", ph.CancelPriceChange())
+	})
+	router.Route("/promotions", func(r chi.Router) {
+		r.Get("/", pr.GetAllPromotions())
+		r.Get("/{id}", pr.GetPromotionById())
+		r.With(middleware.Auth).Post("/", pr.AddPromotion())
+		r.With(middleware.Auth).Put("/{id}", pr.UpdatePromotion())
+		r.With(middleware.Auth).Delete("/{id}", pr.DeletePromotion())
+	})
+	router.Route("/exchange-rates", func(r chi.Router) {
+		r.Get("/", ex.GetAllExchangeRates())
+		r.With(middleware.Auth).Put("/{base}/{quote}", ex.PutExchangeRate())
+		r.With(middleware.Auth).Delete("/{base}/{quote}", ex.DeleteExchangeRate())
+	})
+	router.Route("/tax/jurisdictions", func(r chi.Router) {
+		r.Get("/", tx.GetAllJurisdictions())
+		r.Get("/{code}", tx.GetJurisdiction())
+		r.With(middleware.Auth).Put("/{code}", tx.PutJurisdiction())
+		r.With(middleware.Auth).Delete("/{code}", tx.DeleteJurisdiction())
+	})
+	router.Route("/stock", func(r chi.Router) {
+		// reservations hold and sell stock, checkouts make them for customers
+		r.With(middleware.Auth).Post("/reservations", st.AddReservation())
+		r.With(middleware.Auth).Get("/reservations/{id}", st.GetReservation())
+		r.With(middleware.Auth).Post("/reservations/{id}/commit", st.CommitReservation())
+		r.With(middleware.Auth).Delete("/reservations/{id}", st.ReleaseReservation())
+		r.Get("/{id}", st.GetStockLevel())
+		r.Get("/{id}/movements", st.GetStockMovements())
+		r.With(middleware.Auth).Post("/{id}/movements", st.AddStockMovement())
+		r.With(middleware.Auth).Post("/{id}/transfers", st.AddStockTransfer())
+	})
+	router.Route("/locations", func(r chi.Router) {
+		r.Get("/", lc.GetAllLocations())
+		r.Get("/{id}", lc.GetLocationById())
+		r.With(middleware.Auth).Post("/", lc.AddLocation())
+		r.With(middleware.Auth).Put("/{id}", lc.UpdateLocation())
+		r.With(middleware.Auth).Delete("/{id}", lc.DeleteLocation())
+	})
+	router.Route("/categories", func(r chi.Router) {
+		r.Get("/", ct.GetCategoryTree())
+		r.Get("/{id}", ct.GetCategoryById())
+		r.Get("/{id}/products", ct.GetCategoryProducts())
+		r.With(middleware.Auth).Post("/", ct.AddCategory())
+		r.With(middleware.Auth).Put("/{id}", ct.RenameCategory())
+		r.With(middleware.Auth).Post("/{id}/move", ct.MoveCategory())
+		r.With(middleware.Auth).Delete("/{id}", ct.DeleteCategory())
+	})
+	router.Post("/cart/quote", cart.QuoteCart())
+	router.Route("/orders", func(r chi.Router) {
+		r.Post("/", od.Checkout())
+		r.Get("/", od.GetAllOrders())
+		r.Get("/{id}", od.GetOrderById())
+		r.With(middleware.Auth).Put("/{id}/status", od.UpdateOrderStatus())
+	})
+	router.Route("/suppliers", func(r chi.Router) {
+		r.Get("/", sp.GetAllSuppliers())
+		r.Get("/{id}", sp.GetSupplierById())
+		r.Get("/{id}/products", sp.GetSupplierProducts())
+		r.With(middleware.Auth).Post("/", sp.AddSupplier())
+		r.With(middleware.Auth).Put("/{id}", sp.UpdateSupplier())
+		r.With(middleware.Auth).Delete("/{id}", sp.DeleteSupplier())
+		r.With(middleware.Auth).Put("/{id}/products/{product_id}", sp.PutSupplierProduct())
+		r.With(middleware.Auth).Delete("/{id}/products/{product_id}", sp.DeleteSupplierProduct())
+	})
+	router.Route("/purchase-orders", func(r chi.Router) {
+		r.Get("/", po.GetAllPurchaseOrders())
+		r.Get("/{id}", po.GetPurchaseOrderById())
+		r.With(middleware.Auth).Post("/", po.AddPurchaseOrder())
+		r.With(middleware.Auth).Put("/{id}/lines", po.UpdatePurchaseOrderLines())
+		r.With(middleware.Auth).Put("/{id}/status", po.UpdatePurchaseOrderStatus())
 	})
+	router.Get("/inventory/reorder-suggestions", ro.GetReorderSuggestions())
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", s.port), router); err != nil {
+	if err := http.ListenAndServe(fmt.Sprintf(":%s", s.cfg.Port), router); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,48 @@
+package application
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"supermarket/internal"
+)
+
+// ExpirationScheduler unpublishes the expired products in the background.
+type ExpirationScheduler struct {
+	es   internal.ExpirationService
+	tick <-chan time.Time
+}
+
+// NewExpirationScheduler scans the products with es every time tick fires,
+// usually the channel of a time.Ticker.
+func NewExpirationScheduler(es internal.ExpirationService, tick <-chan time.Time) *ExpirationScheduler {
+	return &ExpirationScheduler{es: es, tick: tick}
+}
+
+// Run scans the products right away and then on every tick, until ctx is
+// done. A scan only starts once the previous one is over, so a tick fired
+// while scanning waits for it.
+func (es *ExpirationScheduler) Run(ctx context.Context) {
+	for {
+		es.Scan(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-es.tick:
+		}
+	}
+}
+
+// Scan unpublishes the expired products once. Failures are logged, the
+// next scan retries.
+func (es *ExpirationScheduler) Scan(ctx context.Context) {
+	ids, err := es.es.UnpublishExpired(ctx)
+	if len(ids) > 0 {
+		log.Printf("unpublished expired products %v", ids)
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Printf("unpublishing expired products: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package application_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/application"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock tests move by hand.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+func TestExpirationScheduler(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Expiration: "10/03/2024", Price: internal.NewMoney(110, "USD"), IsPublished: true, Version: 1},
+		2: {Id: 2, Name: "Bread", Quantity: 2, Code: "c2", Expiration: "11/03/2024", Price: internal.NewMoney(235, "USD"), IsPublished: true, Version: 1},
+	}, LastID: 2}
+	clock := &fakeClock{now: time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC)}
+	tick := make(chan time.Time)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		application.NewExpirationScheduler(service.NewExpirationDefault(db, clock.Now), tick).Run(ctx)
+	}()
+
+	published := func(id int) bool {
+		product, err := db.GetById(context.Background(), id)
+		require.NoError(t, err)
+		return product.IsPublished
+	}
+	// the scheduler only takes a tick once the scan before it is over, so
+	// the second tick waits for the scan of the first one
+	scan := func(now time.Time) {
+		tick <- now
+		tick <- now
+	}
+
+	scan(clock.Now())
+	require.True(t, published(1))
+
+	scan(clock.Add(time.Hour))
+	require.False(t, published(1))
+	require.True(t, published(2))
+
+	scan(clock.Add(24 * time.Hour))
+	require.False(t, published(2))
+
+	cancel()
+	<-done
+}
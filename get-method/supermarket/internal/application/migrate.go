@@ -0,0 +1,48 @@
+package application
+
+import (
+	"database/sql"
+	"fmt"
+
+	"supermarket/internal/repository"
+
+	"supermarket/platform/database/migration"
+)
+
+// Migrate applies the pending migrations of dialect to db.
+func Migrate(db *sql.DB, dialect string) error {
+	migrator, err := NewMigrator(db, dialect)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up()
+	return err
+}
+
+// NewMigrator returns a migrator loaded with the migrations of dialect.
+func NewMigrator(db *sql.DB, dialect string) (*migration.Migrator, error) {
+	migrations, err := repository.Migrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return migration.NewMigrator(db, migrations)
+}
+
+// OpenDatabase opens the database of the configured SQL backend and returns
+// it along with its dialect.
+func OpenDatabase(cfg Config) (*sql.DB, string, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	switch cfg.Backend {
+	case BackendSQLite:
+		db, err := repository.OpenSQLite(cfg.SQLitePath)
+		return db, repository.DialectSQLite, err
+	case BackendMySQL:
+		db, err := repository.OpenMySQL(cfg.MySQLDSN)
+		return db, repository.DialectMySQL, err
+	default:
+		return nil, "", fmt.Errorf("backend %q has no database to migrate", cfg.Backend)
+	}
+}
@@ -0,0 +1,47 @@
+package application
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"supermarket/internal"
+)
+
+// PriceScheduler applies the scheduled price changes in the background.
+type PriceScheduler struct {
+	hs   internal.PriceHistoryService
+	tick <-chan time.Time
+}
+
+// NewPriceScheduler applies the price changes due with hs every time tick
+// fires, usually the channel of a time.Ticker.
+func NewPriceScheduler(hs internal.PriceHistoryService, tick <-chan time.Time) *PriceScheduler {
+	return &PriceScheduler{hs: hs, tick: tick}
+}
+
+// Run applies the changes due right away and then on every tick, until ctx
+// is done, one run at a time like ExpirationScheduler.Run.
+func (ps *PriceScheduler) Run(ctx context.Context) {
+	for {
+		ps.Apply(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ps.tick:
+		}
+	}
+}
+
+// Apply applies the changes due once. Failures are logged, the next run
+// retries.
+func (ps *PriceScheduler) Apply(ctx context.Context) {
+	ids, err := ps.hs.ApplyDue(ctx)
+	if len(ids) > 0 {
+		log.Printf("repriced products %v", ids)
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Printf("applying scheduled price changes: %v", err)
+	}
+}
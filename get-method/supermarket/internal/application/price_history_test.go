@@ -0,0 +1,53 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/application"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceScheduler(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Expiration: "10/03/2030", Price: internal.NewMoney(110, "USD"), IsPublished: true, Version: 1},
+	}, LastID: 1}
+	clock := &fakeClock{now: time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC)}
+	hs := service.NewPriceHistoryDefault(db, repository.NewPriceHistoryMapDB(), clock.Now)
+	_, err := hs.Schedule(context.Background(), internal.PriceChange{ProductId: 1, Price: internal.NewMoney(99, "USD"), EffectiveFrom: clock.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	tick := make(chan time.Time)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		application.NewPriceScheduler(hs, tick).Run(ctx)
+	}()
+
+	price := func() internal.Money {
+		product, err := db.GetById(context.Background(), 1)
+		require.NoError(t, err)
+		return product.Price
+	}
+	// as for the expiration scheduler, the second tick waits for the run of
+	// the first one
+	apply := func(now time.Time) {
+		tick <- now
+		tick <- now
+	}
+
+	apply(clock.Now())
+	require.Equal(t, internal.NewMoney(110, "USD"), price())
+
+	apply(clock.Add(time.Hour))
+	require.Equal(t, internal.NewMoney(99, "USD"), price())
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// MaxCartItems bounds the number of line items of a cart.
+const MaxCartItems = 100
+
+// CartItem is a line of a cart: a product, given by id or by code, and the
+// quantity bought of it.
+type CartItem struct {
+	ProductId int    `json:"product_id,omitempty"`
+	Code      string `json:"code_value,omitempty"`
+	Quantity  int    `json:"quantity"`
+}
+
+// Cart is a list of line items quoted in Currency, DefaultCurrency when it
+// is empty, and taxed as in Jurisdiction, the one of the store when it is
+// empty. The items are bought from the stock of the location StoreId, the
+// default location when it is zero. A cart with At is priced as of that
+// time instead of now, to reprint a past receipt or preview scheduled
+// prices: only the exchange and tax rates are the current ones. It is not
+// checked against the stock and can not be checked out.
+type Cart struct {
+	Items        []CartItem `json:"items"`
+	Currency     string     `json:"currency,omitempty"`
+	Jurisdiction string     `json:"jurisdiction,omitempty"`
+	StoreId      int        `json:"store_id,omitempty"`
+	At           *time.Time `json:"at,omitempty"`
+}
+
+func (c Cart) Validate() error {
+	if len(c.Items) == 0 {
+		return NewInvalidCartError("cart is empty")
+	}
+	if len(c.Items) > MaxCartItems {
+		return NewInvalidCartError("cart has too many items")
+	}
+	if c.Currency != "" && !ValidCurrency(c.Currency) {
+		return NewInvalidCartError("unknown currency")
+	}
+	if c.StoreId < 0 {
+		return NewInvalidCartError("unknown store")
+	}
+	if c.At != nil && c.At.IsZero() {
+		return NewInvalidCartError("invalid time")
+	}
+	return nil
+}
+
+// CartRejectReason tells why a cart line could not be priced.
+type CartRejectReason string
+
+const (
+	RejectInvalidQuantity   CartRejectReason = "invalid_quantity"
+	RejectMissingProduct    CartRejectReason = "missing_product"
+	RejectProductMismatch   CartRejectReason = "product_mismatch"
+	RejectProductNotFound   CartRejectReason = "product_not_found"
+	RejectNotPublished      CartRejectReason = "not_published"
+	RejectExpired           CartRejectReason = "expired"
+	RejectInsufficientStock CartRejectReason = "insufficient_stock"
+	RejectCurrencyMismatch  CartRejectReason = "currency_mismatch"
+)
+
+// ReceiptLine is a priced cart line. Line is the index of the cart item,
+// Subtotal its price before the discounts of the promotions applied to it
+// and Total after them. Conversion tells how the unit price was converted
+// to the currency of the cart, when the product is priced in another one.
+type ReceiptLine struct {
+	Line       int              `json:"line"`
+	ProductId  int              `json:"product_id"`
+	Code       string           `json:"code_value"`
+	Name       string           `json:"name"`
+	Quantity   int              `json:"quantity"`
+	UnitPrice  Money            `json:"unit_price"`
+	Conversion *PriceConversion `json:"conversion,omitempty"`
+	Subtotal   Money            `json:"subtotal"`
+	Discount   Money            `json:"discount"`
+	Total      Money            `json:"total"`
+}
+
+// RejectedLine is a cart line left out of the receipt. Available is the
+// stock still left for the line when the reason is insufficient_stock.
+type RejectedLine struct {
+	Line      int              `json:"line"`
+	ProductId int              `json:"product_id,omitempty"`
+	Code      string           `json:"code_value,omitempty"`
+	Quantity  int              `json:"quantity"`
+	Reason    CartRejectReason `json:"reason"`
+	Available *int             `json:"available,omitempty"`
+}
+
+// Receipt is the pricing of a cart. ItemCount and the amounts only account
+// for the priced lines: Subtotal is their price before discounts, Discount
+// the sum of the Discounts applied and Total what is left to pay. Tax breaks
+// the taxes out when the cart is taxed; Total then includes the taxes added
+// to tax-exclusive prices. StoreId is the store of the cart and At the time
+// it was priced as of, if not when it was quoted.
+type Receipt struct {
+	StoreId   int               `json:"store_id,omitempty"`
+	At        *time.Time        `json:"at,omitempty"`
+	Lines     []ReceiptLine     `json:"lines"`
+	Rejected  []RejectedLine    `json:"rejected"`
+	Discounts []AppliedDiscount `json:"discounts"`
+	ItemCount int               `json:"item_count"`
+	Subtotal  Money             `json:"subtotal"`
+	Discount  Money             `json:"discount"`
+	Tax       *TaxSummary       `json:"tax,omitempty"`
+	Total     Money             `json:"total"`
+}
+
+type CartService interface {
+	// Quote prices cart against the current catalog. Lines that can not be
+	// bought are rejected rather than failing the quote; it fails with
+	// InvalidCartError when the cart itself is malformed.
+	Quote(ctx context.Context, cart Cart) (Receipt, error)
+}
+
+type InvalidCartError struct {
+	Reason string
+}
+
+func (e InvalidCartError) Error() string {
+	return "invalid cart: " + e.Reason
+}
+
+func NewInvalidCartError(reason string) error {
+	return InvalidCartError{Reason: reason}
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"strings"
+)
+
+// MaxCategoryNameLength bounds the length of category names.
+const MaxCategoryNameLength = 100
+
+// Category is a node of the catalog taxonomy, such as Cheese under Dairy.
+// ParentId is the id of the parent category, 0 for top level categories,
+// and Position the place of the category among its siblings, from 0.
+type Category struct {
+	Id       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentId int    `json:"parent_id,omitempty"`
+	Position int    `json:"position"`
+}
+
+func (c Category) Validate() error {
+	name := strings.TrimSpace(c.Name)
+	if name == "" || len(name) > MaxCategoryNameLength {
+		return NewInvalidCategoryError("name")
+	}
+	if c.ParentId < 0 || (c.Id != 0 && c.ParentId == c.Id) {
+		return NewInvalidCategoryError("parent")
+	}
+	if c.Position < 0 {
+		return NewInvalidCategoryError("position")
+	}
+	return nil
+}
+
+// CategoryNode is a category with its subcategories, in order.
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"`
+}
+
+type CategoryRepository interface {
+	// GetAll returns every category sorted by parent, then position.
+	GetAll(ctx context.Context) ([]Category, error)
+	GetById(ctx context.Context, id int) (Category, error)
+	Save(ctx context.Context, category Category) (Category, error)
+	// Update stores categories, all of them or none. It fails with
+	// CategoryNotFoundError when one of them does not exist.
+	Update(ctx context.Context, categories []Category) error
+	// Delete fails with CategoryNotEmptyError when the category still has
+	// subcategories, or products for repositories storing them too.
+	Delete(ctx context.Context, id int) error
+}
+
+type CategoryService interface {
+	// Tree returns the top level categories with their descendants.
+	Tree(ctx context.Context) ([]CategoryNode, error)
+	GetById(ctx context.Context, id int) (Category, error)
+	// Save adds category as the last child of its parent.
+	Save(ctx context.Context, category Category) (Category, error)
+	Rename(ctx context.Context, id int, name string) (Category, error)
+	// Move makes category id the child of parentId, 0 for the top level, at
+	// position among its new siblings, last when position is negative or
+	// past them. It fails with InvalidCategoryError when parentId is the
+	// category or one of its descendants.
+	Move(ctx context.Context, id int, parentId int, position int) (Category, error)
+	// Delete fails with CategoryNotEmptyError when the category still has
+	// subcategories or products.
+	Delete(ctx context.Context, id int) error
+	// Products returns a page of the products in category id or any of its
+	// descendants.
+	Products(ctx context.Context, id int, query ProductPageQuery) (ProductPage, error)
+}
+
+type InvalidCategoryError struct {
+	Reason string
+}
+
+func (e InvalidCategoryError) Error() string {
+	return "invalid category: " + e.Reason
+}
+
+func NewInvalidCategoryError(reason string) error {
+	return InvalidCategoryError{Reason: reason}
+}
+
+type CategoryNotFoundError struct{}
+
+func (e CategoryNotFoundError) Error() string {
+	return "category not found"
+}
+
+func NewCategoryNotFoundError() error {
+	return CategoryNotFoundError{}
+}
+
+type CategoryNotEmptyError struct{}
+
+func (e CategoryNotEmptyError) Error() string {
+	return "category has subcategories or products"
+}
+
+func NewCategoryNotEmptyError() error {
+	return CategoryNotEmptyError{}
+}
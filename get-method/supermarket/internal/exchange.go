@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"time"
+)
+
+// ExchangeRate is the price of a unit of Base in units of Quote, a positive
+// decimal such as "0.9215", as of UpdatedAt.
+type ExchangeRate struct {
+	Base      string    `json:"base"`
+	Quote     string    `json:"quote"`
+	Rate      string    `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var decimalRate = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+func (r ExchangeRate) Validate() error {
+	if !ValidCurrency(r.Base) {
+		return NewInvalidExchangeRateError("base")
+	}
+	if !ValidCurrency(r.Quote) || r.Quote == r.Base {
+		return NewInvalidExchangeRateError("quote")
+	}
+	if !decimalRate.MatchString(r.Rate) || r.Ratio().Sign() <= 0 {
+		return NewInvalidExchangeRateError("rate")
+	}
+	return nil
+}
+
+// Ratio returns the rate as an exact fraction, zero when it is malformed.
+func (r ExchangeRate) Ratio() *big.Rat {
+	ratio, ok := new(big.Rat).SetString(r.Rate)
+	if !ok {
+		return new(big.Rat)
+	}
+	return ratio
+}
+
+// Convert returns m, an amount of Base, in Quote. The result is rounded to
+// the minor unit of Quote half to even.
+func (r ExchangeRate) Convert(m Money) Money {
+	// minor units of one currency to minor units of the other
+	scale := new(big.Rat).SetFrac(
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(CurrencyExponent(r.Quote))), nil),
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(CurrencyExponent(r.Base))), nil),
+	)
+	converted := NewMoney(m.Amount, r.Quote)
+	return converted.MulRat(scale.Mul(scale, r.Ratio()), RoundHalfEven)
+}
+
+// Inverse returns the rate of Quote in Base, at the precision of a rate
+// with twelve decimals.
+func (r ExchangeRate) Inverse() ExchangeRate {
+	inverse := new(big.Rat).Inv(r.Ratio())
+	return ExchangeRate{Base: r.Quote, Quote: r.Base, Rate: trimRate(inverse.FloatString(12)), UpdatedAt: r.UpdatedAt}
+}
+
+// Through returns the rate of Base in the Quote of next, whose Base must be
+// the Quote of r. It is as old as the oldest of both.
+func (r ExchangeRate) Through(next ExchangeRate) ExchangeRate {
+	ratio := new(big.Rat).Mul(r.Ratio(), next.Ratio())
+	updatedAt := r.UpdatedAt
+	if next.UpdatedAt.Before(updatedAt) {
+		updatedAt = next.UpdatedAt
+	}
+	return ExchangeRate{Base: r.Base, Quote: next.Quote, Rate: trimRate(ratio.FloatString(12)), UpdatedAt: updatedAt}
+}
+
+// trimRate drops the trailing zeros of a decimal rate.
+func trimRate(rate string) string {
+	end := len(rate)
+	for end > 0 && rate[end-1] == '0' {
+		end--
+	}
+	if end > 0 && rate[end-1] == '.' {
+		end--
+	}
+	return rate[:end]
+}
+
+// PriceConversion records how Original was converted to Converted, at Rate
+// as of RateTime.
+type PriceConversion struct {
+	Original  Money     `json:"original"`
+	Converted Money     `json:"converted"`
+	Rate      string    `json:"rate"`
+	RateTime  time.Time `json:"rate_timestamp"`
+}
+
+type ExchangeRateRepository interface {
+	GetAll(ctx context.Context) ([]ExchangeRate, error)
+	// Get fails with ExchangeRateNotFoundError when no rate of base in quote
+	// is stored, even if the inverse one is.
+	Get(ctx context.Context, base, quote string) (ExchangeRate, error)
+	// Put stores rate, replacing the one of the same currencies.
+	Put(ctx context.Context, rate ExchangeRate) error
+	Delete(ctx context.Context, base, quote string) error
+}
+
+type ExchangeService interface {
+	GetAll(ctx context.Context) ([]ExchangeRate, error)
+	// Put stores the rate of base in quote as of now. It fails with
+	// InvalidExchangeRateError when the rate is not valid.
+	Put(ctx context.Context, base, quote, rate string) (ExchangeRate, error)
+	Delete(ctx context.Context, base, quote string) error
+	// Convert converts m to currency. It fails with
+	// ExchangeRateNotFoundError when no rate links both currencies.
+	Convert(ctx context.Context, m Money, currency string) (PriceConversion, error)
+}
+
+type InvalidExchangeRateError struct {
+	Field string
+}
+
+func (e InvalidExchangeRateError) Error() string {
+	return "invalid exchange rate: " + e.Field
+}
+
+func NewInvalidExchangeRateError(field string) error {
+	return InvalidExchangeRateError{Field: field}
+}
+
+type ExchangeRateNotFoundError struct {
+	Base  string
+	Quote string
+}
+
+func (e ExchangeRateNotFoundError) Error() string {
+	return fmt.Sprintf("no exchange rate from %s to %s", e.Base, e.Quote)
+}
+
+func NewExchangeRateNotFoundError(base, quote string) error {
+	return ExchangeRateNotFoundError{Base: base, Quote: quote}
+}
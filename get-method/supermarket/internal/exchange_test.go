@@ -0,0 +1,57 @@
+package internal_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangeRateValidate(t *testing.T) {
+	require.NoError(t, internal.ExchangeRate{Base: "USD", Quote: "EUR", Rate: "0.9215"}.Validate())
+
+	for field, rate := range map[string]internal.ExchangeRate{
+		"base":  {Base: "usd", Quote: "EUR", Rate: "0.9"},
+		"quote": {Base: "USD", Quote: "USD", Rate: "1"},
+		"rate":  {Base: "USD", Quote: "EUR", Rate: "1/3"},
+	} {
+		err := rate.Validate()
+		require.True(t, errors.As(err, &internal.InvalidExchangeRateError{}), field)
+		require.Equal(t, field, err.(internal.InvalidExchangeRateError).Field)
+	}
+	for _, rate := range []string{"", "0", "0.000", "-1", "1e3", ".5"} {
+		require.Error(t, internal.ExchangeRate{Base: "USD", Quote: "EUR", Rate: rate}.Validate(), rate)
+	}
+}
+
+func TestExchangeRateConvert(t *testing.T) {
+	for _, tc := range []struct {
+		rate      internal.ExchangeRate
+		money     internal.Money
+		converted internal.Money
+	}{
+		{rate: internal.ExchangeRate{Base: "USD", Quote: "EUR", Rate: "0.9"}, money: usd(1000), converted: internal.NewMoney(900, "EUR")},
+		// 1.125 EUR rounds to the even cent
+		{rate: internal.ExchangeRate{Base: "USD", Quote: "EUR", Rate: "0.9"}, money: usd(125), converted: internal.NewMoney(112, "EUR")},
+		// minor units of currencies with other exponents
+		{rate: internal.ExchangeRate{Base: "USD", Quote: "JPY", Rate: "151.25"}, money: usd(150), converted: internal.NewMoney(227, "JPY")},
+		{rate: internal.ExchangeRate{Base: "JPY", Quote: "KWD", Rate: "0.00203"}, money: internal.NewMoney(1000, "JPY"), converted: internal.NewMoney(2030, "KWD")},
+	} {
+		require.Equal(t, tc.converted, tc.rate.Convert(tc.money), tc.rate.Rate)
+	}
+}
+
+func TestExchangeRateInverseAndThrough(t *testing.T) {
+	older := time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	usdEur := internal.ExchangeRate{Base: "USD", Quote: "EUR", Rate: "0.8", UpdatedAt: newer}
+	require.Equal(t, internal.ExchangeRate{Base: "EUR", Quote: "USD", Rate: "1.25", UpdatedAt: newer}, usdEur.Inverse())
+	require.Equal(t, "0.333333333333", internal.ExchangeRate{Base: "USD", Quote: "EUR", Rate: "3"}.Inverse().Rate)
+
+	eurGbp := internal.ExchangeRate{Base: "EUR", Quote: "GBP", Rate: "0.85", UpdatedAt: older}
+	require.Equal(t, internal.ExchangeRate{Base: "USD", Quote: "GBP", Rate: "0.68", UpdatedAt: older}, usdEur.Through(eurGbp))
+}
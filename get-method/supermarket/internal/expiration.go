@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// ExpiresOn returns the expiration date of the product, at midnight in loc.
+func (p Product) ExpiresOn(loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation("02/01/2006", p.Expiration, loc)
+}
+
+// ExpiredAt reports whether the product is expired at now. Products can be
+// sold through their expiration date and expire once it is over. Products
+// without a valid expiration never expire.
+func (p Product) ExpiredAt(now time.Time) bool {
+	expiresOn, err := p.ExpiresOn(now.Location())
+	if err != nil {
+		return false
+	}
+	return !now.Before(expiresOn.AddDate(0, 0, 1))
+}
+
+// ExpiringProduct is a product reported as expiring. DaysLeft is the number
+// of days it can still be sold, 0 on its expiration date and negative once
+// it is expired.
+type ExpiringProduct struct {
+	Product
+	Expired  bool `json:"expired"`
+	DaysLeft int  `json:"days_left"`
+}
+
+type ExpirationService interface {
+	// Expiring returns the products expiring within the given time, expired
+	// ones included, soonest first.
+	Expiring(ctx context.Context, within time.Duration) ([]ExpiringProduct, error)
+	// UnpublishExpired unpublishes the published products that are expired
+	// and returns their ids.
+	UnpublishExpired(ctx context.Context) ([]int, error)
+}
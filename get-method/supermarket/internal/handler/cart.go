@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+)
+
+type DefaultCart struct {
+	cs internal.CartService
+}
+
+func NewDefaultCart(cs internal.CartService) *DefaultCart {
+	return &DefaultCart{cs: cs}
+}
+
+// QuoteCart responds with the receipt of the cart in the request body. Lines
+// that can not be bought are listed as rejected, with the reason, and the
+// receipt is still returned. The currency query parameter or the
+// Accept-Currency header, see targetCurrency, override the currency of the
+// cart.
+func (cc *DefaultCart) QuoteCart() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var cart internal.Cart
+		if err := json.NewDecoder(req.Body).Decode(&cart); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		currency, err := targetCurrency(req)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "unknown currency")
+			return
+		}
+		if currency != "" {
+			cart.Currency = currency
+		}
+
+		receipt, err := cc.cs.Quote(req.Context(), cart)
+		if err != nil {
+			var invalid internal.InvalidCartError
+			if errors.As(err, &invalid) {
+				response.Error(w, http.StatusBadRequest, invalid.Error())
+				return
+			}
+			response.Error(w, http.StatusInternalServerError, "error quoting cart")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(receipt)
+	}
+}
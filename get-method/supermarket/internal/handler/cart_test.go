@@ -0,0 +1,52 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteCart(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Price: usd(150), IsPublished: true},
+		2: {Id: 2, Name: "Bread", Quantity: 1, Code: "c2", Price: usd(200), IsPublished: true},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 2}
+	hd := handler.NewDefaultCart(service.NewCartDefault(&db, service.CartOptions{}))
+
+	quote := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/cart/quote", strings.NewReader(body))
+		res := httptest.NewRecorder()
+		hd.QuoteCart()(res, req)
+		return res
+	}
+
+	res := quote(`{"items": [{"product_id": 1, "quantity": 2}, {"code_value": "c2", "quantity": 3}]}`)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `{
+		"lines": [{"line": 0, "product_id": 1, "code_value": "c1", "name": "Milk", "quantity": 2, "unit_price": {"amount": "1.50", "currency": "USD"}, "subtotal": {"amount": "3.00", "currency": "USD"}, "discount": {"amount": "0.00", "currency": "USD"}, "total": {"amount": "3.00", "currency": "USD"}}],
+		"rejected": [{"line": 1, "product_id": 2, "code_value": "c2", "quantity": 3, "reason": "insufficient_stock", "available": 1}],
+		"discounts": [],
+		"item_count": 2,
+		"subtotal": {"amount": "3.00", "currency": "USD"},
+		"discount": {"amount": "0.00", "currency": "USD"},
+		"total": {"amount": "3.00", "currency": "USD"}
+	}`, res.Body.String())
+
+	for _, body := range []string{`{`, `{"items": []}`} {
+		res := quote(body)
+		require.Equal(t, http.StatusBadRequest, res.Code, body)
+
+		var errBody map[string]any
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&errBody))
+	}
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type DefaultCategories struct {
+	cs internal.CategoryService
+}
+
+func NewDefaultCategories(cs internal.CategoryService) *DefaultCategories {
+	return &DefaultCategories{cs: cs}
+}
+
+// GetCategoryTree responds with the top level categories, each with its
+// subcategories in order.
+func (cc *DefaultCategories) GetCategoryTree() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		tree, err := cc.cs.Tree(req.Context())
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "error retrieving categories")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(tree)
+	}
+}
+
+func (cc *DefaultCategories) GetCategoryById() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		category, err := cc.cs.GetById(req.Context(), id)
+		if err != nil {
+			writeCategoryError(w, err, "error retrieving category")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(category)
+	}
+}
+
+// GetCategoryProducts lists a page of the products in the category of the
+// id path parameter or in any category under it, with the page query
+// parameters of GetAllProducts.
+func (cc *DefaultCategories) GetCategoryProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+		query, err := parsePageQuery(req)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid page query")
+			return
+		}
+
+		page, err := cc.cs.Products(req.Context(), id, query)
+		if err != nil {
+			if errors.As(err, &internal.InvalidPageQueryError{}) {
+				response.Error(w, http.StatusBadRequest, "invalid page query")
+				return
+			}
+			writeCategoryError(w, err, "error retrieving products")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(page)
+	}
+}
+
+// AddCategory adds the category in the request body, a name and a parent,
+// as the last child of its parent.
+func (cc *DefaultCategories) AddCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var category internal.Category
+		if err := json.NewDecoder(req.Body).Decode(&category); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+
+		category, err := cc.cs.Save(req.Context(), category)
+		if err != nil {
+			writeCategoryError(w, err, "error saving category")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(category)
+	}
+}
+
+type categoryNameBody struct {
+	Name string `json:"name"`
+}
+
+// RenameCategory gives the category of the id path parameter the name in
+// the request body.
+func (cc *DefaultCategories) RenameCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var body categoryNameBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+
+		category, err := cc.cs.Rename(req.Context(), id, body.Name)
+		if err != nil {
+			writeCategoryError(w, err, "error renaming category")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(category)
+	}
+}
+
+// categoryMoveBody is the body of moves. The category goes last among its
+// new siblings when Position is missing.
+type categoryMoveBody struct {
+	ParentId int  `json:"parent_id"`
+	Position *int `json:"position"`
+}
+
+// MoveCategory moves the category of the id path parameter under the parent
+// of the request body, 0 for the top level, at the position given.
+func (cc *DefaultCategories) MoveCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var body categoryMoveBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		position := -1
+		if body.Position != nil {
+			if *body.Position < 0 {
+				response.Error(w, http.StatusBadRequest, "invalid category: position")
+				return
+			}
+			position = *body.Position
+		}
+
+		category, err := cc.cs.Move(req.Context(), id, body.ParentId, position)
+		if err != nil {
+			writeCategoryError(w, err, "error moving category")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(category)
+	}
+}
+
+func (cc *DefaultCategories) DeleteCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		if err := cc.cs.Delete(req.Context(), id); err != nil {
+			writeCategoryError(w, err, "error deleting category")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeCategoryError responds with the status matching err, or with message
+// when it is unexpected.
+func writeCategoryError(w http.ResponseWriter, err error, message string) {
+	var invalid internal.InvalidCategoryError
+	switch {
+	case errors.As(err, &invalid):
+		response.Error(w, http.StatusBadRequest, invalid.Error())
+	case errors.As(err, &internal.CategoryNotEmptyError{}):
+		response.Error(w, http.StatusConflict, "category has subcategories or products")
+	case errors.As(err, &internal.CategoryNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "category not found")
+	default:
+		response.Error(w, http.StatusInternalServerError, message)
+	}
+}
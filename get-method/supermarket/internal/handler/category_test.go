@@ -0,0 +1,99 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryEndpoints(t *testing.T) {
+	categories := repository.NewCategoryMapDB()
+	db := repository.ProductMapDB{Products: map[int]internal.Product{}}
+	hd := handler.NewDefaultCategories(service.NewCategoryDefault(categories, &db))
+
+	do := func(method, target, id, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if id != "" {
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		}
+		res := httptest.NewRecorder()
+		h(res, req)
+		return res
+	}
+
+	res := do("POST", "/categories", "", `{"name": "Dairy"}`, hd.AddCategory())
+	require.Equal(t, http.StatusCreated, res.Code)
+	require.JSONEq(t, `{"id": 1, "name": "Dairy", "position": 0}`, res.Body.String())
+	res = do("POST", "/categories", "", `{"name": "Cheese", "parent_id": 1}`, hd.AddCategory())
+	require.Equal(t, http.StatusCreated, res.Code)
+	res = do("POST", "/categories", "", `{"name": "Bakery"}`, hd.AddCategory())
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	for body, status := range map[string]int{
+		`{"name": " "}`:                    http.StatusBadRequest,
+		`{"name": "dairy"}`:                http.StatusBadRequest,
+		`{"name": "Milk", "parent_id": 9}`: http.StatusBadRequest,
+		`{`:                                http.StatusBadRequest,
+	} {
+		res = do("POST", "/categories", "", body, hd.AddCategory())
+		require.Equal(t, status, res.Code, body)
+	}
+
+	res = do("POST", "/categories/2/move", "2", `{"parent_id": 0, "position": 0}`, hd.MoveCategory())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `{"id": 2, "name": "Cheese", "position": 0}`, res.Body.String())
+	res = do("POST", "/categories/1/move", "1", `{"parent_id": 1}`, hd.MoveCategory())
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	res = do("POST", "/categories/1/move", "1", `{"parent_id": 0, "position": -1}`, hd.MoveCategory())
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	res = do("POST", "/categories/9/move", "9", `{"parent_id": 0}`, hd.MoveCategory())
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	res = do("PUT", "/categories/3", "3", `{"name": "Bread"}`, hd.RenameCategory())
+	require.Equal(t, http.StatusOK, res.Code)
+	res = do("POST", "/categories", "", `{"name": "Rye", "parent_id": 3}`, hd.AddCategory())
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	res = do("GET", "/categories", "", "", hd.GetCategoryTree())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `[
+		{"id": 2, "name": "Cheese", "position": 0, "children": []},
+		{"id": 1, "name": "Dairy", "position": 1, "children": []},
+		{"id": 3, "name": "Bread", "position": 2, "children": [
+			{"id": 4, "name": "Rye", "parent_id": 3, "position": 0, "children": []}
+		]}
+	]`, res.Body.String())
+
+	db.Products[1] = internal.Product{Id: 1, Name: "rye bread", Quantity: 1, Code: "c1", Price: usd(300), CategoryIds: []int{4}}
+	db.LastID = 1
+	res = do("GET", "/categories/3/products", "3", "", hd.GetCategoryProducts())
+	require.Equal(t, http.StatusOK, res.Code)
+	var page internal.ProductPage
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&page))
+	require.Equal(t, 1, page.Total)
+	res = do("GET", "/categories/3/products?sort=color", "3", "", hd.GetCategoryProducts())
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = do("DELETE", "/categories/3", "3", "", hd.DeleteCategory())
+	require.Equal(t, http.StatusConflict, res.Code)
+	res = do("DELETE", "/categories/1", "1", "", hd.DeleteCategory())
+	require.Equal(t, http.StatusNoContent, res.Code)
+	res = do("GET", "/categories/1", "1", "", hd.GetCategoryById())
+	require.Equal(t, http.StatusNotFound, res.Code)
+	res = do("GET", "/categories/3", "3", "", hd.GetCategoryById())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `{"id": 3, "name": "Bread", "position": 1}`, res.Body.String())
+}
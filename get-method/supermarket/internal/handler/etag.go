@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag is the entity tag of a product version.
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatchVersion returns the product version required by the If-Match header
+// of req, 0 when any version will do. ok is false when the header can match
+// no version at all.
+func ifMatchVersion(req *http.Request) (version int, ok bool) {
+	header := strings.TrimSpace(req.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	// If-Match compares strongly, a weak tag never matches
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, false
+	}
+	version, err = strconv.Atoi(tag)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// ifNoneMatch reports whether the If-None-Match header of req matches the
+// given product version.
+func ifNoneMatch(req *http.Request, version int) bool {
+	header := req.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == current {
+			return true
+		}
+	}
+	return false
+}
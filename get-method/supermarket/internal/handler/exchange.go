@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type DefaultExchangeRates struct {
+	es internal.ExchangeService
+}
+
+func NewDefaultExchangeRates(es internal.ExchangeService) *DefaultExchangeRates {
+	return &DefaultExchangeRates{es: es}
+}
+
+func (ec *DefaultExchangeRates) GetAllExchangeRates() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		rates, err := ec.es.GetAll(req.Context())
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "error retrieving exchange rates")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rates)
+	}
+}
+
+// exchangeRateBody is the body of exchange rate updates. The rate may be
+// given as a JSON number or as a decimal string.
+type exchangeRateBody struct {
+	Rate json.Number `json:"rate"`
+}
+
+// PutExchangeRate sets the rate of the base path parameter in the quote
+// one, as of now.
+func (ec *DefaultExchangeRates) PutExchangeRate() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body exchangeRateBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+
+		base, quote := currencyPair(req)
+		rate, err := ec.es.Put(req.Context(), base, quote, body.Rate.String())
+		if err != nil {
+			var invalid internal.InvalidExchangeRateError
+			if errors.As(err, &invalid) {
+				response.Error(w, http.StatusBadRequest, invalid.Error())
+				return
+			}
+			response.Error(w, http.StatusInternalServerError, "error saving exchange rate")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rate)
+	}
+}
+
+func (ec *DefaultExchangeRates) DeleteExchangeRate() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		base, quote := currencyPair(req)
+		if err := ec.es.Delete(req.Context(), base, quote); err != nil {
+			if errors.As(err, &internal.ExchangeRateNotFoundError{}) {
+				response.Error(w, http.StatusNotFound, err.Error())
+				return
+			}
+			response.Error(w, http.StatusInternalServerError, "error deleting exchange rate")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// currencyPair returns the base and quote path parameters, upper cased.
+func currencyPair(req *http.Request) (base, quote string) {
+	return strings.ToUpper(chi.URLParam(req, "base")), strings.ToUpper(chi.URLParam(req, "quote"))
+}
+
+// targetCurrency returns the currency prices are requested in: the currency
+// query parameter, else the first one of the Accept-Currency header, else
+// none. Codes are not case sensitive.
+func targetCurrency(req *http.Request) (string, error) {
+	currency := req.URL.Query().Get("currency")
+	if currency == "" {
+		header := req.Header.Get("Accept-Currency")
+		currency, _, _ = strings.Cut(header, ",")
+		currency, _, _ = strings.Cut(currency, ";")
+	}
+
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency != "" && !internal.ValidCurrency(currency) {
+		return "", internal.NewInvalidMoneyError(currency)
+	}
+	return currency, nil
+}
@@ -0,0 +1,161 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+var rateTime = time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+
+func newExchangeService() *service.ExchangeDefault {
+	return service.NewExchangeDefault(repository.NewExchangeRateMapDB(), func() time.Time { return rateTime })
+}
+
+func TestExchangeRatesCRUD(t *testing.T) {
+	hd := handler.NewDefaultExchangeRates(newExchangeService())
+
+	do := func(method, base, quote, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/exchange-rates/"+base+"/"+quote, strings.NewReader(body))
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("base", base)
+		chiCtx.URLParams.Add("quote", quote)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		res := httptest.NewRecorder()
+		h(res, req)
+		return res
+	}
+
+	res := do("PUT", "USD", "EUR", `{"rate": "0.9215"}`, hd.PutExchangeRate())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `{"base": "USD", "quote": "EUR", "rate": "0.9215", "updated_at": "2024-03-10T09:30:00Z"}`, res.Body.String())
+
+	// rates may be numbers, currencies are not case sensitive
+	res = do("PUT", "usd", "jpy", `{"rate": 151.25}`, hd.PutExchangeRate())
+	require.Equal(t, http.StatusOK, res.Code)
+
+	for _, body := range []string{`{"rate": "-1"}`, `{"rate": "abc"}`, `{}`} {
+		res = do("PUT", "USD", "GBP", body, hd.PutExchangeRate())
+		require.Equal(t, http.StatusBadRequest, res.Code, body)
+	}
+
+	res = do("GET", "", "", "", hd.GetAllExchangeRates())
+	require.Equal(t, http.StatusOK, res.Code)
+	var rates []internal.ExchangeRate
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&rates))
+	require.Len(t, rates, 2)
+	require.Equal(t, "EUR", rates[0].Quote)
+	require.Equal(t, "JPY", rates[1].Quote)
+
+	res = do("DELETE", "USD", "JPY", "", hd.DeleteExchangeRate())
+	require.Equal(t, http.StatusNoContent, res.Code)
+	res = do("DELETE", "USD", "JPY", "", hd.DeleteExchangeRate())
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestGetProductsInCurrency(t *testing.T) {
+	es := newExchangeService()
+	_, err := es.Put(context.Background(), "USD", "EUR", "0.9")
+	require.NoError(t, err)
+
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(250), IsPublished: true},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 1}
+	hd := handler.NewDefaultProducts(service.NewProductDefault(&db, service.ProductOptions{}), handler.ProductOptions{Exchange: es})
+
+	get := func(target, acceptCurrency string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		if acceptCurrency != "" {
+			req.Header.Set("Accept-Currency", acceptCurrency)
+		}
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("id", "1")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		res := httptest.NewRecorder()
+		h(res, req)
+		return res
+	}
+
+	converted := `{
+		"id": 1, "name": "p1", "quantity": 1, "code_value": "c1", "is_published": true, "expiration": "",
+		"price": {"amount": "2.50", "currency": "USD"},
+		"converted_price": {
+			"original": {"amount": "2.50", "currency": "USD"},
+			"converted": {"amount": "2.25", "currency": "EUR"},
+			"rate": "0.9",
+			"rate_timestamp": "2024-03-10T09:30:00Z"
+		}
+	}`
+
+	res := get("/products/1?currency=eur", "", hd.GetProductById())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, converted, res.Body.String())
+
+	res = get("/products", "EUR;q=1, USD;q=0.5", hd.GetAllProducts())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `{"items": [`+converted+`], "total": 1}`, res.Body.String())
+	require.Equal(t, "Accept-Currency", res.Header().Get("Vary"))
+
+	// no rate to JPY, and no such currency
+	for _, target := range []string{"/products/1?currency=JPY", "/products/1?currency=EURO"} {
+		res = get(target, "", hd.GetProductById())
+		require.Equal(t, http.StatusBadRequest, res.Code, target)
+	}
+
+	// without a currency the stored price is returned as is
+	res = get("/products/1", "", hd.GetProductById())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NotContains(t, res.Body.String(), "converted_price")
+}
+
+func TestQuoteCartInCurrency(t *testing.T) {
+	es := newExchangeService()
+	_, err := es.Put(context.Background(), "USD", "EUR", "0.9")
+	require.NoError(t, err)
+
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Price: usd(150), IsPublished: true},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 1}
+	hd := handler.NewDefaultCart(service.NewCartDefault(&db, service.CartOptions{Exchange: es}))
+
+	req := httptest.NewRequest("POST", "/cart/quote?currency=EUR", strings.NewReader(`{"items": [{"product_id": 1, "quantity": 2}], "currency": "USD"}`))
+	res := httptest.NewRecorder()
+	hd.QuoteCart()(res, req)
+
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `{
+		"lines": [{
+			"line": 0, "product_id": 1, "code_value": "c1", "name": "Milk", "quantity": 2,
+			"unit_price": {"amount": "1.35", "currency": "EUR"},
+			"conversion": {
+				"original": {"amount": "1.50", "currency": "USD"},
+				"converted": {"amount": "1.35", "currency": "EUR"},
+				"rate": "0.9",
+				"rate_timestamp": "2024-03-10T09:30:00Z"
+			},
+			"subtotal": {"amount": "2.70", "currency": "EUR"},
+			"discount": {"amount": "0.00", "currency": "EUR"},
+			"total": {"amount": "2.70", "currency": "EUR"}
+		}],
+		"rejected": [],
+		"discounts": [],
+		"item_count": 2,
+		"subtotal": {"amount": "2.70", "currency": "EUR"},
+		"discount": {"amount": "0.00", "currency": "EUR"},
+		"total": {"amount": "2.70", "currency": "EUR"}
+	}`, res.Body.String())
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+)
+
+// defaultExpiringWithin is the window of GetExpiringProducts without a
+// within query parameter.
+const defaultExpiringWithin = 7 * 24 * time.Hour
+
+type DefaultExpirations struct {
+	es internal.ExpirationService
+}
+
+func NewDefaultExpirations(es internal.ExpirationService) *DefaultExpirations {
+	return &DefaultExpirations{es: es}
+}
+
+// GetExpiringProducts lists the products expiring within the time of the
+// within query parameter, a number of days such as 7d or a duration such as
+// 36h, a week when it is missing. Expired products are listed too.
+func (ec *DefaultExpirations) GetExpiringProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		within := defaultExpiringWithin
+		if value := req.URL.Query().Get("within"); value != "" {
+			var err error
+			if within, err = internal.ParsePeriod(value); err != nil {
+				response.Error(w, http.StatusBadRequest, "invalid within")
+				return
+			}
+		}
+
+		products, err := ec.es.Expiring(req.Context(), within)
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "error retrieving expiring products")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(products)
+	}
+}
@@ -0,0 +1,50 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetExpiringProducts(t *testing.T) {
+	db := repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Expiration: "12/03/2024", Price: usd(150), IsPublished: true},
+		2: {Id: 2, Name: "Rice", Quantity: 5, Code: "c2", Expiration: "20/03/2024", Price: usd(150), IsPublished: true},
+	}, LastID: 2}
+	now := func() time.Time { return time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC) }
+	hd := handler.NewDefaultExpirations(service.NewExpirationDefault(&db, now))
+
+	for target, ids := range map[string][]int{
+		"/products/expiring":            {1},
+		"/products/expiring?within=1d":  {},
+		"/products/expiring?within=48h": {1},
+		"/products/expiring?within=10d": {1, 2},
+	} {
+		res := httptest.NewRecorder()
+		hd.GetExpiringProducts()(res, httptest.NewRequest("GET", target, nil))
+		require.Equal(t, http.StatusOK, res.Code, target)
+
+		var products []internal.ExpiringProduct
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&products))
+		got := []int{}
+		for _, p := range products {
+			got = append(got, p.Id)
+		}
+		require.Equal(t, ids, got, target)
+	}
+
+	for _, within := range []string{"-1d", "soon", "d"} {
+		res := httptest.NewRecorder()
+		hd.GetExpiringProducts()(res, httptest.NewRequest("GET", "/products/expiring?within="+within, nil))
+		require.Equal(t, http.StatusBadRequest, res.Code, within)
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type DefaultLocations struct {
+	ls internal.LocationService
+}
+
+func NewDefaultLocations(ls internal.LocationService) *DefaultLocations {
+	return &DefaultLocations{ls: ls}
+}
+
+func (lc *DefaultLocations) GetAllLocations() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		locations, err := lc.ls.GetAll(req.Context())
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "error retrieving locations")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(locations)
+	}
+}
+
+func (lc *DefaultLocations) GetLocationById() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		location, err := lc.ls.GetById(req.Context(), id)
+		if err != nil {
+			writeLocationError(w, err, "error retrieving location")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(location)
+	}
+}
+
+func (lc *DefaultLocations) AddLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var location internal.Location
+		if err := json.NewDecoder(req.Body).Decode(&location); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+
+		location, err := lc.ls.Save(req.Context(), location)
+		if err != nil {
+			writeLocationError(w, err, "error saving location")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(location)
+	}
+}
+
+// UpdateLocation replaces the location of the id path parameter with the one
+// in the request body.
+func (lc *DefaultLocations) UpdateLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var location internal.Location
+		if err := json.NewDecoder(req.Body).Decode(&location); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		location.Id = id
+
+		location, err = lc.ls.Update(req.Context(), location)
+		if err != nil {
+			writeLocationError(w, err, "error updating location")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(location)
+	}
+}
+
+func (lc *DefaultLocations) DeleteLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		if err := lc.ls.Delete(req.Context(), id); err != nil {
+			writeLocationError(w, err, "error deleting location")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeLocationError responds with the status matching err, or with message
+// when it is unexpected.
+func writeLocationError(w http.ResponseWriter, err error, message string) {
+	var (
+		invalid internal.InvalidLocationError
+		inUse   internal.LocationInUseError
+	)
+	switch {
+	case errors.As(err, &invalid):
+		response.Error(w, http.StatusBadRequest, invalid.Error())
+	case errors.As(err, &inUse):
+		response.Error(w, http.StatusConflict, inUse.Error())
+	case errors.As(err, &internal.LocationNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "location not found")
+	default:
+		response.Error(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,105 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocationEndpoints(t *testing.T) {
+	db := repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Price: usd(150), IsPublished: true, Version: 1},
+	}, LastID: 1}
+	ledger, locations := repository.NewStockMapDB(), repository.NewLocationMapDB()
+	stock := service.NewStockDefault(&db, ledger, locations, nil)
+	lc := handler.NewDefaultLocations(service.NewLocationDefault(locations, ledger))
+	st := handler.NewDefaultStock(stock)
+	pc := handler.NewDefaultProducts(service.NewProductDefault(&db, service.ProductOptions{}), handler.ProductOptions{Stock: stock})
+
+	do := func(method, id, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/locations", strings.NewReader(body))
+		if id != "" {
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		}
+		res := httptest.NewRecorder()
+		h(res, req)
+		return res
+	}
+
+	res := do("POST", "", `{"name": "Back room", "kind": "warehouse"}`, lc.AddLocation())
+	require.Equal(t, http.StatusCreated, res.Code)
+	var warehouse internal.Location
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&warehouse))
+	id := strconv.Itoa(warehouse.Id)
+
+	res = do("GET", "", "", lc.GetAllLocations())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `[
+		{"id": 1, "name": "Main store", "kind": "store"},
+		{"id": 2, "name": "Back room", "kind": "warehouse"}
+	]`, res.Body.String())
+
+	res = do("POST", "1", `{"from_location_id": 1, "to_location_id": 2, "quantity": 2}`, st.AddStockTransfer())
+	require.Equal(t, http.StatusCreated, res.Code)
+	for body, code := range map[string]int{
+		`{"from_location_id": 1, "to_location_id": 2, "quantity": 4}`: http.StatusConflict,
+		`{"from_location_id": 1, "to_location_id": 1, "quantity": 1}`: http.StatusBadRequest,
+		`{"from_location_id": 1, "to_location_id": 9, "quantity": 1}`: http.StatusNotFound,
+	} {
+		res = do("POST", "1", body, st.AddStockTransfer())
+		require.Equal(t, code, res.Code, body)
+	}
+
+	req := httptest.NewRequest("GET", "/products/1?include=stock", nil)
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("id", "1")
+	res = httptest.NewRecorder()
+	pc.GetProductById()(res, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx)))
+	require.Equal(t, http.StatusOK, res.Code)
+	var product struct {
+		Quantity int                 `json:"quantity"`
+		Stock    internal.StockLevel `json:"stock"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&product))
+	require.Equal(t, 5, product.Quantity)
+	require.Equal(t, []internal.StockLevel{
+		{ProductId: 1, LocationId: 1, OnHand: 3, Available: 3},
+		{ProductId: 1, LocationId: 2, OnHand: 2, Available: 2},
+	}, product.Stock.Locations)
+
+	req = httptest.NewRequest("GET", "/stock/1?location_id=2", nil)
+	res = httptest.NewRecorder()
+	st.GetStockLevel()(res, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx)))
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `{"product_id": 1, "location_id": 2, "on_hand": 2, "reserved": 0, "available": 2}`, res.Body.String())
+
+	res = do("DELETE", id, "", lc.DeleteLocation())
+	require.Equal(t, http.StatusConflict, res.Code)
+	res = do("DELETE", "1", "", lc.DeleteLocation())
+	require.Equal(t, http.StatusConflict, res.Code)
+	res = do("PUT", id, `{"name": "", "kind": "warehouse"}`, lc.UpdateLocation())
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	res = do("PUT", id, `{"name": "Cellar", "kind": "warehouse"}`, lc.UpdateLocation())
+	require.Equal(t, http.StatusOK, res.Code)
+
+	res = do("POST", "1", `{"from_location_id": 2, "to_location_id": 1, "quantity": 2}`, st.AddStockTransfer())
+	require.Equal(t, http.StatusCreated, res.Code)
+	res = do("DELETE", id, "", lc.DeleteLocation())
+	require.Equal(t, http.StatusNoContent, res.Code)
+	res = do("GET", id, "", lc.GetLocationById())
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
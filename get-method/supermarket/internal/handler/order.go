@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type DefaultOrders struct {
+	os internal.OrderService
+}
+
+func NewDefaultOrders(os internal.OrderService) *DefaultOrders {
+	return &DefaultOrders{os: os}
+}
+
+// Checkout orders the cart in the request body, with the currency override
+// of QuoteCart. Carts with lines that can not be bought are not ordered.
+func (oc *DefaultOrders) Checkout() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var cart internal.Cart
+		if err := json.NewDecoder(req.Body).Decode(&cart); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		currency, err := targetCurrency(req)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "unknown currency")
+			return
+		}
+		if currency != "" {
+			cart.Currency = currency
+		}
+
+		order, err := oc.os.Checkout(req.Context(), cart)
+		if err != nil {
+			writeOrderError(w, err, "error checking out cart")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+// GetAllOrders lists the orders, only those in the status query parameter
+// when it is given.
+func (oc *DefaultOrders) GetAllOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		status := internal.OrderStatus(req.URL.Query().Get("status"))
+		if status != "" && !status.Valid() {
+			response.Error(w, http.StatusBadRequest, "unknown order status")
+			return
+		}
+
+		orders, err := oc.os.GetAll(req.Context(), status)
+		if err != nil {
+			writeOrderError(w, err, "error retrieving orders")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(orders)
+	}
+}
+
+func (oc *DefaultOrders) GetOrderById() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		order, err := oc.os.GetById(req.Context(), id)
+		if err != nil {
+			writeOrderError(w, err, "error retrieving order")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+type orderStatusBody struct {
+	Status internal.OrderStatus `json:"status"`
+}
+
+// UpdateOrderStatus moves the order of the id path parameter to the status
+// in the request body.
+func (oc *DefaultOrders) UpdateOrderStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var body orderStatusBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		if !body.Status.Valid() {
+			response.Error(w, http.StatusBadRequest, "unknown order status")
+			return
+		}
+
+		order, err := oc.os.Transition(req.Context(), id, body.Status)
+		if err != nil {
+			writeOrderError(w, err, "error updating order")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+// writeOrderError responds with the status matching err, or with message
+// when it is unexpected.
+func writeOrderError(w http.ResponseWriter, err error, message string) {
+	var (
+		invalidCart  internal.InvalidCartError
+		rejected     internal.CheckoutRejectedError
+		insufficient internal.InsufficientStockError
+		transition   internal.InvalidOrderTransitionError
+	)
+	switch {
+	case errors.As(err, &invalidCart):
+		response.Error(w, http.StatusBadRequest, invalidCart.Error())
+	case errors.As(err, &rejected):
+		response.Error(w, http.StatusConflict, rejected.Error())
+	case errors.As(err, &insufficient):
+		response.Error(w, http.StatusConflict, insufficient.Error())
+	case errors.As(err, &transition):
+		response.Error(w, http.StatusConflict, transition.Error())
+	case errors.As(err, &internal.OrderNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "order not found")
+	default:
+		response.Error(w, http.StatusInternalServerError, message)
+	}
+}
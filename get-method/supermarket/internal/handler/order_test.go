@@ -0,0 +1,84 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderEndpoints(t *testing.T) {
+	db := repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Price: usd(150), IsPublished: true},
+	}, LastID: 1}
+	orders := service.NewOrderDefault(
+		service.NewCartDefault(&db, service.CartOptions{}),
+		service.NewStockDefault(&db, repository.NewStockMapDB(), nil, nil),
+		repository.NewOrderMapDB(),
+		nil,
+	)
+	hd := handler.NewDefaultOrders(orders)
+
+	do := func(method, target, id, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if id != "" {
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		}
+		res := httptest.NewRecorder()
+		h(res, req)
+		return res
+	}
+
+	res := do("POST", "/orders", "", `{"items": [{"product_id": 1, "quantity": 2}]}`, hd.Checkout())
+	require.Equal(t, http.StatusCreated, res.Code)
+	var order internal.Order
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&order))
+	require.Equal(t, internal.OrderPending, order.Status)
+	require.Equal(t, usd(300), order.Receipt.Total)
+
+	for body, code := range map[string]int{
+		`{"items": [{"product_id": 1, "quantity": 4}]}`: http.StatusConflict,
+		`{"items": []}`: http.StatusBadRequest,
+		`{`:             http.StatusBadRequest,
+	} {
+		res = do("POST", "/orders", "", body, hd.Checkout())
+		require.Equal(t, code, res.Code, body)
+	}
+
+	res = do("GET", "/orders/1", "1", "", hd.GetOrderById())
+	require.Equal(t, http.StatusOK, res.Code)
+	res = do("GET", "/orders/9", "9", "", hd.GetOrderById())
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	for body, code := range map[string]int{
+		`{"status": "fulfilled"}`: http.StatusConflict,
+		`{"status": "lost"}`:      http.StatusBadRequest,
+	} {
+		res = do("PUT", "/orders/1/status", "1", body, hd.UpdateOrderStatus())
+		require.Equal(t, code, res.Code, body)
+	}
+	res = do("PUT", "/orders/1/status", "1", `{"status": "cancelled"}`, hd.UpdateOrderStatus())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, 5, db.Products[1].Quantity)
+
+	res = do("GET", "/orders?status=cancelled", "", "", hd.GetAllOrders())
+	require.Equal(t, http.StatusOK, res.Code)
+	var listed []internal.Order
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&listed))
+	require.Len(t, listed, 1)
+
+	res = do("GET", "/orders?status=lost", "", "", hd.GetAllOrders())
+	require.Equal(t, http.StatusBadRequest, res.Code)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type DefaultPriceHistory struct {
+	hs internal.PriceHistoryService
+}
+
+func NewDefaultPriceHistory(hs internal.PriceHistoryService) *DefaultPriceHistory {
+	return &DefaultPriceHistory{hs: hs}
+}
+
+// GetPriceHistory lists the price changes of the product of the id path
+// parameter, oldest effective first, the scheduled ones included.
+func (hc *DefaultPriceHistory) GetPriceHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		history, err := hc.hs.History(req.Context(), id)
+		if err != nil {
+			writePriceHistoryError(w, err, "error retrieving price history")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(history)
+	}
+}
+
+// priceChangeBody is the body of scheduled price changes.
+type priceChangeBody struct {
+	Price         internal.Money `json:"price"`
+	EffectiveFrom time.Time      `json:"effective_from"`
+}
+
+// SchedulePriceChange plans a change of the price of the product of the id
+// path parameter.
+func (hc *DefaultPriceHistory) SchedulePriceChange() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var body priceChangeBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+
+		change, err := hc.hs.Schedule(req.Context(), internal.PriceChange{ProductId: id, Price: body.Price, EffectiveFrom: body.EffectiveFrom})
+		if err != nil {
+			writePriceHistoryError(w, err, "error scheduling price change")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(change)
+	}
+}
+
+// CancelPriceChange deletes the scheduled change of the change_id path
+// parameter of the product of the id one.
+func (hc *DefaultPriceHistory) CancelPriceChange() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+		changeId, err := strconv.Atoi(chi.URLParam(req, "change_id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing change_id")
+			return
+		}
+
+		if err := hc.hs.Cancel(req.Context(), id, changeId); err != nil {
+			writePriceHistoryError(w, err, "error cancelling price change")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writePriceHistoryError responds with the status matching err, or with
+// message when it is unexpected.
+func writePriceHistoryError(w http.ResponseWriter, err error, message string) {
+	var invalid internal.InvalidPriceChangeError
+	switch {
+	case errors.As(err, &invalid):
+		response.Error(w, http.StatusBadRequest, invalid.Error())
+	case errors.As(err, &internal.PriceChangeNotPendingError{}):
+		response.Error(w, http.StatusConflict, "price change already took effect")
+	case errors.As(err, &internal.ProductNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "product not found")
+	case errors.As(err, &internal.PriceChangeNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "price change not found")
+	default:
+		response.Error(w, http.StatusInternalServerError, message)
+	}
+}
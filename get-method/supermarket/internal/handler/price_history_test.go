@@ -0,0 +1,80 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceHistoryEndpoints(t *testing.T) {
+	db := repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Price: usd(150), IsPublished: true, Version: 1},
+	}, LastID: 1}
+	now := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	history := repository.NewPriceHistoryMapDB()
+	ps := service.NewProductDefault(&db, service.ProductOptions{History: history, Now: func() time.Time { return now }})
+	hd := handler.NewDefaultPriceHistory(service.NewPriceHistoryDefault(&db, history, func() time.Time { return now }))
+
+	do := func(method, id, changeId, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/products/"+id+"/prices", strings.NewReader(body))
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("id", id)
+		if changeId != "" {
+			chiCtx.URLParams.Add("change_id", changeId)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		res := httptest.NewRecorder()
+		h(res, req)
+		return res
+	}
+
+	_, err := ps.PartialUpdate(context.Background(), 1, internal.Product{Price: usd(120)})
+	require.NoError(t, err)
+
+	res := do("POST", "1", "", `{"price": {"amount": 99, "currency": "USD"}, "effective_from": "2024-03-11T00:00:00Z"}`, hd.SchedulePriceChange())
+	require.Equal(t, http.StatusCreated, res.Code)
+	var scheduled internal.PriceChange
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&scheduled))
+	require.True(t, scheduled.Pending)
+
+	res = do("GET", "1", "", "", hd.GetPriceHistory())
+	require.Equal(t, http.StatusOK, res.Code)
+	var changes []internal.PriceChange
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&changes))
+	require.Len(t, changes, 2)
+	require.Equal(t, usd(150), *changes[0].Previous)
+	require.Equal(t, scheduled.Id, changes[1].Id)
+
+	for body, code := range map[string]int{
+		`{"price": {"amount": 99, "currency": "USD"}, "effective_from": "2024-03-01T00:00:00Z"}`: http.StatusBadRequest,
+		`{"price": {"amount": 0, "currency": "USD"}, "effective_from": "2024-03-11T00:00:00Z"}`:  http.StatusBadRequest,
+		`{"price": {"amount": 99, "currency": "USD"}}`:                                           http.StatusBadRequest,
+		`{"price": 99}`: http.StatusBadRequest,
+	} {
+		res = do("POST", "1", "", body, hd.SchedulePriceChange())
+		require.Equal(t, code, res.Code, body)
+	}
+	res = do("GET", "9", "", "", hd.GetPriceHistory())
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	id := strconv.Itoa(scheduled.Id)
+	res = do("DELETE", "1", strconv.Itoa(changes[0].Id), "", hd.CancelPriceChange())
+	require.Equal(t, http.StatusConflict, res.Code)
+	res = do("DELETE", "1", id, "", hd.CancelPriceChange())
+	require.Equal(t, http.StatusNoContent, res.Code)
+	res = do("DELETE", "1", id, "", hd.CancelPriceChange())
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
@@ -16,10 +16,22 @@ import (
 
 type DefaultProducts struct {
 	ps internal.ProductService
+	es internal.ExchangeService
+	ss internal.StockService
 }
 
-func NewDefaultProducts(ps internal.ProductService) *DefaultProducts {
-	return &DefaultProducts{ps: ps}
+// ProductOptions are the optional dependencies of DefaultProducts.
+type ProductOptions struct {
+	// Exchange converts the prices to the currency requested, none can be
+	// requested without it.
+	Exchange internal.ExchangeService
+	// Stock tells the stock of the products, which are served without it
+	// otherwise.
+	Stock internal.StockService
+}
+
+func NewDefaultProducts(ps internal.ProductService, opts ProductOptions) *DefaultProducts {
+	return &DefaultProducts{ps: ps, es: opts.Exchange, ss: opts.Stock}
 }
 
 func (pc *DefaultProducts) AddProduct() http.HandlerFunc {
@@ -35,38 +47,203 @@ func (pc *DefaultProducts) AddProduct() http.HandlerFunc {
 			return
 		}
 
-		productExists, err := pc.ps.CheckUniqueCode(product.Code)
+		product, err := pc.ps.Save(req.Context(), product)
 		if err != nil {
-			response.Error(w, http.StatusInternalServerError, "error retrieving product by code")
-			return
-		}
-
-		if !productExists {
-			response.Error(w, http.StatusConflict, "product already exists")
+			if errors.As(err, &internal.ProductAlreadyExistsError{}) {
+				response.Error(w, http.StatusConflict, "product already exists")
+				return
+			}
+			var invalid internal.InvalidProductError
+			if errors.As(err, &invalid) {
+				response.Error(w, http.StatusBadRequest, "invalid product: "+invalid.Field)
+				return
+			}
+			response.Error(w, http.StatusInternalServerError, "error saving product")
 			return
 		}
 
-		product = pc.ps.Save(product)
-
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", etag(product.Version))
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(product)
 	}
 }
 
+// GetAllProducts lists the products a page at a time. Pages are selected
+// with the limit and either the offset or the cursor query parameters, and
+// sorted by the sort parameter in the direction of order, asc or desc.
+// Prices are converted to the currency requested, if any, see
+// convertedProduct.
 func (pc *DefaultProducts) GetAllProducts() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		products, err := pc.ps.GetAll()
+		query, err := parsePageQuery(req)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid page query")
+			return
+		}
+		currency, ok := pc.requestedCurrency(w, req)
+		if !ok {
+			return
+		}
+
+		page, err := pc.ps.GetPage(req.Context(), query)
 		if err != nil {
+			if errors.As(err, &internal.InvalidPageQueryError{}) {
+				response.Error(w, http.StatusBadRequest, "invalid page query")
+				return
+			}
 			response.Error(w, http.StatusInternalServerError, "error retrieving products")
 			return
 		}
+
+		var body any = page
+		if currency != "" {
+			converted := convertedPage{Items: make([]convertedProduct, 0, len(page.Items)), NextCursor: page.NextCursor, Total: page.Total}
+			for _, product := range page.Items {
+				item, ok := pc.convert(w, req, product, currency)
+				if !ok {
+					return
+				}
+				converted.Items = append(converted.Items, item)
+			}
+			body = converted
+			w.Header().Set("Vary", "Accept-Currency")
+		}
 		w.Header().Set("Content-type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(products)
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// convertedProduct is a product along with its price converted to the
+// currency requested, the rate used and when the rate was set.
+type convertedProduct struct {
+	internal.Product
+	ConvertedPrice internal.PriceConversion `json:"converted_price"`
+}
+
+// productDetail is a product along with its price converted to the currency
+// requested, if any, and its stock broken down by location when the stock
+// is known.
+type productDetail struct {
+	internal.Product
+	ConvertedPrice *internal.PriceConversion `json:"converted_price,omitempty"`
+	Stock          *internal.StockLevel      `json:"stock,omitempty"`
+}
+
+// convertedPage is a page of products with converted prices.
+type convertedPage struct {
+	Items      []convertedProduct `json:"items"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	Total      int                `json:"total"`
+}
+
+// requestedCurrency returns the currency prices are requested in, see
+// targetCurrency. It responds with an error, and reports false, when the
+// currency is malformed or prices can not be converted.
+func (pc *DefaultProducts) requestedCurrency(w http.ResponseWriter, req *http.Request) (string, bool) {
+	currency, err := targetCurrency(req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "unknown currency")
+		return "", false
+	}
+	if currency != "" && pc.es == nil {
+		response.Error(w, http.StatusBadRequest, "currency conversion is not available")
+		return "", false
+	}
+	return currency, true
+}
+
+// convert converts the price of product to currency. It responds with an
+// error, and reports false, when it can not.
+func (pc *DefaultProducts) convert(w http.ResponseWriter, req *http.Request, product internal.Product, currency string) (convertedProduct, bool) {
+	conversion, err := pc.es.Convert(req.Context(), product.Price, currency)
+	if err != nil {
+		if errors.As(err, &internal.ExchangeRateNotFoundError{}) {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return convertedProduct{}, false
+		}
+		response.Error(w, http.StatusInternalServerError, "error converting price")
+		return convertedProduct{}, false
+	}
+	return convertedProduct{Product: product, ConvertedPrice: conversion}, true
+}
+
+// searchResults is the body of name search responses.
+type searchResults struct {
+	Items []internal.ProductSearchResult `json:"items"`
+	Total int                            `json:"total"`
+}
+
+// searchProducts responds with the products whose names best match the
+// words of the q query parameter, most relevant first and at most limit of
+// them.
+func (pc *DefaultProducts) searchProducts(w http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	if params.Has("filter") || params.Has("priceGT") {
+		response.Error(w, http.StatusBadRequest, "q can not be combined with filters")
+		return
+	}
+
+	var limit int
+	if param := params.Get("limit"); param != "" {
+		var err error
+		if limit, err = strconv.Atoi(param); err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing limit")
+			return
+		}
 	}
+
+	results, total, err := pc.ps.Search(req.Context(), params.Get("q"), limit)
+	if err != nil {
+		if errors.As(err, &internal.InvalidPageQueryError{}) {
+			response.Error(w, http.StatusBadRequest, "invalid search query")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "error searching products")
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(searchResults{Items: results, Total: total})
 }
 
+func parsePageQuery(req *http.Request) (internal.ProductPageQuery, error) {
+	params := req.URL.Query()
+	query := internal.ProductPageQuery{
+		SortBy: internal.ProductSortField(params.Get("sort")),
+		Cursor: params.Get("cursor"),
+	}
+
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, internal.NewInvalidPageQueryError("order")
+	}
+
+	var err error
+	if param := params.Get("limit"); param != "" {
+		if query.Limit, err = strconv.Atoi(param); err != nil {
+			return query, internal.NewInvalidPageQueryError("limit")
+		}
+	}
+	if param := params.Get("offset"); param != "" {
+		if query.Offset, err = strconv.Atoi(param); err != nil {
+			return query, internal.NewInvalidPageQueryError("offset")
+		}
+	}
+	return query, nil
+}
+
+// GetProductById responds with a product, its price converted to the
+// currency requested, if any, and its stock at each location with
+// include=stock, when the stock is known. Converted responses are never
+// answered with Not Modified, as the rate may have changed since. The ones
+// with the stock have no ETag, as the stock changes without the version of
+// the product.
 func (pc *DefaultProducts) GetProductById() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		id, err := strconv.Atoi(chi.URLParam(req, "id"))
@@ -74,43 +251,114 @@ func (pc *DefaultProducts) GetProductById() http.HandlerFunc {
 			response.Error(w, http.StatusBadRequest, "error parsing id")
 			return
 		}
+		currency, ok := pc.requestedCurrency(w, req)
+		if !ok {
+			return
+		}
 
-		product, err := pc.ps.GetById(id)
+		product, err := pc.ps.GetById(req.Context(), id)
 		if err != nil {
 			response.Error(w, http.StatusNotFound, "product not found")
 			return
 		}
 
+		body := productDetail{Product: product}
+		if currency != "" {
+			converted, ok := pc.convert(w, req, product, currency)
+			if !ok {
+				return
+			}
+			body.ConvertedPrice = &converted.ConvertedPrice
+			w.Header().Set("Vary", "Accept-Currency")
+		}
+		withStock := pc.ss != nil && req.URL.Query().Get("include") == "stock"
+		if withStock {
+			level, err := pc.ss.Level(req.Context(), product.Id)
+			if err != nil {
+				if errors.As(err, &internal.ProductNotFoundError{}) {
+					response.Error(w, http.StatusNotFound, "product not found")
+					return
+				}
+				response.Error(w, http.StatusInternalServerError, "error retrieving stock")
+				return
+			}
+			body.Stock = &level
+		}
+
+		if !withStock {
+			w.Header().Set("ETag", etag(product.Version))
+			if currency == "" && ifNoneMatch(req, product.Version) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
 		w.Header().Set("Content-type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(product)
+		json.NewEncoder(w).Encode(body)
 
 	}
 }
 
+// GetProductsFiltered lists a page of the products matching the filter query
+// parameter, see internal.ParseProductFilter for its syntax. The legacy
+// priceGT parameter is still understood and combined with it.
+// With a q parameter it searches product names for its words instead, see
+// searchProducts.
 func (pc *DefaultProducts) GetProductsFiltered() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		param := req.URL.Query().Get("priceGT")
-		if param == "" {
-			response.Error(w, http.StatusBadRequest, "priceGT value was not set")
+		if req.URL.Query().Has("q") {
+			pc.searchProducts(w, req)
 			return
 		}
 
-		price, err := strconv.ParseFloat(param, 64)
+		query, err := parsePageQuery(req)
 		if err != nil {
-			response.Error(w, http.StatusBadRequest, "error parsing priceGT value")
+			response.Error(w, http.StatusBadRequest, "invalid page query")
 			return
 		}
 
-		products, err := pc.ps.GetByGreaterPrice(price)
+		var filters internal.AndFilter
+		if param := req.URL.Query().Get("filter"); param != "" {
+			filter, err := internal.ParseProductFilter(param)
+			if err != nil {
+				response.Error(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			filters = append(filters, filter)
+		}
+		if param := req.URL.Query().Get("priceGT"); param != "" {
+			price, err := internal.ParseMoney(param, internal.DefaultCurrency)
+			if err != nil {
+				response.Error(w, http.StatusBadRequest, "error parsing priceGT value")
+				return
+			}
+			filters = append(filters, internal.PriceFilter{Op: internal.OpGt, Value: price})
+		}
+
+		switch len(filters) {
+		case 0:
+			response.Error(w, http.StatusBadRequest, "filter value was not set")
+			return
+		case 1:
+			query.Filter = filters[0]
+		default:
+			query.Filter = filters
+		}
+
+		page, err := pc.ps.GetPage(req.Context(), query)
 		if err != nil {
+			if errors.As(err, &internal.InvalidPageQueryError{}) || errors.As(err, &internal.InvalidFilterError{}) {
+				response.Error(w, http.StatusBadRequest, err.Error())
+				return
+			}
 			response.Error(w, http.StatusInternalServerError, "error retrieving products")
 			return
 		}
 
 		w.Header().Set("Content-type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(products)
+		json.NewEncoder(w).Encode(page)
 	}
 }
 
@@ -122,13 +370,32 @@ func (pc *DefaultProducts) UpdateOrCreateProduct() http.HandlerFunc {
 			return
 		}
 
-		updatedProduct, err := pc.ps.UpdateOrCreate(product)
+		// the version is assigned by the server, clients state the one they
+		// expect through If-Match
+		version, ok := ifMatchVersion(req)
+		if !ok {
+			response.Error(w, http.StatusPreconditionFailed, "product version mismatch")
+			return
+		}
+		product.Version = version
+
+		updatedProduct, err := pc.ps.UpdateOrCreate(req.Context(), product)
 		if err != nil {
+			if errors.As(err, &internal.ProductVersionMismatchError{}) {
+				response.Error(w, http.StatusPreconditionFailed, "product version mismatch")
+				return
+			}
+			var invalid internal.InvalidProductError
+			if errors.As(err, &invalid) {
+				response.Error(w, http.StatusBadRequest, "invalid product: "+invalid.Field)
+				return
+			}
 			response.Error(w, http.StatusInternalServerError, "error updating or creating product")
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", etag(updatedProduct.Version))
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(updatedProduct)
 	}
@@ -148,15 +415,32 @@ func (pc *DefaultProducts) PartialProductUpdate() http.HandlerFunc {
 			return
 		}
 
+		version, ok := ifMatchVersion(req)
+		if !ok {
+			response.Error(w, http.StatusPreconditionFailed, "product version mismatch")
+			return
+		}
+
 		product.Id = id
+		product.Version = version
 
-		updatedProduct, err := pc.ps.PartialUpdate(id, product)
+		updatedProduct, err := pc.ps.PartialUpdate(req.Context(), id, product)
 		if err != nil {
+			if errors.As(err, &internal.ProductVersionMismatchError{}) {
+				response.Error(w, http.StatusPreconditionFailed, "product version mismatch")
+				return
+			}
+			var invalid internal.InvalidProductError
+			if errors.As(err, &invalid) {
+				response.Error(w, http.StatusBadRequest, "invalid product: "+invalid.Field)
+				return
+			}
 			response.Error(w, http.StatusInternalServerError, "error updating product")
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", etag(updatedProduct.Version))
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(updatedProduct)
 	}
@@ -170,12 +454,22 @@ func (pc *DefaultProducts) DeleteProduct() http.HandlerFunc {
 			return
 		}
 
-		err = pc.ps.Delete(id)
+		version, ok := ifMatchVersion(req)
+		if !ok {
+			response.Error(w, http.StatusPreconditionFailed, "product version mismatch")
+			return
+		}
+
+		err = pc.ps.Delete(req.Context(), id, version)
 		if err != nil {
 			if errors.As(err, &internal.ProductNotFoundError{}) {
 				response.Error(w, http.StatusNotFound, "product not found")
 				return
 			}
+			if errors.As(err, &internal.ProductVersionMismatchError{}) {
+				response.Error(w, http.StatusPreconditionFailed, "product version mismatch")
+				return
+			}
 			response.Error(w, http.StatusInternalServerError, "error deleting product")
 			return
 		}
@@ -184,27 +478,38 @@ func (pc *DefaultProducts) DeleteProduct() http.HandlerFunc {
 	}
 }
 
+// GetCartPrice responds with the sum of the prices of the products whose ids
+// the list query parameter holds, as in list=[1,2,3]. POST /cart/quote
+// supersedes it.
 func (pc *DefaultProducts) GetCartPrice() http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 
 		param := req.URL.Query().Get("list")
 		param = strings.Replace(param, "[", "", -1)
 		param = strings.Replace(param, "]", "", -1)
-		productIdsStr := strings.Split(param, ",")
-
-		productIds := make([]int, len(productIdsStr))
 
-		for _, id := range productIdsStr {
-			val, err := strconv.Atoi(id)
-			if err != nil {
-				response.Error(w, http.StatusBadRequest, "error parsing id")
+		var productIds []int
+		if strings.TrimSpace(param) != "" {
+			for _, id := range strings.Split(param, ",") {
+				val, err := strconv.Atoi(strings.TrimSpace(id))
+				if err != nil {
+					response.Error(w, http.StatusBadRequest, "error parsing id")
+					return
+				}
+				productIds = append(productIds, val)
 			}
-			productIds = append(productIds, val)
 		}
 
-		price, err := pc.ps.GetTotalPrice(productIds)
+		price, err := pc.ps.GetTotalPrice(req.Context(), productIds)
 		if err != nil {
-			response.Error(w, http.StatusInternalServerError, "error retrieving cart price")
+			switch {
+			case errors.As(err, &internal.InvalidCartError{}):
+				response.Error(w, http.StatusBadRequest, "product list is empty")
+			case errors.As(err, &internal.ProductNotFoundError{}):
+				response.Error(w, http.StatusNotFound, "product not found")
+			default:
+				response.Error(w, http.StatusInternalServerError, "error retrieving cart price")
+			}
 			return
 		}
 
@@ -3,30 +3,38 @@ package handler_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"supermarket/internal"
 	"supermarket/internal/handler"
 	"supermarket/internal/repository"
 	"supermarket/internal/service"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
 )
 
+// usd returns an amount of cents of the default currency.
+func usd(cents int64) internal.Money {
+	return internal.NewMoney(cents, internal.DefaultCurrency)
+}
+
 func TestGetAllProducts(t *testing.T) {
 	dbData := map[int]internal.Product{
-		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: 1, IsPublished: true},
-		2: {Id: 2, Name: "p2", Quantity: 2, Code: "c2", Price: 2, IsPublished: true},
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true},
+		2: {Id: 2, Name: "p2", Quantity: 2, Code: "c2", Price: usd(200), IsPublished: true},
 	}
 	db := repository.ProductMapDB{Products: dbData, LastID: 2}
-	sv := service.NewProductDefault(&db)
-	hd := handler.NewDefaultProducts(sv)
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
 
 	expectCode := http.StatusOK
-	expectBody, _ := json.Marshal(dbData)
+	expectBody, _ := json.Marshal(internal.ProductPage{Items: []internal.Product{dbData[1], dbData[2]}, Total: 2})
 	expectHeader := http.Header{"Content-Type": []string{"application/json"}}
 
 	req := httptest.NewRequest("GET", "/products", nil)
@@ -38,18 +46,73 @@ func TestGetAllProducts(t *testing.T) {
 	require.Equal(t, expectHeader, res.Header())
 }
 
+func TestGetAllProductsPaginated(t *testing.T) {
+	dbData := map[int]internal.Product{}
+	for id := 1; id <= 5; id++ {
+		dbData[id] = internal.Product{Id: id, Name: fmt.Sprintf("p%d", id), Quantity: id, Code: fmt.Sprintf("c%d", id), Price: usd(int64(6-id) * 100)}
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 5}
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
+
+	get := func(target string) (int, internal.ProductPage) {
+		req := httptest.NewRequest("GET", target, nil)
+		res := httptest.NewRecorder()
+		hd.GetAllProducts()(res, req)
+
+		var page internal.ProductPage
+		if res.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&page))
+		}
+		return res.Code, page
+	}
+
+	code, page := get("/products?sort=price&limit=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []internal.Product{dbData[5], dbData[4]}, page.Items)
+	require.Equal(t, 5, page.Total)
+
+	code, page = get("/products?sort=price&limit=2&cursor=" + page.NextCursor)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []internal.Product{dbData[3], dbData[2]}, page.Items)
+
+	code, page = get("/products?sort=price&limit=2&cursor=" + page.NextCursor)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []internal.Product{dbData[1]}, page.Items)
+	require.Empty(t, page.NextCursor)
+
+	code, page = get("/products?sort=name&order=desc&limit=2&offset=1")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []internal.Product{dbData[4], dbData[3]}, page.Items)
+
+	for _, target := range []string{
+		"/products?sort=code_value",
+		"/products?order=up",
+		"/products?limit=5000",
+		"/products?limit=ten",
+		"/products?offset=-1",
+		"/products?cursor=garbage",
+		"/products?offset=1&cursor=" + internal.NewProductCursor(internal.ProductPageQuery{SortBy: internal.SortById}, dbData[1]),
+		// a cursor only works with the sorting it came from
+		"/products?sort=price&cursor=" + internal.NewProductCursor(internal.ProductPageQuery{SortBy: internal.SortById}, dbData[1]),
+	} {
+		code, _ := get(target)
+		require.Equal(t, http.StatusBadRequest, code, target)
+	}
+}
+
 func TestGetProduct(t *testing.T) {
 	dbData := map[int]internal.Product{
-		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: 1, IsPublished: true},
-		2: {Id: 2, Name: "p2", Quantity: 2, Code: "c2", Price: 2, IsPublished: true},
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true, Version: 4},
+		2: {Id: 2, Name: "p2", Quantity: 2, Code: "c2", Price: usd(200), IsPublished: true, Version: 1},
 	}
 	db := repository.ProductMapDB{Products: dbData, LastID: 2}
-	sv := service.NewProductDefault(&db)
-	hd := handler.NewDefaultProducts(sv)
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
 
 	expectCode := http.StatusOK
 	expectBody, _ := json.Marshal(dbData[1])
-	expectHeader := http.Header{"Content-Type": []string{"application/json"}}
+	expectHeader := http.Header{"Content-Type": []string{"application/json"}, "Etag": []string{`"4"`}}
 
 	req := httptest.NewRequest("GET", "/products/1/", nil)
 
@@ -68,22 +131,24 @@ func TestGetProduct(t *testing.T) {
 
 func TestAddProduct(t *testing.T) {
 	dbData := map[int]internal.Product{
-		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: 1, IsPublished: true},
-		2: {Id: 2, Name: "p2", Quantity: 2, Code: "c2", Price: 2, IsPublished: true},
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true},
+		2: {Id: 2, Name: "p2", Quantity: 2, Code: "c2", Price: usd(200), IsPublished: true},
 	}
 	db := repository.ProductMapDB{Products: dbData, LastID: 2}
-	sv := service.NewProductDefault(&db)
-	hd := handler.NewDefaultProducts(sv)
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
 
 	newProd := internal.Product{
-		Id: 3, Name: "p3", Quantity: 3, Code: "c3", Price: 3, IsPublished: false, Expiration: "01/02/2065",
+		Id: 3, Name: "p3", Quantity: 3, Code: "c3", Price: usd(300), IsPublished: false, Expiration: "01/02/2065",
 	}
+	reqBody, _ := json.Marshal(newProd)
 
+	newProd.Version = 1
 	expectCode := http.StatusCreated
 	expectBody, _ := json.Marshal(newProd)
-	expectHeader := http.Header{"Content-Type": []string{"application/json"}}
+	expectHeader := http.Header{"Content-Type": []string{"application/json"}, "Etag": []string{`"1"`}}
 
-	req := httptest.NewRequest("POST", "/products", strings.NewReader(string(expectBody)))
+	req := httptest.NewRequest("POST", "/products", strings.NewReader(string(reqBody)))
 	res := httptest.NewRecorder()
 	hd.AddProduct()(res, req)
 
@@ -93,14 +158,31 @@ func TestAddProduct(t *testing.T) {
 	require.Equal(t, 3, len(db.Products))
 }
 
+func TestAddProductDuplicateCode(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 1}
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
+
+	body := `{"name":"p2","quantity":2,"code_value":"c1","price":2,"expiration":"01/02/2065"}`
+	req := httptest.NewRequest("POST", "/products", strings.NewReader(body))
+	res := httptest.NewRecorder()
+	hd.AddProduct()(res, req)
+
+	require.Equal(t, http.StatusConflict, res.Code)
+	require.Equal(t, 1, len(db.Products))
+}
+
 func TestDeleteProduct(t *testing.T) {
 	dbData := map[int]internal.Product{
-		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: 1, IsPublished: true},
-		2: {Id: 2, Name: "p2", Quantity: 2, Code: "c2", Price: 2, IsPublished: true},
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true},
+		2: {Id: 2, Name: "p2", Quantity: 2, Code: "c2", Price: usd(200), IsPublished: true},
 	}
 	db := repository.ProductMapDB{Products: dbData, LastID: 2}
-	sv := service.NewProductDefault(&db)
-	hd := handler.NewDefaultProducts(sv)
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
 
 	expectCode := http.StatusOK
 	expectHeader := http.Header{"Content-Type": []string{"application/json"}}
@@ -122,3 +204,322 @@ func TestDeleteProduct(t *testing.T) {
 	_, ok := db.Products[1]
 	require.False(t, ok)
 }
+
+func TestDeleteProductCanceledRequest(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 1}
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
+
+	req := httptest.NewRequest("DELETE", "/products/1/", nil)
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", "1")
+
+	// the client went away before the handler ran
+	ctx, cancel := context.WithCancel(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+	cancel()
+	req = req.WithContext(ctx)
+
+	res := httptest.NewRecorder()
+	hd.DeleteProduct()(res, req)
+
+	require.Equal(t, http.StatusInternalServerError, res.Code)
+	require.Equal(t, 1, len(db.Products))
+}
+
+func TestGetProductNotModified(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true, Version: 3},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 1}
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", "1")
+
+	for header, expectCode := range map[string]int{`"3"`: http.StatusNotModified, `W/"3"`: http.StatusNotModified, `"2", "3"`: http.StatusNotModified, "*": http.StatusNotModified, `"2"`: http.StatusOK} {
+		req := httptest.NewRequest("GET", "/products/1/", nil)
+		req.Header.Set("If-None-Match", header)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+
+		res := httptest.NewRecorder()
+		hd.GetProductById()(res, req)
+
+		require.Equal(t, expectCode, res.Code, header)
+		require.Equal(t, `"3"`, res.Header().Get("ETag"))
+		if expectCode == http.StatusNotModified {
+			require.Empty(t, res.Body.String())
+		}
+	}
+}
+
+func TestGetProductWithStock(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "p1", Quantity: 4, Code: "c1", Price: usd(100), IsPublished: true, Version: 3},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 1}
+	stock := service.NewStockDefault(&db, repository.NewStockMapDB(), nil, nil)
+	hd := handler.NewDefaultProducts(service.NewProductDefault(&db, service.ProductOptions{}), handler.ProductOptions{Stock: stock})
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", "1")
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("If-None-Match", `"3"`)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+
+		res := httptest.NewRecorder()
+		hd.GetProductById()(res, req)
+		return res
+	}
+
+	// the plain product is still answered by version
+	res := get("/products/1")
+	require.Equal(t, http.StatusNotModified, res.Code)
+	require.Equal(t, `"3"`, res.Header().Get("ETag"))
+
+	// reserving changes the stock without the version, which never caches it
+	_, err := stock.Reserve(context.Background(), internal.DefaultLocationId, []internal.ReservationItem{{ProductId: 1, Quantity: 1}}, time.Minute)
+	require.NoError(t, err)
+	res = get("/products/1?include=stock")
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Empty(t, res.Header().Get("ETag"))
+	var product struct {
+		Version int                 `json:"version"`
+		Stock   internal.StockLevel `json:"stock"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&product))
+	require.Equal(t, 3, product.Version)
+	require.Equal(t, 3, product.Stock.Available)
+}
+
+func TestPartialProductUpdateIfMatch(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true, Version: 1},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 1}
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", "1")
+
+	patch := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/products/1", strings.NewReader(`{"name":"patched"}`))
+		req.Header.Set("If-Match", ifMatch)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+
+		res := httptest.NewRecorder()
+		hd.PartialProductUpdate()(res, req)
+		return res
+	}
+
+	res := patch(`"1"`)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, `"2"`, res.Header().Get("ETag"))
+
+	// the second clerk still holds the first version
+	res = patch(`"1"`)
+	require.Equal(t, http.StatusPreconditionFailed, res.Code)
+
+	res = patch(`W/"2"`)
+	require.Equal(t, http.StatusPreconditionFailed, res.Code)
+	require.Equal(t, 2, db.Products[1].Version)
+}
+
+func TestUpdateOrCreateProductIfMatch(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true, Expiration: "01/02/2065", Version: 2},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 1}
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
+
+	put := func(body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/products", strings.NewReader(body))
+		req.Header.Set("If-Match", ifMatch)
+
+		res := httptest.NewRecorder()
+		hd.UpdateOrCreateProduct()(res, req)
+		return res
+	}
+
+	// a version in the body is not a precondition
+	body := `{"id":1,"name":"p1 updated","quantity":1,"code_value":"c1","price":1,"expiration":"01/02/2065","version":1}`
+	res := put(body, `"1"`)
+	require.Equal(t, http.StatusPreconditionFailed, res.Code)
+
+	res = put(body, `"2"`)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, `"3"`, res.Header().Get("ETag"))
+
+	// nothing to match yet
+	res = put(`{"id":2,"name":"p2","quantity":1,"code_value":"c2","price":1,"expiration":"01/02/2065"}`, `"1"`)
+	require.Equal(t, http.StatusPreconditionFailed, res.Code)
+	require.Equal(t, 1, len(db.Products))
+}
+
+func TestDeleteProductIfMatch(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true, Version: 2},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 1}
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", "1")
+
+	for _, c := range []struct {
+		header     string
+		expectCode int
+	}{
+		{`"1"`, http.StatusPreconditionFailed},
+		{"garbage", http.StatusPreconditionFailed},
+		{`"2"`, http.StatusOK},
+		{`"2"`, http.StatusNotFound},
+	} {
+		req := httptest.NewRequest("DELETE", "/products/1/", nil)
+		req.Header.Set("If-Match", c.header)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeContext))
+
+		res := httptest.NewRecorder()
+		hd.DeleteProduct()(res, req)
+
+		require.Equal(t, c.expectCode, res.Code, c.header)
+	}
+	require.Equal(t, 0, len(db.Products))
+}
+
+func TestGetProductsFiltered(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "Sweet Corn", Quantity: 1, Code: "c1", Price: usd(100), IsPublished: true},
+		2: {Id: 2, Name: "Corn Shoots", Quantity: 2, Code: "c2", Price: usd(200)},
+		3: {Id: 3, Name: "Milk", Quantity: 3, Code: "c3", Price: usd(300), IsPublished: true},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 3}
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
+
+	search := func(params url.Values) (int, internal.ProductPage) {
+		req := httptest.NewRequest("GET", "/products/search?"+params.Encode(), nil)
+		res := httptest.NewRecorder()
+		hd.GetProductsFiltered()(res, req)
+
+		var page internal.ProductPage
+		if res.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&page))
+		}
+		return res.Code, page
+	}
+
+	code, page := search(url.Values{"filter": {`name contains corn and (is_published eq true or price gte 2)`}, "sort": {"price"}, "order": {"desc"}})
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []internal.Product{dbData[2], dbData[1]}, page.Items)
+	require.Equal(t, 2, page.Total)
+
+	// the legacy parameter narrows the filter down
+	code, page = search(url.Values{"filter": {"is_published eq true"}, "priceGT": {"1"}})
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []internal.Product{dbData[3]}, page.Items)
+
+	code, page = search(url.Values{"priceGT": {"1.5"}})
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []internal.Product{dbData[2], dbData[3]}, page.Items)
+
+	for _, params := range []url.Values{
+		{},
+		{"priceGT": {"cheap"}},
+		{"filter": {"price gt"}},
+		{"filter": {"color eq red"}},
+		{"filter": {"price gt 1"}, "limit": {"-1"}},
+	} {
+		code, _ := search(params)
+		require.Equal(t, http.StatusBadRequest, code, params.Encode())
+	}
+}
+
+func TestSearchProducts(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "Dark Chocolate", Quantity: 1, Code: "c1", Price: usd(100)},
+		2: {Id: 2, Name: "Chocolate", Quantity: 2, Code: "c2", Price: usd(200)},
+		3: {Id: 3, Name: "Milk", Quantity: 3, Code: "c3", Price: usd(300)},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 3}
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
+
+	search := func(params url.Values) (int, []internal.ProductSearchResult, int) {
+		req := httptest.NewRequest("GET", "/products/search?"+params.Encode(), nil)
+		res := httptest.NewRecorder()
+		hd.GetProductsFiltered()(res, req)
+
+		var body struct {
+			Items []internal.ProductSearchResult `json:"items"`
+			Total int                            `json:"total"`
+		}
+		if res.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		}
+		return res.Code, body.Items, body.Total
+	}
+
+	code, items, total := search(url.Values{"q": {"choc"}})
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 2, total)
+	require.Len(t, items, 2)
+	// the shorter name ranks first
+	require.Equal(t, dbData[2], items[0].Product)
+	require.Equal(t, "<mark>Choc</mark>olate", items[0].Highlighted)
+	require.Equal(t, dbData[1], items[1].Product)
+	require.Greater(t, items[0].Score, items[1].Score)
+
+	code, items, total = search(url.Values{"q": {"chocolate"}, "limit": {"1"}})
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 2, total)
+	require.Len(t, items, 1)
+
+	for _, params := range []url.Values{
+		{"q": {""}},
+		{"q": {"choc"}, "filter": {"price gt 1"}},
+		{"q": {"choc"}, "priceGT": {"1"}},
+		{"q": {"choc"}, "limit": {"1000"}},
+	} {
+		code, _, _ := search(params)
+		require.Equal(t, http.StatusBadRequest, code, params.Encode())
+	}
+}
+
+func TestGetCartPrice(t *testing.T) {
+	dbData := map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 1, Code: "c1", Price: usd(150)},
+		2: {Id: 2, Name: "Bread", Quantity: 1, Code: "c2", Price: usd(200)},
+	}
+	db := repository.ProductMapDB{Products: dbData, LastID: 2}
+	sv := service.NewProductDefault(&db, service.ProductOptions{})
+	hd := handler.NewDefaultProducts(sv, handler.ProductOptions{})
+
+	for _, tc := range []struct {
+		list string
+		code int
+		body string
+	}{
+		{list: "[1,2,1]", code: http.StatusOK, body: `{"amount":"5.00","currency":"USD"}` + "\n"},
+		{list: "[]", code: http.StatusBadRequest},
+		{list: "[1,x]", code: http.StatusBadRequest},
+		{list: "[3]", code: http.StatusNotFound},
+	} {
+		req := httptest.NewRequest("GET", "/products/consumer_price?list="+url.QueryEscape(tc.list), nil)
+		res := httptest.NewRecorder()
+		hd.GetCartPrice()(res, req)
+
+		require.Equal(t, tc.code, res.Code, tc.list)
+		if tc.body != "" {
+			require.Equal(t, tc.body, res.Body.String())
+		}
+	}
+}
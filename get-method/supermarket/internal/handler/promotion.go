@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type DefaultPromotions struct {
+	ps internal.PromotionService
+}
+
+func NewDefaultPromotions(ps internal.PromotionService) *DefaultPromotions {
+	return &DefaultPromotions{ps: ps}
+}
+
+// GetAllPromotions lists the promotions in the order they apply.
+func (pc *DefaultPromotions) GetAllPromotions() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		promotions, err := pc.ps.GetAll(req.Context())
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "error retrieving promotions")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(promotions)
+	}
+}
+
+func (pc *DefaultPromotions) GetPromotionById() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		promotion, err := pc.ps.GetById(req.Context(), id)
+		if err != nil {
+			writePromotionError(w, err, "error retrieving promotion")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(promotion)
+	}
+}
+
+func (pc *DefaultPromotions) AddPromotion() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var promotion internal.Promotion
+		if err := json.NewDecoder(req.Body).Decode(&promotion); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		promotion.Id = 0
+
+		promotion, err := pc.ps.Save(req.Context(), promotion)
+		if err != nil {
+			writePromotionError(w, err, "error saving promotion")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(promotion)
+	}
+}
+
+// UpdatePromotion replaces the promotion of the id path parameter.
+func (pc *DefaultPromotions) UpdatePromotion() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var promotion internal.Promotion
+		if err := json.NewDecoder(req.Body).Decode(&promotion); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		promotion.Id = id
+
+		promotion, err = pc.ps.Update(req.Context(), promotion)
+		if err != nil {
+			writePromotionError(w, err, "error updating promotion")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(promotion)
+	}
+}
+
+func (pc *DefaultPromotions) DeletePromotion() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		if err := pc.ps.Delete(req.Context(), id); err != nil {
+			writePromotionError(w, err, "error deleting promotion")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writePromotionError responds with the status matching err, or with an
+// internal error saying message.
+func writePromotionError(w http.ResponseWriter, err error, message string) {
+	var invalid internal.InvalidPromotionError
+	switch {
+	case errors.As(err, &invalid):
+		response.Error(w, http.StatusBadRequest, invalid.Error())
+	case errors.As(err, &internal.PromotionNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "promotion not found")
+	default:
+		response.Error(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,68 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromotionsCRUD(t *testing.T) {
+	hd := handler.NewDefaultPromotions(service.NewPromotionDefault(repository.NewPromotionMapDB()))
+
+	do := func(method, target, id, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if id != "" {
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		}
+		res := httptest.NewRecorder()
+		h(res, req)
+		return res
+	}
+
+	res := do("POST", "/promotions", "", `{"name": "3 for 2", "kind": "multi_buy", "buy": 3, "pay": 2}`, hd.AddPromotion())
+	require.Equal(t, http.StatusCreated, res.Code)
+	var created internal.Promotion
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+	require.Equal(t, 1, created.Id)
+
+	res = do("POST", "/promotions", "", `{"name": "low", "kind": "percent_off", "percent": 10, "priority": -1}`, hd.AddPromotion())
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	res = do("POST", "/promotions", "", `{"name": "broken", "kind": "multi_buy", "buy": 1}`, hd.AddPromotion())
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = do("PUT", "/promotions/1", "1", `{"name": "3 for 2", "kind": "multi_buy", "buy": 3, "pay": 2, "priority": -2}`, hd.UpdatePromotion())
+	require.Equal(t, http.StatusOK, res.Code)
+
+	// listed in the order they apply
+	res = do("GET", "/promotions", "", "", hd.GetAllPromotions())
+	require.Equal(t, http.StatusOK, res.Code)
+	var promotions []internal.Promotion
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&promotions))
+	require.Len(t, promotions, 2)
+	require.Equal(t, "low", promotions[0].Name)
+	require.Equal(t, -2, promotions[1].Priority)
+
+	res = do("DELETE", "/promotions/1", "1", "", hd.DeletePromotion())
+	require.Equal(t, http.StatusNoContent, res.Code)
+
+	for _, id := range []string{"1", "x"} {
+		res = do("GET", "/promotions/"+id, id, "", hd.GetPromotionById())
+		require.NotEqual(t, http.StatusOK, res.Code, id)
+	}
+	res = do("PUT", "/promotions/1", "1", `{"name": "3 for 2", "kind": "multi_buy", "buy": 3, "pay": 2}`, hd.UpdatePromotion())
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
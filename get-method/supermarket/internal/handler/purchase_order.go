@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type DefaultPurchaseOrders struct {
+	ps internal.PurchaseOrderService
+}
+
+func NewDefaultPurchaseOrders(ps internal.PurchaseOrderService) *DefaultPurchaseOrders {
+	return &DefaultPurchaseOrders{ps: ps}
+}
+
+// purchaseOrderBody is the body of new purchase orders. Lines without a
+// unit cost are priced at the cost of the supplier.
+type purchaseOrderBody struct {
+	SupplierId int                          `json:"supplier_id"`
+	Lines      []internal.PurchaseOrderLine `json:"lines"`
+}
+
+// AddPurchaseOrder drafts the purchase order in the request body.
+func (pc *DefaultPurchaseOrders) AddPurchaseOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body purchaseOrderBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+
+		order, err := pc.ps.Create(req.Context(), body.SupplierId, body.Lines)
+		if err != nil {
+			writePurchaseOrderError(w, err, "error saving purchase order")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+// GetAllPurchaseOrders lists the purchase orders, only those in the status
+// query parameter when it is given.
+func (pc *DefaultPurchaseOrders) GetAllPurchaseOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		status := internal.PurchaseOrderStatus(req.URL.Query().Get("status"))
+		if status != "" && !status.Valid() {
+			response.Error(w, http.StatusBadRequest, "unknown purchase order status")
+			return
+		}
+
+		orders, err := pc.ps.GetAll(req.Context(), status)
+		if err != nil {
+			writePurchaseOrderError(w, err, "error retrieving purchase orders")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(orders)
+	}
+}
+
+func (pc *DefaultPurchaseOrders) GetPurchaseOrderById() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		order, err := pc.ps.GetById(req.Context(), id)
+		if err != nil {
+			writePurchaseOrderError(w, err, "error retrieving purchase order")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+type purchaseOrderLinesBody struct {
+	Lines []internal.PurchaseOrderLine `json:"lines"`
+}
+
+// UpdatePurchaseOrderLines replaces the lines of the draft purchase order of
+// the id path parameter with the ones in the request body.
+func (pc *DefaultPurchaseOrders) UpdatePurchaseOrderLines() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var body purchaseOrderLinesBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+
+		order, err := pc.ps.UpdateLines(req.Context(), id, body.Lines)
+		if err != nil {
+			writePurchaseOrderError(w, err, "error updating purchase order")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+type purchaseOrderStatusBody struct {
+	Status internal.PurchaseOrderStatus `json:"status"`
+}
+
+// UpdatePurchaseOrderStatus moves the purchase order of the id path
+// parameter to the status in the request body. Moving it to received adds
+// its lines to the stock.
+func (pc *DefaultPurchaseOrders) UpdatePurchaseOrderStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var body purchaseOrderStatusBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		if !body.Status.Valid() {
+			response.Error(w, http.StatusBadRequest, "unknown purchase order status")
+			return
+		}
+
+		order, err := pc.ps.Transition(req.Context(), id, body.Status)
+		if err != nil {
+			writePurchaseOrderError(w, err, "error updating purchase order")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+// writePurchaseOrderError responds with the status matching err, or with
+// message when it is unexpected.
+func writePurchaseOrderError(w http.ResponseWriter, err error, message string) {
+	var (
+		invalid    internal.InvalidPurchaseOrderError
+		notDraft   internal.PurchaseOrderNotDraftError
+		transition internal.InvalidPurchaseOrderTransitionError
+	)
+	switch {
+	case errors.As(err, &invalid):
+		response.Error(w, http.StatusBadRequest, invalid.Error())
+	case errors.As(err, &notDraft):
+		response.Error(w, http.StatusConflict, notDraft.Error())
+	case errors.As(err, &transition):
+		response.Error(w, http.StatusConflict, transition.Error())
+	case errors.As(err, &internal.SupplierNotFoundError{}):
+		response.Error(w, http.StatusConflict, "supplier not found")
+	case errors.As(err, &internal.PurchaseOrderNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "purchase order not found")
+	default:
+		response.Error(w, http.StatusInternalServerError, message)
+	}
+}
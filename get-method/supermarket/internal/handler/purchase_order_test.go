@@ -0,0 +1,91 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurchasingEndpoints(t *testing.T) {
+	db := repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Price: usd(110), IsPublished: true, Version: 1},
+	}, LastID: 1}
+	suppliers := repository.NewSupplierMapDB()
+	stock := service.NewStockDefault(&db, repository.NewStockMapDB(), nil, nil)
+	sp := handler.NewDefaultSuppliers(service.NewSupplierDefault(suppliers, &db))
+	po := handler.NewDefaultPurchaseOrders(service.NewPurchaseOrderDefault(suppliers, stock, repository.NewPurchaseOrderMapDB(), nil))
+
+	do := func(method, target string, params map[string]string, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		chiCtx := chi.NewRouteContext()
+		for key, value := range params {
+			chiCtx.URLParams.Add(key, value)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		res := httptest.NewRecorder()
+		h(res, req)
+		return res
+	}
+	id := func(id string) map[string]string { return map[string]string{"id": id} }
+
+	res := do("POST", "/suppliers", nil, `{"name": "Farm", "phone": "555-0100", "lead_time_days": 2}`, sp.AddSupplier())
+	require.Equal(t, http.StatusCreated, res.Code)
+	require.JSONEq(t, `{"id": 1, "name": "Farm", "phone": "555-0100", "lead_time_days": 2}`, res.Body.String())
+	res = do("POST", "/suppliers", nil, `{"name": "Mill", "lead_time_days": -1}`, sp.AddSupplier())
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	res = do("PUT", "/suppliers/2", id("2"), `{"name": "Mill"}`, sp.UpdateSupplier())
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	params := map[string]string{"id": "1", "product_id": "1"}
+	res = do("PUT", "/suppliers/1/products/1", params, `{"cost": "0.60"}`, sp.PutSupplierProduct())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `{"supplier_id": 1, "product_id": 1, "cost": {"amount": "0.60", "currency": "USD"}}`, res.Body.String())
+	res = do("PUT", "/suppliers/1/products/2", map[string]string{"id": "1", "product_id": "2"}, `{"cost": "0.60"}`, sp.PutSupplierProduct())
+	require.Equal(t, http.StatusNotFound, res.Code)
+	res = do("GET", "/suppliers/1/products", id("1"), "", sp.GetSupplierProducts())
+	require.Equal(t, http.StatusOK, res.Code)
+
+	res = do("POST", "/purchase-orders", nil, `{"supplier_id": 1, "lines": [{"product_id": 1, "quantity": 12}]}`, po.AddPurchaseOrder())
+	require.Equal(t, http.StatusCreated, res.Code)
+	var order internal.PurchaseOrder
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&order))
+	require.Equal(t, usd(720), order.Total)
+	res = do("POST", "/purchase-orders", nil, `{"supplier_id": 1, "lines": [{"product_id": 2, "quantity": 1}]}`, po.AddPurchaseOrder())
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = do("PUT", "/purchase-orders/1/status", id("1"), `{"status": "received"}`, po.UpdatePurchaseOrderStatus())
+	require.Equal(t, http.StatusConflict, res.Code)
+	res = do("PUT", "/purchase-orders/1/status", id("1"), `{"status": "shipped"}`, po.UpdatePurchaseOrderStatus())
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	res = do("PUT", "/purchase-orders/1/lines", id("1"), `{"lines": [{"product_id": 1, "quantity": 15}]}`, po.UpdatePurchaseOrderLines())
+	require.Equal(t, http.StatusOK, res.Code)
+	res = do("PUT", "/purchase-orders/1/status", id("1"), `{"status": "sent"}`, po.UpdatePurchaseOrderStatus())
+	require.Equal(t, http.StatusOK, res.Code)
+	res = do("PUT", "/purchase-orders/1/lines", id("1"), `{"lines": [{"product_id": 1, "quantity": 1}]}`, po.UpdatePurchaseOrderLines())
+	require.Equal(t, http.StatusConflict, res.Code)
+	res = do("PUT", "/purchase-orders/1/status", id("1"), `{"status": "received"}`, po.UpdatePurchaseOrderStatus())
+	require.Equal(t, http.StatusOK, res.Code)
+
+	product, err := db.GetById(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, 25, product.Quantity)
+
+	res = do("GET", "/purchase-orders?status=received", nil, "", po.GetAllPurchaseOrders())
+	require.Equal(t, http.StatusOK, res.Code)
+	var orders []internal.PurchaseOrder
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&orders))
+	require.Len(t, orders, 1)
+	res = do("GET", "/purchase-orders/2", id("2"), "", po.GetPurchaseOrderById())
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+)
+
+type DefaultReorders struct {
+	rs     internal.ReorderService
+	policy internal.ReorderPolicy
+}
+
+// NewDefaultReorders suggests reorders under policy unless the request says
+// otherwise.
+func NewDefaultReorders(rs internal.ReorderService, policy internal.ReorderPolicy) *DefaultReorders {
+	return &DefaultReorders{rs: rs, policy: policy}
+}
+
+// GetReorderSuggestions lists the products to reorder, the ones running out
+// soonest first. The windows query parameter, a comma separated list of
+// periods such as 7d,30d, and the safety_days, cover_days and
+// lead_time_days ones override the policy of the handler.
+func (rc *DefaultReorders) GetReorderSuggestions() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		policy, err := reorderPolicy(req, rc.policy)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		suggestions, err := rc.rs.Suggestions(req.Context(), policy)
+		if err != nil {
+			var invalid internal.InvalidReorderPolicyError
+			if errors.As(err, &invalid) {
+				response.Error(w, http.StatusBadRequest, invalid.Error())
+				return
+			}
+			response.Error(w, http.StatusInternalServerError, "error computing reorder suggestions")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(suggestions)
+	}
+}
+
+// reorderPolicy returns policy with the query parameters of req applied.
+func reorderPolicy(req *http.Request, policy internal.ReorderPolicy) (internal.ReorderPolicy, error) {
+	query := req.URL.Query()
+	if value := query.Get("windows"); value != "" {
+		windows, err := internal.ParsePeriods(value)
+		if err != nil {
+			return internal.ReorderPolicy{}, internal.NewInvalidReorderPolicyError("windows")
+		}
+		policy.Windows = windows
+	}
+
+	days := map[string]*int{
+		"safety_days":    &policy.SafetyDays,
+		"cover_days":     &policy.CoverDays,
+		"lead_time_days": &policy.DefaultLeadTimeDays,
+	}
+	for name, target := range days {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return internal.ReorderPolicy{}, internal.NewInvalidReorderPolicyError(name)
+		}
+		*target = n
+	}
+	return policy, nil
+}
@@ -0,0 +1,59 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReorderSuggestions(t *testing.T) {
+	db := repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 20, Code: "c1", Price: usd(110), Version: 1},
+	}, LastID: 1}
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	clock := now.AddDate(0, 0, -10)
+	ledger := repository.NewStockMapDB()
+	stock := service.NewStockDefault(&db, ledger, nil, func() time.Time { return clock })
+	movement, err := internal.NewStockMovement(1, internal.StockSell, 10, "")
+	require.NoError(t, err)
+	_, err = stock.Record(context.Background(), movement)
+	require.NoError(t, err)
+	clock = now
+
+	hd := handler.NewDefaultReorders(service.NewReorderDefault(&db, ledger, nil, nil, func() time.Time { return now }), internal.DefaultReorderPolicy())
+
+	// 10 units were sold 10 days ago, outside of the last week
+	for target, count := range map[string]int{
+		"/inventory/reorder-suggestions":                                 0,
+		"/inventory/reorder-suggestions?windows=7d":                      0,
+		"/inventory/reorder-suggestions?windows=14d&lead_time_days=0":    0,
+		"/inventory/reorder-suggestions?windows=14d&safety_days=20":      1,
+		"/inventory/reorder-suggestions?windows=264h,30d&cover_days=0":   1,
+		"/inventory/reorder-suggestions?windows=30d&lead_time_days=1000": 1,
+		"/inventory/reorder-suggestions?windows=30d&lead_time_days=0":    0,
+	} {
+		res := httptest.NewRecorder()
+		hd.GetReorderSuggestions()(res, httptest.NewRequest("GET", target, nil))
+		require.Equal(t, http.StatusOK, res.Code, target)
+
+		var suggestions []internal.ReorderSuggestion
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&suggestions))
+		require.Len(t, suggestions, count, target)
+	}
+
+	for _, query := range []string{"windows=0d", "windows=week", "windows=7d,", "safety_days=-1", "cover_days=two"} {
+		res := httptest.NewRecorder()
+		hd.GetReorderSuggestions()(res, httptest.NewRequest("GET", "/inventory/reorder-suggestions?"+query, nil))
+		require.Equal(t, http.StatusBadRequest, res.Code, query)
+	}
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type DefaultStock struct {
+	ss internal.StockService
+}
+
+func NewDefaultStock(ss internal.StockService) *DefaultStock {
+	return &DefaultStock{ss: ss}
+}
+
+// GetStockLevel responds with the stock of the product of the id path
+// parameter, broken down by location, or only at the location of the
+// location_id query parameter when there is one.
+func (sc *DefaultStock) GetStockLevel() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var level internal.StockLevel
+		if param := req.URL.Query().Get("location_id"); param != "" {
+			locationId, perr := strconv.Atoi(param)
+			if perr != nil {
+				response.Error(w, http.StatusBadRequest, "error parsing location_id")
+				return
+			}
+			level, err = sc.ss.LevelAt(req.Context(), id, locationId)
+		} else {
+			level, err = sc.ss.Level(req.Context(), id)
+		}
+		if err != nil {
+			writeStockError(w, err, "error retrieving stock")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(level)
+	}
+}
+
+// GetStockMovements lists the stock ledger of the product of the id path
+// parameter, oldest movement first.
+func (sc *DefaultStock) GetStockMovements() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		movements, err := sc.ss.Movements(req.Context(), id)
+		if err != nil {
+			writeStockError(w, err, "error retrieving stock movements")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(movements)
+	}
+}
+
+// stockMovementBody is the body of stock movements. Quantity is the number
+// of units received, sold or written off, and the signed change of the
+// stock for adjustments. They are made at the default location when
+// LocationId is zero.
+type stockMovementBody struct {
+	Kind       internal.StockMovementKind `json:"kind"`
+	Quantity   int                        `json:"quantity"`
+	Reason     string                     `json:"reason"`
+	LocationId int                        `json:"location_id"`
+}
+
+// AddStockMovement records a movement of the stock of the product of the id
+// path parameter.
+func (sc *DefaultStock) AddStockMovement() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var body stockMovementBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		movement, err := internal.NewStockMovement(id, body.Kind, body.Quantity, body.Reason)
+		if err != nil {
+			writeStockError(w, err, "error recording stock movement")
+			return
+		}
+		movement.LocationId = body.LocationId
+
+		movement, err = sc.ss.Record(req.Context(), movement)
+		if err != nil {
+			writeStockError(w, err, "error recording stock movement")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(movement)
+	}
+}
+
+// stockTransferBody is the body of stock transfers, moving Quantity units
+// from the location From to the location To.
+type stockTransferBody struct {
+	From     int    `json:"from_location_id"`
+	To       int    `json:"to_location_id"`
+	Quantity int    `json:"quantity"`
+	Reason   string `json:"reason"`
+}
+
+// AddStockTransfer moves stock of the product of the id path parameter
+// between locations and responds with the pair of movements recorded.
+func (sc *DefaultStock) AddStockTransfer() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var body stockTransferBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+
+		movements, err := sc.ss.Transfer(req.Context(), id, body.From, body.To, body.Quantity, body.Reason)
+		if err != nil {
+			writeStockError(w, err, "error transferring stock")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(movements)
+	}
+}
+
+// reservationBody is the body of reservations. They expire after TTL
+// seconds, internal.DefaultReservationTTL when it is zero, and hold stock
+// at the default location when LocationId is zero.
+type reservationBody struct {
+	LocationId int                        `json:"location_id"`
+	Items      []internal.ReservationItem `json:"items"`
+	TTL        int                        `json:"ttl_seconds"`
+}
+
+func (sc *DefaultStock) AddReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body reservationBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+
+		reservation, err := sc.ss.Reserve(req.Context(), body.LocationId, body.Items, time.Duration(body.TTL)*time.Second)
+		if err != nil {
+			writeStockError(w, err, "error reserving stock")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+func (sc *DefaultStock) GetReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		reservation, err := sc.ss.GetReservation(req.Context(), id)
+		if err != nil {
+			writeStockError(w, err, "error retrieving reservation")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+// CommitReservation sells the stock held by the reservation of the id path
+// parameter and responds with the sales recorded.
+func (sc *DefaultStock) CommitReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		movements, err := sc.ss.Commit(req.Context(), id)
+		if err != nil {
+			writeStockError(w, err, "error committing reservation")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(movements)
+	}
+}
+
+func (sc *DefaultStock) ReleaseReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		if err := sc.ss.Release(req.Context(), id); err != nil {
+			writeStockError(w, err, "error releasing reservation")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeStockError responds with the status matching err, or with message
+// when it is unexpected.
+func writeStockError(w http.ResponseWriter, err error, message string) {
+	var (
+		invalidMovement    internal.InvalidStockMovementError
+		invalidReservation internal.InvalidReservationError
+		insufficient       internal.InsufficientStockError
+	)
+	switch {
+	case errors.As(err, &invalidMovement):
+		response.Error(w, http.StatusBadRequest, invalidMovement.Error())
+	case errors.As(err, &invalidReservation):
+		response.Error(w, http.StatusBadRequest, invalidReservation.Error())
+	case errors.As(err, &insufficient):
+		response.Error(w, http.StatusConflict, insufficient.Error())
+	case errors.As(err, &internal.ProductNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "product not found")
+	case errors.As(err, &internal.ReservationNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "reservation not found")
+	case errors.As(err, &internal.LocationNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "location not found")
+	default:
+		response.Error(w, http.StatusInternalServerError, message)
+	}
+}
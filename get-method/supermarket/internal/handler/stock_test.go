@@ -0,0 +1,77 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStockEndpoints(t *testing.T) {
+	db := repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Price: usd(150), IsPublished: true},
+	}, LastID: 1}
+	hd := handler.NewDefaultStock(service.NewStockDefault(&db, repository.NewStockMapDB(), nil, nil))
+
+	do := func(method, id, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/stock", strings.NewReader(body))
+		if id != "" {
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		}
+		res := httptest.NewRecorder()
+		h(res, req)
+		return res
+	}
+
+	res := do("POST", "1", `{"kind": "receive", "quantity": 10, "reason": "delivery"}`, hd.AddStockMovement())
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	for body, code := range map[string]int{
+		`{"kind": "sell", "quantity": 0}`:  http.StatusBadRequest,
+		`{"kind": "sell", "quantity": 16}`: http.StatusConflict,
+	} {
+		res = do("POST", "1", body, hd.AddStockMovement())
+		require.Equal(t, code, res.Code, body)
+	}
+	res = do("POST", "9", `{"kind": "receive", "quantity": 1}`, hd.AddStockMovement())
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	res = do("POST", "", `{"items": [{"product_id": 1, "quantity": 12}], "ttl_seconds": 60}`, hd.AddReservation())
+	require.Equal(t, http.StatusCreated, res.Code)
+	var reservation internal.Reservation
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&reservation))
+
+	res = do("GET", "1", "", hd.GetStockLevel())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `{"product_id": 1, "on_hand": 15, "reserved": 12, "available": 3, "locations": [{"product_id": 1, "location_id": 1, "on_hand": 15, "reserved": 12, "available": 3}]}`, res.Body.String())
+
+	res = do("POST", "", `{"items": [{"product_id": 1, "quantity": 4}]}`, hd.AddReservation())
+	require.Equal(t, http.StatusConflict, res.Code)
+
+	id := strconv.Itoa(reservation.Id)
+	res = do("POST", id, "", hd.CommitReservation())
+	require.Equal(t, http.StatusOK, res.Code)
+	res = do("DELETE", id, "", hd.ReleaseReservation())
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	res = do("GET", "1", "", hd.GetStockMovements())
+	require.Equal(t, http.StatusOK, res.Code)
+	var movements []internal.StockMovement
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&movements))
+	require.Len(t, movements, 3)
+	require.Equal(t, internal.StockSell, movements[2].Kind)
+	require.Equal(t, 3, db.Products[1].Quantity)
+}
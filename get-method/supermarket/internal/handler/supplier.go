@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type DefaultSuppliers struct {
+	ss internal.SupplierService
+}
+
+func NewDefaultSuppliers(ss internal.SupplierService) *DefaultSuppliers {
+	return &DefaultSuppliers{ss: ss}
+}
+
+func (sc *DefaultSuppliers) GetAllSuppliers() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		suppliers, err := sc.ss.GetAll(req.Context())
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "error retrieving suppliers")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(suppliers)
+	}
+}
+
+func (sc *DefaultSuppliers) GetSupplierById() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		supplier, err := sc.ss.GetById(req.Context(), id)
+		if err != nil {
+			writeSupplierError(w, err, "error retrieving supplier")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(supplier)
+	}
+}
+
+func (sc *DefaultSuppliers) AddSupplier() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var supplier internal.Supplier
+		if err := json.NewDecoder(req.Body).Decode(&supplier); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+
+		supplier, err := sc.ss.Save(req.Context(), supplier)
+		if err != nil {
+			writeSupplierError(w, err, "error saving supplier")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(supplier)
+	}
+}
+
+// UpdateSupplier replaces the supplier of the id path parameter with the one
+// in the request body.
+func (sc *DefaultSuppliers) UpdateSupplier() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		var supplier internal.Supplier
+		if err := json.NewDecoder(req.Body).Decode(&supplier); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		supplier.Id = id
+
+		supplier, err = sc.ss.Update(req.Context(), supplier)
+		if err != nil {
+			writeSupplierError(w, err, "error updating supplier")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(supplier)
+	}
+}
+
+func (sc *DefaultSuppliers) DeleteSupplier() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		if err := sc.ss.Delete(req.Context(), id); err != nil {
+			writeSupplierError(w, err, "error deleting supplier")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetSupplierProducts lists the products the supplier of the id path
+// parameter sells, with their cost.
+func (sc *DefaultSuppliers) GetSupplierProducts() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+
+		links, err := sc.ss.Products(req.Context(), id)
+		if err != nil {
+			writeSupplierError(w, err, "error retrieving supplier products")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(links)
+	}
+}
+
+// PutSupplierProduct links the product of the product_id path parameter to
+// the supplier of the id path parameter, at the cost in the request body.
+func (sc *DefaultSuppliers) PutSupplierProduct() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+		productId, err := strconv.Atoi(chi.URLParam(req, "product_id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing product id")
+			return
+		}
+
+		var link internal.SupplierProduct
+		if err := json.NewDecoder(req.Body).Decode(&link); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		link.SupplierId, link.ProductId = id, productId
+
+		link, err = sc.ss.LinkProduct(req.Context(), link)
+		if err != nil {
+			writeSupplierError(w, err, "error saving supplier product")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(link)
+	}
+}
+
+func (sc *DefaultSuppliers) DeleteSupplierProduct() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(req, "id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing id")
+			return
+		}
+		productId, err := strconv.Atoi(chi.URLParam(req, "product_id"))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "error parsing product id")
+			return
+		}
+
+		if err := sc.ss.UnlinkProduct(req.Context(), id, productId); err != nil {
+			writeSupplierError(w, err, "error deleting supplier product")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeSupplierError responds with the status matching err, or with message
+// when it is unexpected.
+func writeSupplierError(w http.ResponseWriter, err error, message string) {
+	var invalid internal.InvalidSupplierError
+	switch {
+	case errors.As(err, &invalid):
+		response.Error(w, http.StatusBadRequest, invalid.Error())
+	case errors.As(err, &internal.SupplierNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "supplier not found")
+	case errors.As(err, &internal.ProductNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "product not found")
+	case errors.As(err, &internal.SupplierProductNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "product not linked to supplier")
+	default:
+		response.Error(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"supermarket/internal"
+
+	"supermarket/platform/web/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type DefaultTaxes struct {
+	ts internal.TaxService
+}
+
+func NewDefaultTaxes(ts internal.TaxService) *DefaultTaxes {
+	return &DefaultTaxes{ts: ts}
+}
+
+func (tc *DefaultTaxes) GetAllJurisdictions() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		jurisdictions, err := tc.ts.GetAll(req.Context())
+		if err != nil {
+			response.Error(w, http.StatusInternalServerError, "error retrieving tax jurisdictions")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(jurisdictions)
+	}
+}
+
+func (tc *DefaultTaxes) GetJurisdiction() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		jurisdiction, err := tc.ts.GetByCode(req.Context(), jurisdictionCode(req))
+		if err != nil {
+			writeTaxError(w, err, "error retrieving tax jurisdiction")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(jurisdiction)
+	}
+}
+
+// PutJurisdiction sets the tax mode and rates of the jurisdiction of the
+// code path parameter.
+func (tc *DefaultTaxes) PutJurisdiction() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var jurisdiction internal.TaxJurisdiction
+		if err := json.NewDecoder(req.Body).Decode(&jurisdiction); err != nil {
+			response.Error(w, http.StatusBadRequest, "could not decode body")
+			return
+		}
+		jurisdiction.Code = jurisdictionCode(req)
+
+		jurisdiction, err := tc.ts.Put(req.Context(), jurisdiction)
+		if err != nil {
+			writeTaxError(w, err, "error saving tax jurisdiction")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(jurisdiction)
+	}
+}
+
+func (tc *DefaultTaxes) DeleteJurisdiction() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if err := tc.ts.Delete(req.Context(), jurisdictionCode(req)); err != nil {
+			writeTaxError(w, err, "error deleting tax jurisdiction")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// jurisdictionCode returns the code path parameter, upper cased.
+func jurisdictionCode(req *http.Request) string {
+	return strings.ToUpper(chi.URLParam(req, "code"))
+}
+
+// writeTaxError responds with the status matching err, or with message when
+// it is unexpected.
+func writeTaxError(w http.ResponseWriter, err error, message string) {
+	var invalid internal.InvalidTaxJurisdictionError
+	switch {
+	case errors.As(err, &invalid):
+		response.Error(w, http.StatusBadRequest, invalid.Error())
+	case errors.As(err, &internal.TaxJurisdictionNotFoundError{}):
+		response.Error(w, http.StatusNotFound, "tax jurisdiction not found")
+	default:
+		response.Error(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,53 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/handler"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaxJurisdictionsCRUD(t *testing.T) {
+	hd := handler.NewDefaultTaxes(service.NewTaxDefault(repository.NewTaxMapDB(), ""))
+
+	do := func(method, code, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/tax/jurisdictions/"+code, strings.NewReader(body))
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("code", code)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		res := httptest.NewRecorder()
+		h(res, req)
+		return res
+	}
+
+	res := do("PUT", "de", `{"mode": "inclusive", "rates": [{"category": "standard", "name": "VAT 19%", "percent": 19}]}`, hd.PutJurisdiction())
+	require.Equal(t, http.StatusOK, res.Code)
+	require.JSONEq(t, `{"code": "DE", "mode": "inclusive", "rates": [{"category": "standard", "name": "VAT 19%", "percent": 19}]}`, res.Body.String())
+
+	res = do("PUT", "US-CA", `{"mode": "net"}`, hd.PutJurisdiction())
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = do("GET", "DE", "", hd.GetJurisdiction())
+	require.Equal(t, http.StatusOK, res.Code)
+
+	res = do("GET", "", "", hd.GetAllJurisdictions())
+	require.Equal(t, http.StatusOK, res.Code)
+	var jurisdictions []internal.TaxJurisdiction
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&jurisdictions))
+	require.Len(t, jurisdictions, 1)
+
+	res = do("DELETE", "DE", "", hd.DeleteJurisdiction())
+	require.Equal(t, http.StatusNoContent, res.Code)
+	res = do("GET", "DE", "", hd.GetJurisdiction())
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
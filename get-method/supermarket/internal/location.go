@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"context"
+	"strings"
+)
+
+// LocationKind tells whether stock is sold from a location or only kept
+// there.
+type LocationKind string
+
+const (
+	LocationStore     LocationKind = "store"
+	LocationWarehouse LocationKind = "warehouse"
+)
+
+// DefaultLocationId is the location stock is kept at unless told otherwise:
+// the quantity set on a product, the stock received and the carts quoted
+// without a store all go to it. It always exists.
+const DefaultLocationId = 1
+
+// Location is a store or a warehouse keeping stock.
+type Location struct {
+	Id   int          `json:"id"`
+	Name string       `json:"name"`
+	Kind LocationKind `json:"kind"`
+}
+
+func (l Location) Validate() error {
+	if strings.TrimSpace(l.Name) == "" {
+		return NewInvalidLocationError("name")
+	}
+	if l.Kind != LocationStore && l.Kind != LocationWarehouse {
+		return NewInvalidLocationError("kind")
+	}
+	return nil
+}
+
+type LocationRepository interface {
+	// GetAll returns every location sorted by id.
+	GetAll(ctx context.Context) ([]Location, error)
+	GetById(ctx context.Context, id int) (Location, error)
+	Save(ctx context.Context, location Location) (Location, error)
+	Update(ctx context.Context, location Location) (Location, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type LocationService interface {
+	GetAll(ctx context.Context) ([]Location, error)
+	GetById(ctx context.Context, id int) (Location, error)
+	Save(ctx context.Context, location Location) (Location, error)
+	Update(ctx context.Context, location Location) (Location, error)
+	// Delete fails with LocationInUseError when the location is the default
+	// one or still has stock.
+	Delete(ctx context.Context, id int) error
+}
+
+type InvalidLocationError struct {
+	Field string
+}
+
+func (e InvalidLocationError) Error() string {
+	return "invalid location: " + e.Field
+}
+
+func NewInvalidLocationError(field string) error {
+	return InvalidLocationError{Field: field}
+}
+
+type LocationNotFoundError struct{}
+
+func (e LocationNotFoundError) Error() string {
+	return "location not found"
+}
+
+func NewLocationNotFoundError() error {
+	return LocationNotFoundError{}
+}
+
+type LocationInUseError struct {
+	Reason string
+}
+
+func (e LocationInUseError) Error() string {
+	return "location in use: " + e.Reason
+}
+
+func NewLocationInUseError(reason string) error {
+	return LocationInUseError{Reason: reason}
+}
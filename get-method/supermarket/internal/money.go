@@ -0,0 +1,285 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of amounts given without one.
+const DefaultCurrency = "USD"
+
+// currencyExponents holds the number of decimals of the minor unit of the
+// currencies not using cents.
+var currencyExponents = map[string]int{
+	"BHD": 3,
+	"CLP": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"PYG": 0,
+	"TND": 3,
+	"UYI": 0,
+	"VND": 0,
+}
+
+// CurrencyExponent returns the number of decimals of the minor unit of
+// currency, the ISO 4217 one or 2 for the currencies it does not list.
+func CurrencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[currency]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// ValidCurrency reports whether currency looks like an ISO 4217 code.
+func ValidCurrency(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, c := range currency {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// RoundingMode tells how amounts falling between two minor units round.
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the even minor unit, so rounding many amounts
+	// does not drift either way. Discounts are rounded this way.
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds away from zero.
+	RoundHalfUp
+)
+
+// Money is an exact amount of a currency, counted in minor units, cents for
+// most currencies.
+//
+// In JSON it is an object holding the amount as a decimal string, as in
+// {"amount": "12.50", "currency": "USD"}, and null for the zero Money{}. A
+// bare number or string is also accepted and taken in DefaultCurrency.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+type InvalidMoneyError struct {
+	Value string
+}
+
+func (e InvalidMoneyError) Error() string {
+	return fmt.Sprintf("invalid amount of money %q", e.Value)
+}
+
+func NewInvalidMoneyError(value string) error {
+	return InvalidMoneyError{Value: value}
+}
+
+// ParseMoney parses a decimal amount of currency such as "-12.5". It fails
+// with InvalidMoneyError when the amount has more decimals than the minor
+// unit of currency, rather than rounding it.
+func ParseMoney(s, currency string) (Money, error) {
+	if !ValidCurrency(currency) {
+		return Money{}, NewInvalidMoneyError(s)
+	}
+
+	digits := s
+	negative := strings.HasPrefix(digits, "-")
+	if negative || strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+	}
+	whole, fraction, hasPoint := strings.Cut(digits, ".")
+	exponent := CurrencyExponent(currency)
+	if whole == "" || (hasPoint && fraction == "") || len(fraction) > exponent {
+		return Money{}, NewInvalidMoneyError(s)
+	}
+	for _, c := range whole + fraction {
+		if c < '0' || c > '9' {
+			return Money{}, NewInvalidMoneyError(s)
+		}
+	}
+
+	fraction += strings.Repeat("0", exponent-len(fraction))
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, NewInvalidMoneyError(s)
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// String returns the amount as a decimal with the digits of the minor unit
+// of its currency, without the currency.
+func (m Money) String() string {
+	exponent := CurrencyExponent(m.Currency)
+
+	sign := ""
+	amount := strconv.FormatUint(uint64(m.Amount), 10)
+	if m.Amount < 0 {
+		sign = "-"
+		amount = strconv.FormatUint(uint64(-m.Amount), 10)
+	}
+	if exponent == 0 {
+		return sign + amount
+	}
+	if len(amount) <= exponent {
+		amount = strings.Repeat("0", exponent-len(amount)+1) + amount
+	}
+	return sign + amount[:len(amount)-exponent] + "." + amount[len(amount)-exponent:]
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// sameCurrency returns the currency of the result of an operation between m
+// and o. A zero Money{} takes the currency of the other operand, so sums can
+// start from it.
+func (m Money) sameCurrency(o Money) string {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency
+	case m == Money{}:
+		return o.Currency
+	case o == Money{}:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: mixing %s and %s amounts", m.Currency, o.Currency))
+}
+
+// Add returns m+o. It panics when their currencies differ.
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.sameCurrency(o)}
+}
+
+// Sub returns m-o. It panics when their currencies differ.
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.sameCurrency(o)}
+}
+
+// Cmp compares m and o as cmp.Compare does. It panics when their currencies
+// differ.
+func (m Money) Cmp(o Money) int {
+	m.sameCurrency(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Mul returns m times n.
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// MulRat returns m times r, rounded to the minor unit as mode says.
+func (m Money) MulRat(r *big.Rat, mode RoundingMode) Money {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), r)
+	return Money{Amount: roundRat(product, mode), Currency: m.Currency}
+}
+
+// Percent returns percent percent of m, rounded as mode says. percent is
+// taken as the decimal it prints as, so 12.3 is exactly 12.3%.
+func (m Money) Percent(percent float64, mode RoundingMode) Money {
+	return m.MulRat(PercentRat(percent), mode)
+}
+
+// PercentRat returns percent/100 as an exact fraction of the decimal percent
+// prints as.
+func PercentRat(percent float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(percent, 'f', -1, 64))
+	return r.Quo(r, big.NewRat(100, 1))
+}
+
+// roundRat rounds r to an integer as mode says.
+func roundRat(r *big.Rat, mode RoundingMode) int64 {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() == 0 {
+		return quo.Int64()
+	}
+
+	// compare twice the remainder with the denominator to tell which
+	// integer r is closer to
+	half := new(big.Int).Abs(rem)
+	half.Lsh(half, 1)
+	away := false
+	switch half.Cmp(r.Denom()) {
+	case 1:
+		away = true
+	case 0:
+		away = mode == RoundHalfUp || quo.Bit(0) == 1
+	}
+	if away {
+		quo.Add(quo, big.NewInt(int64(r.Sign())))
+	}
+	return quo.Int64()
+}
+
+type moneyJSON struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m == (Money{}) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{Amount: m.String(), Currency: m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var value moneyJSON
+	if bytes.HasPrefix(data, []byte("{")) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(data, &value.Amount); err != nil {
+		return NewInvalidMoneyError(string(data))
+	}
+
+	if value.Currency == "" {
+		value.Currency = DefaultCurrency
+	}
+	parsed, err := ParseMoney(value.Amount.String(), value.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
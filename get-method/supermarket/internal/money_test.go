@@ -0,0 +1,115 @@
+package internal_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"supermarket/internal"
+
+	"github.com/stretchr/testify/require"
+)
+
+// usd returns an amount of cents of the default currency.
+func usd(cents int64) internal.Money {
+	return internal.NewMoney(cents, internal.DefaultCurrency)
+}
+
+func TestParseMoney(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		currency string
+		amount   int64
+		text     string
+	}{
+		{value: "12.5", currency: "USD", amount: 1250, text: "12.50"},
+		{value: "0.05", currency: "USD", amount: 5, text: "0.05"},
+		{value: "-3", currency: "EUR", amount: -300, text: "-3.00"},
+		{value: "1500", currency: "JPY", amount: 1500, text: "1500"},
+		{value: "1.234", currency: "KWD", amount: 1234, text: "1.234"},
+	} {
+		money, err := internal.ParseMoney(tc.value, tc.currency)
+		require.NoError(t, err, tc.value)
+		require.Equal(t, internal.NewMoney(tc.amount, tc.currency), money)
+		require.Equal(t, tc.text, money.String())
+	}
+
+	for _, tc := range [][2]string{
+		{"12.345", "USD"},
+		{"1.5", "JPY"},
+		{"", "USD"},
+		{"1.", "USD"},
+		{".5", "USD"},
+		{"1e2", "USD"},
+		{"NaN", "USD"},
+		{"99999999999999999999", "USD"},
+		{"1", "usd"},
+	} {
+		_, err := internal.ParseMoney(tc[0], tc[1])
+		require.True(t, errors.As(err, &internal.InvalidMoneyError{}), tc)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	// the float64 sum would be 0.30000000000000004
+	require.Equal(t, usd(30), usd(10).Add(usd(20)))
+	require.Equal(t, usd(-10), usd(10).Sub(usd(20)))
+	require.Equal(t, usd(3), internal.Money{}.Add(usd(3)))
+	require.Equal(t, usd(330), usd(110).Mul(3))
+	require.Equal(t, -1, usd(1).Cmp(usd(2)))
+
+	require.Panics(t, func() { usd(1).Add(internal.NewMoney(1, "EUR")) })
+}
+
+func TestMoneyRounding(t *testing.T) {
+	for _, tc := range []struct {
+		amount  int64
+		percent float64
+		mode    internal.RoundingMode
+		want    int64
+	}{
+		// 10% of 0.25 is 2.5 cents
+		{amount: 25, percent: 10, mode: internal.RoundHalfEven, want: 2},
+		{amount: 25, percent: 10, mode: internal.RoundHalfUp, want: 3},
+		{amount: 35, percent: 10, mode: internal.RoundHalfEven, want: 4},
+		{amount: -25, percent: 10, mode: internal.RoundHalfUp, want: -3},
+		{amount: -25, percent: 10, mode: internal.RoundHalfEven, want: -2},
+		// 12.3% is exact, not the closest float64
+		{amount: 1000, percent: 12.3, mode: internal.RoundHalfEven, want: 123},
+		{amount: 999, percent: 33.3, mode: internal.RoundHalfEven, want: 333},
+	} {
+		require.Equal(t, usd(tc.want), usd(tc.amount).Percent(tc.percent, tc.mode), tc)
+	}
+
+	require.Equal(t, usd(33), usd(100).MulRat(big.NewRat(1, 3), internal.RoundHalfEven))
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(internal.NewMoney(1005, "EUR"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"amount": "10.05", "currency": "EUR"}`, string(data))
+
+	// no amount at all
+	data, err = json.Marshal(internal.Money{})
+	require.NoError(t, err)
+	require.Equal(t, "null", string(data))
+
+	for input, want := range map[string]internal.Money{
+		`null`:                                   {},
+		`{"amount": "10.05", "currency": "EUR"}`: internal.NewMoney(1005, "EUR"),
+		`{"amount": 10.05, "currency": "EUR"}`:   internal.NewMoney(1005, "EUR"),
+		`{"amount": "0.1"}`:                      usd(10),
+		`71.42`:                                  usd(7142),
+		`"0.3"`:                                  usd(30),
+	} {
+		var money internal.Money
+		require.NoError(t, json.Unmarshal([]byte(input), &money), input)
+		require.Equal(t, want, money, input)
+	}
+
+	for _, input := range []string{`"cheap"`, `1.001`, `true`, `{"amount": "1", "currency": "dollars"}`} {
+		var money internal.Money
+		require.Error(t, json.Unmarshal([]byte(input), &money), input)
+	}
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OrderStatus is the stage of an order in its lifecycle.
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderFulfilled OrderStatus = "fulfilled"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+// orderTransitions holds the statuses each status can move to. Cancelled
+// and refunded orders are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderFulfilled, OrderRefunded},
+	OrderFulfilled: {OrderRefunded},
+}
+
+// Valid reports whether s is a known status.
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case OrderPending, OrderPaid, OrderFulfilled, OrderCancelled, OrderRefunded:
+		return true
+	}
+	return false
+}
+
+// CanMoveTo reports whether an order in status s can move to status to.
+func (s OrderStatus) CanMoveTo(to OrderStatus) bool {
+	return slices.Contains(orderTransitions[s], to)
+}
+
+// RestoresStock reports whether moving an order from status s to status to
+// puts its items back in stock: when it is cancelled, or refunded before
+// being fulfilled. Refunds of fulfilled orders leave the stock alone, the
+// items were shipped.
+func (s OrderStatus) RestoresStock(to OrderStatus) bool {
+	return to == OrderCancelled || (s == OrderPaid && to == OrderRefunded)
+}
+
+// OrderEvent records when an order moved to Status.
+type OrderEvent struct {
+	Status OrderStatus `json:"status"`
+	At     time.Time   `json:"at"`
+}
+
+// Order is a cart bought at the prices of its Receipt, every line of which
+// was taken off the stock. History lists the statuses the order went
+// through, oldest first, the last one being Status.
+type Order struct {
+	Id        int          `json:"id"`
+	Status    OrderStatus  `json:"status"`
+	Receipt   Receipt      `json:"receipt"`
+	History   []OrderEvent `json:"history"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type OrderRepository interface {
+	// GetAll returns the orders in status, or all of them when it is empty,
+	// sorted by id.
+	GetAll(ctx context.Context, status OrderStatus) ([]Order, error)
+	GetById(ctx context.Context, id int) (Order, error)
+	Save(ctx context.Context, order Order) (Order, error)
+	// Update replaces the order with the id of order.
+	Update(ctx context.Context, order Order) (Order, error)
+}
+
+type OrderService interface {
+	// Checkout quotes cart and orders it, taking its items off the stock. It
+	// fails with CheckoutRejectedError when a line of the cart can not be
+	// bought, and with InsufficientStockError when the stock went short
+	// since the quote.
+	Checkout(ctx context.Context, cart Cart) (Order, error)
+	GetAll(ctx context.Context, status OrderStatus) ([]Order, error)
+	GetById(ctx context.Context, id int) (Order, error)
+	// Transition moves an order to status, restoring its stock when it is
+	// cancelled or refunded before fulfilment. It fails with
+	// InvalidOrderTransitionError when the order can not move to status.
+	Transition(ctx context.Context, id int, status OrderStatus) (Order, error)
+}
+
+// CheckoutRejectedError lists the lines of a cart that could not be bought.
+type CheckoutRejectedError struct {
+	Rejected []RejectedLine
+}
+
+func (e CheckoutRejectedError) Error() string {
+	lines := make([]string, len(e.Rejected))
+	for i, line := range e.Rejected {
+		lines[i] = fmt.Sprintf("%d (%s)", line.Line, line.Reason)
+	}
+	if len(lines) == 0 {
+		return "cart has nothing to buy"
+	}
+	return "cart lines can not be bought: " + strings.Join(lines, ", ")
+}
+
+func NewCheckoutRejectedError(rejected []RejectedLine) error {
+	return CheckoutRejectedError{Rejected: rejected}
+}
+
+type InvalidOrderTransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e InvalidOrderTransitionError) Error() string {
+	return fmt.Sprintf("order can not move from %s to %s", e.From, e.To)
+}
+
+func NewInvalidOrderTransitionError(from, to OrderStatus) error {
+	return InvalidOrderTransitionError{From: from, To: to}
+}
+
+type OrderNotFoundError struct{}
+
+func (e OrderNotFoundError) Error() string {
+	return "order not found"
+}
+
+func NewOrderNotFoundError() error {
+	return OrderNotFoundError{}
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// PriceChange is an entry of the price history of a product: it is priced
+// at Price from EffectiveFrom on, until the next change. Previous is the
+// price it had before, once the change took effect. Pending changes are
+// scheduled ones waiting for EffectiveFrom to be repriced.
+type PriceChange struct {
+	Id            int       `json:"id"`
+	ProductId     int       `json:"product_id"`
+	Price         Money     `json:"price"`
+	Previous      *Money    `json:"previous,omitempty"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	Pending       bool      `json:"pending,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (c PriceChange) Validate() error {
+	if c.ProductId <= 0 {
+		return NewInvalidPriceChangeError("product_id")
+	}
+	if !c.Price.IsPositive() || !ValidCurrency(c.Price.Currency) {
+		return NewInvalidPriceChangeError("price")
+	}
+	if c.EffectiveFrom.IsZero() {
+		return NewInvalidPriceChangeError("effective_from")
+	}
+	return nil
+}
+
+// PriceAt returns the price of a product at t given its history, oldest
+// change first, and its current price. Before the first change it had the
+// price replaced by the first change that took effect, when known, and
+// otherwise the price of the first change, or its current price without
+// history.
+func PriceAt(history []PriceChange, current Money, t time.Time) Money {
+	if len(history) == 0 {
+		return current
+	}
+	price := history[0].Price
+	for _, change := range history {
+		if change.Previous != nil {
+			price = *change.Previous
+			break
+		}
+	}
+	for _, change := range history {
+		if change.EffectiveFrom.After(t) {
+			break
+		}
+		price = change.Price
+	}
+	return price
+}
+
+type PriceHistoryRepository interface {
+	// History returns the price changes of product id, oldest effective
+	// first.
+	History(ctx context.Context, productId int) ([]PriceChange, error)
+	// Pending returns the pending changes effective at until, oldest
+	// effective first.
+	Pending(ctx context.Context, until time.Time) ([]PriceChange, error)
+	GetById(ctx context.Context, id int) (PriceChange, error)
+	Save(ctx context.Context, change PriceChange) (PriceChange, error)
+	Update(ctx context.Context, change PriceChange) (PriceChange, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type PriceHistoryService interface {
+	// History fails with ProductNotFoundError when the product does not
+	// exist.
+	History(ctx context.Context, productId int) ([]PriceChange, error)
+	// Schedule plans a change of price effective in the future. It fails
+	// with InvalidPriceChangeError when it is not.
+	Schedule(ctx context.Context, change PriceChange) (PriceChange, error)
+	// Cancel deletes a scheduled change of product id. It fails with
+	// PriceChangeNotPendingError when it already took effect.
+	Cancel(ctx context.Context, productId int, id int) error
+	// PriceAt returns the price product had, or is scheduled to have, at t.
+	PriceAt(ctx context.Context, product Product, t time.Time) (Money, error)
+	// ApplyDue reprices the products whose scheduled changes took effect
+	// and returns their ids.
+	ApplyDue(ctx context.Context) ([]int, error)
+}
+
+type InvalidPriceChangeError struct {
+	Field string
+}
+
+func (e InvalidPriceChangeError) Error() string {
+	return "invalid price change: " + e.Field
+}
+
+func NewInvalidPriceChangeError(field string) error {
+	return InvalidPriceChangeError{Field: field}
+}
+
+type PriceChangeNotFoundError struct{}
+
+func (e PriceChangeNotFoundError) Error() string {
+	return "price change not found"
+}
+
+func NewPriceChangeNotFoundError() error {
+	return PriceChangeNotFoundError{}
+}
+
+type PriceChangeNotPendingError struct{}
+
+func (e PriceChangeNotPendingError) Error() string {
+	return "price change already took effect"
+}
+
+func NewPriceChangeNotPendingError() error {
+	return PriceChangeNotPendingError{}
+}
@@ -1,36 +1,70 @@
 package internal
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type ProductRepository interface {
-	Start() (int, error)
-	GetAll() (map[int]Product, error)
-	GetById(id int) (Product, error)
-	Save(product Product) Product
-	GetByGreaterPrice(price float64) ([]Product, error)
-	GetByCode(code string) (*Product, error)
-	UpdateOrCreate(product Product) (Product, error)
-	PartialUpdate(id int, product Product) (Product, error)
-	Delete(id int) error
+	Start(ctx context.Context) (int, error)
+	GetAll(ctx context.Context) (map[int]Product, error)
+	// GetPage returns a page of the products sorted as query says. query
+	// must be valid.
+	GetPage(ctx context.Context, query ProductPageQuery) (ProductPage, error)
+	GetById(ctx context.Context, id int) (Product, error)
+	Save(ctx context.Context, product Product) (Product, error)
+	// GetByGreaterPrice returns the products priced above price, in its
+	// currency.
+	GetByGreaterPrice(ctx context.Context, price Money) ([]Product, error)
+	GetByCode(ctx context.Context, code string) (*Product, error)
+	UpdateOrCreate(ctx context.Context, product Product) (Product, error)
+	PartialUpdate(ctx context.Context, id int, product Product) (Product, error)
+	Delete(ctx context.Context, id int) error
 }
 
 type ProductDBRepository interface {
-	GetById(id int) (Product, error)
-	Create(product *Product) error
-	Update(product *Product) error
-	Delete(id int) error
+	GetById(ctx context.Context, id int) (Product, error)
+	Create(ctx context.Context, product *Product) error
+	Update(ctx context.Context, product *Product) error
+	Delete(ctx context.Context, id int) error
+}
+
+// ProductUnitOfWork runs several repository operations as one transaction.
+type ProductUnitOfWork interface {
+	// WithinTransaction calls fn with a repository whose writes are all
+	// committed when fn returns nil and all discarded otherwise. fn must only
+	// use the repository it is given.
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context, repo ProductRepository) error) error
+}
+
+// ProductSearcher is implemented by repositories able to search products by
+// name.
+type ProductSearcher interface {
+	// SearchNames returns the limit products most relevant to text and the
+	// number of products matching it.
+	SearchNames(ctx context.Context, text string, limit int) ([]ProductSearchResult, int, error)
 }
 
 type ProductService interface {
-	CheckUniqueCode(code string) (bool, error)
-	Save(product Product) Product
-	GetAll() (map[int]Product, error)
-	GetById(id int) (Product, error)
-	GetByGreaterPrice(price float64) ([]Product, error)
-	UpdateOrCreate(product Product) (Product, error)
-	PartialUpdate(id int, product Product) (Product, error)
-	Delete(id int) error
-	GetTotalPrice(productIds []int) (float64, error)
+	CheckUniqueCode(ctx context.Context, code string) (bool, error)
+	Save(ctx context.Context, product Product) (Product, error)
+	GetAll(ctx context.Context) (map[int]Product, error)
+	// GetPage fails with InvalidPageQueryError when query is not valid once
+	// its defaults are applied.
+	GetPage(ctx context.Context, query ProductPageQuery) (ProductPage, error)
+	GetById(ctx context.Context, id int) (Product, error)
+	GetByGreaterPrice(ctx context.Context, price Money) ([]Product, error)
+	// UpdateOrCreate and PartialUpdate fail with ProductVersionMismatchError
+	// when product.Version is set and is not the stored version.
+	UpdateOrCreate(ctx context.Context, product Product) (Product, error)
+	PartialUpdate(ctx context.Context, id int, product Product) (Product, error)
+	// Delete fails with ProductVersionMismatchError when version is set and is
+	// not the stored version.
+	Delete(ctx context.Context, id int, version int) error
+	GetTotalPrice(ctx context.Context, productIds []int) (Money, error)
+	// Search returns the limit products whose names are most relevant to
+	// text and the number of products matching it.
+	Search(ctx context.Context, text string, limit int) ([]ProductSearchResult, int, error)
 }
 
 type InvalidProductError struct {
@@ -65,32 +99,75 @@ func NewProductAlreadyExistsError() error {
 	return ProductAlreadyExistsError{}
 }
 
+// ProductSearchResult is a product found by a text search. Score ranks the
+// results, the higher the more relevant, and Highlighted is the product name
+// as HTML with the matching parts marked.
+type ProductSearchResult struct {
+	Product
+	Score       float64 `json:"score"`
+	Highlighted string  `json:"highlighted"`
+}
+
+// ProductVersionMismatchError is returned when a write expects a product
+// version other than the stored one.
+type ProductVersionMismatchError struct {
+	Expected int
+	Actual   int
+}
+
+func (e ProductVersionMismatchError) Error() string {
+	return "product version mismatch"
+}
+
+func NewProductVersionMismatchError(expected, actual int) error {
+	return ProductVersionMismatchError{Expected: expected, Actual: actual}
+}
+
+// Product is an item of the catalog. Version starts at 1 and is incremented
+// by the repositories on every write. TaxCategory selects the tax rate of
+// the product, DefaultTaxCategory when it is empty. CategoryIds lists the
+// catalog categories the product belongs to, in increasing order.
 type Product struct {
-	Id          int     `json:"id,omitempty"`
-	Name        string  `json:"name"`
-	Quantity    int     `json:"quantity"`
-	Code        string  `json:"code_value"`
-	IsPublished bool    `json:"is_published,omitempty"`
-	Expiration  string  `json:"expiration"`
-	Price       float64 `json:"price"`
+	Id          int    `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Quantity    int    `json:"quantity"`
+	Code        string `json:"code_value"`
+	IsPublished bool   `json:"is_published,omitempty"`
+	Expiration  string `json:"expiration"`
+	Price       Money  `json:"price"`
+	TaxCategory string `json:"tax_category,omitempty"`
+	CategoryIds []int  `json:"category_ids,omitempty"`
+	Version     int    `json:"version,omitempty"`
 }
 
 func (p Product) Validate() error {
-	if p.Name == "" {
-		return NewInvalidProductError("name")
+	if err := p.ValidateDetails(); err != nil {
+		return err
 	}
 	if p.Quantity == 0 {
 		return NewInvalidProductError("quantity")
 	}
+	return nil
+}
+
+// ValidateDetails checks every field but the quantity, which can be none once
+// the stock ledger of the product sets it.
+func (p Product) ValidateDetails() error {
+	if p.Name == "" {
+		return NewInvalidProductError("name")
+	}
 	if p.Code == "" {
 		return NewInvalidProductError("code")
 	}
 	if p.Expiration == "" {
 		return NewInvalidProductError("expiration")
 	}
-	if p.Price <= 0 {
+	if !p.Price.IsPositive() {
 		return NewInvalidProductError("price")
 	}
+	if !ValidCurrency(p.Price.Currency) {
+		return NewInvalidProductError("currency")
+	}
 	if _, err := time.Parse("02/01/2006", p.Expiration); err != nil {
 		return NewInvalidProductError("date")
 	}
@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ProductFilter is a node of a product filter expression. Repositories
+// either evaluate filters with Match or translate them into their own query
+// language.
+type ProductFilter interface {
+	// Match reports whether product satisfies the filter.
+	Match(product Product) bool
+	// Validate fails with InvalidFilterError when the filter is malformed.
+	Validate() error
+}
+
+// FilterOp is the operator of a filter condition.
+type FilterOp string
+
+const (
+	OpEq       FilterOp = "eq"
+	OpGt       FilterOp = "gt"
+	OpGte      FilterOp = "gte"
+	OpLt       FilterOp = "lt"
+	OpLte      FilterOp = "lte"
+	OpBefore   FilterOp = "before"
+	OpAfter    FilterOp = "after"
+	OpContains FilterOp = "contains"
+	OpPrefix   FilterOp = "prefix"
+	OpIn       FilterOp = "in"
+)
+
+// comparison reports whether op holds for a result c of cmp.Compare, and
+// whether op is a comparison at all.
+func (op FilterOp) comparison(c int) (holds bool, ok bool) {
+	switch op {
+	case OpEq:
+		return c == 0, true
+	case OpGt:
+		return c > 0, true
+	case OpGte:
+		return c >= 0, true
+	case OpLt:
+		return c < 0, true
+	case OpLte:
+		return c <= 0, true
+	}
+	return false, false
+}
+
+type InvalidFilterError struct {
+	Reason string
+}
+
+func (e InvalidFilterError) Error() string {
+	return "invalid filter: " + e.Reason
+}
+
+func NewInvalidFilterError(reason string) error {
+	return InvalidFilterError{Reason: reason}
+}
+
+// AndFilter matches products matching all of its filters.
+type AndFilter []ProductFilter
+
+func (f AndFilter) Match(product Product) bool {
+	for _, filter := range f {
+		if !filter.Match(product) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f AndFilter) Validate() error {
+	return validateFilters(f)
+}
+
+// OrFilter matches products matching any of its filters.
+type OrFilter []ProductFilter
+
+func (f OrFilter) Match(product Product) bool {
+	for _, filter := range f {
+		if filter.Match(product) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f OrFilter) Validate() error {
+	return validateFilters(f)
+}
+
+func validateFilters(filters []ProductFilter) error {
+	for _, filter := range filters {
+		if filter == nil {
+			return NewInvalidFilterError("missing condition")
+		}
+		if err := filter.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PriceFilter compares the price of products with Value. Products priced
+// in another currency never match.
+type PriceFilter struct {
+	Op    FilterOp
+	Value Money
+}
+
+func (f PriceFilter) Match(product Product) bool {
+	if product.Price.Currency != f.Value.Currency {
+		return false
+	}
+	holds, _ := f.Op.comparison(product.Price.Cmp(f.Value))
+	return holds
+}
+
+func (f PriceFilter) Validate() error {
+	if _, ok := f.Op.comparison(0); !ok {
+		return NewInvalidFilterError("price can not be filtered with " + string(f.Op))
+	}
+	return nil
+}
+
+// QuantityFilter compares the quantity of products with Value.
+type QuantityFilter struct {
+	Op    FilterOp
+	Value int
+}
+
+func (f QuantityFilter) Match(product Product) bool {
+	holds, _ := f.Op.comparison(cmp.Compare(product.Quantity, f.Value))
+	return holds
+}
+
+func (f QuantityFilter) Validate() error {
+	if _, ok := f.Op.comparison(0); !ok {
+		return NewInvalidFilterError("quantity can not be filtered with " + string(f.Op))
+	}
+	return nil
+}
+
+// PublishedFilter matches products whose IsPublished is Value.
+type PublishedFilter struct {
+	Value bool
+}
+
+func (f PublishedFilter) Match(product Product) bool {
+	return product.IsPublished == f.Value
+}
+
+func (f PublishedFilter) Validate() error {
+	return nil
+}
+
+// ExpirationFilter matches products expiring strictly before or after Date.
+// Products without an expiration never match.
+type ExpirationFilter struct {
+	Op   FilterOp
+	Date time.Time
+}
+
+func (f ExpirationFilter) Match(product Product) bool {
+	expiration, err := time.Parse("02/01/2006", product.Expiration)
+	if err != nil {
+		return false
+	}
+
+	switch f.Op {
+	case OpBefore:
+		return expiration.Before(f.Date)
+	case OpAfter:
+		return expiration.After(f.Date)
+	}
+	return false
+}
+
+func (f ExpirationFilter) Validate() error {
+	if f.Op != OpBefore && f.Op != OpAfter {
+		return NewInvalidFilterError("expiration can not be filtered with " + string(f.Op))
+	}
+	return nil
+}
+
+// NameFilter matches products whose name contains or starts with Value,
+// ignoring case.
+type NameFilter struct {
+	Op    FilterOp
+	Value string
+}
+
+func (f NameFilter) Match(product Product) bool {
+	name, value := strings.ToLower(product.Name), strings.ToLower(f.Value)
+
+	switch f.Op {
+	case OpContains:
+		return strings.Contains(name, value)
+	case OpPrefix:
+		return strings.HasPrefix(name, value)
+	}
+	return false
+}
+
+func (f NameFilter) Validate() error {
+	if f.Op != OpContains && f.Op != OpPrefix {
+		return NewInvalidFilterError("name can not be filtered with " + string(f.Op))
+	}
+	return nil
+}
+
+// CodeFilter matches products whose code is one of Codes.
+type CodeFilter struct {
+	Codes []string
+}
+
+func (f CodeFilter) Match(product Product) bool {
+	for _, code := range f.Codes {
+		if product.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (f CodeFilter) Validate() error {
+	return nil
+}
+
+// CategoryFilter matches products in any of the categories of Ids. It is
+// not part of the filter language: the categories under a category are
+// resolved by the category service.
+type CategoryFilter struct {
+	Ids []int
+}
+
+func (f CategoryFilter) Match(product Product) bool {
+	for _, id := range product.CategoryIds {
+		if slices.Contains(f.Ids, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f CategoryFilter) Validate() error {
+	return nil
+}
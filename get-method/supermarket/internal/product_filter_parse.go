@@ -0,0 +1,334 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxFilterDepth bounds the nesting of parenthesized filters.
+const maxFilterDepth = 16
+
+// ParseProductFilter parses a filter expression such as
+//
+//	price gte 10 and price lt 20 and (name contains "milk" or code_value in (c1, c2))
+//
+// Conditions are a field, an operator and a value:
+//
+//	price, quantity  eq, gt, gte, lt, lte and a number, prices being
+//	                 amounts of DefaultCurrency
+//	is_published     eq and true or false
+//	expiration       before, after and a dd/mm/yyyy date
+//	name             contains, prefix and a string
+//	code_value       eq and a string, or in and a parenthesized list of them
+//
+// and binds tighter than or, and values containing spaces or punctuation are
+// double quoted.
+func ParseProductFilter(expression string) (ProductFilter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	filter, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+	return filter, nil
+}
+
+type filterTokenKind int
+
+const (
+	tokenWord filterTokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+	tokenComma
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	pos  int
+}
+
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	for pos := 0; pos < len(expression); {
+		c := rune(expression[pos])
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: tokenOpen, text: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: tokenClose, text: ")", pos: pos})
+			pos++
+		case c == ',':
+			tokens = append(tokens, filterToken{kind: tokenComma, text: ",", pos: pos})
+			pos++
+		case c == '"':
+			end := pos + 1
+			for end < len(expression) && expression[end] != '"' {
+				if expression[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expression) {
+				return nil, NewInvalidFilterError(fmt.Sprintf("unterminated string at %d", pos))
+			}
+			value, err := strconv.Unquote(expression[pos : end+1])
+			if err != nil {
+				return nil, NewInvalidFilterError(fmt.Sprintf("malformed string at %d", pos))
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: value, pos: pos})
+			pos = end + 1
+		default:
+			end := pos
+			for end < len(expression) && isFilterWordByte(expression[end]) {
+				end++
+			}
+			if end == pos {
+				return nil, NewInvalidFilterError(fmt.Sprintf("unexpected %q at %d", c, pos))
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, text: expression[pos:end], pos: pos})
+			pos = end
+		}
+	}
+	return tokens, nil
+}
+
+func isFilterWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("_-+./:", c) >= 0
+}
+
+type filterParser struct {
+	tokens []filterToken
+	next   int
+}
+
+func (p *filterParser) done() bool {
+	return p.next >= len(p.tokens)
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) errorf(format string, args ...any) error {
+	where := "at end of filter"
+	if !p.done() {
+		where = fmt.Sprintf("at %d", p.peek().pos)
+	}
+	return NewInvalidFilterError(fmt.Sprintf(format, args...) + " " + where)
+}
+
+// keyword consumes the next token when it is the given keyword.
+func (p *filterParser) keyword(word string) bool {
+	if p.done() || p.peek().kind != tokenWord || !strings.EqualFold(p.peek().text, word) {
+		return false
+	}
+	p.next++
+	return true
+}
+
+func (p *filterParser) expect(kind filterTokenKind, what string) (filterToken, error) {
+	if p.done() {
+		return filterToken{}, p.errorf("missing %s", what)
+	}
+	token := p.peek()
+	if token.kind != kind {
+		return filterToken{}, p.errorf("expected %s", what)
+	}
+	p.next++
+	return token, nil
+}
+
+// value consumes a word or a string.
+func (p *filterParser) value() (string, error) {
+	if p.done() {
+		return "", p.errorf("missing value")
+	}
+	token := p.peek()
+	if token.kind != tokenWord && token.kind != tokenString {
+		return "", p.errorf("expected a value")
+	}
+	p.next++
+	return token.text, nil
+}
+
+func (p *filterParser) parseOr(depth int) (ProductFilter, error) {
+	filters := OrFilter{}
+	for {
+		filter, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+		if !p.keyword("or") {
+			break
+		}
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return filters, nil
+}
+
+func (p *filterParser) parseAnd(depth int) (ProductFilter, error) {
+	filters := AndFilter{}
+	for {
+		filter, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+		if !p.keyword("and") {
+			break
+		}
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return filters, nil
+}
+
+func (p *filterParser) parseUnary(depth int) (ProductFilter, error) {
+	if p.done() {
+		return nil, p.errorf("missing condition")
+	}
+	if p.peek().kind != tokenOpen {
+		return p.parseCondition()
+	}
+
+	if depth >= maxFilterDepth {
+		return nil, p.errorf("filter nested too deeply")
+	}
+	p.next++
+	filter, err := p.parseOr(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenClose, `")"`); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func (p *filterParser) parseCondition() (ProductFilter, error) {
+	field, err := p.expect(tokenWord, "a field")
+	if err != nil {
+		return nil, err
+	}
+	opToken, err := p.expect(tokenWord, "an operator")
+	if err != nil {
+		return nil, err
+	}
+	op := FilterOp(strings.ToLower(opToken.text))
+
+	var filter ProductFilter
+	switch strings.ToLower(field.text) {
+	case "price":
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		price, err := ParseMoney(value, DefaultCurrency)
+		if err != nil {
+			return nil, NewInvalidFilterError(fmt.Sprintf("price %q is not an amount of %s", value, DefaultCurrency))
+		}
+		filter = PriceFilter{Op: op, Value: price}
+	case "quantity":
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		quantity, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, NewInvalidFilterError(fmt.Sprintf("quantity %q is not an integer", value))
+		}
+		filter = QuantityFilter{Op: op, Value: quantity}
+	case "is_published":
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		published, err := strconv.ParseBool(value)
+		if err != nil || op != OpEq {
+			return nil, NewInvalidFilterError("is_published only supports eq true or eq false")
+		}
+		filter = PublishedFilter{Value: published}
+	case "expiration":
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		date, err := time.Parse("02/01/2006", value)
+		if err != nil {
+			return nil, NewInvalidFilterError(fmt.Sprintf("expiration %q is not a dd/mm/yyyy date", value))
+		}
+		filter = ExpirationFilter{Op: op, Date: date}
+	case "name":
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		filter = NameFilter{Op: op, Value: value}
+	case "code_value":
+		codes, err := p.parseCodes(op)
+		if err != nil {
+			return nil, err
+		}
+		filter = CodeFilter{Codes: codes}
+	default:
+		return nil, NewInvalidFilterError(fmt.Sprintf("unknown field %q at %d", field.text, field.pos))
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func (p *filterParser) parseCodes(op FilterOp) ([]string, error) {
+	switch op {
+	case OpEq:
+		code, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return []string{code}, nil
+	case OpIn:
+	default:
+		return nil, NewInvalidFilterError("code_value can not be filtered with " + string(op))
+	}
+
+	if _, err := p.expect(tokenOpen, `"("`); err != nil {
+		return nil, err
+	}
+	var codes []string
+	for {
+		code, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		if p.done() || p.peek().kind != tokenComma {
+			break
+		}
+		p.next++
+	}
+	if _, err := p.expect(tokenClose, `")"`); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
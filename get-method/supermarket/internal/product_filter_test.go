@@ -0,0 +1,96 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"supermarket/internal"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProductFilter(t *testing.T) {
+	date := time.Date(2065, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	for expression, expected := range map[string]internal.ProductFilter{
+		"price gt 10":                  internal.PriceFilter{Op: internal.OpGt, Value: usd(1000)},
+		"PRICE LTE 2.5":                internal.PriceFilter{Op: internal.OpLte, Value: usd(250)},
+		"quantity gte 3":               internal.QuantityFilter{Op: internal.OpGte, Value: 3},
+		"is_published eq false":        internal.PublishedFilter{Value: false},
+		"expiration before 01/02/2065": internal.ExpirationFilter{Op: internal.OpBefore, Date: date},
+		`name contains "sweet corn"`:   internal.NameFilter{Op: internal.OpContains, Value: "sweet corn"},
+		`name prefix "say \"hi\""`:     internal.NameFilter{Op: internal.OpPrefix, Value: `say "hi"`},
+		"code_value in (c1, \"c 2\")":  internal.CodeFilter{Codes: []string{"c1", "c 2"}},
+		"code_value eq 0009-1111":      internal.CodeFilter{Codes: []string{"0009-1111"}},
+		"((price gt 1))":               internal.PriceFilter{Op: internal.OpGt, Value: usd(100)},
+		"price gt 1 and price lt 5 or quantity eq 0": internal.OrFilter{
+			internal.AndFilter{internal.PriceFilter{Op: internal.OpGt, Value: usd(100)}, internal.PriceFilter{Op: internal.OpLt, Value: usd(500)}},
+			internal.QuantityFilter{Op: internal.OpEq, Value: 0},
+		},
+		"price gt 1 and (price lt 5 or quantity eq 0)": internal.AndFilter{
+			internal.PriceFilter{Op: internal.OpGt, Value: usd(100)},
+			internal.OrFilter{internal.PriceFilter{Op: internal.OpLt, Value: usd(500)}, internal.QuantityFilter{Op: internal.OpEq, Value: 0}},
+		},
+	} {
+		filter, err := internal.ParseProductFilter(expression)
+		require.NoError(t, err, expression)
+		require.Equal(t, expected, filter, expression)
+	}
+}
+
+func TestParseProductFilterRejectsMalformedFilters(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"price",
+		"price gt",
+		"price gt ten",
+		"price gt NaN",
+		"price contains 1",
+		"quantity gt 1.5",
+		"is_published eq maybe",
+		"is_published gt true",
+		"expiration before 2065-02-01",
+		"expiration gt 01/02/2065",
+		"name eq milk",
+		"code_value in c1",
+		"code_value in (c1,",
+		"stock gt 1",
+		"price gt 1 and",
+		"price gt 1 price lt 2",
+		"(price gt 1",
+		"price gt 1)",
+		`name contains "milk`,
+		"name contains milk; DROP TABLE products",
+		"((((((((((((((((((price gt 1))))))))))))))))))",
+	} {
+		_, err := internal.ParseProductFilter(expression)
+		require.ErrorAs(t, err, &internal.InvalidFilterError{}, expression)
+	}
+}
+
+func TestProductFilterMatch(t *testing.T) {
+	product := internal.Product{Id: 1, Name: "Sweet Corn", Quantity: 5, Code: "c1", IsPublished: true, Expiration: "15/01/2065", Price: usd(250)}
+
+	for expression, expected := range map[string]bool{
+		"price eq 2.5":                                         true,
+		"price gt 2.5":                                         false,
+		"quantity lte 5 and quantity gte 5":                    true,
+		"is_published eq true":                                 true,
+		"expiration before 01/02/2065":                         true,
+		"expiration after 15/01/2065":                          false,
+		"name contains CORN":                                   true,
+		"name prefix corn":                                     false,
+		"code_value in (c0, c1)":                               true,
+		"price gt 10 or name prefix sweet":                     true,
+		"price gt 10 or (name prefix sweet and quantity lt 5)": false,
+	} {
+		filter, err := internal.ParseProductFilter(expression)
+		require.NoError(t, err, expression)
+		require.Equal(t, expected, filter.Match(product), expression)
+	}
+
+	// products without an expiration match no expiration condition
+	product.Expiration = ""
+	require.False(t, internal.ExpirationFilter{Op: internal.OpBefore, Date: time.Now()}.Match(product))
+	require.False(t, internal.ExpirationFilter{Op: internal.OpAfter, Date: time.Time{}}.Match(product))
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// ProductSortField is a product field listings can be sorted by. Prices sort
+// by their amount in minor units, whatever their currency.
+type ProductSortField string
+
+const (
+	SortById         ProductSortField = "id"
+	SortByName       ProductSortField = "name"
+	SortByPrice      ProductSortField = "price"
+	SortByQuantity   ProductSortField = "quantity"
+	SortByExpiration ProductSortField = "expiration"
+)
+
+func (f ProductSortField) valid() bool {
+	switch f {
+	case SortById, SortByName, SortByPrice, SortByQuantity, SortByExpiration:
+		return true
+	}
+	return false
+}
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 1000
+
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// ProductPageQuery selects a page of a sorted product listing, made of the
+// products matching Filter or of all of them when it is nil. Ties on the
+// sort field are broken by id, in the same direction, so the order is stable.
+// A page starts either Offset products into the listing or right after the
+// product Cursor points to, never both.
+type ProductPageQuery struct {
+	SortBy     ProductSortField
+	Descending bool
+	Limit      int
+	Offset     int
+	Cursor     string
+	Filter     ProductFilter
+}
+
+// ProductPage is a page of a product listing. NextCursor is empty on the
+// last page and Total counts every product matching the filter.
+type ProductPage struct {
+	Items      []Product `json:"items"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Total      int       `json:"total"`
+}
+
+type InvalidPageQueryError struct {
+	Field string
+}
+
+func (e InvalidPageQueryError) Error() string {
+	return "invalid page query"
+}
+
+func NewInvalidPageQueryError(field string) error {
+	return InvalidPageQueryError{Field: field}
+}
+
+// WithDefaults returns the query with its unset fields filled in.
+func (q ProductPageQuery) WithDefaults() ProductPageQuery {
+	if q.SortBy == "" {
+		q.SortBy = SortById
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultPageLimit
+	}
+	return q
+}
+
+func (q ProductPageQuery) Validate() error {
+	if !q.SortBy.valid() {
+		return NewInvalidPageQueryError("sort")
+	}
+	if q.Limit < 1 || q.Limit > MaxPageLimit {
+		return NewInvalidPageQueryError("limit")
+	}
+	if q.Offset < 0 || (q.Offset > 0 && q.Cursor != "") {
+		return NewInvalidPageQueryError("offset")
+	}
+	if q.Cursor != "" {
+		if _, err := q.CursorProduct(); err != nil {
+			return err
+		}
+	}
+	if q.Filter != nil {
+		return q.Filter.Validate()
+	}
+	return nil
+}
+
+// productCursor is the encoded form of a cursor: the sorting it belongs to,
+// the sort key and the id of the last product of a page.
+type productCursor struct {
+	SortBy     ProductSortField `json:"s"`
+	Descending bool             `json:"d,omitempty"`
+	Id         int              `json:"i"`
+	Key        json.RawMessage  `json:"k,omitempty"`
+}
+
+// NewProductCursor returns the cursor of the page following last under the
+// sorting of q.
+func NewProductCursor(q ProductPageQuery, last Product) string {
+	cursor := productCursor{SortBy: q.SortBy, Descending: q.Descending, Id: last.Id}
+
+	var key any
+	switch q.SortBy {
+	case SortByName:
+		key = last.Name
+	case SortByPrice:
+		key = last.Price
+	case SortByQuantity:
+		key = last.Quantity
+	case SortByExpiration:
+		key = last.Expiration
+	}
+	if key != nil {
+		cursor.Key, _ = json.Marshal(key)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// CursorProduct decodes the cursor of q into the product the page starts
+// after. Only its id and sort field are set.
+func (q ProductPageQuery) CursorProduct() (Product, error) {
+	var (
+		cursor  productCursor
+		product Product
+	)
+
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return Product{}, NewInvalidPageQueryError("cursor")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return Product{}, NewInvalidPageQueryError("cursor")
+	}
+	// a cursor only makes sense for the sorting it was built for
+	if cursor.SortBy != q.SortBy || cursor.Descending != q.Descending {
+		return Product{}, NewInvalidPageQueryError("cursor")
+	}
+
+	product.Id = cursor.Id
+	var key any
+	switch q.SortBy {
+	case SortByName:
+		key = &product.Name
+	case SortByPrice:
+		key = &product.Price
+	case SortByQuantity:
+		key = &product.Quantity
+	case SortByExpiration:
+		key = &product.Expiration
+	}
+	if key != nil {
+		if err := json.Unmarshal(cursor.Key, key); err != nil {
+			return Product{}, NewInvalidPageQueryError("cursor")
+		}
+	}
+	return product, nil
+}
@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// PromotionKind is the kind of discount a promotion grants.
+type PromotionKind string
+
+const (
+	// PromotionMultiBuy charges Pay units out of every Buy units of a
+	// product, as in 3 for 2.
+	PromotionMultiBuy PromotionKind = "multi_buy"
+	// PromotionPercentOff takes Percent off the targeted lines.
+	PromotionPercentOff PromotionKind = "percent_off"
+	// PromotionBuyXGetY takes Percent off Get units of GetProductId for
+	// every Buy targeted units bought.
+	PromotionBuyXGetY PromotionKind = "buy_x_get_y"
+	// PromotionBasketThreshold takes Amount, or Percent, off baskets whose
+	// total reaches Threshold. Baskets in another currency than Threshold
+	// are left alone.
+	PromotionBasketThreshold PromotionKind = "basket_threshold"
+)
+
+// Promotion is a discount rule applied when quoting carts.
+//
+// Promotions are applied by decreasing Priority, then by id, the line ones
+// before the basket ones. A promotion that is not Stackable only applies to
+// lines no other promotion discounted, and no promotion applies after it to
+// the lines it discounted; a Stackable one discounts what is left of the
+// price of lines not locked that way. Basket promotions stack the same way
+// with each other.
+//
+// The line promotions apply to the products listed in ProductIds, or to
+// every product when it is empty, further narrowed to the products expiring
+// within ExpiringWithinDays days when it is set. StartsAt and EndsAt, both
+// dd/mm/yyyy and optional, bound the days the promotion is valid, inclusive.
+type Promotion struct {
+	Id        int           `json:"id,omitempty"`
+	Name      string        `json:"name"`
+	Kind      PromotionKind `json:"kind"`
+	Priority  int           `json:"priority"`
+	Stackable bool          `json:"stackable"`
+	StartsAt  string        `json:"starts_at,omitempty"`
+	EndsAt    string        `json:"ends_at,omitempty"`
+
+	ProductIds         []int `json:"product_ids,omitempty"`
+	ExpiringWithinDays int   `json:"expiring_within_days,omitempty"`
+
+	Buy          int `json:"buy,omitempty"`
+	Pay          int `json:"pay,omitempty"`
+	Get          int `json:"get,omitempty"`
+	GetProductId int `json:"get_product_id,omitempty"`
+	// Percent is the discount of percent_off and basket_threshold
+	// promotions, and of the units given by buy_x_get_y ones, which are free
+	// when it is zero.
+	Percent   float64 `json:"percent,omitempty"`
+	Amount    Money   `json:"amount"`
+	Threshold Money   `json:"threshold"`
+}
+
+func (p Promotion) Validate() error {
+	if p.Name == "" {
+		return NewInvalidPromotionError("name")
+	}
+	if p.ExpiringWithinDays < 0 {
+		return NewInvalidPromotionError("expiring_within_days")
+	}
+
+	start, end, err := p.validity()
+	if err != nil {
+		return err
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return NewInvalidPromotionError("ends_at")
+	}
+
+	validPercent := p.Percent > 0 && p.Percent <= 100
+	switch p.Kind {
+	case PromotionMultiBuy:
+		if p.Buy < 2 {
+			return NewInvalidPromotionError("buy")
+		}
+		if p.Pay < 0 || p.Pay >= p.Buy {
+			return NewInvalidPromotionError("pay")
+		}
+	case PromotionPercentOff:
+		if !validPercent {
+			return NewInvalidPromotionError("percent")
+		}
+	case PromotionBuyXGetY:
+		if p.Buy < 1 {
+			return NewInvalidPromotionError("buy")
+		}
+		if p.Get < 1 {
+			return NewInvalidPromotionError("get")
+		}
+		if p.GetProductId == 0 {
+			return NewInvalidPromotionError("get_product_id")
+		}
+		if p.Percent != 0 && !validPercent {
+			return NewInvalidPromotionError("percent")
+		}
+	case PromotionBasketThreshold:
+		if !p.Threshold.IsPositive() || !ValidCurrency(p.Threshold.Currency) {
+			return NewInvalidPromotionError("threshold")
+		}
+		// exactly one of a fixed amount, in the currency of the threshold,
+		// or a percentage
+		if p.Amount.IsPositive() == validPercent || p.Amount.IsNegative() {
+			return NewInvalidPromotionError("amount")
+		}
+		if p.Amount.IsPositive() && p.Amount.Currency != p.Threshold.Currency {
+			return NewInvalidPromotionError("amount")
+		}
+	default:
+		return NewInvalidPromotionError("kind")
+	}
+	return nil
+}
+
+// validity parses StartsAt and EndsAt, zero when unset.
+func (p Promotion) validity() (start, end time.Time, err error) {
+	if p.StartsAt != "" {
+		if start, err = time.Parse("02/01/2006", p.StartsAt); err != nil {
+			return time.Time{}, time.Time{}, NewInvalidPromotionError("starts_at")
+		}
+	}
+	if p.EndsAt != "" {
+		if end, err = time.Parse("02/01/2006", p.EndsAt); err != nil {
+			return time.Time{}, time.Time{}, NewInvalidPromotionError("ends_at")
+		}
+	}
+	return start, end, nil
+}
+
+// ActiveOn reports whether the promotion is valid on the day of t.
+func (p Promotion) ActiveOn(t time.Time) bool {
+	start, end, err := p.validity()
+	if err != nil {
+		return false
+	}
+
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return (start.IsZero() || !day.Before(start)) && (end.IsZero() || !day.After(end))
+}
+
+// Basket reports whether the promotion discounts the whole basket rather
+// than some lines.
+func (p Promotion) Basket() bool {
+	return p.Kind == PromotionBasketThreshold
+}
+
+type PromotionRepository interface {
+	GetAll(ctx context.Context) ([]Promotion, error)
+	GetById(ctx context.Context, id int) (Promotion, error)
+	Save(ctx context.Context, promotion Promotion) (Promotion, error)
+	// Update replaces the promotion with the id of promotion.
+	Update(ctx context.Context, promotion Promotion) (Promotion, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type PromotionService interface {
+	// GetAll returns the promotions in the order they apply.
+	GetAll(ctx context.Context) ([]Promotion, error)
+	GetById(ctx context.Context, id int) (Promotion, error)
+	// Save and Update fail with InvalidPromotionError when promotion is not
+	// valid.
+	Save(ctx context.Context, promotion Promotion) (Promotion, error)
+	Update(ctx context.Context, promotion Promotion) (Promotion, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// AppliedDiscount explains a discount of a quote: the promotion granting
+// it, the cart lines it applied to, none for basket promotions, and the
+// amount taken off.
+type AppliedDiscount struct {
+	PromotionId int           `json:"promotion_id"`
+	Name        string        `json:"name"`
+	Kind        PromotionKind `json:"kind"`
+	Lines       []int         `json:"lines,omitempty"`
+	Amount      Money         `json:"amount"`
+	Description string        `json:"description"`
+}
+
+type InvalidPromotionError struct {
+	Field string
+}
+
+func (e InvalidPromotionError) Error() string {
+	return "invalid promotion: " + e.Field
+}
+
+func NewInvalidPromotionError(field string) error {
+	return InvalidPromotionError{Field: field}
+}
+
+type PromotionNotFoundError struct{}
+
+func (e PromotionNotFoundError) Error() string {
+	return "promotion not found"
+}
+
+func NewPromotionNotFoundError() error {
+	return PromotionNotFoundError{}
+}
@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// MaxPurchaseOrderLines bounds the number of lines of a purchase order.
+const MaxPurchaseOrderLines = 100
+
+// PurchaseOrderStatus is the stage of a purchase order in its lifecycle.
+type PurchaseOrderStatus string
+
+const (
+	// PurchaseOrderDraft orders are being prepared, their lines can still
+	// change.
+	PurchaseOrderDraft PurchaseOrderStatus = "draft"
+	// PurchaseOrderSent orders were sent to the supplier.
+	PurchaseOrderSent PurchaseOrderStatus = "sent"
+	// PurchaseOrderReceived orders were delivered, their lines were added
+	// to the stock.
+	PurchaseOrderReceived PurchaseOrderStatus = "received"
+	// PurchaseOrderCancelled orders will not be delivered.
+	PurchaseOrderCancelled PurchaseOrderStatus = "cancelled"
+)
+
+// purchaseOrderTransitions holds the statuses each status can move to.
+// Received and cancelled purchase orders are final.
+var purchaseOrderTransitions = map[PurchaseOrderStatus][]PurchaseOrderStatus{
+	PurchaseOrderDraft: {PurchaseOrderSent, PurchaseOrderCancelled},
+	PurchaseOrderSent:  {PurchaseOrderReceived, PurchaseOrderCancelled},
+}
+
+// Valid reports whether s is a known status.
+func (s PurchaseOrderStatus) Valid() bool {
+	switch s {
+	case PurchaseOrderDraft, PurchaseOrderSent, PurchaseOrderReceived, PurchaseOrderCancelled:
+		return true
+	}
+	return false
+}
+
+// CanMoveTo reports whether a purchase order in status s can move to status
+// to.
+func (s PurchaseOrderStatus) CanMoveTo(to PurchaseOrderStatus) bool {
+	return slices.Contains(purchaseOrderTransitions[s], to)
+}
+
+// PurchaseOrderLine orders Quantity units of a product at UnitCost each,
+// Total in all.
+type PurchaseOrderLine struct {
+	ProductId int   `json:"product_id"`
+	Quantity  int   `json:"quantity"`
+	UnitCost  Money `json:"unit_cost"`
+	Total     Money `json:"total"`
+}
+
+// PurchaseOrderEvent records when a purchase order moved to Status.
+type PurchaseOrderEvent struct {
+	Status PurchaseOrderStatus `json:"status"`
+	At     time.Time           `json:"at"`
+}
+
+// PurchaseOrder restocks products from a supplier. Total is the sum of the
+// totals of its lines, all of them in the same currency. ExpectedAt is when
+// the delivery is due, set from the lead time of the supplier once the
+// order is sent. History lists the statuses the order went through, oldest
+// first, the last one being Status.
+type PurchaseOrder struct {
+	Id         int                  `json:"id"`
+	SupplierId int                  `json:"supplier_id"`
+	Status     PurchaseOrderStatus  `json:"status"`
+	Lines      []PurchaseOrderLine  `json:"lines"`
+	Total      Money                `json:"total"`
+	ExpectedAt *time.Time           `json:"expected_at,omitempty"`
+	History    []PurchaseOrderEvent `json:"history"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+}
+
+// Validate checks the supplier and the lines of the order, each product
+// being ordered on one line only.
+func (o PurchaseOrder) Validate() error {
+	if o.SupplierId <= 0 {
+		return NewInvalidPurchaseOrderError("supplier_id")
+	}
+	if len(o.Lines) == 0 || len(o.Lines) > MaxPurchaseOrderLines {
+		return NewInvalidPurchaseOrderError("lines")
+	}
+	seen := map[int]bool{}
+	for _, line := range o.Lines {
+		if line.ProductId <= 0 || seen[line.ProductId] {
+			return NewInvalidPurchaseOrderError("product_id")
+		}
+		if line.Quantity <= 0 {
+			return NewInvalidPurchaseOrderError("quantity")
+		}
+		if line.UnitCost.IsNegative() {
+			return NewInvalidPurchaseOrderError("unit_cost")
+		}
+		seen[line.ProductId] = true
+	}
+	return nil
+}
+
+type PurchaseOrderRepository interface {
+	// GetAll returns the purchase orders in status, or all of them when it
+	// is empty, sorted by id.
+	GetAll(ctx context.Context, status PurchaseOrderStatus) ([]PurchaseOrder, error)
+	GetById(ctx context.Context, id int) (PurchaseOrder, error)
+	Save(ctx context.Context, order PurchaseOrder) (PurchaseOrder, error)
+	// Update replaces the purchase order with the id of order.
+	Update(ctx context.Context, order PurchaseOrder) (PurchaseOrder, error)
+}
+
+type PurchaseOrderService interface {
+	// Create drafts a purchase order of lines to supplier id. Lines without
+	// a unit cost are priced at the cost of the supplier. It fails with
+	// InvalidPurchaseOrderError when a product is not linked to the
+	// supplier.
+	Create(ctx context.Context, supplierId int, lines []PurchaseOrderLine) (PurchaseOrder, error)
+	GetAll(ctx context.Context, status PurchaseOrderStatus) ([]PurchaseOrder, error)
+	GetById(ctx context.Context, id int) (PurchaseOrder, error)
+	// UpdateLines replaces the lines of a purchase order, pricing them as
+	// Create does. It fails with PurchaseOrderNotDraftError once the order
+	// was sent.
+	UpdateLines(ctx context.Context, id int, lines []PurchaseOrderLine) (PurchaseOrder, error)
+	// Transition moves a purchase order to status, adding its lines to the
+	// stock when it is received. It fails with
+	// InvalidPurchaseOrderTransitionError when the order can not move to
+	// status.
+	Transition(ctx context.Context, id int, status PurchaseOrderStatus) (PurchaseOrder, error)
+}
+
+type InvalidPurchaseOrderError struct {
+	Field string
+}
+
+func (e InvalidPurchaseOrderError) Error() string {
+	return "invalid purchase order: " + e.Field
+}
+
+func NewInvalidPurchaseOrderError(field string) error {
+	return InvalidPurchaseOrderError{Field: field}
+}
+
+type InvalidPurchaseOrderTransitionError struct {
+	From PurchaseOrderStatus
+	To   PurchaseOrderStatus
+}
+
+func (e InvalidPurchaseOrderTransitionError) Error() string {
+	return fmt.Sprintf("purchase order can not move from %s to %s", e.From, e.To)
+}
+
+func NewInvalidPurchaseOrderTransitionError(from, to PurchaseOrderStatus) error {
+	return InvalidPurchaseOrderTransitionError{From: from, To: to}
+}
+
+type PurchaseOrderNotDraftError struct {
+	Status PurchaseOrderStatus
+}
+
+func (e PurchaseOrderNotDraftError) Error() string {
+	return fmt.Sprintf("purchase order is %s, only drafts can change", e.Status)
+}
+
+func NewPurchaseOrderNotDraftError(status PurchaseOrderStatus) error {
+	return PurchaseOrderNotDraftError{Status: status}
+}
+
+type PurchaseOrderNotFoundError struct{}
+
+func (e PurchaseOrderNotFoundError) Error() string {
+	return "purchase order not found"
+}
+
+func NewPurchaseOrderNotFoundError() error {
+	return PurchaseOrderNotFoundError{}
+}
@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsePeriod parses a non negative number of days, such as 7d, or a
+// duration as time.ParseDuration does.
+func ParsePeriod(value string) (time.Duration, error) {
+	var period time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		period = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if period, err = time.ParseDuration(value); err != nil {
+			return 0, err
+		}
+	}
+	if period < 0 {
+		return 0, errors.New("negative period")
+	}
+	return period, nil
+}
+
+// ParsePeriods parses a comma separated list of periods, such as 7d,30d.
+func ParsePeriods(value string) ([]time.Duration, error) {
+	var periods []time.Duration
+	for _, field := range strings.Split(value, ",") {
+		period, err := ParsePeriod(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, period)
+	}
+	return periods, nil
+}
+
+// FormatPeriod formats a period as ParsePeriod parses it, as a number of
+// days when it is whole days.
+func FormatPeriod(period time.Duration) string {
+	const day = 24 * time.Hour
+	if period > 0 && period%day == 0 {
+		return strconv.Itoa(int(period/day)) + "d"
+	}
+	return period.String()
+}
+
+// MaxReorderWindows bounds the number of sales windows of a reorder policy.
+const MaxReorderWindows = 10
+
+// ReorderPolicy is how reorder suggestions are computed. The sales velocity
+// of a product is measured over each of Windows, the fastest one being
+// planned for. Stock is reordered when it would not last the lead time of
+// the supplier plus SafetyDays, and enough is ordered to last CoverDays
+// more. Products no supplier sells take DefaultLeadTimeDays to restock.
+type ReorderPolicy struct {
+	Windows             []time.Duration
+	SafetyDays          int
+	CoverDays           int
+	DefaultLeadTimeDays int
+}
+
+// DefaultReorderPolicy returns the policy of the reorder suggestions unless
+// configured otherwise.
+func DefaultReorderPolicy() ReorderPolicy {
+	return ReorderPolicy{
+		Windows:             []time.Duration{7 * 24 * time.Hour, 30 * 24 * time.Hour},
+		SafetyDays:          3,
+		CoverDays:           14,
+		DefaultLeadTimeDays: 7,
+	}
+}
+
+func (p ReorderPolicy) Validate() error {
+	if len(p.Windows) == 0 || len(p.Windows) > MaxReorderWindows {
+		return NewInvalidReorderPolicyError("windows")
+	}
+	for _, window := range p.Windows {
+		if window <= 0 {
+			return NewInvalidReorderPolicyError("windows")
+		}
+	}
+	if p.SafetyDays < 0 {
+		return NewInvalidReorderPolicyError("safety_days")
+	}
+	if p.CoverDays < 0 {
+		return NewInvalidReorderPolicyError("cover_days")
+	}
+	if p.DefaultLeadTimeDays < 0 {
+		return NewInvalidReorderPolicyError("lead_time_days")
+	}
+	return nil
+}
+
+// SalesVelocity is how fast a product sold over the Window up to now.
+type SalesVelocity struct {
+	Window      string  `json:"window"`
+	UnitsSold   int     `json:"units_sold"`
+	UnitsPerDay float64 `json:"units_per_day"`
+}
+
+// ReorderSuggestion suggests ordering SuggestedQuantity units of a product
+// whose stock position, what is available plus what purchase orders sent
+// still bring, fell to its ReorderPoint. UnitsPerDay is the fastest of
+// Velocities and DaysOfStock how long what is available lasts at it.
+// SupplierId is the supplier with the shortest lead time, if any sells the
+// product.
+type ReorderSuggestion struct {
+	ProductId         int             `json:"product_id"`
+	Name              string          `json:"name"`
+	Code              string          `json:"code"`
+	Available         int             `json:"available"`
+	OnOrder           int             `json:"on_order"`
+	Velocities        []SalesVelocity `json:"velocities"`
+	UnitsPerDay       float64         `json:"units_per_day"`
+	DaysOfStock       float64         `json:"days_of_stock"`
+	SupplierId        int             `json:"supplier_id,omitempty"`
+	LeadTimeDays      int             `json:"lead_time_days"`
+	SafetyStock       int             `json:"safety_stock"`
+	ReorderPoint      int             `json:"reorder_point"`
+	SuggestedQuantity int             `json:"suggested_quantity"`
+}
+
+type ReorderService interface {
+	// Suggestions returns the products to reorder under policy, the ones
+	// running out soonest first. Products that did not sell over any
+	// window are never suggested.
+	Suggestions(ctx context.Context, policy ReorderPolicy) ([]ReorderSuggestion, error)
+}
+
+type InvalidReorderPolicyError struct {
+	Field string
+}
+
+func (e InvalidReorderPolicyError) Error() string {
+	return "invalid reorder policy: " + e.Field
+}
+
+func NewInvalidReorderPolicyError(field string) error {
+	return InvalidReorderPolicyError{Field: field}
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"supermarket/internal"
+	"sync"
+)
+
+// CategoryMapDB is an in-memory category repository safe for concurrent
+// use. When it has a file, every write is logged to it before being
+// acknowledged.
+type CategoryMapDB struct {
+	mu         sync.RWMutex
+	categories *table[internal.Category]
+}
+
+func NewCategoryMapDB() *CategoryMapDB {
+	return &CategoryMapDB{categories: newTable[internal.Category]()}
+}
+
+// NewCategoryFileDB keeps the categories in the file at path, loading the
+// ones already saved there.
+func NewCategoryFileDB(path string) (*CategoryMapDB, error) {
+	categories, err := openTable[internal.Category](path)
+	if err != nil {
+		return nil, err
+	}
+	return &CategoryMapDB{categories: categories}, nil
+}
+
+// sortedCategories returns the values of categories sorted by parent, then
+// position, then id.
+func sortedCategories(categories map[int]internal.Category) []internal.Category {
+	sorted := make([]internal.Category, 0, len(categories))
+	for _, category := range categories {
+		sorted = append(sorted, category)
+	}
+	slices.SortFunc(sorted, func(a, b internal.Category) int {
+		if a.ParentId != b.ParentId {
+			return cmp.Compare(a.ParentId, b.ParentId)
+		}
+		if a.Position != b.Position {
+			return cmp.Compare(a.Position, b.Position)
+		}
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return sorted
+}
+
+func (cdb *CategoryMapDB) GetAll(ctx context.Context) ([]internal.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cdb.mu.RLock()
+	defer cdb.mu.RUnlock()
+
+	return sortedCategories(cdb.categories.rows), nil
+}
+
+func (cdb *CategoryMapDB) GetById(ctx context.Context, id int) (internal.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Category{}, err
+	}
+
+	cdb.mu.RLock()
+	defer cdb.mu.RUnlock()
+
+	category, ok := cdb.categories.rows[id]
+	if !ok {
+		return internal.Category{}, internal.NewCategoryNotFoundError()
+	}
+	return category, nil
+}
+
+func (cdb *CategoryMapDB) Save(ctx context.Context, category internal.Category) (internal.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Category{}, err
+	}
+
+	cdb.mu.Lock()
+	defer cdb.mu.Unlock()
+
+	category.Id = cdb.categories.nextId()
+	if err := cdb.categories.write(putRow(category.Id, category)); err != nil {
+		return internal.Category{}, err
+	}
+	return category, nil
+}
+
+func (cdb *CategoryMapDB) Update(ctx context.Context, updated []internal.Category) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cdb.mu.Lock()
+	defer cdb.mu.Unlock()
+
+	changes := make([]tableChange[internal.Category], 0, len(updated))
+	for _, category := range updated {
+		if _, ok := cdb.categories.rows[category.Id]; !ok {
+			return internal.NewCategoryNotFoundError()
+		}
+		changes = append(changes, putRow(category.Id, category))
+	}
+	return cdb.categories.write(changes...)
+}
+
+func (cdb *CategoryMapDB) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cdb.mu.Lock()
+	defer cdb.mu.Unlock()
+
+	if _, ok := cdb.categories.rows[id]; !ok {
+		return internal.NewCategoryNotFoundError()
+	}
+	for _, category := range cdb.categories.rows {
+		if category.ParentId == id {
+			return internal.NewCategoryNotEmptyError()
+		}
+	}
+
+	return cdb.categories.write(deleteRow[internal.Category](id))
+}
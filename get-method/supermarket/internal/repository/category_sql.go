@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"supermarket/internal"
+
+	"github.com/go-sql-driver/mysql"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func NewCategorySQL(db *sql.DB) *CategorySQL {
+	return &CategorySQL{db: db}
+}
+
+// CategorySQL is a CategoryRepository backed by the SQLite or MySQL
+// database of the products, which keeps the categories of the products
+// consistent with them. Top level categories have a NULL parent.
+type CategorySQL struct {
+	db *sql.DB
+}
+
+// Queries, the same in both dialects.
+const (
+	sqlGetAllCategories = "SELECT id, name, COALESCE(parent_id, 0), position FROM categories ORDER BY COALESCE(parent_id, 0), position, id"
+	sqlGetCategoryById  = "SELECT id, name, COALESCE(parent_id, 0), position FROM categories WHERE id = ?"
+	sqlCreateCategory   = "INSERT INTO categories (name, parent_id, position) VALUES (?, ?, ?)"
+	sqlUpdateCategory   = "UPDATE categories SET name = ?, parent_id = ?, position = ? WHERE id = ?"
+	sqlDeleteCategory   = "DELETE FROM categories WHERE id = ?"
+)
+
+func (cdb *CategorySQL) GetAll(ctx context.Context) ([]internal.Category, error) {
+	rows, err := cdb.db.QueryContext(ctx, sqlGetAllCategories)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []internal.Category{}
+	for rows.Next() {
+		var category internal.Category
+		if err := rows.Scan(&category.Id, &category.Name, &category.ParentId, &category.Position); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (cdb *CategorySQL) GetById(ctx context.Context, id int) (internal.Category, error) {
+	var category internal.Category
+	if err := cdb.db.QueryRowContext(ctx, sqlGetCategoryById, id).Scan(&category.Id, &category.Name, &category.ParentId, &category.Position); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.Category{}, internal.NewCategoryNotFoundError()
+		}
+		return internal.Category{}, err
+	}
+	return category, nil
+}
+
+func (cdb *CategorySQL) Save(ctx context.Context, category internal.Category) (internal.Category, error) {
+	result, err := cdb.db.ExecContext(ctx, sqlCreateCategory, category.Name, sqlParentId(category.ParentId), category.Position)
+	if err != nil {
+		if isSQLForeignKeyViolation(err) {
+			return internal.Category{}, internal.NewInvalidCategoryError("parent")
+		}
+		return internal.Category{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return internal.Category{}, err
+	}
+	category.Id = int(id)
+	return category, nil
+}
+
+func (cdb *CategorySQL) Update(ctx context.Context, categories []internal.Category) error {
+	return withinSQLTransaction(ctx, cdb.db, func(tx *sql.Tx) error {
+		for _, category := range categories {
+			result, err := tx.ExecContext(ctx, sqlUpdateCategory, category.Name, sqlParentId(category.ParentId), category.Position, category.Id)
+			if err != nil {
+				if isSQLForeignKeyViolation(err) {
+					return internal.NewInvalidCategoryError("parent")
+				}
+				return err
+			}
+
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return internal.NewCategoryNotFoundError()
+			}
+		}
+		return nil
+	})
+}
+
+func (cdb *CategorySQL) Delete(ctx context.Context, id int) error {
+	result, err := cdb.db.ExecContext(ctx, sqlDeleteCategory, id)
+	if err != nil {
+		if isSQLForeignKeyViolation(err) {
+			return internal.NewCategoryNotEmptyError()
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return internal.NewCategoryNotFoundError()
+	}
+	return nil
+}
+
+// sqlParentId stores the top level parent, 0, as NULL.
+func sqlParentId(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// MySQL error numbers of rows still referenced and of references to
+// missing rows.
+const (
+	mysqlRowIsReferencedErr = 1451
+	mysqlNoReferencedRowErr = 1452
+)
+
+// isSQLForeignKeyViolation reports whether err is a foreign key violation
+// of either SQL engine.
+func isSQLForeignKeyViolation(err error) bool {
+	var sqliteError *sqlite.Error
+	if errors.As(err, &sqliteError) {
+		return sqliteError.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+	}
+	var mysqlError *mysql.MySQLError
+	if errors.As(err, &mysqlError) {
+		return mysqlError.Number == mysqlRowIsReferencedErr || mysqlError.Number == mysqlNoReferencedRowErr
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryFileDBReloadsWhatWasSaved(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.json.categories")
+	cdb, err := repository.NewCategoryFileDB(path)
+	require.NoError(t, err)
+
+	bakery, err := cdb.Save(ctx, internal.Category{Name: "Bakery"})
+	require.NoError(t, err)
+	dairy, err := cdb.Save(ctx, internal.Category{Name: "Dairy", Position: 1})
+	require.NoError(t, err)
+	cheese, err := cdb.Save(ctx, internal.Category{Name: "Cheese", ParentId: dairy.Id})
+	require.NoError(t, err)
+
+	cheese.Name = "Cheeses"
+	require.NoError(t, cdb.Update(ctx, []internal.Category{cheese}))
+	require.ErrorAs(t, cdb.Update(ctx, []internal.Category{cheese, {Id: 99, Name: "Other"}}), &internal.CategoryNotFoundError{})
+	require.ErrorAs(t, cdb.Delete(ctx, dairy.Id), &internal.CategoryNotEmptyError{})
+	require.NoError(t, cdb.Delete(ctx, bakery.Id))
+
+	reopened, err := repository.NewCategoryFileDB(path)
+	require.NoError(t, err)
+	categories, err := reopened.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Category{dairy, cheese}, categories)
+
+	// ids keep growing after a restart
+	drinks, err := reopened.Save(ctx, internal.Category{Name: "Drinks"})
+	require.NoError(t, err)
+	require.Equal(t, cheese.Id+1, drinks.Id)
+}
+
+func TestCategorySQLite(t *testing.T) {
+	ctx := context.Background()
+	db := openMigratedSQLite(t)
+	cdb := repository.NewCategorySQL(db)
+
+	dairy, err := cdb.Save(ctx, internal.Category{Name: "Dairy"})
+	require.NoError(t, err)
+	cheese, err := cdb.Save(ctx, internal.Category{Name: "Cheese", ParentId: dairy.Id})
+	require.NoError(t, err)
+	bakery, err := cdb.Save(ctx, internal.Category{Name: "Bakery", Position: 1})
+	require.NoError(t, err)
+
+	categories, err := cdb.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Category{dairy, bakery, cheese}, categories)
+
+	_, err = cdb.Save(ctx, internal.Category{Name: "Other", ParentId: 99})
+	require.ErrorAs(t, err, &internal.InvalidCategoryError{})
+
+	// an update is all or nothing
+	moved := cheese
+	moved.ParentId = bakery.Id
+	require.ErrorAs(t, cdb.Update(ctx, []internal.Category{moved, {Id: 99, Name: "Other"}}), &internal.CategoryNotFoundError{})
+	found, err := cdb.GetById(ctx, cheese.Id)
+	require.NoError(t, err)
+	require.Equal(t, cheese, found)
+	// an unchanged category is still found
+	require.NoError(t, cdb.Update(ctx, []internal.Category{cheese}))
+
+	_, err = cdb.GetById(ctx, 99)
+	require.ErrorAs(t, err, &internal.CategoryNotFoundError{})
+	require.ErrorAs(t, cdb.Delete(ctx, dairy.Id), &internal.CategoryNotEmptyError{})
+	require.ErrorAs(t, cdb.Delete(ctx, 99), &internal.CategoryNotFoundError{})
+}
+
+func TestProductSQLiteCategories(t *testing.T) {
+	ctx := context.Background()
+	db := openMigratedSQLite(t)
+	pdb := repository.NewProductSQLite(db)
+	cdb := repository.NewCategorySQL(db)
+
+	dairy, err := cdb.Save(ctx, internal.Category{Name: "Dairy"})
+	require.NoError(t, err)
+	bakery, err := cdb.Save(ctx, internal.Category{Name: "Bakery", Position: 1})
+	require.NoError(t, err)
+
+	milk, err := pdb.Save(ctx, internal.Product{Name: "milk", Quantity: 1, Code: "c1", Price: usd(100), CategoryIds: []int{dairy.Id}})
+	require.NoError(t, err)
+	cake, err := pdb.Save(ctx, internal.Product{Name: "cake", Quantity: 1, Code: "c2", Price: usd(500), CategoryIds: []int{dairy.Id, bakery.Id}})
+	require.NoError(t, err)
+
+	_, err = pdb.Save(ctx, internal.Product{Name: "other", Quantity: 1, Code: "c3", Price: usd(100), CategoryIds: []int{99}})
+	require.ErrorAs(t, err, &internal.InvalidProductError{})
+	_, err = pdb.GetByCode(ctx, "c3")
+	require.Error(t, err)
+
+	found, err := pdb.GetById(ctx, cake.Id)
+	require.NoError(t, err)
+	require.Equal(t, cake, found)
+
+	page, err := pdb.GetPage(ctx, internal.ProductPageQuery{Filter: internal.CategoryFilter{Ids: []int{bakery.Id}}}.WithDefaults())
+	require.NoError(t, err)
+	require.Equal(t, []int{cake.Id}, pageIds(page))
+	page, err = pdb.GetPage(ctx, internal.ProductPageQuery{Filter: internal.CategoryFilter{}}.WithDefaults())
+	require.NoError(t, err)
+	require.Empty(t, pageIds(page))
+
+	milk.CategoryIds = []int{bakery.Id}
+	milk, err = pdb.UpdateOrCreate(ctx, milk)
+	require.NoError(t, err)
+	patched, err := pdb.PartialUpdate(ctx, cake.Id, internal.Product{CategoryIds: []int{bakery.Id}})
+	require.NoError(t, err)
+	require.Equal(t, []int{bakery.Id}, patched.CategoryIds)
+
+	products, err := pdb.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{bakery.Id}, products[milk.Id].CategoryIds)
+
+	// products keep their categories from being deleted, and take their
+	// assignments along when they go
+	require.NoError(t, cdb.Delete(ctx, dairy.Id))
+	require.ErrorAs(t, cdb.Delete(ctx, bakery.Id), &internal.CategoryNotEmptyError{})
+	require.NoError(t, pdb.Delete(ctx, milk.Id))
+	require.NoError(t, pdb.Delete(ctx, cake.Id))
+	require.NoError(t, cdb.Delete(ctx, bakery.Id))
+}
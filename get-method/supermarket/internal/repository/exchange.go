@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"supermarket/internal"
+	"sync"
+)
+
+type currencyPair struct {
+	base, quote string
+}
+
+// ExchangeRateMapDB is an in-memory exchange rate repository safe for
+// concurrent use.
+type ExchangeRateMapDB struct {
+	mu    sync.RWMutex
+	rates map[currencyPair]internal.ExchangeRate
+}
+
+func NewExchangeRateMapDB() *ExchangeRateMapDB {
+	return &ExchangeRateMapDB{rates: map[currencyPair]internal.ExchangeRate{}}
+}
+
+// LoadExchangeRates reads the exchange rates of a JSON file holding an
+// array of them.
+func LoadExchangeRates(path string) ([]internal.ExchangeRate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []internal.ExchangeRate
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, err
+	}
+	for _, rate := range rates {
+		if err := rate.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rates, nil
+}
+
+// GetAll returns the rates sorted by base and quote currency.
+func (rdb *ExchangeRateMapDB) GetAll(ctx context.Context) ([]internal.ExchangeRate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rdb.mu.RLock()
+	defer rdb.mu.RUnlock()
+
+	rates := make([]internal.ExchangeRate, 0, len(rdb.rates))
+	for _, rate := range rdb.rates {
+		rates = append(rates, rate)
+	}
+	slices.SortFunc(rates, func(a, b internal.ExchangeRate) int {
+		if c := strings.Compare(a.Base, b.Base); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Quote, b.Quote)
+	})
+	return rates, nil
+}
+
+func (rdb *ExchangeRateMapDB) Get(ctx context.Context, base, quote string) (internal.ExchangeRate, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.ExchangeRate{}, err
+	}
+
+	rdb.mu.RLock()
+	defer rdb.mu.RUnlock()
+
+	rate, ok := rdb.rates[currencyPair{base: base, quote: quote}]
+	if !ok {
+		return internal.ExchangeRate{}, internal.NewExchangeRateNotFoundError(base, quote)
+	}
+	return rate, nil
+}
+
+func (rdb *ExchangeRateMapDB) Put(ctx context.Context, rate internal.ExchangeRate) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	rdb.mu.Lock()
+	defer rdb.mu.Unlock()
+
+	rdb.rates[currencyPair{base: rate.Base, quote: rate.Quote}] = rate
+	return nil
+}
+
+func (rdb *ExchangeRateMapDB) Delete(ctx context.Context, base, quote string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	rdb.mu.Lock()
+	defer rdb.mu.Unlock()
+
+	pair := currencyPair{base: base, quote: quote}
+	if _, ok := rdb.rates[pair]; !ok {
+		return internal.NewExchangeRateNotFoundError(base, quote)
+	}
+	delete(rdb.rates, pair)
+	return nil
+}
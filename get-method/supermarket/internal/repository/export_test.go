@@ -0,0 +1,6 @@
+package repository
+
+// SetCompactionHook lets tests interrupt a compaction after any of its steps.
+func SetCompactionHook(fdb *ProductFileDB, hook func(step string) error) {
+	fdb.afterStep = hook
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"supermarket/internal"
+	"sync"
+)
+
+// LocationMapDB is an in-memory location repository safe for concurrent
+// use. When it has a file, every write is logged to it before being
+// acknowledged.
+type LocationMapDB struct {
+	mu        sync.RWMutex
+	locations *table[internal.Location]
+}
+
+// NewLocationMapDB returns a repository holding the default location, the
+// main store.
+func NewLocationMapDB() *LocationMapDB {
+	ldb := &LocationMapDB{locations: newTable[internal.Location]()}
+	ldb.locations.apply([]tableChange[internal.Location]{putRow(internal.DefaultLocationId, defaultLocation())})
+	return ldb
+}
+
+// NewLocationFileDB keeps the locations in the file at path, loading the
+// ones already saved there. A new file starts with the default location.
+func NewLocationFileDB(path string) (*LocationMapDB, error) {
+	locations, err := openTable[internal.Location](path)
+	if err != nil {
+		return nil, err
+	}
+	if locations.lastId == 0 {
+		if err := locations.write(putRow(internal.DefaultLocationId, defaultLocation())); err != nil {
+			return nil, err
+		}
+	}
+	return &LocationMapDB{locations: locations}, nil
+}
+
+func defaultLocation() internal.Location {
+	return internal.Location{Id: internal.DefaultLocationId, Name: "Main store", Kind: internal.LocationStore}
+}
+
+func (ldb *LocationMapDB) GetAll(ctx context.Context) ([]internal.Location, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	locations := make([]internal.Location, 0, len(ldb.locations.rows))
+	for _, location := range ldb.locations.rows {
+		locations = append(locations, location)
+	}
+	slices.SortFunc(locations, func(a, b internal.Location) int { return a.Id - b.Id })
+	return locations, nil
+}
+
+func (ldb *LocationMapDB) GetById(ctx context.Context, id int) (internal.Location, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Location{}, err
+	}
+
+	ldb.mu.RLock()
+	defer ldb.mu.RUnlock()
+
+	location, ok := ldb.locations.rows[id]
+	if !ok {
+		return internal.Location{}, internal.NewLocationNotFoundError()
+	}
+	return location, nil
+}
+
+func (ldb *LocationMapDB) Save(ctx context.Context, location internal.Location) (internal.Location, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Location{}, err
+	}
+
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	location.Id = ldb.locations.nextId()
+	if err := ldb.locations.write(putRow(location.Id, location)); err != nil {
+		return internal.Location{}, err
+	}
+	return location, nil
+}
+
+func (ldb *LocationMapDB) Update(ctx context.Context, location internal.Location) (internal.Location, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Location{}, err
+	}
+
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	if _, ok := ldb.locations.rows[location.Id]; !ok {
+		return internal.Location{}, internal.NewLocationNotFoundError()
+	}
+	if err := ldb.locations.write(putRow(location.Id, location)); err != nil {
+		return internal.Location{}, err
+	}
+	return location, nil
+}
+
+func (ldb *LocationMapDB) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	if _, ok := ldb.locations.rows[id]; !ok {
+		return internal.NewLocationNotFoundError()
+	}
+	return ldb.locations.write(deleteRow[internal.Location](id))
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"supermarket/internal"
+)
+
+func NewLocationSQL(db *sql.DB) *LocationSQL {
+	return &LocationSQL{db: db}
+}
+
+// LocationSQL is a LocationRepository backed by the SQLite or MySQL
+// database of the products. Its migration adds the default location.
+type LocationSQL struct {
+	db *sql.DB
+}
+
+// Queries, the same in both dialects.
+const (
+	sqlGetAllLocations = "SELECT id, name, kind FROM locations ORDER BY id"
+	sqlGetLocationById = "SELECT id, name, kind FROM locations WHERE id = ?"
+	sqlCreateLocation  = "INSERT INTO locations (name, kind) VALUES (?, ?)"
+	sqlUpdateLocation  = "UPDATE locations SET name = ?, kind = ? WHERE id = ?"
+	sqlDeleteLocation  = "DELETE FROM locations WHERE id = ?"
+)
+
+func (ldb *LocationSQL) GetAll(ctx context.Context) ([]internal.Location, error) {
+	rows, err := ldb.db.QueryContext(ctx, sqlGetAllLocations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []internal.Location{}
+	for rows.Next() {
+		var location internal.Location
+		if err := rows.Scan(&location.Id, &location.Name, &location.Kind); err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+func (ldb *LocationSQL) GetById(ctx context.Context, id int) (internal.Location, error) {
+	var location internal.Location
+	if err := ldb.db.QueryRowContext(ctx, sqlGetLocationById, id).Scan(&location.Id, &location.Name, &location.Kind); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.Location{}, internal.NewLocationNotFoundError()
+		}
+		return internal.Location{}, err
+	}
+	return location, nil
+}
+
+func (ldb *LocationSQL) Save(ctx context.Context, location internal.Location) (internal.Location, error) {
+	result, err := ldb.db.ExecContext(ctx, sqlCreateLocation, location.Name, location.Kind)
+	if err != nil {
+		return internal.Location{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return internal.Location{}, err
+	}
+	location.Id = int(id)
+	return location, nil
+}
+
+func (ldb *LocationSQL) Update(ctx context.Context, location internal.Location) (internal.Location, error) {
+	result, err := ldb.db.ExecContext(ctx, sqlUpdateLocation, location.Name, location.Kind, location.Id)
+	if err != nil {
+		return internal.Location{}, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return internal.Location{}, err
+	}
+	if rowsAffected == 0 {
+		return internal.Location{}, internal.NewLocationNotFoundError()
+	}
+	return location, nil
+}
+
+func (ldb *LocationSQL) Delete(ctx context.Context, id int) error {
+	result, err := ldb.db.ExecContext(ctx, sqlDeleteLocation, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return internal.NewLocationNotFoundError()
+	}
+	return nil
+}
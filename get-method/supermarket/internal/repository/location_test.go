@@ -0,0 +1,76 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testLocationRepository runs the checks every location repository passes,
+// on one holding only the default location.
+func testLocationRepository(t *testing.T, ldb internal.LocationRepository) {
+	ctx := context.Background()
+	main := internal.Location{Id: internal.DefaultLocationId, Name: "Main store", Kind: internal.LocationStore}
+
+	locations, err := ldb.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Location{main}, locations)
+
+	warehouse, err := ldb.Save(ctx, internal.Location{Name: "Warehouse", Kind: internal.LocationWarehouse})
+	require.NoError(t, err)
+	require.Greater(t, warehouse.Id, main.Id)
+	kiosk, err := ldb.Save(ctx, internal.Location{Name: "Kiosk", Kind: internal.LocationStore})
+	require.NoError(t, err)
+
+	warehouse.Name = "North warehouse"
+	_, err = ldb.Update(ctx, warehouse)
+	require.NoError(t, err)
+	found, err := ldb.GetById(ctx, warehouse.Id)
+	require.NoError(t, err)
+	require.Equal(t, warehouse, found)
+
+	require.NoError(t, ldb.Delete(ctx, kiosk.Id))
+	locations, err = ldb.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Location{main, warehouse}, locations)
+
+	_, err = ldb.GetById(ctx, kiosk.Id)
+	require.ErrorAs(t, err, &internal.LocationNotFoundError{})
+	_, err = ldb.Update(ctx, internal.Location{Id: 99, Name: "Nowhere", Kind: internal.LocationStore})
+	require.ErrorAs(t, err, &internal.LocationNotFoundError{})
+	require.ErrorAs(t, ldb.Delete(ctx, 99), &internal.LocationNotFoundError{})
+}
+
+func TestLocationMapDB(t *testing.T) {
+	testLocationRepository(t, repository.NewLocationMapDB())
+}
+
+func TestLocationSQLite(t *testing.T) {
+	testLocationRepository(t, repository.NewLocationSQL(openMigratedSQLite(t)))
+}
+
+func TestLocationFileDBReloadsWhatWasSaved(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.json.locations")
+	ldb, err := repository.NewLocationFileDB(path)
+	require.NoError(t, err)
+	testLocationRepository(t, ldb)
+
+	reopened, err := repository.NewLocationFileDB(path)
+	require.NoError(t, err)
+	locations, err := ldb.GetAll(ctx)
+	require.NoError(t, err)
+	reloaded, err := reopened.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, locations, reloaded)
+
+	// ids keep growing after a restart, even past the deleted kiosk
+	location, err := reopened.Save(ctx, internal.Location{Name: "Outlet", Kind: internal.LocationStore})
+	require.NoError(t, err)
+	require.Equal(t, 4, location.Id)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// SQL dialects migrations are shipped for
+const (
+	DialectSQLite = "sqlite"
+	DialectMySQL  = "mysql"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// Migrations returns the schema migrations of the given SQL dialect.
+func Migrations(dialect string) (fs.FS, error) {
+	return fs.Sub(migrations, "migrations/"+dialect)
+}
@@ -0,0 +1,11 @@
+-- Products table as shipped by the original MySQL dump. Existing databases
+-- built from that dump already have it and only get the version recorded.
+CREATE TABLE IF NOT EXISTS products (
+  id int DEFAULT NULL,
+  name varchar(50) DEFAULT NULL,
+  quantity int DEFAULT NULL,
+  code_value varchar(50) DEFAULT NULL,
+  is_published varchar(50) DEFAULT NULL,
+  expiration date DEFAULT NULL,
+  price decimal(5,2) DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,11 @@
+ALTER TABLE products
+  DROP INDEX products_code_value,
+  MODIFY id int NOT NULL,
+  DROP PRIMARY KEY,
+  MODIFY id int DEFAULT NULL,
+  MODIFY name varchar(50) DEFAULT NULL,
+  MODIFY quantity int DEFAULT NULL,
+  MODIFY code_value varchar(50) DEFAULT NULL,
+  MODIFY is_published varchar(50) DEFAULT NULL,
+  MODIFY expiration date DEFAULT NULL,
+  MODIFY price decimal(5,2) DEFAULT NULL;
@@ -0,0 +1,11 @@
+-- Give products a primary key, a unique code and proper column types.
+UPDATE products SET is_published = '0' WHERE is_published IS NULL OR is_published NOT IN ('0', '1');
+ALTER TABLE products
+  MODIFY id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  MODIFY name varchar(50) NOT NULL,
+  MODIFY quantity int NOT NULL,
+  MODIFY code_value varchar(50) NOT NULL,
+  MODIFY is_published boolean NOT NULL DEFAULT FALSE,
+  MODIFY expiration date NULL,
+  MODIFY price decimal(10,2) NOT NULL,
+  ADD UNIQUE KEY products_code_value (code_value);
@@ -0,0 +1,2 @@
+-- Count the writes to each product so clients can detect lost updates.
+ALTER TABLE products ADD COLUMN version INT UNSIGNED NOT NULL DEFAULT 1;
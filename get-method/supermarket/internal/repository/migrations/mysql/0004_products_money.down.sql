@@ -0,0 +1,7 @@
+-- Prices go back to a decimal in the unit of their currency, which is only
+-- right for currencies with cents.
+ALTER TABLE products ADD COLUMN price decimal(10,2) NOT NULL DEFAULT 0;
+UPDATE products SET price = price_minor / 100;
+ALTER TABLE products
+  DROP COLUMN currency,
+  DROP COLUMN price_minor;
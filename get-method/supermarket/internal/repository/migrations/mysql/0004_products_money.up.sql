@@ -0,0 +1,7 @@
+-- Store prices exactly, as minor units of their currency. Every existing
+-- price was in cents of the default currency.
+ALTER TABLE products
+  ADD COLUMN price_minor BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+UPDATE products SET price_minor = ROUND(price * 100);
+ALTER TABLE products DROP COLUMN price;
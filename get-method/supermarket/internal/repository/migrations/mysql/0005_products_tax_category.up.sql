@@ -0,0 +1,3 @@
+-- Products without a tax category are taxed at the standard rate of the
+-- jurisdiction.
+ALTER TABLE products ADD COLUMN tax_category VARCHAR(50) NOT NULL DEFAULT '';
@@ -0,0 +1,18 @@
+CREATE TABLE categories (
+  id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name varchar(100) NOT NULL,
+  parent_id int NULL,
+  position int NOT NULL DEFAULT 0,
+  KEY categories_parent (parent_id, position),
+  CONSTRAINT categories_parent_fk FOREIGN KEY (parent_id) REFERENCES categories (id)
+);
+-- Deleting a product drops it from its categories, categories still holding
+-- products can not be deleted.
+CREATE TABLE product_categories (
+  product_id int NOT NULL,
+  category_id int NOT NULL,
+  PRIMARY KEY (product_id, category_id),
+  KEY product_categories_category (category_id),
+  CONSTRAINT product_categories_product_fk FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE,
+  CONSTRAINT product_categories_category_fk FOREIGN KEY (category_id) REFERENCES categories (id)
+);
@@ -0,0 +1,3 @@
+DROP TABLE stock_reservation_items;
+DROP TABLE stock_reservations;
+DROP TABLE stock_movements;
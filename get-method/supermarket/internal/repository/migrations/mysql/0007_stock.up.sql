@@ -0,0 +1,31 @@
+-- The stock ledger, the stock of a product being the sum of its movements,
+-- and the reservations holding some of it. Times are kept in UTC.
+CREATE TABLE stock_movements (
+  id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  product_id int NOT NULL,
+  location_id int NOT NULL,
+  kind varchar(20) NOT NULL,
+  quantity int NOT NULL,
+  reason text NOT NULL,
+  reservation_id int NOT NULL DEFAULT 0,
+  transfer_id int NOT NULL DEFAULT 0,
+  created_at datetime(6) NOT NULL,
+  KEY stock_movements_product (product_id, location_id),
+  KEY stock_movements_location (location_id, product_id)
+);
+CREATE TABLE stock_reservations (
+  id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  location_id int NOT NULL,
+  created_at datetime(6) NOT NULL,
+  expires_at datetime(6) NOT NULL,
+  KEY stock_reservations_expires (expires_at)
+);
+CREATE TABLE stock_reservation_items (
+  reservation_id int NOT NULL,
+  position int NOT NULL,
+  product_id int NOT NULL,
+  quantity int NOT NULL,
+  PRIMARY KEY (reservation_id, position),
+  KEY stock_reservation_items_product (product_id),
+  CONSTRAINT stock_reservation_items_reservation_fk FOREIGN KEY (reservation_id) REFERENCES stock_reservations (id) ON DELETE CASCADE
+);
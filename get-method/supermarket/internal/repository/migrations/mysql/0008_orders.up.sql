@@ -0,0 +1,11 @@
+-- Orders keep their receipt and their history as JSON, both are only read
+-- along with the order.
+CREATE TABLE orders (
+  id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  status varchar(20) NOT NULL,
+  receipt mediumtext NOT NULL,
+  history text NOT NULL,
+  created_at datetime(6) NOT NULL,
+  updated_at datetime(6) NOT NULL,
+  KEY orders_status (status)
+);
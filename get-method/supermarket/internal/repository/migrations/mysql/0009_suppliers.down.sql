@@ -0,0 +1,3 @@
+DROP TABLE purchase_orders;
+DROP TABLE supplier_products;
+DROP TABLE suppliers;
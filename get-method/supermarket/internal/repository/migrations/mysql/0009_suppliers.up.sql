@@ -0,0 +1,32 @@
+-- Suppliers with the products they sell, and the purchase orders restocking
+-- from them. The lines and the history of a purchase order are kept as JSON,
+-- both are only read along with the order.
+CREATE TABLE suppliers (
+  id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  email varchar(255) NOT NULL DEFAULT '',
+  phone varchar(50) NOT NULL DEFAULT '',
+  lead_time_days int NOT NULL DEFAULT 0
+);
+CREATE TABLE supplier_products (
+  supplier_id int NOT NULL,
+  product_id int NOT NULL,
+  supplier_code varchar(255) NOT NULL DEFAULT '',
+  cost_minor BIGINT NOT NULL,
+  currency CHAR(3) NOT NULL,
+  PRIMARY KEY (supplier_id, product_id),
+  CONSTRAINT supplier_products_supplier_fk FOREIGN KEY (supplier_id) REFERENCES suppliers (id) ON DELETE CASCADE
+);
+CREATE TABLE purchase_orders (
+  id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  supplier_id int NOT NULL,
+  status varchar(20) NOT NULL,
+  order_lines mediumtext NOT NULL,
+  total_minor BIGINT NOT NULL,
+  currency CHAR(3) NOT NULL,
+  expected_at datetime(6) NULL,
+  history text NOT NULL,
+  created_at datetime(6) NOT NULL,
+  updated_at datetime(6) NOT NULL,
+  KEY purchase_orders_status (status)
+);
@@ -0,0 +1,8 @@
+-- The stores and warehouses keeping stock, starting with the default
+-- location every existing movement is at.
+CREATE TABLE locations (
+  id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  kind varchar(20) NOT NULL
+);
+INSERT INTO locations (id, name, kind) VALUES (1, 'Main store', 'store');
@@ -0,0 +1,15 @@
+-- The price history of the products, with the scheduled changes waiting to
+-- take effect. The previous price is only known once a change took effect.
+CREATE TABLE price_changes (
+  id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  product_id int NOT NULL,
+  price_minor BIGINT NOT NULL,
+  currency CHAR(3) NOT NULL,
+  previous_minor BIGINT NULL,
+  previous_currency CHAR(3) NULL,
+  effective_from datetime(6) NOT NULL,
+  pending BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at datetime(6) NOT NULL,
+  KEY price_changes_product (product_id, effective_from),
+  KEY price_changes_pending (pending, effective_from)
+);
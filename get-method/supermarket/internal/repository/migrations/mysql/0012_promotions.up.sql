@@ -0,0 +1,22 @@
+-- The promotions applied when quoting carts. The products a promotion
+-- targets are kept as JSON, they are only read along with it.
+CREATE TABLE promotions (
+  id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  kind varchar(20) NOT NULL,
+  priority int NOT NULL DEFAULT 0,
+  stackable BOOLEAN NOT NULL DEFAULT FALSE,
+  starts_at varchar(10) NOT NULL DEFAULT '',
+  ends_at varchar(10) NOT NULL DEFAULT '',
+  product_ids text NOT NULL,
+  expiring_within_days int NOT NULL DEFAULT 0,
+  buy int NOT NULL DEFAULT 0,
+  pay int NOT NULL DEFAULT 0,
+  get_units int NOT NULL DEFAULT 0,
+  get_product_id int NOT NULL DEFAULT 0,
+  percent DOUBLE NOT NULL DEFAULT 0,
+  amount_minor BIGINT NOT NULL DEFAULT 0,
+  amount_currency CHAR(3) NOT NULL DEFAULT '',
+  threshold_minor BIGINT NOT NULL DEFAULT 0,
+  threshold_currency CHAR(3) NOT NULL DEFAULT ''
+);
@@ -0,0 +1,7 @@
+-- The jurisdictions the store sells in. Their rates are kept as JSON, they
+-- are only read along with the jurisdiction.
+CREATE TABLE tax_jurisdictions (
+  code varchar(50) NOT NULL PRIMARY KEY,
+  mode varchar(20) NOT NULL,
+  rates text NOT NULL
+);
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS products_code_value ON products (code_value);
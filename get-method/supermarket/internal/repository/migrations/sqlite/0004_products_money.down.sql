@@ -0,0 +1,4 @@
+ALTER TABLE products ADD COLUMN price REAL NOT NULL DEFAULT 0;
+UPDATE products SET price = price_minor / 100.0;
+ALTER TABLE products DROP COLUMN currency;
+ALTER TABLE products DROP COLUMN price_minor;
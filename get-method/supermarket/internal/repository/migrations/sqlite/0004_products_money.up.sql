@@ -0,0 +1,4 @@
+ALTER TABLE products ADD COLUMN price_minor INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+UPDATE products SET price_minor = CAST(ROUND(price * 100) AS INTEGER);
+ALTER TABLE products DROP COLUMN price;
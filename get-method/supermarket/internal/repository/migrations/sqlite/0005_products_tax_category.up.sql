@@ -0,0 +1 @@
+ALTER TABLE products ADD COLUMN tax_category TEXT NOT NULL DEFAULT '';
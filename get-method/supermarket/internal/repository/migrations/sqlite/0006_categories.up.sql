@@ -0,0 +1,15 @@
+CREATE TABLE categories (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  parent_id INTEGER REFERENCES categories (id),
+  position INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX categories_parent ON categories (parent_id, position);
+-- Deleting a product drops it from its categories, categories still holding
+-- products can not be deleted.
+CREATE TABLE product_categories (
+  product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  category_id INTEGER NOT NULL REFERENCES categories (id),
+  PRIMARY KEY (product_id, category_id)
+);
+CREATE INDEX product_categories_category ON product_categories (category_id);
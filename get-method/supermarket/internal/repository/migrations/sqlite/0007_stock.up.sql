@@ -0,0 +1,31 @@
+-- The stock ledger, the stock of a product being the sum of its movements,
+-- and the reservations holding some of it. Times are kept in UTC as text
+-- that sorts chronologically.
+CREATE TABLE stock_movements (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  product_id INTEGER NOT NULL,
+  location_id INTEGER NOT NULL,
+  kind TEXT NOT NULL,
+  quantity INTEGER NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  reservation_id INTEGER NOT NULL DEFAULT 0,
+  transfer_id INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX stock_movements_product ON stock_movements (product_id, location_id);
+CREATE INDEX stock_movements_location ON stock_movements (location_id, product_id);
+CREATE TABLE stock_reservations (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  location_id INTEGER NOT NULL,
+  created_at TEXT NOT NULL,
+  expires_at TEXT NOT NULL
+);
+CREATE INDEX stock_reservations_expires ON stock_reservations (expires_at);
+CREATE TABLE stock_reservation_items (
+  reservation_id INTEGER NOT NULL REFERENCES stock_reservations (id) ON DELETE CASCADE,
+  position INTEGER NOT NULL,
+  product_id INTEGER NOT NULL,
+  quantity INTEGER NOT NULL,
+  PRIMARY KEY (reservation_id, position)
+);
+CREATE INDEX stock_reservation_items_product ON stock_reservation_items (product_id);
@@ -0,0 +1,11 @@
+-- Orders keep their receipt and their history as JSON, both are only read
+-- along with the order.
+CREATE TABLE orders (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  status TEXT NOT NULL,
+  receipt TEXT NOT NULL,
+  history TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+CREATE INDEX orders_status ON orders (status);
@@ -0,0 +1,31 @@
+-- Suppliers with the products they sell, and the purchase orders restocking
+-- from them. The lines and the history of a purchase order are kept as JSON,
+-- both are only read along with the order.
+CREATE TABLE suppliers (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  email TEXT NOT NULL DEFAULT '',
+  phone TEXT NOT NULL DEFAULT '',
+  lead_time_days INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE supplier_products (
+  supplier_id INTEGER NOT NULL REFERENCES suppliers (id) ON DELETE CASCADE,
+  product_id INTEGER NOT NULL,
+  supplier_code TEXT NOT NULL DEFAULT '',
+  cost_minor INTEGER NOT NULL,
+  currency TEXT NOT NULL,
+  PRIMARY KEY (supplier_id, product_id)
+);
+CREATE TABLE purchase_orders (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  supplier_id INTEGER NOT NULL,
+  status TEXT NOT NULL,
+  order_lines TEXT NOT NULL,
+  total_minor INTEGER NOT NULL,
+  currency TEXT NOT NULL,
+  expected_at TEXT,
+  history TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+CREATE INDEX purchase_orders_status ON purchase_orders (status);
@@ -0,0 +1,8 @@
+-- The stores and warehouses keeping stock, starting with the default
+-- location every existing movement is at.
+CREATE TABLE locations (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  kind TEXT NOT NULL
+);
+INSERT INTO locations (id, name, kind) VALUES (1, 'Main store', 'store');
@@ -0,0 +1,15 @@
+-- The price history of the products, with the scheduled changes waiting to
+-- take effect. The previous price is only known once a change took effect.
+CREATE TABLE price_changes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  product_id INTEGER NOT NULL,
+  price_minor INTEGER NOT NULL,
+  currency TEXT NOT NULL,
+  previous_minor INTEGER,
+  previous_currency TEXT,
+  effective_from TEXT NOT NULL,
+  pending INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL
+);
+CREATE INDEX price_changes_product ON price_changes (product_id, effective_from);
+CREATE INDEX price_changes_pending ON price_changes (pending, effective_from);
@@ -0,0 +1,22 @@
+-- The promotions applied when quoting carts. The products a promotion
+-- targets are kept as JSON, they are only read along with it.
+CREATE TABLE promotions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  priority INTEGER NOT NULL DEFAULT 0,
+  stackable INTEGER NOT NULL DEFAULT 0,
+  starts_at TEXT NOT NULL DEFAULT '',
+  ends_at TEXT NOT NULL DEFAULT '',
+  product_ids TEXT NOT NULL,
+  expiring_within_days INTEGER NOT NULL DEFAULT 0,
+  buy INTEGER NOT NULL DEFAULT 0,
+  pay INTEGER NOT NULL DEFAULT 0,
+  get_units INTEGER NOT NULL DEFAULT 0,
+  get_product_id INTEGER NOT NULL DEFAULT 0,
+  percent REAL NOT NULL DEFAULT 0,
+  amount_minor INTEGER NOT NULL DEFAULT 0,
+  amount_currency TEXT NOT NULL DEFAULT '',
+  threshold_minor INTEGER NOT NULL DEFAULT 0,
+  threshold_currency TEXT NOT NULL DEFAULT ''
+);
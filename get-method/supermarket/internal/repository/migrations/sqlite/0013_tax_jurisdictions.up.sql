@@ -0,0 +1,7 @@
+-- The jurisdictions the store sells in. Their rates are kept as JSON, they
+-- are only read along with the jurisdiction.
+CREATE TABLE tax_jurisdictions (
+  code TEXT PRIMARY KEY,
+  mode TEXT NOT NULL,
+  rates TEXT NOT NULL
+);
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"supermarket/internal"
+	"sync"
+)
+
+// OrderMapDB is an in-memory order repository safe for concurrent use. When
+// it has a file, every write is logged to it before being acknowledged.
+type OrderMapDB struct {
+	mu     sync.RWMutex
+	orders *table[internal.Order]
+}
+
+func NewOrderMapDB() *OrderMapDB {
+	return &OrderMapDB{orders: newTable[internal.Order]()}
+}
+
+// NewOrderFileDB keeps the orders in the file at path, loading the ones
+// already saved there.
+func NewOrderFileDB(path string) (*OrderMapDB, error) {
+	orders, err := openTable[internal.Order](path)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderMapDB{orders: orders}, nil
+}
+
+func (odb *OrderMapDB) GetAll(ctx context.Context, status internal.OrderStatus) ([]internal.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	odb.mu.RLock()
+	defer odb.mu.RUnlock()
+
+	orders := []internal.Order{}
+	for _, order := range odb.orders.rows {
+		if status == "" || order.Status == status {
+			orders = append(orders, cloneOrder(order))
+		}
+	}
+	slices.SortFunc(orders, func(a, b internal.Order) int { return a.Id - b.Id })
+	return orders, nil
+}
+
+func (odb *OrderMapDB) GetById(ctx context.Context, id int) (internal.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Order{}, err
+	}
+
+	odb.mu.RLock()
+	defer odb.mu.RUnlock()
+
+	order, ok := odb.orders.rows[id]
+	if !ok {
+		return internal.Order{}, internal.NewOrderNotFoundError()
+	}
+	return cloneOrder(order), nil
+}
+
+func (odb *OrderMapDB) Save(ctx context.Context, order internal.Order) (internal.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Order{}, err
+	}
+
+	odb.mu.Lock()
+	defer odb.mu.Unlock()
+
+	order.Id = odb.orders.nextId()
+	if err := odb.orders.write(putRow(order.Id, cloneOrder(order))); err != nil {
+		return internal.Order{}, err
+	}
+	return order, nil
+}
+
+func (odb *OrderMapDB) Update(ctx context.Context, order internal.Order) (internal.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Order{}, err
+	}
+
+	odb.mu.Lock()
+	defer odb.mu.Unlock()
+
+	if _, ok := odb.orders.rows[order.Id]; !ok {
+		return internal.Order{}, internal.NewOrderNotFoundError()
+	}
+	if err := odb.orders.write(putRow(order.Id, cloneOrder(order))); err != nil {
+		return internal.Order{}, err
+	}
+	return order, nil
+}
+
+// cloneOrder copies the history and the receipt lines of order, so callers
+// can not change the stored ones. The lines are not changed once ordered,
+// so the values they point to are shared.
+func cloneOrder(order internal.Order) internal.Order {
+	order.History = slices.Clone(order.History)
+	order.Receipt.Lines = slices.Clone(order.Receipt.Lines)
+	order.Receipt.Rejected = slices.Clone(order.Receipt.Rejected)
+	order.Receipt.Discounts = slices.Clone(order.Receipt.Discounts)
+	return order
+}
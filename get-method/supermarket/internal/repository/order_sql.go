@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"supermarket/internal"
+)
+
+func NewOrderSQL(db *sql.DB) *OrderSQL {
+	return &OrderSQL{db: db}
+}
+
+// OrderSQL is an OrderRepository backed by the SQLite or MySQL database of
+// the products. The receipt and the history of an order are only ever read
+// along with it, they are kept as JSON in its row.
+type OrderSQL struct {
+	db *sql.DB
+}
+
+// Queries, the same in both dialects. An empty status selects every order.
+const (
+	sqlGetOrders    = "SELECT id, status, receipt, history, created_at, updated_at FROM orders WHERE ? = '' OR status = ? ORDER BY id"
+	sqlGetOrderById = "SELECT id, status, receipt, history, created_at, updated_at FROM orders WHERE id = ?"
+	sqlCreateOrder  = "INSERT INTO orders (status, receipt, history, created_at, updated_at) VALUES (?, ?, ?, ?, ?)"
+	sqlUpdateOrder  = "UPDATE orders SET status = ?, receipt = ?, history = ?, updated_at = ? WHERE id = ?"
+)
+
+func (odb *OrderSQL) GetAll(ctx context.Context, status internal.OrderStatus) ([]internal.Order, error) {
+	rows, err := odb.db.QueryContext(ctx, sqlGetOrders, status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []internal.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (odb *OrderSQL) GetById(ctx context.Context, id int) (internal.Order, error) {
+	order, err := scanOrder(odb.db.QueryRowContext(ctx, sqlGetOrderById, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.Order{}, internal.NewOrderNotFoundError()
+		}
+		return internal.Order{}, err
+	}
+	return order, nil
+}
+
+func (odb *OrderSQL) Save(ctx context.Context, order internal.Order) (internal.Order, error) {
+	receipt, history, err := marshalOrder(order)
+	if err != nil {
+		return internal.Order{}, err
+	}
+
+	result, err := odb.db.ExecContext(ctx, sqlCreateOrder, order.Status, receipt, history, toSQLTime(order.CreatedAt), toSQLTime(order.UpdatedAt))
+	if err != nil {
+		return internal.Order{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return internal.Order{}, err
+	}
+	order.Id = int(id)
+	return order, nil
+}
+
+func (odb *OrderSQL) Update(ctx context.Context, order internal.Order) (internal.Order, error) {
+	receipt, history, err := marshalOrder(order)
+	if err != nil {
+		return internal.Order{}, err
+	}
+
+	result, err := odb.db.ExecContext(ctx, sqlUpdateOrder, order.Status, receipt, history, toSQLTime(order.UpdatedAt), order.Id)
+	if err != nil {
+		return internal.Order{}, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return internal.Order{}, err
+	}
+	if rowsAffected == 0 {
+		return internal.Order{}, internal.NewOrderNotFoundError()
+	}
+	return order, nil
+}
+
+// marshalOrder returns the stored form of the receipt and the history of
+// order.
+func marshalOrder(order internal.Order) (string, string, error) {
+	receipt, err := json.Marshal(order.Receipt)
+	if err != nil {
+		return "", "", err
+	}
+	history, err := json.Marshal(order.History)
+	if err != nil {
+		return "", "", err
+	}
+	return string(receipt), string(history), nil
+}
+
+func scanOrder(row rowScanner) (internal.Order, error) {
+	var (
+		order                internal.Order
+		receipt, history     string
+		createdAt, updatedAt string
+	)
+	if err := row.Scan(&order.Id, &order.Status, &receipt, &history, &createdAt, &updatedAt); err != nil {
+		return internal.Order{}, err
+	}
+
+	if err := json.Unmarshal([]byte(receipt), &order.Receipt); err != nil {
+		return internal.Order{}, err
+	}
+	if err := json.Unmarshal([]byte(history), &order.History); err != nil {
+		return internal.Order{}, err
+	}
+	var err error
+	if order.CreatedAt, err = fromSQLTime(createdAt); err != nil {
+		return internal.Order{}, err
+	}
+	if order.UpdatedAt, err = fromSQLTime(updatedAt); err != nil {
+		return internal.Order{}, err
+	}
+	return order, nil
+}
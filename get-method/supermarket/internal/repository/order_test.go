@@ -0,0 +1,101 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newOrder returns an order of two units of product 1, pending since at.
+func newOrder(at time.Time) internal.Order {
+	available := 3
+	return internal.Order{
+		Status: internal.OrderPending,
+		Receipt: internal.Receipt{
+			At:        &at,
+			Lines:     []internal.ReceiptLine{{Line: 1, ProductId: 1, Code: "c1", Name: "Milk", Quantity: 2, UnitPrice: usd(110), Subtotal: usd(220), Total: usd(220)}},
+			Rejected:  []internal.RejectedLine{{Line: 2, ProductId: 2, Quantity: 5, Reason: internal.RejectInsufficientStock, Available: &available}},
+			Discounts: []internal.AppliedDiscount{},
+			ItemCount: 2,
+			Subtotal:  usd(220),
+			Total:     usd(220),
+		},
+		History:   []internal.OrderEvent{{Status: internal.OrderPending, At: at}},
+		CreatedAt: at,
+		UpdatedAt: at,
+	}
+}
+
+// testOrderRepository runs the checks every order repository passes, on an
+// empty one.
+func testOrderRepository(t *testing.T, odb internal.OrderRepository) {
+	ctx := context.Background()
+	at := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+
+	first, err := odb.Save(ctx, newOrder(at))
+	require.NoError(t, err)
+	second, err := odb.Save(ctx, newOrder(at.Add(time.Minute)))
+	require.NoError(t, err)
+	require.Greater(t, second.Id, first.Id)
+
+	found, err := odb.GetById(ctx, first.Id)
+	require.NoError(t, err)
+	require.Equal(t, first, found)
+
+	paid := at.Add(time.Hour)
+	first.Status = internal.OrderPaid
+	first.History = append(first.History, internal.OrderEvent{Status: internal.OrderPaid, At: paid})
+	first.UpdatedAt = paid
+	_, err = odb.Update(ctx, first)
+	require.NoError(t, err)
+
+	orders, err := odb.GetAll(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, []internal.Order{first, second}, orders)
+	orders, err = odb.GetAll(ctx, internal.OrderPending)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Order{second}, orders)
+	orders, err = odb.GetAll(ctx, internal.OrderCancelled)
+	require.NoError(t, err)
+	require.Empty(t, orders)
+
+	_, err = odb.GetById(ctx, 99)
+	require.ErrorAs(t, err, &internal.OrderNotFoundError{})
+	_, err = odb.Update(ctx, internal.Order{Id: 99, Status: internal.OrderPaid})
+	require.ErrorAs(t, err, &internal.OrderNotFoundError{})
+}
+
+func TestOrderMapDB(t *testing.T) {
+	testOrderRepository(t, repository.NewOrderMapDB())
+}
+
+func TestOrderSQLite(t *testing.T) {
+	testOrderRepository(t, repository.NewOrderSQL(openMigratedSQLite(t)))
+}
+
+func TestOrderFileDBReloadsWhatWasSaved(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.json.orders")
+	odb, err := repository.NewOrderFileDB(path)
+	require.NoError(t, err)
+	testOrderRepository(t, odb)
+
+	reopened, err := repository.NewOrderFileDB(path)
+	require.NoError(t, err)
+	orders, err := odb.GetAll(ctx, "")
+	require.NoError(t, err)
+	reloaded, err := reopened.GetAll(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, orders, reloaded)
+
+	// ids keep growing after a restart
+	order, err := reopened.Save(ctx, newOrder(time.Date(2024, 3, 11, 9, 30, 0, 0, time.UTC)))
+	require.NoError(t, err)
+	require.Equal(t, orders[len(orders)-1].Id+1, order.Id)
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"supermarket/internal"
+	"sync"
+	"time"
+)
+
+// PriceHistoryMapDB is an in-memory price history repository safe for
+// concurrent use. When it has a file, every write is logged to it before
+// being acknowledged.
+type PriceHistoryMapDB struct {
+	mu      sync.RWMutex
+	changes *table[internal.PriceChange]
+}
+
+func NewPriceHistoryMapDB() *PriceHistoryMapDB {
+	return &PriceHistoryMapDB{changes: newTable[internal.PriceChange]()}
+}
+
+// NewPriceHistoryFileDB keeps the price changes in the file at path, loading
+// the ones already saved there.
+func NewPriceHistoryFileDB(path string) (*PriceHistoryMapDB, error) {
+	changes, err := openTable[internal.PriceChange](path)
+	if err != nil {
+		return nil, err
+	}
+	return &PriceHistoryMapDB{changes: changes}, nil
+}
+
+// clonePriceChange returns a copy of change not sharing its previous price.
+func clonePriceChange(change internal.PriceChange) internal.PriceChange {
+	if change.Previous != nil {
+		previous := *change.Previous
+		change.Previous = &previous
+	}
+	return change
+}
+
+// sortPriceChanges sorts changes oldest effective first, the ones effective
+// at the same time in the order they were made.
+func sortPriceChanges(changes []internal.PriceChange) {
+	slices.SortFunc(changes, func(a, b internal.PriceChange) int {
+		if c := a.EffectiveFrom.Compare(b.EffectiveFrom); c != 0 {
+			return c
+		}
+		return a.Id - b.Id
+	})
+}
+
+func (hdb *PriceHistoryMapDB) History(ctx context.Context, productId int) ([]internal.PriceChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+
+	history := []internal.PriceChange{}
+	for _, change := range hdb.changes.rows {
+		if change.ProductId == productId {
+			history = append(history, clonePriceChange(change))
+		}
+	}
+	sortPriceChanges(history)
+	return history, nil
+}
+
+func (hdb *PriceHistoryMapDB) Pending(ctx context.Context, until time.Time) ([]internal.PriceChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+
+	pending := []internal.PriceChange{}
+	for _, change := range hdb.changes.rows {
+		if change.Pending && !change.EffectiveFrom.After(until) {
+			pending = append(pending, clonePriceChange(change))
+		}
+	}
+	sortPriceChanges(pending)
+	return pending, nil
+}
+
+func (hdb *PriceHistoryMapDB) GetById(ctx context.Context, id int) (internal.PriceChange, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.PriceChange{}, err
+	}
+
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+
+	change, ok := hdb.changes.rows[id]
+	if !ok {
+		return internal.PriceChange{}, internal.NewPriceChangeNotFoundError()
+	}
+	return clonePriceChange(change), nil
+}
+
+func (hdb *PriceHistoryMapDB) Save(ctx context.Context, change internal.PriceChange) (internal.PriceChange, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.PriceChange{}, err
+	}
+
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+
+	change.Id = hdb.changes.nextId()
+	if err := hdb.changes.write(putRow(change.Id, clonePriceChange(change))); err != nil {
+		return internal.PriceChange{}, err
+	}
+	return change, nil
+}
+
+func (hdb *PriceHistoryMapDB) Update(ctx context.Context, change internal.PriceChange) (internal.PriceChange, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.PriceChange{}, err
+	}
+
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+
+	if _, ok := hdb.changes.rows[change.Id]; !ok {
+		return internal.PriceChange{}, internal.NewPriceChangeNotFoundError()
+	}
+	if err := hdb.changes.write(putRow(change.Id, clonePriceChange(change))); err != nil {
+		return internal.PriceChange{}, err
+	}
+	return change, nil
+}
+
+func (hdb *PriceHistoryMapDB) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+
+	if _, ok := hdb.changes.rows[id]; !ok {
+		return internal.NewPriceChangeNotFoundError()
+	}
+	return hdb.changes.write(deleteRow[internal.PriceChange](id))
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"supermarket/internal"
+	"time"
+)
+
+func NewPriceHistorySQL(db *sql.DB) *PriceHistorySQL {
+	return &PriceHistorySQL{db: db}
+}
+
+// PriceHistorySQL is a PriceHistoryRepository backed by the SQLite or MySQL
+// database of the products.
+type PriceHistorySQL struct {
+	db *sql.DB
+}
+
+// Queries, the same in both dialects. Changes effective at the same time
+// sort in the order they were made.
+const (
+	sqlGetPriceHistory    = "SELECT id, product_id, price_minor, currency, previous_minor, previous_currency, effective_from, pending, created_at FROM price_changes WHERE product_id = ? ORDER BY effective_from, id"
+	sqlGetPendingPrices   = "SELECT id, product_id, price_minor, currency, previous_minor, previous_currency, effective_from, pending, created_at FROM price_changes WHERE pending = 1 AND effective_from <= ? ORDER BY effective_from, id"
+	sqlGetPriceChangeById = "SELECT id, product_id, price_minor, currency, previous_minor, previous_currency, effective_from, pending, created_at FROM price_changes WHERE id = ?"
+	sqlCreatePriceChange  = "INSERT INTO price_changes (product_id, price_minor, currency, previous_minor, previous_currency, effective_from, pending, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	sqlUpdatePriceChange  = "UPDATE price_changes SET product_id = ?, price_minor = ?, currency = ?, previous_minor = ?, previous_currency = ?, effective_from = ?, pending = ?, created_at = ? WHERE id = ?"
+	sqlDeletePriceChange  = "DELETE FROM price_changes WHERE id = ?"
+)
+
+func (hdb *PriceHistorySQL) History(ctx context.Context, productId int) ([]internal.PriceChange, error) {
+	return queryPriceChanges(ctx, hdb.db, sqlGetPriceHistory, productId)
+}
+
+func (hdb *PriceHistorySQL) Pending(ctx context.Context, until time.Time) ([]internal.PriceChange, error) {
+	return queryPriceChanges(ctx, hdb.db, sqlGetPendingPrices, toSQLTime(until))
+}
+
+// queryPriceChanges returns the price changes query selects.
+func queryPriceChanges(ctx context.Context, db sqlConn, query string, args ...any) ([]internal.PriceChange, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []internal.PriceChange{}
+	for rows.Next() {
+		change, err := scanPriceChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (hdb *PriceHistorySQL) GetById(ctx context.Context, id int) (internal.PriceChange, error) {
+	change, err := scanPriceChange(hdb.db.QueryRowContext(ctx, sqlGetPriceChangeById, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.PriceChange{}, internal.NewPriceChangeNotFoundError()
+		}
+		return internal.PriceChange{}, err
+	}
+	return change, nil
+}
+
+func (hdb *PriceHistorySQL) Save(ctx context.Context, change internal.PriceChange) (internal.PriceChange, error) {
+	previousMinor, previousCurrency := toSQLNullMoney(change.Previous)
+	result, err := hdb.db.ExecContext(ctx, sqlCreatePriceChange, change.ProductId, change.Price.Amount, change.Price.Currency, previousMinor, previousCurrency, toSQLTime(change.EffectiveFrom), change.Pending, toSQLTime(change.CreatedAt))
+	if err != nil {
+		return internal.PriceChange{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return internal.PriceChange{}, err
+	}
+	change.Id = int(id)
+	return change, nil
+}
+
+func (hdb *PriceHistorySQL) Update(ctx context.Context, change internal.PriceChange) (internal.PriceChange, error) {
+	previousMinor, previousCurrency := toSQLNullMoney(change.Previous)
+	result, err := hdb.db.ExecContext(ctx, sqlUpdatePriceChange, change.ProductId, change.Price.Amount, change.Price.Currency, previousMinor, previousCurrency, toSQLTime(change.EffectiveFrom), change.Pending, toSQLTime(change.CreatedAt), change.Id)
+	if err != nil {
+		return internal.PriceChange{}, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return internal.PriceChange{}, err
+	}
+	if rowsAffected == 0 {
+		return internal.PriceChange{}, internal.NewPriceChangeNotFoundError()
+	}
+	return change, nil
+}
+
+func (hdb *PriceHistorySQL) Delete(ctx context.Context, id int) error {
+	result, err := hdb.db.ExecContext(ctx, sqlDeletePriceChange, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return internal.NewPriceChangeNotFoundError()
+	}
+	return nil
+}
+
+// toSQLNullMoney converts money into its stored amount and currency, NULL
+// when it is nil.
+func toSQLNullMoney(money *internal.Money) (sql.NullInt64, sql.NullString) {
+	if money == nil {
+		return sql.NullInt64{}, sql.NullString{}
+	}
+	return sql.NullInt64{Int64: money.Amount, Valid: true}, sql.NullString{String: money.Currency, Valid: true}
+}
+
+func scanPriceChange(row rowScanner) (internal.PriceChange, error) {
+	var (
+		change                   internal.PriceChange
+		previousMinor            sql.NullInt64
+		previousCurrency         sql.NullString
+		effectiveFrom, createdAt string
+	)
+	if err := row.Scan(&change.Id, &change.ProductId, &change.Price.Amount, &change.Price.Currency, &previousMinor, &previousCurrency, &effectiveFrom, &change.Pending, &createdAt); err != nil {
+		return internal.PriceChange{}, err
+	}
+
+	if previousMinor.Valid {
+		change.Previous = &internal.Money{Amount: previousMinor.Int64, Currency: previousCurrency.String}
+	}
+	var err error
+	if change.EffectiveFrom, err = fromSQLTime(effectiveFrom); err != nil {
+		return internal.PriceChange{}, err
+	}
+	if change.CreatedAt, err = fromSQLTime(createdAt); err != nil {
+		return internal.PriceChange{}, err
+	}
+	return change, nil
+}
@@ -0,0 +1,98 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testPriceHistoryRepository runs the checks every price history repository
+// passes, on an empty one.
+func testPriceHistoryRepository(t *testing.T, hdb internal.PriceHistoryRepository) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	previous := usd(100)
+
+	applied, err := hdb.Save(ctx, internal.PriceChange{ProductId: 1, Price: usd(120), Previous: &previous, EffectiveFrom: now, CreatedAt: now})
+	require.NoError(t, err)
+	scheduled, err := hdb.Save(ctx, internal.PriceChange{ProductId: 1, Price: usd(90), EffectiveFrom: now.Add(24 * time.Hour), Pending: true, CreatedAt: now})
+	require.NoError(t, err)
+	// effective before the first one, though made after it
+	earlier, err := hdb.Save(ctx, internal.PriceChange{ProductId: 1, Price: usd(110), Previous: &previous, EffectiveFrom: now.Add(-time.Hour), CreatedAt: now})
+	require.NoError(t, err)
+	other, err := hdb.Save(ctx, internal.PriceChange{ProductId: 2, Price: usd(300), EffectiveFrom: now.Add(time.Hour), Pending: true, CreatedAt: now})
+	require.NoError(t, err)
+	require.Greater(t, scheduled.Id, applied.Id)
+
+	found, err := hdb.GetById(ctx, applied.Id)
+	require.NoError(t, err)
+	require.Equal(t, applied, found)
+
+	history, err := hdb.History(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []internal.PriceChange{earlier, applied, scheduled}, history)
+	pending, err := hdb.Pending(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []internal.PriceChange{other}, pending)
+
+	// the scheduled change takes effect
+	replaced := usd(120)
+	scheduled.Pending, scheduled.Previous = false, &replaced
+	_, err = hdb.Update(ctx, scheduled)
+	require.NoError(t, err)
+	found, err = hdb.GetById(ctx, scheduled.Id)
+	require.NoError(t, err)
+	require.Equal(t, scheduled, found)
+	pending, err = hdb.Pending(ctx, now.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []internal.PriceChange{other}, pending)
+
+	require.NoError(t, hdb.Delete(ctx, other.Id))
+	history, err = hdb.History(ctx, 2)
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	_, err = hdb.GetById(ctx, other.Id)
+	require.ErrorAs(t, err, &internal.PriceChangeNotFoundError{})
+	_, err = hdb.Update(ctx, internal.PriceChange{Id: 99, ProductId: 1, Price: usd(100), EffectiveFrom: now})
+	require.ErrorAs(t, err, &internal.PriceChangeNotFoundError{})
+	require.ErrorAs(t, hdb.Delete(ctx, 99), &internal.PriceChangeNotFoundError{})
+}
+
+func TestPriceHistoryMapDB(t *testing.T) {
+	testPriceHistoryRepository(t, repository.NewPriceHistoryMapDB())
+}
+
+func TestPriceHistorySQLite(t *testing.T) {
+	testPriceHistoryRepository(t, repository.NewPriceHistorySQL(openMigratedSQLite(t)))
+}
+
+func TestPriceHistoryFileDBReloadsWhatWasSaved(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.json.price-history")
+	hdb, err := repository.NewPriceHistoryFileDB(path)
+	require.NoError(t, err)
+	testPriceHistoryRepository(t, hdb)
+
+	reopened, err := repository.NewPriceHistoryFileDB(path)
+	require.NoError(t, err)
+	history, err := hdb.History(ctx, 1)
+	require.NoError(t, err)
+	reloaded, err := reopened.History(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, history, reloaded)
+
+	// ids keep growing after a restart
+	at := time.Date(2024, 3, 12, 9, 30, 0, 0, time.UTC)
+	change, err := reopened.Save(ctx, internal.PriceChange{ProductId: 1, Price: usd(95), EffectiveFrom: at, CreatedAt: at})
+	require.NoError(t, err)
+	for _, saved := range history {
+		require.Greater(t, change.Id, saved.Id)
+	}
+}
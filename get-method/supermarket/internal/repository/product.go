@@ -1,15 +1,32 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"os"
 	"supermarket/internal"
+	"sync"
+	"sync/atomic"
 )
 
+// ProductMapDB is an in-memory product repository safe for concurrent use.
+// Readers get copies of the stored products, writes to a single product are
+// serialized through a per-product lock and ids are allocated atomically.
 type ProductMapDB struct {
 	Products map[int]internal.Product
-	LastID   int
+	LastID   int64
+
+	// mu guards the Products map itself and is only held for map access.
+	mu sync.RWMutex
+	// txMu is held shared by single writes and exclusively by transactions,
+	// so a transaction sees no other write until it commits.
+	txMu sync.RWMutex
+	// locks holds a *sync.Mutex per product id, serializing writes to it.
+	locks sync.Map
+	// journal, when set, records every write before it becomes visible.
+	// A failing journal aborts the write.
+	journal func(change productChange) error
 }
 
 func NewProductRepository() (*ProductMapDB, error) {
@@ -17,19 +34,24 @@ func NewProductRepository() (*ProductMapDB, error) {
 		Products: map[int]internal.Product{},
 		LastID:   0,
 	}
-	lastId, err := pdb.Start()
+	lastId, err := pdb.Start(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	pdb.LastID = lastId
+	atomic.StoreInt64(&pdb.LastID, int64(lastId))
 	return pdb, nil
 }
 
-func (pdb *ProductMapDB) Start() (int, error) {
+func (pdb *ProductMapDB) Start(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	file, err := os.Open("./get-method/supermarket/docs/db/products.json")
 	if err != nil {
 		return 0, err
 	}
+	defer file.Close()
 
 	jsonData, err := io.ReadAll(file)
 	if err != nil {
@@ -41,47 +63,173 @@ func (pdb *ProductMapDB) Start() (int, error) {
 		return 0, err
 	}
 
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+
 	var lastId int = 0
 	for _, product := range readProducts {
-		pdb.Products[product.Id] = product
+		pdb.Products[product.Id] = withInitialVersion(product)
 		lastId = max(lastId, product.Id)
 	}
 
 	return lastId, nil
 }
 
-func (pdb *ProductMapDB) GetAll() (map[int]internal.Product, error) {
-	return pdb.Products, nil
+// lockProduct acquires the write lock of the given product id and returns
+// the function releasing it.
+func (pdb *ProductMapDB) lockProduct(id int) func() {
+	l, _ := pdb.locks.LoadOrStore(id, &sync.Mutex{})
+	mu := l.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
 }
 
-func (pdb *ProductMapDB) GetById(id int) (internal.Product, error) {
-	for _, product := range pdb.Products {
-		if product.Id == id {
-			return product, nil
+// nextID atomically allocates a new product id.
+func (pdb *ProductMapDB) nextID() int {
+	return int(atomic.AddInt64(&pdb.LastID, 1))
+}
+
+// observeID makes sure ids allocated later never collide with id.
+func (pdb *ProductMapDB) observeID(id int) {
+	for {
+		last := atomic.LoadInt64(&pdb.LastID)
+		if int64(id) <= last || atomic.CompareAndSwapInt64(&pdb.LastID, last, int64(id)) {
+			return
+		}
+	}
+}
+
+// commit records change through journal, when set, and applies it to the
+// products map. A write whose context ended while it waited for its locks is
+// dropped. The caller must hold mu.
+func (pdb *ProductMapDB) commit(ctx context.Context, change productChange) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if pdb.journal != nil {
+		if err := pdb.journal(change); err != nil {
+			return err
 		}
 	}
-	return internal.Product{}, internal.NewProductNotFoundError()
+	change.apply(pdb.Products)
+	return nil
+}
+
+// nextVersion returns the version a write to product id stores.
+// The caller must hold mu.
+func (pdb *ProductMapDB) nextVersion(id int) int {
+	return pdb.Products[id].Version + 1
 }
 
-func (pdb *ProductMapDB) Save(product internal.Product) internal.Product {
-	pdb.LastID++
-	product.Id = pdb.LastID
-	pdb.Products[pdb.LastID] = product
+// withInitialVersion gives products stored before they were versioned their
+// first version.
+func withInitialVersion(product internal.Product) internal.Product {
+	if product.Version == 0 {
+		product.Version = 1
+	}
 	return product
 }
 
-func (pdb *ProductMapDB) GetByGreaterPrice(price float64) ([]internal.Product, error) {
-	okProducts := []internal.Product{}
+// codeTaken reports whether a product other than id uses code.
+// The caller must hold mu.
+func (pdb *ProductMapDB) codeTaken(code string, id int) bool {
+	for _, product := range pdb.Products {
+		if product.Code == code && product.Id != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (pdb *ProductMapDB) GetAll(ctx context.Context) (map[int]internal.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pdb.mu.RLock()
+	defer pdb.mu.RUnlock()
+
+	products := make(map[int]internal.Product, len(pdb.Products))
+	for id, product := range pdb.Products {
+		products[id] = product
+	}
+	return products, nil
+}
+
+func (pdb *ProductMapDB) GetPage(ctx context.Context, query internal.ProductPageQuery) (internal.ProductPage, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.ProductPage{}, err
+	}
+
+	pdb.mu.RLock()
+	products := make([]internal.Product, 0, len(pdb.Products))
+	for _, product := range pdb.Products {
+		products = append(products, product)
+	}
+	pdb.mu.RUnlock()
+
+	return pageProducts(products, query)
+}
+
+func (pdb *ProductMapDB) GetById(ctx context.Context, id int) (internal.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Product{}, err
+	}
+
+	pdb.mu.RLock()
+	defer pdb.mu.RUnlock()
 
+	product, ok := pdb.Products[id]
+	if !ok {
+		return internal.Product{}, internal.NewProductNotFoundError()
+	}
+	return product, nil
+}
+
+func (pdb *ProductMapDB) Save(ctx context.Context, product internal.Product) (internal.Product, error) {
+	pdb.txMu.RLock()
+	defer pdb.txMu.RUnlock()
+
+	product.Id = pdb.nextID()
+	product.Version = 1
+
+	unlock := pdb.lockProduct(product.Id)
+	defer unlock()
+
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+
+	if err := pdb.commit(ctx, putChange(product)); err != nil {
+		return internal.Product{}, err
+	}
+	return product, nil
+}
+
+func (pdb *ProductMapDB) GetByGreaterPrice(ctx context.Context, price internal.Money) ([]internal.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pdb.mu.RLock()
+	defer pdb.mu.RUnlock()
+
+	okProducts := []internal.Product{}
 	for _, product := range pdb.Products {
-		if product.Price > price {
+		if product.Price.Currency == price.Currency && product.Price.Amount > price.Amount {
 			okProducts = append(okProducts, product)
 		}
 	}
 	return okProducts, nil
 }
 
-func (pdb *ProductMapDB) GetByCode(code string) (*internal.Product, error) {
+func (pdb *ProductMapDB) GetByCode(ctx context.Context, code string) (*internal.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pdb.mu.RLock()
+	defer pdb.mu.RUnlock()
+
 	for _, product := range pdb.Products {
 		if product.Code == code {
 			return &product, nil
@@ -90,35 +238,78 @@ func (pdb *ProductMapDB) GetByCode(code string) (*internal.Product, error) {
 	return nil, internal.NewProductNotFoundError()
 }
 
-func (pdb *ProductMapDB) UpdateOrCreate(product internal.Product) (internal.Product, error) {
+func (pdb *ProductMapDB) UpdateOrCreate(ctx context.Context, product internal.Product) (internal.Product, error) {
+	pdb.txMu.RLock()
+	defer pdb.txMu.RUnlock()
+
 	if product.Id == 0 {
-		p, err := pdb.GetByCode(product.Code)
-		if err == nil && p.Id != product.Id {
+		// the uniqueness check and the insert happen under the same lock so
+		// two concurrent creations can not both claim the same code
+		pdb.mu.Lock()
+		defer pdb.mu.Unlock()
+
+		if pdb.codeTaken(product.Code, 0) {
 			return internal.Product{}, internal.NewInvalidProductError("code is not unique")
 		}
-		pdb.Save(product)
-		product.Id = pdb.LastID
+		product.Id = pdb.nextID()
+		product.Version = 1
+		if err := pdb.commit(ctx, putChange(product)); err != nil {
+			return internal.Product{}, err
+		}
 		return product, nil
 	}
 
-	pdb.Products[product.Id] = product
+	unlock := pdb.lockProduct(product.Id)
+	defer unlock()
+
+	pdb.observeID(product.Id)
+
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+
+	product.Version = pdb.nextVersion(product.Id)
+	if err := pdb.commit(ctx, putChange(product)); err != nil {
+		return internal.Product{}, err
+	}
 	return product, nil
 }
 
-func (pdb *ProductMapDB) PartialUpdate(id int, product internal.Product) (internal.Product, error) {
+func (pdb *ProductMapDB) PartialUpdate(ctx context.Context, id int, product internal.Product) (internal.Product, error) {
+	pdb.txMu.RLock()
+	defer pdb.txMu.RUnlock()
 
-	pdb.Products[id] = product
+	unlock := pdb.lockProduct(id)
+	defer unlock()
 
-	return product, nil
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
 
+	// the product may have been deleted since the caller read it
+	if _, ok := pdb.Products[id]; !ok {
+		return internal.Product{}, internal.NewProductNotFoundError()
+	}
+
+	product.Id = id
+	product.Version = pdb.nextVersion(id)
+	if err := pdb.commit(ctx, putChange(product)); err != nil {
+		return internal.Product{}, err
+	}
+	return product, nil
 }
 
-func (pdb *ProductMapDB) Delete(id int) error {
-	_, ok := pdb.Products[id]
-	if !ok {
+func (pdb *ProductMapDB) Delete(ctx context.Context, id int) error {
+	pdb.txMu.RLock()
+	defer pdb.txMu.RUnlock()
+
+	unlock := pdb.lockProduct(id)
+	defer unlock()
+
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+
+	if _, ok := pdb.Products[id]; !ok {
 		return internal.NewProductNotFoundError()
 	}
 
-	delete(pdb.Products, id)
-	return nil
+	return pdb.commit(ctx, deleteChange(id))
 }
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"supermarket/internal"
@@ -8,106 +9,280 @@ import (
 	"github.com/go-sql-driver/mysql"
 )
 
+// OpenMySQL connects to the MySQL server described by dsn. Updates report
+// the rows they match, as in SQLite, rather than the rows they change, so
+// the repositories tell a missing row from an unchanged one the same way in
+// both dialects.
+func OpenMySQL(dsn string) (*sql.DB, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ClientFoundRows = true
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
 func NewProductDB(db *sql.DB) *ProductDB {
-	return &ProductDB{db: db}
+	return &ProductDB{db: db, conn: db}
 }
 
+// ProductDB is a ProductRepository backed by a MySQL database.
 type ProductDB struct {
 	db *sql.DB
+	// conn runs the queries, it is db or, within a transaction, tx.
+	conn sqlConn
+	tx   *sql.Tx
+}
+
+// WithinTransaction runs fn in a database transaction. Calls made while
+// already in one join it.
+func (pdb *ProductDB) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repo internal.ProductRepository) error) error {
+	return pdb.transaction(ctx, func(tx *ProductDB) error {
+		return fn(ctx, tx)
+	})
+}
+
+func (pdb *ProductDB) transaction(ctx context.Context, fn func(tx *ProductDB) error) error {
+	if pdb.tx != nil {
+		return fn(pdb)
+	}
+	return withinSQLTransaction(ctx, pdb.db, func(tx *sql.Tx) error {
+		return fn(&ProductDB{db: pdb.db, conn: tx, tx: tx})
+	})
 }
 
 // Queries
 const (
-	GetProductById = "SELECT id, name, quantity, code_value, is_published, expiration, price FROM products WHERE id = ?"
-	CreateProduct  = "INSERT INTO products (name, quantity, code_value, is_published, expiration, price) VALUES (?, ?, ?, ?, ?, ?)"
-	UpdateProduct  = "UPDATE products SET name = ?, quantity = ?, code_value = ?, is_published = ?, expiration = ?, price = ? WHERE id = ?"
-	DeleteProduct  = "DELETE FROM products WHERE id = ?"
+	GetLastProductId      = "SELECT COALESCE(MAX(id), 0) FROM products"
+	GetAllProducts        = "SELECT id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category, version FROM products ORDER BY id"
+	GetProductById        = "SELECT id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category, version FROM products WHERE id = ?"
+	GetProductByIdForLock = "SELECT id FROM products WHERE id = ? FOR UPDATE"
+	GetProductVersion     = "SELECT version FROM products WHERE id = ?"
+	GetProductByCode      = "SELECT id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category, version FROM products WHERE code_value = ?"
+	GetProductsByPrice    = "SELECT id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category, version FROM products WHERE currency = ? AND price_minor > ? ORDER BY id"
+	CreateProduct         = "INSERT INTO products (name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	CreateProductWithId   = "INSERT INTO products (id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	UpdateProduct         = "UPDATE products SET name = ?, quantity = ?, code_value = ?, is_published = ?, expiration = ?, price_minor = ?, currency = ?, tax_category = ?, version = version + 1 WHERE id = ?"
+	DeleteProduct         = "DELETE FROM products WHERE id = ?"
 )
 
-func (pdb *ProductDB) GetById(id int) (internal.Product, error) {
-	row := pdb.db.QueryRow(GetProductById, id)
-	if err := row.Err(); err != nil {
+// Start returns the last id in use, the schema is managed outside the
+// repository.
+func (pdb *ProductDB) Start(ctx context.Context) (int, error) {
+	var lastId int
+	if err := pdb.conn.QueryRowContext(ctx, GetLastProductId).Scan(&lastId); err != nil {
+		return 0, err
+	}
+	return lastId, nil
+}
+
+func (pdb *ProductDB) GetAll(ctx context.Context) (map[int]internal.Product, error) {
+	products, err := queryProducts(ctx, pdb.conn, GetAllProducts)
+	if err != nil {
+		return nil, err
+	}
+
+	productsMap := make(map[int]internal.Product, len(products))
+	for _, product := range products {
+		productsMap[product.Id] = product
+	}
+	return productsMap, nil
+}
+
+func (pdb *ProductDB) GetPage(ctx context.Context, query internal.ProductPageQuery) (internal.ProductPage, error) {
+	return queryProductPage(ctx, pdb.conn, DialectMySQL, query)
+}
+
+func (pdb *ProductDB) GetById(ctx context.Context, id int) (internal.Product, error) {
+	return queryProduct(ctx, pdb.conn, GetProductById, id)
+}
+
+func (pdb *ProductDB) Save(ctx context.Context, product internal.Product) (internal.Product, error) {
+	if err := pdb.Create(ctx, &product); err != nil {
+		return internal.Product{}, err
+	}
+	return product, nil
+}
+
+func (pdb *ProductDB) GetByGreaterPrice(ctx context.Context, price internal.Money) ([]internal.Product, error) {
+	return queryProducts(ctx, pdb.conn, GetProductsByPrice, price.Currency, price.Amount)
+}
+
+func (pdb *ProductDB) GetByCode(ctx context.Context, code string) (*internal.Product, error) {
+	product, err := queryProduct(ctx, pdb.conn, GetProductByCode, code)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (pdb *ProductDB) UpdateOrCreate(ctx context.Context, product internal.Product) (internal.Product, error) {
+	if product.Id == 0 {
+		err := pdb.Create(ctx, &product)
+		if errors.As(err, &internal.ProductAlreadyExistsError{}) {
+			return internal.Product{}, internal.NewInvalidProductError("code is not unique")
+		}
+		return product, err
+	}
+
+	expiration, err := toSQLDate(product.Expiration)
+	if err != nil {
 		return internal.Product{}, err
 	}
 
-	var product internal.Product
-	if err := row.Scan(&product.Id, &product.Name, &product.Quantity, &product.Code, &product.IsPublished, &product.Expiration, &product.Price); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return internal.Product{}, internal.NewProductNotFoundError()
+	// ON DUPLICATE KEY UPDATE would also fire on a code clash and overwrite
+	// the other product, so lock the id and pick the statement ourselves
+	err = pdb.transaction(ctx, func(tx *ProductDB) error {
+		var id int
+		if err := tx.conn.QueryRowContext(ctx, GetProductByIdForLock, product.Id).Scan(&id); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			if _, err := tx.conn.ExecContext(ctx,
+				CreateProductWithId,
+				product.Id,
+				product.Name,
+				product.Quantity,
+				product.Code,
+				product.IsPublished,
+				expiration,
+				product.Price.Amount,
+				product.Price.Currency,
+				product.TaxCategory,
+			); err != nil {
+				if isMySQLDuplicateEntry(err) {
+					return internal.NewInvalidProductError("code is not unique")
+				}
+				return err
+			}
+			product.Version = 1
+			return saveProductCategories(ctx, tx.conn, product.Id, product.CategoryIds)
+		}
+
+		if err := tx.Update(ctx, &product); err != nil {
+			if errors.As(err, &internal.ProductAlreadyExistsError{}) {
+				return internal.NewInvalidProductError("code is not unique")
+			}
+			return err
 		}
+		return nil
+	})
+	if err != nil {
 		return internal.Product{}, err
 	}
+	return product, nil
+}
 
+func (pdb *ProductDB) PartialUpdate(ctx context.Context, id int, product internal.Product) (internal.Product, error) {
+	product.Id = id
+	if err := pdb.Update(ctx, &product); err != nil {
+		if errors.As(err, &internal.ProductAlreadyExistsError{}) {
+			return internal.Product{}, internal.NewInvalidProductError("code is not unique")
+		}
+		return internal.Product{}, err
+	}
 	return product, nil
 }
 
-func (pdb *ProductDB) Create(product *internal.Product) error {
-	result, err := pdb.db.Exec(
-		CreateProduct,
-		product.Name,
-		product.Quantity,
-		product.Code,
-		product.IsPublished,
-		product.Expiration,
-		product.Price,
-	)
+func (pdb *ProductDB) Create(ctx context.Context, product *internal.Product) error {
+	expiration, err := toSQLDate(product.Expiration)
 	if err != nil {
-		var mysqlError *mysql.MySQLError
-		if errors.As(err, &mysqlError) {
-			switch mysqlError.Number {
-			case 1062:
+		return err
+	}
+
+	// the product and its categories are stored together
+	err = pdb.transaction(ctx, func(tx *ProductDB) error {
+		result, err := tx.conn.ExecContext(ctx,
+			CreateProduct,
+			product.Name,
+			product.Quantity,
+			product.Code,
+			product.IsPublished,
+			expiration,
+			product.Price.Amount,
+			product.Price.Currency,
+			product.TaxCategory,
+		)
+		if err != nil {
+			if isMySQLDuplicateEntry(err) {
 				return internal.NewProductAlreadyExistsError()
 			}
 			return err
 		}
-	}
 
-	id, err := result.LastInsertId()
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		product.Id = int(id)
+		return saveProductCategories(ctx, tx.conn, product.Id, product.CategoryIds)
+	})
 	if err != nil {
 		return err
 	}
 
-	product.Id = int(id)
+	product.Version = 1
 
 	return nil
 }
 
-func (pdb *ProductDB) Update(product *internal.Product) error {
-	row, err := pdb.db.Exec(
-		UpdateProduct,
-		product.Name,
-		product.Quantity,
-		product.Code,
-		product.IsPublished,
-		product.Expiration,
-		product.Price,
-		product.Id,
-	)
+func (pdb *ProductDB) Update(ctx context.Context, product *internal.Product) error {
+	expiration, err := toSQLDate(product.Expiration)
 	if err != nil {
-		var mysqlError *mysql.MySQLError
-		if errors.As(err, &mysqlError) {
-			switch mysqlError.Number {
-			case 1062:
+		return err
+	}
+
+	// the new version has to be read back in the same transaction, where the
+	// categories are replaced too
+	return pdb.transaction(ctx, func(tx *ProductDB) error {
+		row, err := tx.conn.ExecContext(ctx,
+			UpdateProduct,
+			product.Name,
+			product.Quantity,
+			product.Code,
+			product.IsPublished,
+			expiration,
+			product.Price.Amount,
+			product.Price.Currency,
+			product.TaxCategory,
+			product.Id,
+		)
+		if err != nil {
+			if isMySQLDuplicateEntry(err) {
 				return internal.NewProductAlreadyExistsError()
 			}
 			return err
 		}
-	}
 
-	rowsAffected, err := row.RowsAffected()
-	if err != nil {
-		return err
-	}
+		// the version always changes, so an existing row is always affected
+		rowsAffected, err := row.RowsAffected()
+		if err != nil {
+			return err
+		}
 
-	if rowsAffected == 0 {
-		return internal.NewProductNotFoundError()
-	}
+		if rowsAffected == 0 {
+			return internal.NewProductNotFoundError()
+		}
 
-	return nil
+		if err := tx.conn.QueryRowContext(ctx, GetProductVersion, product.Id).Scan(&product.Version); err != nil {
+			return err
+		}
+		return saveProductCategories(ctx, tx.conn, product.Id, product.CategoryIds)
+	})
 }
 
-func (pdb *ProductDB) Delete(id int) error {
-	row, err := pdb.db.Exec(DeleteProduct, id)
+func (pdb *ProductDB) Delete(ctx context.Context, id int) error {
+	row, err := pdb.conn.ExecContext(ctx, DeleteProduct, id)
 	if err != nil {
 		return err
 	}
@@ -123,3 +298,11 @@ func (pdb *ProductDB) Delete(id int) error {
 
 	return nil
 }
+
+// mysqlDuplicateEntryErr is the error number of a unique key violation.
+const mysqlDuplicateEntryErr = 1062
+
+func isMySQLDuplicateEntry(err error) bool {
+	var mysqlError *mysql.MySQLError
+	return errors.As(err, &mysqlError) && mysqlError.Number == mysqlDuplicateEntryErr
+}
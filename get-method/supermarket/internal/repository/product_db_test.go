@@ -0,0 +1,184 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"supermarket/platform/database/migration"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+// Statements ProductDB shares with the other SQL repositories.
+const (
+	mockGetCategories    = "SELECT product_id, category_id FROM product_categories WHERE product_id IN (?) ORDER BY product_id, category_id"
+	mockDeleteCategories = "DELETE FROM product_categories WHERE product_id = ?"
+	mockInsertCategory   = "INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)"
+)
+
+var mockProductColumns = []string{"id", "name", "quantity", "code_value", "is_published", "expiration", "price_minor", "currency", "tax_category", "version"}
+
+func newMockDB(t *testing.T) (*repository.ProductDB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+	})
+	return repository.NewProductDB(db), mock
+}
+
+func TestProductDBGetById(t *testing.T) {
+	pdb, mock := newMockDB(t)
+
+	mock.ExpectQuery(repository.GetProductById).WithArgs(1).WillReturnRows(
+		sqlmock.NewRows(mockProductColumns).AddRow(1, "p1", 3, "c1", true, "2065-02-01", 150, "USD", "food", 2))
+	mock.ExpectQuery(mockGetCategories).WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"product_id", "category_id"}).AddRow(1, 4).AddRow(1, 7))
+	mock.ExpectQuery(repository.GetProductById).WithArgs(9).WillReturnError(sql.ErrNoRows)
+
+	product, err := pdb.GetById(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, internal.Product{Id: 1, Name: "p1", Quantity: 3, Code: "c1", IsPublished: true, Expiration: "01/02/2065", Price: usd(150), TaxCategory: "food", CategoryIds: []int{4, 7}, Version: 2}, product)
+
+	_, err = pdb.GetById(context.Background(), 9)
+	require.ErrorAs(t, err, &internal.ProductNotFoundError{})
+}
+
+func TestProductDBSave(t *testing.T) {
+	pdb, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(repository.CreateProduct).
+		WithArgs("p1", 3, "c1", true, "2065-02-01", int64(150), "USD", "").
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec(mockDeleteCategories).WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(mockInsertCategory).WithArgs(5, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	saved, err := pdb.Save(context.Background(), internal.Product{Name: "p1", Quantity: 3, Code: "c1", IsPublished: true, Expiration: "01/02/2065", Price: usd(150), CategoryIds: []int{4}})
+	require.NoError(t, err)
+	require.Equal(t, 5, saved.Id)
+	require.Equal(t, 1, saved.Version)
+
+	// a unique key violation is a duplicate code
+	mock.ExpectBegin()
+	mock.ExpectExec(repository.CreateProduct).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	mock.ExpectRollback()
+
+	_, err = pdb.Save(context.Background(), internal.Product{Name: "p2", Quantity: 1, Code: "c1", Price: usd(100)})
+	require.ErrorAs(t, err, &internal.ProductAlreadyExistsError{})
+}
+
+func TestProductDBUpdate(t *testing.T) {
+	pdb, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(repository.UpdateProduct).
+		WithArgs("p1", 4, "c1", false, nil, int64(200), "USD", "", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(repository.GetProductVersion).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+	mock.ExpectExec(mockDeleteCategories).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	updated, err := pdb.PartialUpdate(context.Background(), 1, internal.Product{Name: "p1", Quantity: 4, Code: "c1", Price: usd(200)})
+	require.NoError(t, err)
+	require.Equal(t, 3, updated.Version)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(repository.UpdateProduct).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err = pdb.PartialUpdate(context.Background(), 9, internal.Product{Name: "p9", Quantity: 1, Code: "c9", Price: usd(100)})
+	require.ErrorAs(t, err, &internal.ProductNotFoundError{})
+
+	mock.ExpectBegin()
+	mock.ExpectExec(repository.UpdateProduct).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	mock.ExpectRollback()
+
+	_, err = pdb.PartialUpdate(context.Background(), 1, internal.Product{Name: "p1", Quantity: 1, Code: "c2", Price: usd(100)})
+	require.ErrorAs(t, err, &internal.InvalidProductError{})
+}
+
+func TestProductDBUpdateOrCreateWithIdInserts(t *testing.T) {
+	pdb, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(repository.GetProductByIdForLock).WithArgs(42).WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec(repository.CreateProductWithId).
+		WithArgs(42, "p42", 1, "c42", false, nil, int64(100), "USD", "").
+		WillReturnResult(sqlmock.NewResult(42, 1))
+	mock.ExpectExec(mockDeleteCategories).WithArgs(42).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	created, err := pdb.UpdateOrCreate(context.Background(), internal.Product{Id: 42, Name: "p42", Quantity: 1, Code: "c42", Price: usd(100)})
+	require.NoError(t, err)
+	require.Equal(t, 1, created.Version)
+}
+
+func TestProductDBDelete(t *testing.T) {
+	pdb, mock := newMockDB(t)
+
+	mock.ExpectExec(repository.DeleteProduct).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(repository.DeleteProduct).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, pdb.Delete(context.Background(), 1))
+	require.ErrorAs(t, pdb.Delete(context.Background(), 9), &internal.ProductNotFoundError{})
+}
+
+func TestProductDBGetByGreaterPrice(t *testing.T) {
+	pdb, mock := newMockDB(t)
+
+	mock.ExpectQuery(repository.GetProductsByPrice).WithArgs("USD", int64(1000)).WillReturnRows(
+		sqlmock.NewRows(mockProductColumns).AddRow(2, "p2", 1, "c2", false, nil, 2000, "USD", "", 1))
+	mock.ExpectQuery(mockGetCategories).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"product_id", "category_id"}))
+
+	expensive, err := pdb.GetByGreaterPrice(context.Background(), usd(1000))
+	require.NoError(t, err)
+	require.Equal(t, []internal.Product{{Id: 2, Name: "p2", Quantity: 1, Code: "c2", Price: usd(2000), Version: 1}}, expensive)
+}
+
+// TestProductDBMySQL runs against the server of MYSQL_TEST_DSN, an empty
+// database the migrations are applied to.
+func TestProductDBMySQL(t *testing.T) {
+	dsn := os.Getenv("MYSQL_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MYSQL_TEST_DSN not set")
+	}
+	db, err := repository.OpenMySQL(dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrations, err := repository.Migrations(repository.DialectMySQL)
+	require.NoError(t, err)
+	migrator, err := migration.NewMigrator(db, migrations)
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+
+	pdb := repository.NewProductDB(db)
+	ctx := context.Background()
+
+	saved, err := pdb.Save(ctx, internal.Product{Name: "p1", Quantity: 1, Code: "c1", Price: usd(150), Expiration: "01/02/2065"})
+	require.NoError(t, err)
+	defer pdb.Delete(ctx, saved.Id)
+
+	_, err = pdb.Save(ctx, internal.Product{Name: "dup", Quantity: 1, Code: "c1", Price: usd(100)})
+	require.ErrorAs(t, err, &internal.ProductAlreadyExistsError{})
+
+	saved.Quantity = 5
+	updated, err := pdb.PartialUpdate(ctx, saved.Id, saved)
+	require.NoError(t, err)
+	require.Equal(t, 2, updated.Version)
+
+	found, err := pdb.GetById(ctx, saved.Id)
+	require.NoError(t, err)
+	require.Equal(t, updated, found)
+}
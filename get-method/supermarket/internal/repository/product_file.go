@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"supermarket/internal"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCompactAfter is the number of logged changes after which the
+// write-ahead log is folded into a new snapshot.
+const DefaultCompactAfter = 1000
+
+// ProductFileDB is a ProductRepository persisting every write to disk before
+// acknowledging it. Writes are appended to a write-ahead log, which is
+// compacted in the background into a snapshot kept by a StorageRepository
+// once it grows past compactAfter records. Reads are served from memory.
+type ProductFileDB struct {
+	*ProductMapDB
+	storage      internal.StorageRepository
+	wal          *WAL
+	compactAfter int
+
+	// compactMu makes compactions run one at a time.
+	compactMu  sync.Mutex
+	compacting atomic.Bool
+	background sync.WaitGroup
+	// afterStep, when set, is called after each compaction step; tests
+	// use it to simulate crashes.
+	afterStep func(step string) error
+}
+
+func NewProductFileRepository(storage internal.StorageRepository, walPath string, compactAfter int) (*ProductFileDB, error) {
+	wal, err := OpenWAL(walPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fdb := &ProductFileDB{
+		ProductMapDB: &ProductMapDB{
+			Products: map[int]internal.Product{},
+			LastID:   0,
+		},
+		storage:      storage,
+		wal:          wal,
+		compactAfter: compactAfter,
+	}
+	fdb.journal = fdb.append
+
+	lastId, err := fdb.Start(context.Background())
+	if err != nil {
+		wal.Close()
+		return nil, err
+	}
+	atomic.StoreInt64(&fdb.LastID, int64(lastId))
+	return fdb, nil
+}
+
+// Start loads the last snapshot and replays the write-ahead log on top of it.
+func (fdb *ProductFileDB) Start(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	products, err := fdb.storage.GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := fdb.wal.Replay(func(change productChange) {
+		change.apply(products)
+	}); err != nil {
+		return 0, err
+	}
+
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+
+	var lastId int = 0
+	for id, product := range products {
+		products[id] = withInitialVersion(product)
+		lastId = max(lastId, id)
+	}
+	fdb.Products = products
+
+	return lastId, nil
+}
+
+// append logs change and starts a background compaction when the log got
+// too long. It runs with mu held.
+func (fdb *ProductFileDB) append(change productChange) error {
+	if err := fdb.wal.Append(change); err != nil {
+		return err
+	}
+
+	if fdb.compactAfter > 0 && fdb.wal.Records() >= fdb.compactAfter && fdb.compacting.CompareAndSwap(false, true) {
+		fdb.background.Add(1)
+		go func() {
+			defer fdb.background.Done()
+			defer fdb.compacting.Store(false)
+
+			if err := fdb.Compact(); err != nil {
+				log.Printf("compacting product log: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Compact folds the write-ahead log into a new snapshot.
+// A crash at any point leaves files Start recovers from: the rotated log is
+// only discarded once the snapshot containing its changes has been saved,
+// and replaying changes already in the snapshot is harmless.
+func (fdb *ProductFileDB) Compact() error {
+	fdb.compactMu.Lock()
+	defer fdb.compactMu.Unlock()
+
+	// the snapshot and the rotation must observe the same writes
+	fdb.mu.Lock()
+	snapshot := make(map[int]internal.Product, len(fdb.Products))
+	for id, product := range fdb.Products {
+		snapshot[id] = product
+	}
+	err := fdb.wal.Rotate()
+	fdb.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := fdb.step("rotate"); err != nil {
+		return err
+	}
+
+	if err := fdb.storage.Save(snapshot); err != nil {
+		return err
+	}
+	if err := fdb.step("snapshot"); err != nil {
+		return err
+	}
+
+	if err := fdb.wal.Discard(); err != nil {
+		return err
+	}
+	return fdb.step("discard")
+}
+
+func (fdb *ProductFileDB) step(name string) error {
+	if fdb.afterStep == nil {
+		return nil
+	}
+	return fdb.afterStep(name)
+}
+
+// Close waits for a running compaction and closes the write-ahead log.
+func (fdb *ProductFileDB) Close() error {
+	fdb.background.Wait()
+	return fdb.wal.Close()
+}
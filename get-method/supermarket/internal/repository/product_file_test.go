@@ -0,0 +1,221 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errCrash = errors.New("simulated crash")
+
+type fileDBPaths struct {
+	dir      string
+	snapshot string
+	wal      string
+}
+
+func newFileDBPaths(t *testing.T) fileDBPaths {
+	dir := t.TempDir()
+	return fileDBPaths{
+		dir:      dir,
+		snapshot: filepath.Join(dir, "products.json"),
+		wal:      filepath.Join(dir, "products.json.wal"),
+	}
+}
+
+func openFileDB(t *testing.T, paths fileDBPaths, compactAfter int) *repository.ProductFileDB {
+	db, err := repository.NewProductFileRepository(repository.NewStorage(paths.snapshot), paths.wal, compactAfter)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func saveProducts(t *testing.T, db internal.ProductRepository, from, to int) {
+	for i := from; i <= to; i++ {
+		_, err := db.Save(context.Background(), internal.Product{Name: fmt.Sprintf("p%d", i), Quantity: i, Code: fmt.Sprintf("c%d", i), Price: usd(int64(i) * 100)})
+		require.NoError(t, err)
+	}
+}
+
+func requireSameProducts(t *testing.T, expected, actual internal.ProductRepository) {
+	expectedProducts, err := expected.GetAll(context.Background())
+	require.NoError(t, err)
+	actualProducts, err := actual.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, expectedProducts, actualProducts)
+}
+
+func TestProductFileDBReloadsWhatWasSaved(t *testing.T) {
+	paths := newFileDBPaths(t)
+	db := openFileDB(t, paths, 0)
+
+	p1, err := db.Save(context.Background(), internal.Product{Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), Expiration: "01/02/2065"})
+	require.NoError(t, err)
+	p2, err := db.UpdateOrCreate(context.Background(), internal.Product{Name: "p2", Quantity: 2, Code: "c2", Price: usd(200), Expiration: "01/02/2065"})
+	require.NoError(t, err)
+	p3, err := db.Save(context.Background(), internal.Product{Name: "p3", Quantity: 3, Code: "c3", Price: usd(300), Expiration: "01/02/2065"})
+	require.NoError(t, err)
+
+	p2.Name = "p2 patched"
+	p2, err = db.PartialUpdate(context.Background(), p2.Id, p2)
+	require.NoError(t, err)
+	require.Equal(t, 2, p2.Version)
+	require.NoError(t, db.Delete(context.Background(), p1.Id))
+
+	reopened := openFileDB(t, paths, 0)
+
+	products, err := reopened.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[int]internal.Product{p2.Id: p2, p3.Id: p3}, products)
+
+	// ids keep growing after a restart
+	p4, err := reopened.Save(context.Background(), internal.Product{Name: "p4", Quantity: 4, Code: "c4", Price: usd(400)})
+	require.NoError(t, err)
+	require.Equal(t, p3.Id+1, p4.Id)
+}
+
+func TestProductFileDBRejectsWriteWhenLogFails(t *testing.T) {
+	paths := newFileDBPaths(t)
+	db := openFileDB(t, paths, 0)
+	saveProducts(t, db, 1, 2)
+
+	require.NoError(t, db.Close())
+
+	_, err := db.Save(context.Background(), internal.Product{Name: "p3", Quantity: 3, Code: "c3", Price: usd(300)})
+	require.Error(t, err)
+	require.Error(t, db.Delete(context.Background(), 1))
+
+	products, err := db.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	require.Contains(t, products, 1)
+}
+
+func TestProductFileDBToleratesTornFinalRecord(t *testing.T) {
+	paths := newFileDBPaths(t)
+	db := openFileDB(t, paths, 0)
+	saveProducts(t, db, 1, 3)
+
+	// a crash in the middle of appending the fourth record
+	wal, err := os.OpenFile(paths.wal, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = wal.WriteString(`1234abcd {"op":"put","id":4,"prod`)
+	require.NoError(t, err)
+	require.NoError(t, wal.Close())
+
+	reopened := openFileDB(t, paths, 0)
+	requireSameProducts(t, db, reopened)
+
+	// the torn record was truncated so new records replay fine
+	saveProducts(t, reopened, 4, 5)
+	requireSameProducts(t, reopened, openFileDB(t, paths, 0))
+}
+
+func TestProductFileDBDetectsCorruptedRecord(t *testing.T) {
+	paths := newFileDBPaths(t)
+	db := openFileDB(t, paths, 0)
+	saveProducts(t, db, 1, 3)
+	require.NoError(t, db.Close())
+
+	data, err := os.ReadFile(paths.wal)
+	require.NoError(t, err)
+	data[12] ^= 0xff
+	require.NoError(t, os.WriteFile(paths.wal, data, 0o644))
+
+	_, err = repository.NewProductFileRepository(repository.NewStorage(paths.snapshot), paths.wal, 0)
+	require.ErrorAs(t, err, &repository.WALCorruptedError{})
+}
+
+func TestProductFileDBRecoversFromCrashDuringCompaction(t *testing.T) {
+	for _, step := range []string{"rotate", "snapshot", "discard"} {
+		t.Run(step, func(t *testing.T) {
+			paths := newFileDBPaths(t)
+			db := openFileDB(t, paths, 0)
+
+			saveProducts(t, db, 1, 5)
+			require.NoError(t, db.Delete(context.Background(), 2))
+			require.NoError(t, db.Compact())
+
+			saveProducts(t, db, 6, 8)
+			_, err := db.PartialUpdate(context.Background(), 1, internal.Product{Name: "patched", Quantity: 1, Code: "c1", Price: usd(100)})
+			require.NoError(t, err)
+
+			repository.SetCompactionHook(db, func(s string) error {
+				if s == step {
+					return errCrash
+				}
+				return nil
+			})
+			require.ErrorIs(t, db.Compact(), errCrash)
+
+			// writes accepted after the interrupted compaction are kept too
+			require.NoError(t, db.Delete(context.Background(), 6))
+			saveProducts(t, db, 9, 9)
+
+			reopened := openFileDB(t, paths, 0)
+			requireSameProducts(t, db, reopened)
+
+			// a later compaction picks up whatever was left behind
+			require.NoError(t, reopened.Compact())
+			saveProducts(t, reopened, 10, 10)
+			requireSameProducts(t, reopened, openFileDB(t, paths, 0))
+
+			_, err = os.Stat(paths.wal + ".compacting")
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+func TestProductFileDBRecoversFromCrashDuringSnapshotWrite(t *testing.T) {
+	paths := newFileDBPaths(t)
+	db := openFileDB(t, paths, 0)
+	saveProducts(t, db, 1, 3)
+	require.NoError(t, db.Compact())
+	saveProducts(t, db, 4, 4)
+
+	// a crash after writing the temporary snapshot but before the rename
+	require.NoError(t, os.WriteFile(filepath.Join(paths.dir, ".products.json.tmp-123"), []byte(`[{"id":9`), 0o644))
+
+	requireSameProducts(t, db, openFileDB(t, paths, 0))
+}
+
+func TestProductFileDBCompactsInBackground(t *testing.T) {
+	paths := newFileDBPaths(t)
+	db := openFileDB(t, paths, 5)
+	saveProducts(t, db, 1, 23)
+	require.NoError(t, db.Close())
+
+	// at most compactAfter records stay in the log
+	data, err := os.ReadFile(paths.wal)
+	require.NoError(t, err)
+	require.Less(t, len(data), 5*200)
+
+	reopened := openFileDB(t, paths, 5)
+	products, err := reopened.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, products, 23)
+}
+
+func TestStorageSaveLeavesNoTemporaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	storage := repository.NewStorage(filepath.Join(dir, "products.json"))
+
+	require.NoError(t, storage.Save(map[int]internal.Product{1: {Id: 1, Name: "p1"}}))
+	require.NoError(t, storage.Save(map[int]internal.Product{2: {Id: 2, Name: "p2"}}))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	products, err := storage.GetAll()
+	require.NoError(t, err)
+	require.Equal(t, map[int]internal.Product{2: {Id: 2, Name: "p2"}}, products)
+}
@@ -0,0 +1,58 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"supermarket/internal"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductRepositoryFilters(t *testing.T) {
+	expected := map[string][]int{
+		"price gte 1.5 and price lt 3":               {2, 3, 6, 7},
+		"quantity eq 0 or name prefix CH":            {1, 3, 4, 7},
+		"expiration before 01/02/2065":               {3, 6, 7},
+		"expiration after 31/12/2064":                {1, 3, 4, 7},
+		"name contains AN":                           {1, 5},
+		"code_value in (c0, c6, c9)":                 {1, 7},
+		"is_published eq true":                       {},
+		"is_published eq false and quantity gt 1":    {3, 6},
+		`name contains "%"`:                          {},
+		`name contains "_"`:                          {},
+		`name contains "'; DROP TABLE products; --"`: {},
+		"price gt 0 and (quantity lt 1 or (expiration after 01/01/2065 and name prefix c))": {1, 3, 4, 7},
+	}
+
+	for name, newRepository := range pageRepositories {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepository(t)
+			savePageProducts(t, repo)
+
+			for expression, ids := range expected {
+				filter, err := internal.ParseProductFilter(expression)
+				require.NoError(t, err, expression)
+
+				// walk the pages, so filters and cursors are combined
+				query := internal.ProductPageQuery{SortBy: internal.SortById, Limit: 2, Filter: filter}
+				got := []int{}
+				for {
+					page, err := repo.GetPage(context.Background(), query)
+					require.NoError(t, err, expression)
+					require.Equal(t, len(ids), page.Total, expression)
+					got = append(got, pageIds(page)...)
+					if page.NextCursor == "" {
+						break
+					}
+					query.Cursor = page.NextCursor
+				}
+				require.Equal(t, ids, got, expression)
+			}
+
+			products, err := repo.GetAll(context.Background())
+			require.NoError(t, err)
+			require.Len(t, products, 7)
+		})
+	}
+}
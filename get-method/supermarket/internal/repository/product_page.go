@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"cmp"
+	"sort"
+	"strings"
+	"supermarket/internal"
+)
+
+// noExpiration is the sort key of products without an expiration, which
+// sort before all others.
+const noExpiration = "0001-01-01"
+
+func expirationSortKey(expiration string) string {
+	date, err := toSQLDate(expiration)
+	if err != nil || !date.Valid {
+		return noExpiration
+	}
+	return date.String
+}
+
+// compareProducts orders a and b by the field of query and then by id,
+// reversed when query is descending.
+func compareProducts(a, b internal.Product, query internal.ProductPageQuery) int {
+	var c int
+	switch query.SortBy {
+	case internal.SortByName:
+		c = strings.Compare(a.Name, b.Name)
+	case internal.SortByPrice:
+		c = cmp.Compare(a.Price.Amount, b.Price.Amount)
+	case internal.SortByQuantity:
+		c = cmp.Compare(a.Quantity, b.Quantity)
+	case internal.SortByExpiration:
+		c = strings.Compare(expirationSortKey(a.Expiration), expirationSortKey(b.Expiration))
+	}
+	if c == 0 {
+		c = cmp.Compare(a.Id, b.Id)
+	}
+	if query.Descending {
+		return -c
+	}
+	return c
+}
+
+// pageProducts sorts products, which it owns, and returns the page query
+// selects from them.
+func pageProducts(products []internal.Product, query internal.ProductPageQuery) (internal.ProductPage, error) {
+	if query.Filter != nil {
+		matching := products[:0]
+		for _, product := range products {
+			if query.Filter.Match(product) {
+				matching = append(matching, product)
+			}
+		}
+		products = matching
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		return compareProducts(products[i], products[j], query) < 0
+	})
+
+	start := query.Offset
+	if query.Cursor != "" {
+		after, err := query.CursorProduct()
+		if err != nil {
+			return internal.ProductPage{}, err
+		}
+		start = sort.Search(len(products), func(i int) bool {
+			return compareProducts(after, products[i], query) < 0
+		})
+	}
+	start = min(start, len(products))
+	end := min(start+query.Limit, len(products))
+
+	page := internal.ProductPage{
+		Items: append([]internal.Product{}, products[start:end]...),
+		Total: len(products),
+	}
+	if end < len(products) {
+		page.NextCursor = internal.NewProductCursor(query, products[end-1])
+	}
+	return page, nil
+}
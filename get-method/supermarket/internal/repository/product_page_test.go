@@ -0,0 +1,168 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+var pageRepositories = map[string]func(t *testing.T) internal.ProductRepository{
+	"memory": func(t *testing.T) internal.ProductRepository {
+		return &repository.ProductMapDB{Products: map[int]internal.Product{}}
+	},
+	"file": func(t *testing.T) internal.ProductRepository {
+		return openFileDB(t, newFileDBPaths(t), 0)
+	},
+	"sqlite": func(t *testing.T) internal.ProductRepository {
+		return newSQLiteDB(t)
+	},
+}
+
+// savePageProducts saves products sharing sort keys, so ordering relies on
+// the id tie-break, some of them without an expiration.
+func savePageProducts(t *testing.T, repo internal.ProductRepository) {
+	names := []string{"banana", "apple", "cherry", "apple", "banana", "date", "apple"}
+	expirations := []string{"01/02/2065", "", "15/01/2065", "01/02/2065", "", "31/12/2064", "15/01/2065"}
+	for i, name := range names {
+		_, err := repo.Save(context.Background(), internal.Product{
+			Name:       name,
+			Quantity:   i % 3,
+			Code:       fmt.Sprintf("c%d", i),
+			Price:      usd(int64(i%4)*100 + 50),
+			Expiration: expirations[i],
+		})
+		require.NoError(t, err)
+	}
+}
+
+func pageIds(page internal.ProductPage) []int {
+	ids := []int{}
+	for _, product := range page.Items {
+		ids = append(ids, product.Id)
+	}
+	return ids
+}
+
+func TestProductRepositoryPagination(t *testing.T) {
+	expected := map[internal.ProductSortField][]int{
+		internal.SortById:         {1, 2, 3, 4, 5, 6, 7},
+		internal.SortByName:       {2, 4, 7, 1, 5, 3, 6},
+		internal.SortByPrice:      {1, 5, 2, 6, 3, 7, 4},
+		internal.SortByQuantity:   {1, 4, 7, 2, 5, 3, 6},
+		internal.SortByExpiration: {2, 5, 6, 3, 7, 1, 4},
+	}
+
+	for name, newRepository := range pageRepositories {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepository(t)
+			savePageProducts(t, repo)
+
+			for field, ids := range expected {
+				for _, descending := range []bool{false, true} {
+					want := append([]int{}, ids...)
+					if descending {
+						for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+							want[i], want[j] = want[j], want[i]
+						}
+					}
+
+					// walk every page through cursors
+					query := internal.ProductPageQuery{SortBy: field, Descending: descending, Limit: 3}
+					got := []int{}
+					for {
+						page, err := repo.GetPage(context.Background(), query)
+						require.NoError(t, err)
+						require.Equal(t, 7, page.Total)
+						got = append(got, pageIds(page)...)
+						if page.NextCursor == "" {
+							break
+						}
+						query.Cursor = page.NextCursor
+					}
+					require.Equal(t, want, got, "sort %s, descending %v", field, descending)
+
+					// and through offsets
+					page, err := repo.GetPage(context.Background(), internal.ProductPageQuery{SortBy: field, Descending: descending, Limit: 3, Offset: 3})
+					require.NoError(t, err)
+					require.Equal(t, want[3:6], pageIds(page))
+					require.NotEmpty(t, page.NextCursor)
+				}
+			}
+
+			page, err := repo.GetPage(context.Background(), internal.ProductPageQuery{SortBy: internal.SortById, Limit: 3, Offset: 10})
+			require.NoError(t, err)
+			require.Equal(t, []internal.Product{}, page.Items)
+			require.Empty(t, page.NextCursor)
+		})
+	}
+}
+
+func TestProductRepositoryCursorSurvivesWrites(t *testing.T) {
+	for name, newRepository := range pageRepositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepository(t)
+			savePageProducts(t, repo)
+
+			query := internal.ProductPageQuery{SortBy: internal.SortByName, Limit: 3}
+			page, err := repo.GetPage(ctx, query)
+			require.NoError(t, err)
+			require.Equal(t, []int{2, 4, 7}, pageIds(page))
+
+			// products before the cursor neither shift nor repeat the next page
+			require.NoError(t, repo.Delete(ctx, 2))
+			_, err = repo.Save(ctx, internal.Product{Name: "aardvark", Quantity: 1, Code: "new", Price: usd(100)})
+			require.NoError(t, err)
+
+			query.Cursor = page.NextCursor
+			page, err = repo.GetPage(ctx, query)
+			require.NoError(t, err)
+			require.Equal(t, []int{1, 5, 3}, pageIds(page))
+			require.Equal(t, 7, page.Total)
+		})
+	}
+}
+
+func TestProductRepositoryNamesSortByteWise(t *testing.T) {
+	for name, newRepository := range pageRepositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newRepository(t)
+			for i, name := range []string{"apple", "Banana", "cherry", "Apple", "éclair"} {
+				_, err := repo.Save(ctx, internal.Product{Name: name, Quantity: 1, Code: fmt.Sprintf("c%d", i), Price: usd(100)})
+				require.NoError(t, err)
+			}
+
+			// upper case before lower case, whatever the backend
+			query := internal.ProductPageQuery{SortBy: internal.SortByName, Limit: 2}
+			page, err := repo.GetPage(ctx, query)
+			require.NoError(t, err)
+			require.Equal(t, []int{4, 2}, pageIds(page))
+
+			query.Cursor = page.NextCursor
+			page, err = repo.GetPage(ctx, query)
+			require.NoError(t, err)
+			require.Equal(t, []int{1, 3}, pageIds(page))
+		})
+	}
+}
+
+func TestProductDBPageCollation(t *testing.T) {
+	pdb, mock := newMockDB(t)
+
+	mock.ExpectQuery("SELECT COUNT(*) FROM products").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category, version FROM products WHERE (name COLLATE utf8mb4_bin, id) > (?, ?) ORDER BY name COLLATE utf8mb4_bin ASC, id ASC LIMIT ? OFFSET ?").
+		WithArgs("apple", 1, 3, 0).
+		WillReturnRows(sqlmock.NewRows(mockProductColumns))
+
+	query := internal.ProductPageQuery{SortBy: internal.SortByName, Limit: 2}
+	query.Cursor = internal.NewProductCursor(query, internal.Product{Id: 1, Name: "apple"})
+	_, err := pdb.GetPage(context.Background(), query)
+	require.NoError(t, err)
+}
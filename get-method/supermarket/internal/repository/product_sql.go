@@ -0,0 +1,419 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"supermarket/internal"
+	"time"
+)
+
+// Helpers shared by the SQL product repositories.
+
+// sqlConn is implemented by both *sql.DB and *sql.Tx, so repositories run
+// the same queries inside and outside a transaction.
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// withinSQLTransaction runs fn in a transaction of db, committing it when fn
+// succeeds and rolling it back otherwise.
+func withinSQLTransaction(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryProducts returns the products query selects, with their categories.
+func queryProducts(ctx context.Context, db sqlConn, query string, args ...any) ([]internal.Product, error) {
+	products, err := scanProducts(ctx, db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	// the categories are read once the rows are closed, a transaction can
+	// not run a query while reading another
+	if err := loadProductCategories(ctx, db, products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func scanProducts(ctx context.Context, db sqlConn, query string, args ...any) ([]internal.Product, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []internal.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func queryProduct(ctx context.Context, db sqlConn, query string, args ...any) (internal.Product, error) {
+	product, err := scanProduct(db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.Product{}, internal.NewProductNotFoundError()
+		}
+		return internal.Product{}, err
+	}
+
+	products := []internal.Product{product}
+	if err := loadProductCategories(ctx, db, products); err != nil {
+		return internal.Product{}, err
+	}
+	return products[0], nil
+}
+
+// Product category queries, the same in both dialects.
+const (
+	sqlGetProductCategories    = "SELECT product_id, category_id FROM product_categories WHERE product_id IN (%s) ORDER BY product_id, category_id"
+	sqlDeleteProductCategories = "DELETE FROM product_categories WHERE product_id = ?"
+	sqlInsertProductCategory   = "INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)"
+)
+
+// sqlCategoriesBatch bounds the products whose categories are read by one
+// query, keeping it under the placeholder limits of both engines.
+const sqlCategoriesBatch = 500
+
+// loadProductCategories sets the CategoryIds of products.
+func loadProductCategories(ctx context.Context, db sqlConn, products []internal.Product) error {
+	index := make(map[int]int, len(products))
+	for i, product := range products {
+		index[product.Id] = i
+	}
+
+	for start := 0; start < len(products); start += sqlCategoriesBatch {
+		batch := products[start:min(start+sqlCategoriesBatch, len(products))]
+		args := make([]any, len(batch))
+		for i, product := range batch {
+			args[i] = product.Id
+		}
+		statement := fmt.Sprintf(sqlGetProductCategories, "?"+strings.Repeat(", ?", len(batch)-1))
+
+		rows, err := db.QueryContext(ctx, statement, args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var productId, categoryId int
+			if err := rows.Scan(&productId, &categoryId); err != nil {
+				rows.Close()
+				return err
+			}
+			product := &products[index[productId]]
+			product.CategoryIds = append(product.CategoryIds, categoryId)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveProductCategories replaces the categories of product id with
+// categoryIds. Unknown categories fail with InvalidProductError.
+func saveProductCategories(ctx context.Context, db sqlConn, id int, categoryIds []int) error {
+	if _, err := db.ExecContext(ctx, sqlDeleteProductCategories, id); err != nil {
+		return err
+	}
+	for _, categoryId := range categoryIds {
+		if _, err := db.ExecContext(ctx, sqlInsertProductCategory, id, categoryId); err != nil {
+			if isSQLForeignKeyViolation(err) {
+				return internal.NewInvalidProductError("category_ids")
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Pagination queries, built from the sort field of each page.
+const (
+	sqlCountProducts  = "SELECT COUNT(*) FROM products"
+	sqlProductColumns = "id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category, version"
+)
+
+// sqlSortKeys maps each sort field to the expression products are sorted by.
+// Names are sorted with sqlBinaryCollations instead.
+var sqlSortKeys = map[internal.ProductSortField]string{
+	internal.SortById:         "id",
+	internal.SortByPrice:      "price_minor",
+	internal.SortByQuantity:   "quantity",
+	internal.SortByExpiration: "COALESCE(expiration, '" + noExpiration + "')",
+}
+
+// sqlBinaryCollations are the collations comparing names byte by byte in
+// each dialect, the order strings.Compare gives the memory and file
+// backends. MySQL ignores case by default, SQLite does not.
+var sqlBinaryCollations = map[string]string{
+	DialectSQLite: "BINARY",
+	DialectMySQL:  "utf8mb4_bin",
+}
+
+// sqlSortKey returns the expression products are sorted by for field in
+// dialect.
+func sqlSortKey(field internal.ProductSortField, dialect string) string {
+	if field == internal.SortByName {
+		return "name COLLATE " + sqlBinaryCollations[dialect]
+	}
+	return sqlSortKeys[field]
+}
+
+// sqlSortValue returns the value product has for the sort key of field.
+func sqlSortValue(product internal.Product, field internal.ProductSortField) any {
+	switch field {
+	case internal.SortByName:
+		return product.Name
+	case internal.SortByPrice:
+		return product.Price.Amount
+	case internal.SortByQuantity:
+		return product.Quantity
+	case internal.SortByExpiration:
+		return expirationSortKey(product.Expiration)
+	}
+	return product.Id
+}
+
+// queryProductPage returns the page query selects from the products table
+// of a dialect database. Pages after a cursor are found by comparing
+// (sort key, id) pairs, so they stay consistent while products are added or
+// removed.
+func queryProductPage(ctx context.Context, db sqlConn, dialect string, query internal.ProductPageQuery) (internal.ProductPage, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if query.Filter != nil {
+		condition, filterArgs, err := sqlFilter(query.Filter)
+		if err != nil {
+			return internal.ProductPage{}, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, sqlCountProducts+sqlWhere(conditions), args...).Scan(&total); err != nil {
+		return internal.ProductPage{}, err
+	}
+
+	key := sqlSortKey(query.SortBy, dialect)
+	order, compare := "ASC", ">"
+	if query.Descending {
+		order, compare = "DESC", "<"
+	}
+
+	if query.Cursor != "" {
+		after, err := query.CursorProduct()
+		if err != nil {
+			return internal.ProductPage{}, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (?, ?)", key, compare))
+		args = append(args, sqlSortValue(after, query.SortBy), after.Id)
+	}
+	where := sqlWhere(conditions)
+
+	// one extra product tells whether there is a next page
+	statement := fmt.Sprintf("SELECT %s FROM products%s ORDER BY %s %s, id %s LIMIT ? OFFSET ?", sqlProductColumns, where, key, order, order)
+	args = append(args, query.Limit+1, query.Offset)
+
+	products, err := queryProducts(ctx, db, statement, args...)
+	if err != nil {
+		return internal.ProductPage{}, err
+	}
+
+	page := internal.ProductPage{Items: products, Total: total}
+	if len(products) > query.Limit {
+		page.Items = products[:query.Limit]
+		page.NextCursor = internal.NewProductCursor(query, page.Items[query.Limit-1])
+	}
+	return page, nil
+}
+
+func sqlWhere(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// sqlComparisons maps filter comparison operators to SQL ones.
+var sqlComparisons = map[internal.FilterOp]string{
+	internal.OpEq:     "=",
+	internal.OpGt:     ">",
+	internal.OpGte:    ">=",
+	internal.OpLt:     "<",
+	internal.OpLte:    "<=",
+	internal.OpBefore: "<",
+	internal.OpAfter:  ">",
+}
+
+// sqlLikeEscape escapes the LIKE wildcards of patterns. It is not a
+// backslash, whose meaning inside string literals differs between MySQL
+// and SQLite.
+const sqlLikeEscape = "!"
+
+var sqlLikeEscaper = strings.NewReplacer(sqlLikeEscape, sqlLikeEscape+sqlLikeEscape, "%", sqlLikeEscape+"%", "_", sqlLikeEscape+"_")
+
+// sqlFilter translates filter into a SQL condition on the products table.
+// Every value is passed as a placeholder argument, the condition text only
+// holds column names and operators from fixed tables.
+func sqlFilter(filter internal.ProductFilter) (string, []any, error) {
+	// leaves are validated first, so their operators are known
+	comparison := func(leaf internal.ProductFilter, column string, op internal.FilterOp, value any) (string, []any, error) {
+		if err := leaf.Validate(); err != nil {
+			return "", nil, err
+		}
+		return column + " " + sqlComparisons[op] + " ?", []any{value}, nil
+	}
+
+	switch f := filter.(type) {
+	case internal.AndFilter:
+		return sqlFilters(f, " AND ", "1 = 1")
+	case internal.OrFilter:
+		return sqlFilters(f, " OR ", "1 = 0")
+	case internal.PriceFilter:
+		condition, args, err := comparison(f, "price_minor", f.Op, f.Value.Amount)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(currency = ? AND " + condition + ")", append([]any{f.Value.Currency}, args...), nil
+	case internal.QuantityFilter:
+		return comparison(f, "quantity", f.Op, f.Value)
+	case internal.PublishedFilter:
+		return "is_published = ?", []any{f.Value}, nil
+	case internal.ExpirationFilter:
+		return comparison(f, "expiration", f.Op, f.Date.Format(sqlDateLayout))
+	case internal.NameFilter:
+		pattern := sqlLikeEscaper.Replace(f.Value) + "%"
+		switch f.Op {
+		case internal.OpContains:
+			pattern = "%" + pattern
+		case internal.OpPrefix:
+		default:
+			return "", nil, f.Validate()
+		}
+		// LIKE ignores case in both SQLite and the MySQL default collation
+		return "name LIKE ? ESCAPE '" + sqlLikeEscape + "'", []any{pattern}, nil
+	case internal.CodeFilter:
+		if len(f.Codes) == 0 {
+			return "1 = 0", nil, nil
+		}
+		args := make([]any, len(f.Codes))
+		for i, code := range f.Codes {
+			args[i] = code
+		}
+		return "code_value IN (?" + strings.Repeat(", ?", len(f.Codes)-1) + ")", args, nil
+	case internal.CategoryFilter:
+		if len(f.Ids) == 0 {
+			return "1 = 0", nil, nil
+		}
+		args := make([]any, len(f.Ids))
+		for i, id := range f.Ids {
+			args[i] = id
+		}
+		return "id IN (SELECT product_id FROM product_categories WHERE category_id IN (?" + strings.Repeat(", ?", len(f.Ids)-1) + "))", args, nil
+	}
+	return "", nil, internal.NewInvalidFilterError(fmt.Sprintf("unsupported filter %T", filter))
+}
+
+func sqlFilters(filters []internal.ProductFilter, separator, empty string) (string, []any, error) {
+	if len(filters) == 0 {
+		return empty, nil, nil
+	}
+
+	var (
+		conditions []string
+		args       []any
+	)
+	for _, filter := range filters {
+		condition, filterArgs, err := sqlFilter(filter)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
+	return "(" + strings.Join(conditions, separator) + ")", args, nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProduct(row rowScanner) (internal.Product, error) {
+	var (
+		product    internal.Product
+		expiration sql.NullString
+	)
+	if err := row.Scan(&product.Id, &product.Name, &product.Quantity, &product.Code, &product.IsPublished, &expiration, &product.Price.Amount, &product.Price.Currency, &product.TaxCategory, &product.Version); err != nil {
+		return internal.Product{}, err
+	}
+
+	var err error
+	if product.Expiration, err = fromSQLDate(expiration); err != nil {
+		return internal.Product{}, err
+	}
+	return product, nil
+}
+
+// sqlDateLayout is how dates are stored, so they sort chronologically.
+const sqlDateLayout = "2006-01-02"
+
+// toSQLDate converts a product expiration into its stored form.
+func toSQLDate(expiration string) (sql.NullString, error) {
+	if expiration == "" {
+		return sql.NullString{}, nil
+	}
+
+	date, err := time.Parse("02/01/2006", expiration)
+	if err != nil {
+		return sql.NullString{}, internal.NewInvalidProductError("expiration")
+	}
+	return sql.NullString{String: date.Format(sqlDateLayout), Valid: true}, nil
+}
+
+// fromSQLDate converts a stored date back into a product expiration.
+func fromSQLDate(date sql.NullString) (string, error) {
+	if !date.Valid || date.String == "" {
+		return "", nil
+	}
+
+	// drivers may hand back a full timestamp for date columns
+	value := date.String
+	if len(value) > len(sqlDateLayout) {
+		value = value[:len(sqlDateLayout)]
+	}
+
+	parsed, err := time.Parse(sqlDateLayout, value)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Format("02/01/2006"), nil
+}
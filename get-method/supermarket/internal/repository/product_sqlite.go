@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"supermarket/internal"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// OpenSQLite opens, creating it if needed, the SQLite database file at path.
+func OpenSQLite(path string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func NewProductSQLite(db *sql.DB) *ProductSQLite {
+	return &ProductSQLite{db: db, conn: db}
+}
+
+// ProductSQLite is a ProductRepository backed by an embedded SQLite database.
+type ProductSQLite struct {
+	db *sql.DB
+	// conn runs the queries, it is db or, within a transaction, tx.
+	conn sqlConn
+	tx   *sql.Tx
+}
+
+// WithinTransaction runs fn in a database transaction. Calls made while
+// already in one join it.
+func (pdb *ProductSQLite) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repo internal.ProductRepository) error) error {
+	return pdb.transaction(ctx, func(tx *ProductSQLite) error {
+		return fn(ctx, tx)
+	})
+}
+
+func (pdb *ProductSQLite) transaction(ctx context.Context, fn func(tx *ProductSQLite) error) error {
+	if pdb.tx != nil {
+		return fn(pdb)
+	}
+	return withinSQLTransaction(ctx, pdb.db, func(tx *sql.Tx) error {
+		return fn(&ProductSQLite{db: pdb.db, conn: tx, tx: tx})
+	})
+}
+
+// Queries
+const (
+	SQLiteGetLastProductId   = "SELECT COALESCE(MAX(id), 0) FROM products"
+	SQLiteGetAllProducts     = "SELECT id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category, version FROM products ORDER BY id"
+	SQLiteGetProductById     = "SELECT id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category, version FROM products WHERE id = ?"
+	SQLiteGetProductByCode   = "SELECT id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category, version FROM products WHERE code_value = ?"
+	SQLiteGetProductsByPrice = "SELECT id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category, version FROM products WHERE currency = ? AND price_minor > ? ORDER BY id"
+	SQLiteCreateProduct      = "INSERT INTO products (name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	SQLiteUpsertProduct      = `INSERT INTO products (id, name, quantity, code_value, is_published, expiration, price_minor, currency, tax_category) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, quantity = excluded.quantity, code_value = excluded.code_value,
+		is_published = excluded.is_published, expiration = excluded.expiration, price_minor = excluded.price_minor, currency = excluded.currency, tax_category = excluded.tax_category, version = products.version + 1
+		RETURNING version`
+	SQLiteUpdateProduct = "UPDATE products SET name = ?, quantity = ?, code_value = ?, is_published = ?, expiration = ?, price_minor = ?, currency = ?, tax_category = ?, version = version + 1 WHERE id = ? RETURNING version"
+	SQLiteDeleteProduct = "DELETE FROM products WHERE id = ?"
+)
+
+// Start returns the last id in use, the schema is created by the sqlite
+// migrations.
+func (pdb *ProductSQLite) Start(ctx context.Context) (int, error) {
+	var lastId int
+	if err := pdb.conn.QueryRowContext(ctx, SQLiteGetLastProductId).Scan(&lastId); err != nil {
+		return 0, err
+	}
+	return lastId, nil
+}
+
+func (pdb *ProductSQLite) GetAll(ctx context.Context) (map[int]internal.Product, error) {
+	products, err := queryProducts(ctx, pdb.conn, SQLiteGetAllProducts)
+	if err != nil {
+		return nil, err
+	}
+
+	productsMap := make(map[int]internal.Product, len(products))
+	for _, product := range products {
+		productsMap[product.Id] = product
+	}
+	return productsMap, nil
+}
+
+func (pdb *ProductSQLite) GetPage(ctx context.Context, query internal.ProductPageQuery) (internal.ProductPage, error) {
+	return queryProductPage(ctx, pdb.conn, DialectSQLite, query)
+}
+
+func (pdb *ProductSQLite) GetById(ctx context.Context, id int) (internal.Product, error) {
+	return queryProduct(ctx, pdb.conn, SQLiteGetProductById, id)
+}
+
+func (pdb *ProductSQLite) Save(ctx context.Context, product internal.Product) (internal.Product, error) {
+	expiration, err := toSQLDate(product.Expiration)
+	if err != nil {
+		return internal.Product{}, err
+	}
+
+	// the product and its categories are stored together
+	err = pdb.transaction(ctx, func(tx *ProductSQLite) error {
+		result, err := tx.conn.ExecContext(ctx,
+			SQLiteCreateProduct,
+			product.Name,
+			product.Quantity,
+			product.Code,
+			product.IsPublished,
+			expiration,
+			product.Price.Amount,
+			product.Price.Currency,
+			product.TaxCategory,
+		)
+		if err != nil {
+			if isSQLiteUniqueViolation(err) {
+				return internal.NewProductAlreadyExistsError()
+			}
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		product.Id = int(id)
+		return saveProductCategories(ctx, tx.conn, product.Id, product.CategoryIds)
+	})
+	if err != nil {
+		return internal.Product{}, err
+	}
+
+	product.Version = 1
+	return product, nil
+}
+
+func (pdb *ProductSQLite) GetByGreaterPrice(ctx context.Context, price internal.Money) ([]internal.Product, error) {
+	return queryProducts(ctx, pdb.conn, SQLiteGetProductsByPrice, price.Currency, price.Amount)
+}
+
+func (pdb *ProductSQLite) GetByCode(ctx context.Context, code string) (*internal.Product, error) {
+	product, err := queryProduct(ctx, pdb.conn, SQLiteGetProductByCode, code)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (pdb *ProductSQLite) UpdateOrCreate(ctx context.Context, product internal.Product) (internal.Product, error) {
+	if product.Id == 0 {
+		created, err := pdb.Save(ctx, product)
+		if errors.As(err, &internal.ProductAlreadyExistsError{}) {
+			return internal.Product{}, internal.NewInvalidProductError("code is not unique")
+		}
+		return created, err
+	}
+
+	expiration, err := toSQLDate(product.Expiration)
+	if err != nil {
+		return internal.Product{}, err
+	}
+
+	err = pdb.transaction(ctx, func(tx *ProductSQLite) error {
+		if err := tx.conn.QueryRowContext(ctx,
+			SQLiteUpsertProduct,
+			product.Id,
+			product.Name,
+			product.Quantity,
+			product.Code,
+			product.IsPublished,
+			expiration,
+			product.Price.Amount,
+			product.Price.Currency,
+			product.TaxCategory,
+		).Scan(&product.Version); err != nil {
+			if isSQLiteUniqueViolation(err) {
+				return internal.NewInvalidProductError("code is not unique")
+			}
+			return err
+		}
+		return saveProductCategories(ctx, tx.conn, product.Id, product.CategoryIds)
+	})
+	if err != nil {
+		return internal.Product{}, err
+	}
+
+	return product, nil
+}
+
+func (pdb *ProductSQLite) PartialUpdate(ctx context.Context, id int, product internal.Product) (internal.Product, error) {
+	expiration, err := toSQLDate(product.Expiration)
+	if err != nil {
+		return internal.Product{}, err
+	}
+
+	err = pdb.transaction(ctx, func(tx *ProductSQLite) error {
+		if err := tx.conn.QueryRowContext(ctx,
+			SQLiteUpdateProduct,
+			product.Name,
+			product.Quantity,
+			product.Code,
+			product.IsPublished,
+			expiration,
+			product.Price.Amount,
+			product.Price.Currency,
+			product.TaxCategory,
+			id,
+		).Scan(&product.Version); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return internal.NewProductNotFoundError()
+			}
+			if isSQLiteUniqueViolation(err) {
+				return internal.NewInvalidProductError("code is not unique")
+			}
+			return err
+		}
+		return saveProductCategories(ctx, tx.conn, id, product.CategoryIds)
+	})
+	if err != nil {
+		return internal.Product{}, err
+	}
+
+	product.Id = id
+	return product, nil
+}
+
+func (pdb *ProductSQLite) Delete(ctx context.Context, id int) error {
+	result, err := pdb.conn.ExecContext(ctx, SQLiteDeleteProduct, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return internal.NewProductNotFoundError()
+	}
+
+	return nil
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteError *sqlite.Error
+	if errors.As(err, &sqliteError) {
+		switch sqliteError.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return true
+		}
+	}
+	return false
+}
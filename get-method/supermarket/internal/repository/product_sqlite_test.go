@@ -0,0 +1,242 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"supermarket/platform/database/migration"
+
+	"github.com/stretchr/testify/require"
+)
+
+func openMigratedSQLite(t *testing.T) *sql.DB {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "supermarket.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := repository.Migrations(repository.DialectSQLite)
+	require.NoError(t, err)
+	migrator, err := migration.NewMigrator(db, migrations)
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	return db
+}
+
+func newSQLiteDB(t *testing.T) *repository.ProductSQLite {
+	pdb := repository.NewProductSQLite(openMigratedSQLite(t))
+	lastId, err := pdb.Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, lastId)
+	return pdb
+}
+
+func TestProductSQLiteCRUD(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	p1, err := db.Save(context.Background(), internal.Product{Name: "p1", Quantity: 1, Code: "c1", Price: usd(150), IsPublished: true, Expiration: "01/02/2065"})
+	require.NoError(t, err)
+	require.Equal(t, 1, p1.Id)
+	require.Equal(t, 1, p1.Version)
+	p2, err := db.UpdateOrCreate(context.Background(), internal.Product{Name: "p2", Quantity: 2, Code: "c2", Price: usd(2000), Expiration: "31/12/2065"})
+	require.NoError(t, err)
+	require.Equal(t, 2, p2.Id)
+
+	got, err := db.GetById(context.Background(), p1.Id)
+	require.NoError(t, err)
+	require.Equal(t, p1, got)
+
+	byCode, err := db.GetByCode(context.Background(), "c2")
+	require.NoError(t, err)
+	require.Equal(t, p2, *byCode)
+
+	expensive, err := db.GetByGreaterPrice(context.Background(), usd(1000))
+	require.NoError(t, err)
+	require.Equal(t, []internal.Product{p2}, expensive)
+
+	p1.Name = "p1 patched"
+	p1.Quantity = 10
+	p1.Version = 2
+	patched, err := db.PartialUpdate(context.Background(), p1.Id, p1)
+	require.NoError(t, err)
+	require.Equal(t, p1, patched)
+
+	p2.Price = usd(2500)
+	p2.Version = 2
+	updated, err := db.UpdateOrCreate(context.Background(), p2)
+	require.NoError(t, err)
+	require.Equal(t, p2, updated)
+
+	all, err := db.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[int]internal.Product{p1.Id: p1, p2.Id: p2}, all)
+
+	require.NoError(t, db.Delete(context.Background(), p1.Id))
+	_, err = db.GetById(context.Background(), p1.Id)
+	require.ErrorAs(t, err, &internal.ProductNotFoundError{})
+	require.ErrorAs(t, db.Delete(context.Background(), p1.Id), &internal.ProductNotFoundError{})
+}
+
+func TestProductSQLiteUpdateOrCreateWithIdInserts(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	product := internal.Product{Id: 42, Name: "p42", Quantity: 1, Code: "c42", Price: usd(100)}
+	created, err := db.UpdateOrCreate(context.Background(), product)
+	require.NoError(t, err)
+	product.Version = 1
+	require.Equal(t, product, created)
+
+	got, err := db.GetById(context.Background(), 42)
+	require.NoError(t, err)
+	require.Equal(t, product, got)
+}
+
+func TestProductSQLiteEnforcesUniqueCode(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	p1, err := db.Save(context.Background(), internal.Product{Name: "p1", Quantity: 1, Code: "c1", Price: usd(100)})
+	require.NoError(t, err)
+	p2, err := db.Save(context.Background(), internal.Product{Name: "p2", Quantity: 1, Code: "c2", Price: usd(100)})
+	require.NoError(t, err)
+
+	_, err = db.Save(context.Background(), internal.Product{Name: "dup", Quantity: 1, Code: "c1", Price: usd(100)})
+	require.ErrorAs(t, err, &internal.ProductAlreadyExistsError{})
+
+	_, err = db.UpdateOrCreate(context.Background(), internal.Product{Name: "dup", Quantity: 1, Code: "c1", Price: usd(100)})
+	require.ErrorAs(t, err, &internal.InvalidProductError{})
+
+	p2.Code = p1.Code
+	_, err = db.PartialUpdate(context.Background(), p2.Id, p2)
+	require.ErrorAs(t, err, &internal.InvalidProductError{})
+}
+
+func TestProductSQLitePartialUpdateMissingProduct(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	_, err := db.PartialUpdate(context.Background(), 7, internal.Product{Name: "p", Quantity: 1, Code: "c", Price: usd(100)})
+	require.ErrorAs(t, err, &internal.ProductNotFoundError{})
+}
+
+func TestSQLiteMigrationsRoundTrip(t *testing.T) {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "supermarket.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrations, err := repository.Migrations(repository.DialectSQLite)
+	require.NoError(t, err)
+	migrator, err := migration.NewMigrator(db, migrations)
+	require.NoError(t, err)
+
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	for {
+		if _, err := migrator.Down(); err != nil {
+			require.ErrorAs(t, err, &migration.NoMigrationError{})
+			break
+		}
+	}
+	applied, err := migrator.Up()
+	require.NoError(t, err)
+	require.NotEmpty(t, applied)
+}
+
+func TestProductSQLiteAbortsWhenContextEnds(t *testing.T) {
+	db := openMigratedSQLite(t)
+	db.SetMaxOpenConns(1)
+	pdb := repository.NewProductSQLite(db)
+
+	// hold the only connection so the write has to wait for it
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = pdb.Save(ctx, internal.Product{Name: "p1", Quantity: 1, Code: "c1", Price: usd(100)})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+
+	_, err = pdb.GetById(ctx, 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, conn.Close())
+
+	products, err := pdb.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, products)
+}
+
+func TestProductSQLitePricesAreExact(t *testing.T) {
+	pdb := newSQLiteDB(t)
+	ctx := context.Background()
+
+	// beyond the range and precision a float or decimal(5,2) column keeps
+	prices := []internal.Money{
+		internal.NewMoney(123456789012, "USD"),
+		internal.NewMoney(1, "EUR"),
+		internal.NewMoney(1500, "JPY"),
+	}
+	for i, price := range prices {
+		saved, err := pdb.Save(ctx, internal.Product{Name: "p", Quantity: 1, Code: fmt.Sprintf("c%d", i), Price: price})
+		require.NoError(t, err)
+
+		found, err := pdb.GetById(ctx, saved.Id)
+		require.NoError(t, err)
+		require.Equal(t, price, found.Price)
+	}
+
+	// only prices of the same currency compare
+	expensive, err := pdb.GetByGreaterPrice(ctx, internal.NewMoney(0, "EUR"))
+	require.NoError(t, err)
+	require.Len(t, expensive, 1)
+	require.Equal(t, prices[1], expensive[0].Price)
+}
+
+func TestProductSQLiteMoneyMigration(t *testing.T) {
+	db := openMigratedSQLite(t)
+
+	migrations, err := repository.Migrations(repository.DialectSQLite)
+	require.NoError(t, err)
+	migrator, err := migration.NewMigrator(db, migrations)
+	require.NoError(t, err)
+
+	// a price stored before prices were kept in minor units
+	for _, name := range []string{"tax_jurisdictions", "promotions", "price_history", "locations", "suppliers", "orders", "stock", "categories", "products_tax_category", "products_money"} {
+		undone, err := migrator.Down()
+		require.NoError(t, err)
+		require.Equal(t, name, undone.Name)
+	}
+	_, err = db.Exec("INSERT INTO products (name, quantity, code_value, price) VALUES ('p', 1, 'c1', 71.42)")
+	require.NoError(t, err)
+
+	_, err = migrator.Up()
+	require.NoError(t, err)
+
+	product, err := repository.NewProductSQLite(db).GetByCode(context.Background(), "c1")
+	require.NoError(t, err)
+	require.Equal(t, usd(7142), product.Price)
+}
+
+func TestProductSQLiteTaxCategory(t *testing.T) {
+	pdb := newSQLiteDB(t)
+	ctx := context.Background()
+
+	saved, err := pdb.Save(ctx, internal.Product{Name: "p", Quantity: 1, Code: "c1", Price: usd(100), TaxCategory: "food"})
+	require.NoError(t, err)
+
+	saved.TaxCategory = "books"
+	_, err = pdb.UpdateOrCreate(ctx, saved)
+	require.NoError(t, err)
+
+	found, err := pdb.GetById(ctx, saved.Id)
+	require.NoError(t, err)
+	require.Equal(t, "books", found.TaxCategory)
+}
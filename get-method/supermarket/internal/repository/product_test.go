@@ -0,0 +1,197 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usd returns an amount of cents of the default currency.
+func usd(cents int64) internal.Money {
+	return internal.NewMoney(cents, internal.DefaultCurrency)
+}
+
+const (
+	stressWorkers    = 16
+	stressIterations = 200
+)
+
+func newStressDB() *repository.ProductMapDB {
+	dbData := map[int]internal.Product{}
+	for id := 1; id <= 10; id++ {
+		dbData[id] = internal.Product{Id: id, Name: fmt.Sprintf("p%d", id), Quantity: id, Code: fmt.Sprintf("c%d", id), Price: usd(int64(id) * 100)}
+	}
+	return &repository.ProductMapDB{Products: dbData, LastID: 10}
+}
+
+// TestProductMapDBConcurrentAccess hammers every internal.ProductRepository
+// method in parallel, it is meant to be run with -race.
+func TestProductMapDBConcurrentAccess(t *testing.T) {
+	var db internal.ProductRepository = newStressDB()
+
+	var wg sync.WaitGroup
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < stressIterations; i++ {
+				id := i%10 + 1
+				switch i % 8 {
+				case 0:
+					products, err := db.GetAll(context.Background())
+					assert.NoError(t, err)
+					// the snapshot must be safe to mutate by the caller
+					for k := range products {
+						delete(products, k)
+					}
+				case 1:
+					_, _ = db.GetById(context.Background(), id)
+				case 2:
+					_, err := db.Save(context.Background(), internal.Product{Name: "new", Quantity: 1, Code: fmt.Sprintf("s%d-%d", w, i), Price: usd(100)})
+					assert.NoError(t, err)
+				case 3:
+					_, err := db.GetByGreaterPrice(context.Background(), usd(500))
+					assert.NoError(t, err)
+				case 4:
+					_, _ = db.GetByCode(context.Background(), fmt.Sprintf("c%d", id))
+				case 5:
+					_, _ = db.UpdateOrCreate(context.Background(), internal.Product{Name: "upsert", Quantity: 1, Code: fmt.Sprintf("u%d-%d", w, i), Price: usd(100)})
+				case 6:
+					_, _ = db.PartialUpdate(context.Background(), id, internal.Product{Name: "patched", Quantity: 2, Code: fmt.Sprintf("c%d", id), Price: usd(200)})
+				case 7:
+					_ = db.Delete(context.Background(), id+10*(w%2))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	products, err := db.GetAll(context.Background())
+	require.NoError(t, err)
+	for id, product := range products {
+		require.Equal(t, id, product.Id)
+	}
+}
+
+func TestProductMapDBConcurrentSaveAllocatesUniqueIds(t *testing.T) {
+	db := newStressDB()
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ids = map[int]bool{}
+	)
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < stressIterations; i++ {
+				product, err := db.Save(context.Background(), internal.Product{Name: "p", Quantity: 1, Code: fmt.Sprintf("s%d-%d", w, i), Price: usd(100)})
+				assert.NoError(t, err)
+
+				mu.Lock()
+				assert.False(t, ids[product.Id], "id %d allocated twice", product.Id)
+				ids[product.Id] = true
+				mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	products, err := db.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, products, 10+stressWorkers*stressIterations)
+	require.EqualValues(t, 10+stressWorkers*stressIterations, db.LastID)
+}
+
+func TestProductMapDBConcurrentCreateKeepsCodesUnique(t *testing.T) {
+	db := newStressDB()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+	)
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.UpdateOrCreate(context.Background(), internal.Product{Name: "dup", Quantity: 1, Code: "shared", Price: usd(100)})
+			if err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, created)
+}
+
+func TestProductMapDBConcurrentDeleteAndUpdate(t *testing.T) {
+	db := newStressDB()
+
+	var wg sync.WaitGroup
+	for id := 1; id <= 10; id++ {
+		wg.Add(2)
+		go func(id int) {
+			defer wg.Done()
+			_ = db.Delete(context.Background(), id)
+		}(id)
+		go func(id int) {
+			defer wg.Done()
+			_, _ = db.PartialUpdate(context.Background(), id, internal.Product{Name: "patched", Quantity: 1, Code: fmt.Sprintf("c%d", id), Price: usd(100)})
+		}(id)
+	}
+	wg.Wait()
+
+	// a partial update racing a delete must never resurrect the product
+	products, err := db.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, products)
+}
+
+func TestProductMapDBGetAllReturnsSnapshot(t *testing.T) {
+	db := newStressDB()
+
+	snapshot, err := db.GetAll(context.Background())
+	require.NoError(t, err)
+
+	_, err = db.Save(context.Background(), internal.Product{Name: "later", Quantity: 1, Code: "later", Price: usd(100)})
+	require.NoError(t, err)
+	require.NoError(t, db.Delete(context.Background(), 1))
+
+	require.Len(t, snapshot, 10)
+	require.Contains(t, snapshot, 1)
+}
+
+func TestProductMapDBHonoursCanceledContext(t *testing.T) {
+	db := newStressDB()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := db.GetAll(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = db.GetById(ctx, 1)
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = db.Save(ctx, internal.Product{Name: "p", Quantity: 1, Code: "new", Price: usd(100)})
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = db.UpdateOrCreate(ctx, internal.Product{Id: 1, Name: "p", Quantity: 1, Code: "c1", Price: usd(100)})
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, db.Delete(ctx, 1), context.Canceled)
+
+	// nothing was written
+	products, err := db.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, products, 10)
+	require.Equal(t, "p1", products[1].Name)
+}
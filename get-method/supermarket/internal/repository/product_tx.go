@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"supermarket/internal"
+)
+
+// ProductTransactionError is returned when using a repository whose
+// transaction already finished.
+type ProductTransactionError struct{}
+
+func (e ProductTransactionError) Error() string {
+	return "transaction already finished"
+}
+
+// WithinTransaction runs fn against a view of the products that buffers its
+// writes. Other writers wait until fn returns; the buffered writes are then
+// committed as a single change, so readers and the journal see all or none.
+func (pdb *ProductMapDB) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repo internal.ProductRepository) error) error {
+	pdb.txMu.Lock()
+	defer pdb.txMu.Unlock()
+
+	tx := &mapTx{db: pdb, overlay: map[int]*internal.Product{}}
+	err := fn(ctx, tx)
+	tx.done = true
+	if err != nil {
+		return err
+	}
+	if len(tx.changes) == 0 {
+		return nil
+	}
+
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+
+	if len(tx.changes) == 1 {
+		return pdb.commit(ctx, tx.changes[0])
+	}
+	return pdb.commit(ctx, batchChange(tx.changes))
+}
+
+// mapTx is the ProductRepository given to a ProductMapDB transaction.
+type mapTx struct {
+	db      *ProductMapDB
+	changes []productChange
+	// overlay holds the products written by the transaction, nil for the
+	// deleted ones.
+	overlay map[int]*internal.Product
+	done    bool
+}
+
+func (tx *mapTx) check(ctx context.Context) error {
+	if tx.done {
+		return ProductTransactionError{}
+	}
+	return ctx.Err()
+}
+
+// products returns the committed products with the transaction writes on top.
+func (tx *mapTx) products() map[int]internal.Product {
+	tx.db.mu.RLock()
+	products := make(map[int]internal.Product, len(tx.db.Products)+len(tx.overlay))
+	for id, product := range tx.db.Products {
+		products[id] = product
+	}
+	tx.db.mu.RUnlock()
+
+	for id, product := range tx.overlay {
+		if product == nil {
+			delete(products, id)
+			continue
+		}
+		products[id] = *product
+	}
+	return products
+}
+
+func (tx *mapTx) get(id int) (internal.Product, bool) {
+	if product, ok := tx.overlay[id]; ok {
+		if product == nil {
+			return internal.Product{}, false
+		}
+		return *product, true
+	}
+
+	tx.db.mu.RLock()
+	defer tx.db.mu.RUnlock()
+
+	product, ok := tx.db.Products[id]
+	return product, ok
+}
+
+func (tx *mapTx) put(product internal.Product) {
+	tx.overlay[product.Id] = &product
+	tx.changes = append(tx.changes, putChange(product))
+}
+
+func (tx *mapTx) Start(ctx context.Context) (int, error) {
+	return 0, errors.New("can not start a repository within a transaction")
+}
+
+func (tx *mapTx) GetAll(ctx context.Context) (map[int]internal.Product, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	return tx.products(), nil
+}
+
+func (tx *mapTx) GetPage(ctx context.Context, query internal.ProductPageQuery) (internal.ProductPage, error) {
+	if err := tx.check(ctx); err != nil {
+		return internal.ProductPage{}, err
+	}
+
+	products := []internal.Product{}
+	for _, product := range tx.products() {
+		products = append(products, product)
+	}
+	return pageProducts(products, query)
+}
+
+func (tx *mapTx) GetById(ctx context.Context, id int) (internal.Product, error) {
+	if err := tx.check(ctx); err != nil {
+		return internal.Product{}, err
+	}
+
+	product, ok := tx.get(id)
+	if !ok {
+		return internal.Product{}, internal.NewProductNotFoundError()
+	}
+	return product, nil
+}
+
+func (tx *mapTx) Save(ctx context.Context, product internal.Product) (internal.Product, error) {
+	if err := tx.check(ctx); err != nil {
+		return internal.Product{}, err
+	}
+
+	product.Id = tx.db.nextID()
+	product.Version = 1
+	tx.put(product)
+	return product, nil
+}
+
+func (tx *mapTx) GetByGreaterPrice(ctx context.Context, price internal.Money) ([]internal.Product, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+
+	okProducts := []internal.Product{}
+	for _, product := range tx.products() {
+		if product.Price.Currency == price.Currency && product.Price.Amount > price.Amount {
+			okProducts = append(okProducts, product)
+		}
+	}
+	return okProducts, nil
+}
+
+func (tx *mapTx) GetByCode(ctx context.Context, code string) (*internal.Product, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, product := range tx.products() {
+		if product.Code == code {
+			return &product, nil
+		}
+	}
+	return nil, internal.NewProductNotFoundError()
+}
+
+func (tx *mapTx) UpdateOrCreate(ctx context.Context, product internal.Product) (internal.Product, error) {
+	if err := tx.check(ctx); err != nil {
+		return internal.Product{}, err
+	}
+
+	if product.Id == 0 {
+		for _, other := range tx.products() {
+			if other.Code == product.Code {
+				return internal.Product{}, internal.NewInvalidProductError("code is not unique")
+			}
+		}
+		product.Id = tx.db.nextID()
+	} else {
+		tx.db.observeID(product.Id)
+	}
+
+	current, _ := tx.get(product.Id)
+	product.Version = current.Version + 1
+	tx.put(product)
+	return product, nil
+}
+
+func (tx *mapTx) PartialUpdate(ctx context.Context, id int, product internal.Product) (internal.Product, error) {
+	if err := tx.check(ctx); err != nil {
+		return internal.Product{}, err
+	}
+
+	current, ok := tx.get(id)
+	if !ok {
+		return internal.Product{}, internal.NewProductNotFoundError()
+	}
+
+	product.Id = id
+	product.Version = current.Version + 1
+	tx.put(product)
+	return product, nil
+}
+
+func (tx *mapTx) Delete(ctx context.Context, id int) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+
+	if _, ok := tx.get(id); !ok {
+		return internal.NewProductNotFoundError()
+	}
+
+	tx.overlay[id] = nil
+	tx.changes = append(tx.changes, deleteChange(id))
+	return nil
+}
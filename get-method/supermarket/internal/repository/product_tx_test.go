@@ -0,0 +1,125 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errRollback = errors.New("rollback")
+
+// transfer moves one unit of quantity from product `from` to product `to`
+// and then fails with fail, when set.
+func transfer(from, to int, fail error) func(ctx context.Context, repo internal.ProductRepository) error {
+	return func(ctx context.Context, repo internal.ProductRepository) error {
+		source, err := repo.GetById(ctx, from)
+		if err != nil {
+			return err
+		}
+		target, err := repo.GetById(ctx, to)
+		if err != nil {
+			return err
+		}
+
+		source.Quantity--
+		target.Quantity++
+		if _, err := repo.PartialUpdate(ctx, from, source); err != nil {
+			return err
+		}
+		if _, err := repo.PartialUpdate(ctx, to, target); err != nil {
+			return err
+		}
+		return fail
+	}
+}
+
+func testUnitOfWork(t *testing.T, repo internal.ProductRepository, uow internal.ProductUnitOfWork) {
+	ctx := context.Background()
+	p1, err := repo.Save(ctx, internal.Product{Name: "p1", Quantity: 10, Code: "c1", Price: usd(100)})
+	require.NoError(t, err)
+	p2, err := repo.Save(ctx, internal.Product{Name: "p2", Quantity: 10, Code: "c2", Price: usd(200)})
+	require.NoError(t, err)
+
+	// a failing unit of work leaves no trace
+	require.ErrorIs(t, uow.WithinTransaction(ctx, transfer(p1.Id, p2.Id, errRollback)), errRollback)
+	require.ErrorIs(t, uow.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+		if _, err := repo.Save(ctx, internal.Product{Name: "p3", Quantity: 1, Code: "c3", Price: usd(300)}); err != nil {
+			return err
+		}
+		if err := repo.Delete(ctx, p1.Id); err != nil {
+			return err
+		}
+		return errRollback
+	}), errRollback)
+
+	products, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[int]internal.Product{p1.Id: p1, p2.Id: p2}, products)
+
+	// a successful one applies every write, and sees its own writes
+	require.NoError(t, uow.WithinTransaction(ctx, transfer(p1.Id, p2.Id, nil)))
+	require.NoError(t, uow.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+		if err := repo.Delete(ctx, p1.Id); err != nil {
+			return err
+		}
+		if _, err := repo.GetById(ctx, p1.Id); !errors.As(err, &internal.ProductNotFoundError{}) {
+			return errors.New("deleted product still visible")
+		}
+		p3, err := repo.Save(ctx, internal.Product{Name: "p3", Quantity: 1, Code: "c1", Price: usd(300)})
+		if err != nil {
+			return err
+		}
+		found, err := repo.GetByCode(ctx, "c1")
+		if err != nil {
+			return err
+		}
+		if found.Id != p3.Id {
+			return errors.New("saved product not visible")
+		}
+		return nil
+	}))
+
+	products, err = repo.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	require.NotContains(t, products, p1.Id)
+	require.Equal(t, 11, products[p2.Id].Quantity)
+}
+
+func TestProductMapDBWithinTransaction(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{}}
+	testUnitOfWork(t, db, db)
+}
+
+func TestProductFileDBWithinTransaction(t *testing.T) {
+	paths := newFileDBPaths(t)
+	db := openFileDB(t, paths, 0)
+	testUnitOfWork(t, db, db)
+
+	// committed transactions are replayed as a whole
+	requireSameProducts(t, db, openFileDB(t, paths, 0))
+}
+
+func TestProductFileDBTransactionIsNotLoggedWhenRolledBack(t *testing.T) {
+	paths := newFileDBPaths(t)
+	db := openFileDB(t, paths, 0)
+	saveProducts(t, db, 1, 2)
+
+	require.ErrorIs(t, db.WithinTransaction(context.Background(), transfer(1, 2, errRollback)), errRollback)
+	require.NoError(t, db.Close())
+
+	reopened := openFileDB(t, paths, 0)
+	product, err := reopened.GetById(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, product.Quantity)
+}
+
+func TestProductSQLiteWithinTransaction(t *testing.T) {
+	db := newSQLiteDB(t)
+	testUnitOfWork(t, db, db)
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"supermarket/internal"
+	"sync"
+)
+
+// PromotionMapDB is an in-memory promotion repository safe for concurrent
+// use. When it has a file, every write is logged to it before being
+// acknowledged.
+type PromotionMapDB struct {
+	mu         sync.RWMutex
+	promotions *table[internal.Promotion]
+}
+
+func NewPromotionMapDB() *PromotionMapDB {
+	return &PromotionMapDB{promotions: newTable[internal.Promotion]()}
+}
+
+// NewPromotionFileDB keeps the promotions in the file at path, loading the
+// ones already saved there.
+func NewPromotionFileDB(path string) (*PromotionMapDB, error) {
+	promotions, err := openTable[internal.Promotion](path)
+	if err != nil {
+		return nil, err
+	}
+	return &PromotionMapDB{promotions: promotions}, nil
+}
+
+// GetAll returns the promotions sorted by id.
+func (pdb *PromotionMapDB) GetAll(ctx context.Context) ([]internal.Promotion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pdb.mu.RLock()
+	defer pdb.mu.RUnlock()
+
+	promotions := make([]internal.Promotion, 0, len(pdb.promotions.rows))
+	for _, promotion := range pdb.promotions.rows {
+		promotions = append(promotions, clonePromotion(promotion))
+	}
+	slices.SortFunc(promotions, func(a, b internal.Promotion) int { return a.Id - b.Id })
+	return promotions, nil
+}
+
+func (pdb *PromotionMapDB) GetById(ctx context.Context, id int) (internal.Promotion, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Promotion{}, err
+	}
+
+	pdb.mu.RLock()
+	defer pdb.mu.RUnlock()
+
+	promotion, ok := pdb.promotions.rows[id]
+	if !ok {
+		return internal.Promotion{}, internal.NewPromotionNotFoundError()
+	}
+	return clonePromotion(promotion), nil
+}
+
+func (pdb *PromotionMapDB) Save(ctx context.Context, promotion internal.Promotion) (internal.Promotion, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Promotion{}, err
+	}
+
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+
+	promotion.Id = pdb.promotions.nextId()
+	if err := pdb.promotions.write(putRow(promotion.Id, clonePromotion(promotion))); err != nil {
+		return internal.Promotion{}, err
+	}
+	return promotion, nil
+}
+
+func (pdb *PromotionMapDB) Update(ctx context.Context, promotion internal.Promotion) (internal.Promotion, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Promotion{}, err
+	}
+
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+
+	if _, ok := pdb.promotions.rows[promotion.Id]; !ok {
+		return internal.Promotion{}, internal.NewPromotionNotFoundError()
+	}
+	if err := pdb.promotions.write(putRow(promotion.Id, clonePromotion(promotion))); err != nil {
+		return internal.Promotion{}, err
+	}
+	return promotion, nil
+}
+
+func (pdb *PromotionMapDB) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+
+	if _, ok := pdb.promotions.rows[id]; !ok {
+		return internal.NewPromotionNotFoundError()
+	}
+	return pdb.promotions.write(deleteRow[internal.Promotion](id))
+}
+
+// clonePromotion copies the slices of promotion so callers can not alter
+// the stored one.
+func clonePromotion(promotion internal.Promotion) internal.Promotion {
+	promotion.ProductIds = slices.Clone(promotion.ProductIds)
+	return promotion
+}
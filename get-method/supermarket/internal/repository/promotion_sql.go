@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"supermarket/internal"
+)
+
+func NewPromotionSQL(db *sql.DB) *PromotionSQL {
+	return &PromotionSQL{db: db}
+}
+
+// PromotionSQL is a PromotionRepository backed by the SQLite or MySQL
+// database of the products.
+type PromotionSQL struct {
+	db *sql.DB
+}
+
+// Queries, the same in both dialects.
+const (
+	sqlGetAllPromotions = "SELECT id, name, kind, priority, stackable, starts_at, ends_at, product_ids, expiring_within_days, buy, pay, get_units, get_product_id, percent, amount_minor, amount_currency, threshold_minor, threshold_currency FROM promotions ORDER BY id"
+	sqlGetPromotionById = "SELECT id, name, kind, priority, stackable, starts_at, ends_at, product_ids, expiring_within_days, buy, pay, get_units, get_product_id, percent, amount_minor, amount_currency, threshold_minor, threshold_currency FROM promotions WHERE id = ?"
+	sqlCreatePromotion  = "INSERT INTO promotions (name, kind, priority, stackable, starts_at, ends_at, product_ids, expiring_within_days, buy, pay, get_units, get_product_id, percent, amount_minor, amount_currency, threshold_minor, threshold_currency) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	sqlUpdatePromotion  = "UPDATE promotions SET name = ?, kind = ?, priority = ?, stackable = ?, starts_at = ?, ends_at = ?, product_ids = ?, expiring_within_days = ?, buy = ?, pay = ?, get_units = ?, get_product_id = ?, percent = ?, amount_minor = ?, amount_currency = ?, threshold_minor = ?, threshold_currency = ? WHERE id = ?"
+	sqlDeletePromotion  = "DELETE FROM promotions WHERE id = ?"
+)
+
+func (pdb *PromotionSQL) GetAll(ctx context.Context) ([]internal.Promotion, error) {
+	rows, err := pdb.db.QueryContext(ctx, sqlGetAllPromotions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := []internal.Promotion{}
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+func (pdb *PromotionSQL) GetById(ctx context.Context, id int) (internal.Promotion, error) {
+	promotion, err := scanPromotion(pdb.db.QueryRowContext(ctx, sqlGetPromotionById, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.Promotion{}, internal.NewPromotionNotFoundError()
+		}
+		return internal.Promotion{}, err
+	}
+	return promotion, nil
+}
+
+func (pdb *PromotionSQL) Save(ctx context.Context, promotion internal.Promotion) (internal.Promotion, error) {
+	productIds, err := json.Marshal(promotion.ProductIds)
+	if err != nil {
+		return internal.Promotion{}, err
+	}
+
+	result, err := pdb.db.ExecContext(ctx, sqlCreatePromotion, promotionArgs(promotion, string(productIds))...)
+	if err != nil {
+		return internal.Promotion{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return internal.Promotion{}, err
+	}
+	promotion.Id = int(id)
+	return promotion, nil
+}
+
+func (pdb *PromotionSQL) Update(ctx context.Context, promotion internal.Promotion) (internal.Promotion, error) {
+	productIds, err := json.Marshal(promotion.ProductIds)
+	if err != nil {
+		return internal.Promotion{}, err
+	}
+
+	result, err := pdb.db.ExecContext(ctx, sqlUpdatePromotion, append(promotionArgs(promotion, string(productIds)), promotion.Id)...)
+	if err != nil {
+		return internal.Promotion{}, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return internal.Promotion{}, err
+	}
+	if rowsAffected == 0 {
+		return internal.Promotion{}, internal.NewPromotionNotFoundError()
+	}
+	return promotion, nil
+}
+
+func (pdb *PromotionSQL) Delete(ctx context.Context, id int) error {
+	result, err := pdb.db.ExecContext(ctx, sqlDeletePromotion, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return internal.NewPromotionNotFoundError()
+	}
+	return nil
+}
+
+// promotionArgs returns the stored columns of promotion, but its id, with
+// its products as productIds.
+func promotionArgs(promotion internal.Promotion, productIds string) []any {
+	return []any{
+		promotion.Name,
+		promotion.Kind,
+		promotion.Priority,
+		promotion.Stackable,
+		promotion.StartsAt,
+		promotion.EndsAt,
+		productIds,
+		promotion.ExpiringWithinDays,
+		promotion.Buy,
+		promotion.Pay,
+		promotion.Get,
+		promotion.GetProductId,
+		promotion.Percent,
+		promotion.Amount.Amount,
+		promotion.Amount.Currency,
+		promotion.Threshold.Amount,
+		promotion.Threshold.Currency,
+	}
+}
+
+func scanPromotion(row rowScanner) (internal.Promotion, error) {
+	var (
+		promotion  internal.Promotion
+		productIds string
+	)
+	if err := row.Scan(
+		&promotion.Id,
+		&promotion.Name,
+		&promotion.Kind,
+		&promotion.Priority,
+		&promotion.Stackable,
+		&promotion.StartsAt,
+		&promotion.EndsAt,
+		&productIds,
+		&promotion.ExpiringWithinDays,
+		&promotion.Buy,
+		&promotion.Pay,
+		&promotion.Get,
+		&promotion.GetProductId,
+		&promotion.Percent,
+		&promotion.Amount.Amount,
+		&promotion.Amount.Currency,
+		&promotion.Threshold.Amount,
+		&promotion.Threshold.Currency,
+	); err != nil {
+		return internal.Promotion{}, err
+	}
+
+	if err := json.Unmarshal([]byte(productIds), &promotion.ProductIds); err != nil {
+		return internal.Promotion{}, err
+	}
+	return promotion, nil
+}
@@ -0,0 +1,71 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testPromotionRepository runs the checks every promotion repository
+// passes, on an empty one.
+func testPromotionRepository(t *testing.T, pdb internal.PromotionRepository) {
+	ctx := context.Background()
+
+	multiBuy, err := pdb.Save(ctx, internal.Promotion{Name: "3 for 2", Kind: internal.PromotionMultiBuy, Priority: 2, ProductIds: []int{1, 2}, Buy: 3, Pay: 2, StartsAt: "01/03/2024", EndsAt: "31/03/2024"})
+	require.NoError(t, err)
+	basket, err := pdb.Save(ctx, internal.Promotion{Name: "5 off 50", Kind: internal.PromotionBasketThreshold, Stackable: true, Amount: usd(500), Threshold: usd(5000)})
+	require.NoError(t, err)
+	require.Greater(t, basket.Id, multiBuy.Id)
+
+	found, err := pdb.GetById(ctx, multiBuy.Id)
+	require.NoError(t, err)
+	require.Equal(t, multiBuy, found)
+
+	basket.Percent, basket.Amount = 12.5, internal.Money{}
+	_, err = pdb.Update(ctx, basket)
+	require.NoError(t, err)
+	promotions, err := pdb.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Promotion{multiBuy, basket}, promotions)
+
+	require.NoError(t, pdb.Delete(ctx, multiBuy.Id))
+	_, err = pdb.GetById(ctx, multiBuy.Id)
+	require.ErrorAs(t, err, &internal.PromotionNotFoundError{})
+	_, err = pdb.Update(ctx, internal.Promotion{Id: 99, Name: "Gone", Kind: internal.PromotionPercentOff, Percent: 10})
+	require.ErrorAs(t, err, &internal.PromotionNotFoundError{})
+	require.ErrorAs(t, pdb.Delete(ctx, 99), &internal.PromotionNotFoundError{})
+}
+
+func TestPromotionMapDB(t *testing.T) {
+	testPromotionRepository(t, repository.NewPromotionMapDB())
+}
+
+func TestPromotionSQLite(t *testing.T) {
+	testPromotionRepository(t, repository.NewPromotionSQL(openMigratedSQLite(t)))
+}
+
+func TestPromotionFileDBReloadsWhatWasSaved(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.json.promotions")
+	pdb, err := repository.NewPromotionFileDB(path)
+	require.NoError(t, err)
+	testPromotionRepository(t, pdb)
+
+	reopened, err := repository.NewPromotionFileDB(path)
+	require.NoError(t, err)
+	promotions, err := pdb.GetAll(ctx)
+	require.NoError(t, err)
+	reloaded, err := reopened.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, promotions, reloaded)
+
+	// ids keep growing after a restart
+	promotion, err := reopened.Save(ctx, internal.Promotion{Name: "10% off", Kind: internal.PromotionPercentOff, Percent: 10})
+	require.NoError(t, err)
+	require.Equal(t, promotions[len(promotions)-1].Id+1, promotion.Id)
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"supermarket/internal"
+	"sync"
+)
+
+// PurchaseOrderMapDB is an in-memory purchase order repository safe for
+// concurrent use. When it has a file, every write is logged to it before
+// being acknowledged.
+type PurchaseOrderMapDB struct {
+	mu     sync.RWMutex
+	orders *table[internal.PurchaseOrder]
+}
+
+func NewPurchaseOrderMapDB() *PurchaseOrderMapDB {
+	return &PurchaseOrderMapDB{orders: newTable[internal.PurchaseOrder]()}
+}
+
+// NewPurchaseOrderFileDB keeps the purchase orders in the file at path,
+// loading the ones already saved there.
+func NewPurchaseOrderFileDB(path string) (*PurchaseOrderMapDB, error) {
+	orders, err := openTable[internal.PurchaseOrder](path)
+	if err != nil {
+		return nil, err
+	}
+	return &PurchaseOrderMapDB{orders: orders}, nil
+}
+
+func (pdb *PurchaseOrderMapDB) GetAll(ctx context.Context, status internal.PurchaseOrderStatus) ([]internal.PurchaseOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pdb.mu.RLock()
+	defer pdb.mu.RUnlock()
+
+	orders := []internal.PurchaseOrder{}
+	for _, order := range pdb.orders.rows {
+		if status == "" || order.Status == status {
+			orders = append(orders, clonePurchaseOrder(order))
+		}
+	}
+	slices.SortFunc(orders, func(a, b internal.PurchaseOrder) int { return a.Id - b.Id })
+	return orders, nil
+}
+
+func (pdb *PurchaseOrderMapDB) GetById(ctx context.Context, id int) (internal.PurchaseOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+
+	pdb.mu.RLock()
+	defer pdb.mu.RUnlock()
+
+	order, ok := pdb.orders.rows[id]
+	if !ok {
+		return internal.PurchaseOrder{}, internal.NewPurchaseOrderNotFoundError()
+	}
+	return clonePurchaseOrder(order), nil
+}
+
+func (pdb *PurchaseOrderMapDB) Save(ctx context.Context, order internal.PurchaseOrder) (internal.PurchaseOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+
+	order.Id = pdb.orders.nextId()
+	if err := pdb.orders.write(putRow(order.Id, clonePurchaseOrder(order))); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	return order, nil
+}
+
+func (pdb *PurchaseOrderMapDB) Update(ctx context.Context, order internal.PurchaseOrder) (internal.PurchaseOrder, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+
+	pdb.mu.Lock()
+	defer pdb.mu.Unlock()
+
+	if _, ok := pdb.orders.rows[order.Id]; !ok {
+		return internal.PurchaseOrder{}, internal.NewPurchaseOrderNotFoundError()
+	}
+	if err := pdb.orders.write(putRow(order.Id, clonePurchaseOrder(order))); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	return order, nil
+}
+
+// clonePurchaseOrder copies the lines and the history of order, so callers
+// can not change the stored ones.
+func clonePurchaseOrder(order internal.PurchaseOrder) internal.PurchaseOrder {
+	order.Lines = slices.Clone(order.Lines)
+	order.History = slices.Clone(order.History)
+	if order.ExpectedAt != nil {
+		expectedAt := *order.ExpectedAt
+		order.ExpectedAt = &expectedAt
+	}
+	return order
+}
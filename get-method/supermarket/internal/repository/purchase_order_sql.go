@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"supermarket/internal"
+)
+
+func NewPurchaseOrderSQL(db *sql.DB) *PurchaseOrderSQL {
+	return &PurchaseOrderSQL{db: db}
+}
+
+// PurchaseOrderSQL is a PurchaseOrderRepository backed by the SQLite or
+// MySQL database of the products. The lines and the history of an order
+// are only ever read along with it, they are kept as JSON in its row.
+type PurchaseOrderSQL struct {
+	db *sql.DB
+}
+
+// Queries, the same in both dialects. An empty status selects every order.
+const (
+	sqlGetPurchaseOrders    = "SELECT id, supplier_id, status, order_lines, total_minor, currency, expected_at, history, created_at, updated_at FROM purchase_orders WHERE ? = '' OR status = ? ORDER BY id"
+	sqlGetPurchaseOrderById = "SELECT id, supplier_id, status, order_lines, total_minor, currency, expected_at, history, created_at, updated_at FROM purchase_orders WHERE id = ?"
+	sqlCreatePurchaseOrder  = "INSERT INTO purchase_orders (supplier_id, status, order_lines, total_minor, currency, expected_at, history, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	sqlUpdatePurchaseOrder  = "UPDATE purchase_orders SET supplier_id = ?, status = ?, order_lines = ?, total_minor = ?, currency = ?, expected_at = ?, history = ?, updated_at = ? WHERE id = ?"
+)
+
+func (pdb *PurchaseOrderSQL) GetAll(ctx context.Context, status internal.PurchaseOrderStatus) ([]internal.PurchaseOrder, error) {
+	rows, err := pdb.db.QueryContext(ctx, sqlGetPurchaseOrders, status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []internal.PurchaseOrder{}
+	for rows.Next() {
+		order, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (pdb *PurchaseOrderSQL) GetById(ctx context.Context, id int) (internal.PurchaseOrder, error) {
+	order, err := scanPurchaseOrder(pdb.db.QueryRowContext(ctx, sqlGetPurchaseOrderById, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.PurchaseOrder{}, internal.NewPurchaseOrderNotFoundError()
+		}
+		return internal.PurchaseOrder{}, err
+	}
+	return order, nil
+}
+
+func (pdb *PurchaseOrderSQL) Save(ctx context.Context, order internal.PurchaseOrder) (internal.PurchaseOrder, error) {
+	lines, history, err := marshalPurchaseOrder(order)
+	if err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+
+	result, err := pdb.db.ExecContext(ctx, sqlCreatePurchaseOrder, order.SupplierId, order.Status, lines, order.Total.Amount, order.Total.Currency, toSQLNullTime(order.ExpectedAt), history, toSQLTime(order.CreatedAt), toSQLTime(order.UpdatedAt))
+	if err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	order.Id = int(id)
+	return order, nil
+}
+
+func (pdb *PurchaseOrderSQL) Update(ctx context.Context, order internal.PurchaseOrder) (internal.PurchaseOrder, error) {
+	lines, history, err := marshalPurchaseOrder(order)
+	if err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+
+	result, err := pdb.db.ExecContext(ctx, sqlUpdatePurchaseOrder, order.SupplierId, order.Status, lines, order.Total.Amount, order.Total.Currency, toSQLNullTime(order.ExpectedAt), history, toSQLTime(order.UpdatedAt), order.Id)
+	if err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	if rowsAffected == 0 {
+		return internal.PurchaseOrder{}, internal.NewPurchaseOrderNotFoundError()
+	}
+	return order, nil
+}
+
+// marshalPurchaseOrder returns the stored form of the lines and the history
+// of order.
+func marshalPurchaseOrder(order internal.PurchaseOrder) (string, string, error) {
+	lines, err := json.Marshal(order.Lines)
+	if err != nil {
+		return "", "", err
+	}
+	history, err := json.Marshal(order.History)
+	if err != nil {
+		return "", "", err
+	}
+	return string(lines), string(history), nil
+}
+
+func scanPurchaseOrder(row rowScanner) (internal.PurchaseOrder, error) {
+	var (
+		order                internal.PurchaseOrder
+		lines, history       string
+		expectedAt           sql.NullString
+		createdAt, updatedAt string
+	)
+	if err := row.Scan(&order.Id, &order.SupplierId, &order.Status, &lines, &order.Total.Amount, &order.Total.Currency, &expectedAt, &history, &createdAt, &updatedAt); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+
+	if err := json.Unmarshal([]byte(lines), &order.Lines); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	if err := json.Unmarshal([]byte(history), &order.History); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	var err error
+	if order.ExpectedAt, err = fromSQLNullTime(expectedAt); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	if order.CreatedAt, err = fromSQLTime(createdAt); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	if order.UpdatedAt, err = fromSQLTime(updatedAt); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	return order, nil
+}
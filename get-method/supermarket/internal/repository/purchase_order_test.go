@@ -0,0 +1,96 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newPurchaseOrder returns a draft of four units of product 1 from supplier
+// 1, created at at.
+func newPurchaseOrder(at time.Time) internal.PurchaseOrder {
+	return internal.PurchaseOrder{
+		SupplierId: 1,
+		Status:     internal.PurchaseOrderDraft,
+		Lines:      []internal.PurchaseOrderLine{{ProductId: 1, Quantity: 4, UnitCost: usd(50), Total: usd(200)}},
+		Total:      usd(200),
+		History:    []internal.PurchaseOrderEvent{{Status: internal.PurchaseOrderDraft, At: at}},
+		CreatedAt:  at,
+		UpdatedAt:  at,
+	}
+}
+
+// testPurchaseOrderRepository runs the checks every purchase order
+// repository passes, on an empty one.
+func testPurchaseOrderRepository(t *testing.T, pdb internal.PurchaseOrderRepository) {
+	ctx := context.Background()
+	at := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+
+	first, err := pdb.Save(ctx, newPurchaseOrder(at))
+	require.NoError(t, err)
+	second, err := pdb.Save(ctx, newPurchaseOrder(at.Add(time.Minute)))
+	require.NoError(t, err)
+	require.Greater(t, second.Id, first.Id)
+
+	found, err := pdb.GetById(ctx, first.Id)
+	require.NoError(t, err)
+	require.Equal(t, first, found)
+
+	sent, expected := at.Add(time.Hour), at.Add(48*time.Hour)
+	first.Status = internal.PurchaseOrderSent
+	first.ExpectedAt = &expected
+	first.History = append(first.History, internal.PurchaseOrderEvent{Status: internal.PurchaseOrderSent, At: sent})
+	first.UpdatedAt = sent
+	_, err = pdb.Update(ctx, first)
+	require.NoError(t, err)
+
+	orders, err := pdb.GetAll(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, []internal.PurchaseOrder{first, second}, orders)
+	orders, err = pdb.GetAll(ctx, internal.PurchaseOrderDraft)
+	require.NoError(t, err)
+	require.Equal(t, []internal.PurchaseOrder{second}, orders)
+	orders, err = pdb.GetAll(ctx, internal.PurchaseOrderReceived)
+	require.NoError(t, err)
+	require.Empty(t, orders)
+
+	_, err = pdb.GetById(ctx, 99)
+	require.ErrorAs(t, err, &internal.PurchaseOrderNotFoundError{})
+	_, err = pdb.Update(ctx, internal.PurchaseOrder{Id: 99, Status: internal.PurchaseOrderSent})
+	require.ErrorAs(t, err, &internal.PurchaseOrderNotFoundError{})
+}
+
+func TestPurchaseOrderMapDB(t *testing.T) {
+	testPurchaseOrderRepository(t, repository.NewPurchaseOrderMapDB())
+}
+
+func TestPurchaseOrderSQLite(t *testing.T) {
+	testPurchaseOrderRepository(t, repository.NewPurchaseOrderSQL(openMigratedSQLite(t)))
+}
+
+func TestPurchaseOrderFileDBReloadsWhatWasSaved(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.json.purchase-orders")
+	pdb, err := repository.NewPurchaseOrderFileDB(path)
+	require.NoError(t, err)
+	testPurchaseOrderRepository(t, pdb)
+
+	reopened, err := repository.NewPurchaseOrderFileDB(path)
+	require.NoError(t, err)
+	orders, err := pdb.GetAll(ctx, "")
+	require.NoError(t, err)
+	reloaded, err := reopened.GetAll(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, orders, reloaded)
+
+	// ids keep growing after a restart
+	order, err := reopened.Save(ctx, newPurchaseOrder(time.Date(2024, 3, 11, 9, 30, 0, 0, time.UTC)))
+	require.NoError(t, err)
+	require.Equal(t, orders[len(orders)-1].Id+1, order.Id)
+}
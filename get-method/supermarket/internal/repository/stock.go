@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"supermarket/internal"
+	"sync"
+	"time"
+)
+
+// StockMapDB is an in-memory stock ledger and reservation repository safe
+// for concurrent use. When it has a file, every write is logged to it before
+// being acknowledged.
+type StockMapDB struct {
+	mu     sync.RWMutex
+	ledger *table[internal.StockMovement]
+	// movements holds the ledger of each product, oldest first
+	movements map[int][]internal.StockMovement
+	onHand    map[int]int
+	// atLocation holds the quantity on hand of each product by location
+	atLocation map[int]map[int]int
+
+	reservations *table[internal.Reservation]
+}
+
+func NewStockMapDB() *StockMapDB {
+	return newStockMapDB(newTable[internal.StockMovement](), newTable[internal.Reservation]())
+}
+
+// NewStockFileDB keeps the ledger in the file at path and the reservations
+// in the one at path plus ".reservations", loading the ones already saved
+// there.
+func NewStockFileDB(path string) (*StockMapDB, error) {
+	ledger, err := openTable[internal.StockMovement](path)
+	if err != nil {
+		return nil, err
+	}
+	reservations, err := openTable[internal.Reservation](path + ".reservations")
+	if err != nil {
+		return nil, err
+	}
+	return newStockMapDB(ledger, reservations), nil
+}
+
+func newStockMapDB(ledger *table[internal.StockMovement], reservations *table[internal.Reservation]) *StockMapDB {
+	sdb := &StockMapDB{
+		ledger:       ledger,
+		movements:    map[int][]internal.StockMovement{},
+		onHand:       map[int]int{},
+		atLocation:   map[int]map[int]int{},
+		reservations: reservations,
+	}
+
+	movements := make([]internal.StockMovement, 0, len(ledger.rows))
+	for _, movement := range ledger.rows {
+		movements = append(movements, movement)
+	}
+	slices.SortFunc(movements, func(a, b internal.StockMovement) int { return a.Id - b.Id })
+	for _, movement := range movements {
+		sdb.apply(movement)
+	}
+	return sdb
+}
+
+func (sdb *StockMapDB) Movements(ctx context.Context, productId int) ([]internal.StockMovement, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sdb.mu.RLock()
+	defer sdb.mu.RUnlock()
+
+	movements := slices.Clone(sdb.movements[productId])
+	if movements == nil {
+		movements = []internal.StockMovement{}
+	}
+	return movements, nil
+}
+
+func (sdb *StockMapDB) OnHand(ctx context.Context, productId int) (int, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+
+	sdb.mu.RLock()
+	defer sdb.mu.RUnlock()
+
+	onHand, ok := sdb.onHand[productId]
+	return onHand, ok, nil
+}
+
+func (sdb *StockMapDB) OnHandByLocation(ctx context.Context, productId int) (map[int]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sdb.mu.RLock()
+	defer sdb.mu.RUnlock()
+
+	onHand := map[int]int{}
+	for locationId, quantity := range sdb.atLocation[productId] {
+		onHand[locationId] = quantity
+	}
+	return onHand, nil
+}
+
+func (sdb *StockMapDB) Stocked(ctx context.Context, locationId int) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	sdb.mu.RLock()
+	defer sdb.mu.RUnlock()
+
+	for _, onHand := range sdb.atLocation {
+		if onHand[locationId] != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (sdb *StockMapDB) Record(ctx context.Context, movement internal.StockMovement) (internal.StockMovement, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.StockMovement{}, err
+	}
+
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	movement.Id = sdb.ledger.nextId()
+	if err := sdb.record(movement); err != nil {
+		return internal.StockMovement{}, err
+	}
+	return movement, nil
+}
+
+func (sdb *StockMapDB) RecordTransfer(ctx context.Context, out, in internal.StockMovement) ([]internal.StockMovement, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	out.Id = sdb.ledger.nextId()
+	in.Id = out.Id + 1
+	out.TransferId = out.Id
+	in.TransferId = out.Id
+	if err := sdb.record(out, in); err != nil {
+		return nil, err
+	}
+	return []internal.StockMovement{out, in}, nil
+}
+
+// record logs movements, which have their ids, together and adds them to
+// the ledger. The caller must hold the write lock.
+func (sdb *StockMapDB) record(movements ...internal.StockMovement) error {
+	changes := make([]tableChange[internal.StockMovement], 0, len(movements))
+	for _, movement := range movements {
+		changes = append(changes, putRow(movement.Id, movement))
+	}
+	if err := sdb.ledger.write(changes...); err != nil {
+		return err
+	}
+	for _, movement := range movements {
+		sdb.apply(movement)
+	}
+	return nil
+}
+
+// apply adds movement to the ledger of its product. The caller must hold
+// the write lock.
+func (sdb *StockMapDB) apply(movement internal.StockMovement) {
+	sdb.movements[movement.ProductId] = append(sdb.movements[movement.ProductId], movement)
+	sdb.onHand[movement.ProductId] += movement.Quantity
+	if sdb.atLocation[movement.ProductId] == nil {
+		sdb.atLocation[movement.ProductId] = map[int]int{}
+	}
+	sdb.atLocation[movement.ProductId][movement.LocationId] += movement.Quantity
+}
+
+func (sdb *StockMapDB) ReservedByLocation(ctx context.Context, productId int, now time.Time) (map[int]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sdb.mu.RLock()
+	defer sdb.mu.RUnlock()
+
+	reserved := map[int]int{}
+	for _, reservation := range sdb.reservations.rows {
+		if !reservation.ActiveAt(now) {
+			continue
+		}
+		for _, item := range reservation.Items {
+			if item.ProductId == productId {
+				reserved[reservation.LocationId] += item.Quantity
+			}
+		}
+	}
+	return reserved, nil
+}
+
+func (sdb *StockMapDB) SaveReservation(ctx context.Context, reservation internal.Reservation) (internal.Reservation, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Reservation{}, err
+	}
+
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	reservation.Id = sdb.reservations.nextId()
+	reservation.Items = slices.Clone(reservation.Items)
+	if err := sdb.reservations.write(putRow(reservation.Id, reservation)); err != nil {
+		return internal.Reservation{}, err
+	}
+	return reservation, nil
+}
+
+func (sdb *StockMapDB) GetReservation(ctx context.Context, id int) (internal.Reservation, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Reservation{}, err
+	}
+
+	sdb.mu.RLock()
+	defer sdb.mu.RUnlock()
+
+	reservation, ok := sdb.reservations.rows[id]
+	if !ok {
+		return internal.Reservation{}, internal.NewReservationNotFoundError()
+	}
+	reservation.Items = slices.Clone(reservation.Items)
+	return reservation, nil
+}
+
+func (sdb *StockMapDB) DeleteReservation(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	if _, ok := sdb.reservations.rows[id]; !ok {
+		return internal.NewReservationNotFoundError()
+	}
+	return sdb.reservations.write(deleteRow[internal.Reservation](id))
+}
+
+func (sdb *StockMapDB) DeleteExpiredReservations(ctx context.Context, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	var expired []tableChange[internal.Reservation]
+	for id, reservation := range sdb.reservations.rows {
+		if !reservation.ActiveAt(now) {
+			expired = append(expired, deleteRow[internal.Reservation](id))
+		}
+	}
+	// nothing to save when none expired
+	if len(expired) == 0 {
+		return nil
+	}
+	return sdb.reservations.write(expired...)
+}
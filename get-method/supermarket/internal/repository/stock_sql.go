@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"supermarket/internal"
+	"time"
+)
+
+func NewStockSQL(db *sql.DB) *StockSQL {
+	return &StockSQL{db: db}
+}
+
+// StockSQL is a StockRepository backed by the SQLite or MySQL database of
+// the products. The stock of a product is the sum of its movements, the
+// items of a reservation go when it does.
+type StockSQL struct {
+	db *sql.DB
+}
+
+// Queries, the same in both dialects.
+const (
+	sqlGetMovements              = "SELECT id, product_id, location_id, kind, quantity, reason, reservation_id, transfer_id, created_at FROM stock_movements WHERE product_id = ? ORDER BY id"
+	sqlGetOnHand                 = "SELECT COALESCE(SUM(quantity), 0), COUNT(*) FROM stock_movements WHERE product_id = ?"
+	sqlGetOnHandByLocation       = "SELECT location_id, SUM(quantity) FROM stock_movements WHERE product_id = ? GROUP BY location_id"
+	sqlGetStockedProduct         = "SELECT product_id FROM stock_movements WHERE location_id = ? GROUP BY product_id HAVING SUM(quantity) <> 0 LIMIT 1"
+	sqlCreateMovement            = "INSERT INTO stock_movements (product_id, location_id, kind, quantity, reason, reservation_id, transfer_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	sqlSetTransferId             = "UPDATE stock_movements SET transfer_id = ? WHERE id = ?"
+	sqlGetReservedByLocation     = "SELECT r.location_id, SUM(i.quantity) FROM stock_reservation_items i JOIN stock_reservations r ON r.id = i.reservation_id WHERE i.product_id = ? AND r.expires_at > ? GROUP BY r.location_id"
+	sqlCreateReservation         = "INSERT INTO stock_reservations (location_id, created_at, expires_at) VALUES (?, ?, ?)"
+	sqlCreateReservationItem     = "INSERT INTO stock_reservation_items (reservation_id, position, product_id, quantity) VALUES (?, ?, ?, ?)"
+	sqlGetReservation            = "SELECT id, location_id, created_at, expires_at FROM stock_reservations WHERE id = ?"
+	sqlGetReservationItems       = "SELECT product_id, quantity FROM stock_reservation_items WHERE reservation_id = ? ORDER BY position"
+	sqlDeleteReservation         = "DELETE FROM stock_reservations WHERE id = ?"
+	sqlDeleteExpiredReservations = "DELETE FROM stock_reservations WHERE expires_at <= ?"
+)
+
+func (sdb *StockSQL) Movements(ctx context.Context, productId int) ([]internal.StockMovement, error) {
+	rows, err := sdb.db.QueryContext(ctx, sqlGetMovements, productId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []internal.StockMovement{}
+	for rows.Next() {
+		var (
+			movement  internal.StockMovement
+			createdAt string
+		)
+		if err := rows.Scan(&movement.Id, &movement.ProductId, &movement.LocationId, &movement.Kind, &movement.Quantity, &movement.Reason, &movement.ReservationId, &movement.TransferId, &createdAt); err != nil {
+			return nil, err
+		}
+		if movement.CreatedAt, err = fromSQLTime(createdAt); err != nil {
+			return nil, err
+		}
+		movements = append(movements, movement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+func (sdb *StockSQL) OnHand(ctx context.Context, productId int) (int, bool, error) {
+	var onHand, movements int
+	if err := sdb.db.QueryRowContext(ctx, sqlGetOnHand, productId).Scan(&onHand, &movements); err != nil {
+		return 0, false, err
+	}
+	return onHand, movements > 0, nil
+}
+
+func (sdb *StockSQL) OnHandByLocation(ctx context.Context, productId int) (map[int]int, error) {
+	return queryQuantities(ctx, sdb.db, sqlGetOnHandByLocation, productId)
+}
+
+func (sdb *StockSQL) Stocked(ctx context.Context, locationId int) (bool, error) {
+	var productId int
+	if err := sdb.db.QueryRowContext(ctx, sqlGetStockedProduct, locationId).Scan(&productId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (sdb *StockSQL) Record(ctx context.Context, movement internal.StockMovement) (internal.StockMovement, error) {
+	return recordMovement(ctx, sdb.db, movement)
+}
+
+func (sdb *StockSQL) RecordTransfer(ctx context.Context, out, in internal.StockMovement) ([]internal.StockMovement, error) {
+	var movements []internal.StockMovement
+	err := withinSQLTransaction(ctx, sdb.db, func(tx *sql.Tx) error {
+		// the transfer takes the id of the movement taking the units off
+		out.TransferId = 0
+		recorded, err := recordMovement(ctx, tx, out)
+		if err != nil {
+			return err
+		}
+		recorded.TransferId = recorded.Id
+		if _, err := tx.ExecContext(ctx, sqlSetTransferId, recorded.TransferId, recorded.Id); err != nil {
+			return err
+		}
+
+		in.TransferId = recorded.TransferId
+		received, err := recordMovement(ctx, tx, in)
+		if err != nil {
+			return err
+		}
+		movements = []internal.StockMovement{recorded, received}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+// recordMovement inserts movement into the ledger and returns it with its
+// id.
+func recordMovement(ctx context.Context, db sqlConn, movement internal.StockMovement) (internal.StockMovement, error) {
+	result, err := db.ExecContext(ctx, sqlCreateMovement, movement.ProductId, movement.LocationId, movement.Kind, movement.Quantity, movement.Reason, movement.ReservationId, movement.TransferId, toSQLTime(movement.CreatedAt))
+	if err != nil {
+		return internal.StockMovement{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return internal.StockMovement{}, err
+	}
+	movement.Id = int(id)
+	return movement, nil
+}
+
+func (sdb *StockSQL) ReservedByLocation(ctx context.Context, productId int, now time.Time) (map[int]int, error) {
+	return queryQuantities(ctx, sdb.db, sqlGetReservedByLocation, productId, toSQLTime(now))
+}
+
+// queryQuantities returns the quantities query selects by location.
+func queryQuantities(ctx context.Context, db sqlConn, query string, args ...any) (map[int]int, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quantities := map[int]int{}
+	for rows.Next() {
+		var locationId, quantity int
+		if err := rows.Scan(&locationId, &quantity); err != nil {
+			return nil, err
+		}
+		quantities[locationId] = quantity
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return quantities, nil
+}
+
+func (sdb *StockSQL) SaveReservation(ctx context.Context, reservation internal.Reservation) (internal.Reservation, error) {
+	err := withinSQLTransaction(ctx, sdb.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, sqlCreateReservation, reservation.LocationId, toSQLTime(reservation.CreatedAt), toSQLTime(reservation.ExpiresAt))
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		reservation.Id = int(id)
+
+		for i, item := range reservation.Items {
+			if _, err := tx.ExecContext(ctx, sqlCreateReservationItem, reservation.Id, i, item.ProductId, item.Quantity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return internal.Reservation{}, err
+	}
+	return reservation, nil
+}
+
+func (sdb *StockSQL) GetReservation(ctx context.Context, id int) (internal.Reservation, error) {
+	var (
+		reservation          internal.Reservation
+		createdAt, expiresAt string
+	)
+	if err := sdb.db.QueryRowContext(ctx, sqlGetReservation, id).Scan(&reservation.Id, &reservation.LocationId, &createdAt, &expiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.Reservation{}, internal.NewReservationNotFoundError()
+		}
+		return internal.Reservation{}, err
+	}
+
+	var err error
+	if reservation.CreatedAt, err = fromSQLTime(createdAt); err != nil {
+		return internal.Reservation{}, err
+	}
+	if reservation.ExpiresAt, err = fromSQLTime(expiresAt); err != nil {
+		return internal.Reservation{}, err
+	}
+
+	rows, err := sdb.db.QueryContext(ctx, sqlGetReservationItems, id)
+	if err != nil {
+		return internal.Reservation{}, err
+	}
+	defer rows.Close()
+
+	reservation.Items = []internal.ReservationItem{}
+	for rows.Next() {
+		var item internal.ReservationItem
+		if err := rows.Scan(&item.ProductId, &item.Quantity); err != nil {
+			return internal.Reservation{}, err
+		}
+		reservation.Items = append(reservation.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return internal.Reservation{}, err
+	}
+	return reservation, nil
+}
+
+func (sdb *StockSQL) DeleteReservation(ctx context.Context, id int) error {
+	result, err := sdb.db.ExecContext(ctx, sqlDeleteReservation, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return internal.NewReservationNotFoundError()
+	}
+	return nil
+}
+
+func (sdb *StockSQL) DeleteExpiredReservations(ctx context.Context, now time.Time) error {
+	_, err := sdb.db.ExecContext(ctx, sqlDeleteExpiredReservations, toSQLTime(now))
+	return err
+}
+
+// sqlTimeLayout is how times are stored, in UTC and to the microsecond, so
+// they sort chronologically as text too. It is the form MySQL hands back
+// DATETIME(6) columns in.
+const sqlTimeLayout = "2006-01-02 15:04:05.000000"
+
+// toSQLTime converts t into its stored form.
+func toSQLTime(t time.Time) string {
+	return t.UTC().Format(sqlTimeLayout)
+}
+
+// fromSQLTime converts a stored time back, in UTC.
+func fromSQLTime(value string) (time.Time, error) {
+	t, err := time.Parse(sqlTimeLayout, value)
+	if err != nil {
+		// MySQL connections parsing times hand them back as time.Time,
+		// which database/sql formats as RFC 3339
+		if t, rerr := time.Parse(time.RFC3339Nano, value); rerr == nil {
+			return t.UTC(), nil
+		}
+		return time.Time{}, err
+	}
+	return t, nil
+}
+
+// toSQLNullTime converts t into its stored form, NULL when it is nil.
+func toSQLNullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: toSQLTime(*t), Valid: true}
+}
+
+// fromSQLNullTime converts a stored time back, nil when it is NULL.
+func fromSQLNullTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	t, err := fromSQLTime(value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
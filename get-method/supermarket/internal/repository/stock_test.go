@@ -0,0 +1,145 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testStockRepository runs the checks every stock repository passes, on an
+// empty one.
+func testStockRepository(t *testing.T, sdb internal.StockRepository) {
+	ctx := context.Background()
+	at := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+
+	_, opened, err := sdb.OnHand(ctx, 1)
+	require.NoError(t, err)
+	require.False(t, opened)
+
+	received, err := sdb.Record(ctx, internal.StockMovement{ProductId: 1, LocationId: 1, Kind: internal.StockReceive, Quantity: 10, Reason: "delivery", CreatedAt: at})
+	require.NoError(t, err)
+	sold, err := sdb.Record(ctx, internal.StockMovement{ProductId: 1, LocationId: 1, Kind: internal.StockSell, Quantity: -3, ReservationId: 4, CreatedAt: at.Add(time.Minute)})
+	require.NoError(t, err)
+	require.Greater(t, sold.Id, received.Id)
+
+	transfer, err := sdb.RecordTransfer(ctx,
+		internal.StockMovement{ProductId: 1, LocationId: 1, Kind: internal.StockTransfer, Quantity: -7, CreatedAt: at},
+		internal.StockMovement{ProductId: 1, LocationId: 2, Kind: internal.StockTransfer, Quantity: 7, CreatedAt: at})
+	require.NoError(t, err)
+	require.Len(t, transfer, 2)
+	require.Equal(t, transfer[0].Id, transfer[0].TransferId)
+	require.Equal(t, transfer[0].Id, transfer[1].TransferId)
+	require.Greater(t, transfer[1].Id, transfer[0].Id)
+
+	movements, err := sdb.Movements(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []internal.StockMovement{received, sold, transfer[0], transfer[1]}, movements)
+	movements, err = sdb.Movements(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, []internal.StockMovement{}, movements)
+
+	onHand, opened, err := sdb.OnHand(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, opened)
+	require.Equal(t, 7, onHand)
+	byLocation, err := sdb.OnHandByLocation(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 0, 2: 7}, byLocation)
+
+	for locationId, stocked := range map[int]bool{1: false, 2: true, 3: false} {
+		got, err := sdb.Stocked(ctx, locationId)
+		require.NoError(t, err)
+		require.Equal(t, stocked, got, "location %d", locationId)
+	}
+
+	reservation, err := sdb.SaveReservation(ctx, internal.Reservation{
+		LocationId: 2,
+		Items:      []internal.ReservationItem{{ProductId: 3, Quantity: 1}, {ProductId: 1, Quantity: 2}},
+		CreatedAt:  at,
+		ExpiresAt:  at.Add(internal.DefaultReservationTTL),
+	})
+	require.NoError(t, err)
+	expired, err := sdb.SaveReservation(ctx, internal.Reservation{
+		LocationId: 1,
+		Items:      []internal.ReservationItem{{ProductId: 1, Quantity: 1}},
+		CreatedAt:  at.Add(-time.Hour),
+		ExpiresAt:  at,
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, reservation.Id, expired.Id)
+
+	found, err := sdb.GetReservation(ctx, reservation.Id)
+	require.NoError(t, err)
+	require.Equal(t, reservation, found)
+
+	// reservations stop holding stock once they expire
+	reserved, err := sdb.ReservedByLocation(ctx, 1, at)
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{2: 2}, reserved)
+	reserved, err = sdb.ReservedByLocation(ctx, 1, at.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 1, 2: 2}, reserved)
+
+	require.NoError(t, sdb.DeleteExpiredReservations(ctx, at))
+	_, err = sdb.GetReservation(ctx, expired.Id)
+	require.ErrorAs(t, err, &internal.ReservationNotFoundError{})
+
+	require.NoError(t, sdb.DeleteReservation(ctx, reservation.Id))
+	require.ErrorAs(t, sdb.DeleteReservation(ctx, reservation.Id), &internal.ReservationNotFoundError{})
+	reserved, err = sdb.ReservedByLocation(ctx, 1, at.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, reserved)
+}
+
+func TestStockMapDB(t *testing.T) {
+	testStockRepository(t, repository.NewStockMapDB())
+}
+
+func TestStockSQLite(t *testing.T) {
+	testStockRepository(t, repository.NewStockSQL(openMigratedSQLite(t)))
+}
+
+func TestStockFileDBReloadsWhatWasSaved(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.json.stock")
+	sdb, err := repository.NewStockFileDB(path)
+	require.NoError(t, err)
+	testStockRepository(t, sdb)
+
+	at := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	kept, err := sdb.SaveReservation(ctx, internal.Reservation{LocationId: 1, Items: []internal.ReservationItem{{ProductId: 1, Quantity: 1}}, CreatedAt: at, ExpiresAt: at.Add(time.Hour)})
+	require.NoError(t, err)
+	deleted, err := sdb.SaveReservation(ctx, internal.Reservation{LocationId: 1, Items: []internal.ReservationItem{{ProductId: 1, Quantity: 1}}, CreatedAt: at, ExpiresAt: at.Add(time.Hour)})
+	require.NoError(t, err)
+	require.NoError(t, sdb.DeleteReservation(ctx, deleted.Id))
+
+	reopened, err := repository.NewStockFileDB(path)
+	require.NoError(t, err)
+	for _, productId := range []int{1, 2} {
+		movements, err := sdb.Movements(ctx, productId)
+		require.NoError(t, err)
+		reloaded, err := reopened.Movements(ctx, productId)
+		require.NoError(t, err)
+		require.Equal(t, movements, reloaded)
+	}
+	byLocation, err := reopened.OnHandByLocation(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 0, 2: 7}, byLocation)
+	found, err := reopened.GetReservation(ctx, kept.Id)
+	require.NoError(t, err)
+	require.Equal(t, kept, found)
+
+	// ids keep growing after a restart, even those of deleted reservations
+	movement, err := reopened.Record(ctx, internal.StockMovement{ProductId: 2, LocationId: 1, Kind: internal.StockReceive, Quantity: 1, CreatedAt: at})
+	require.NoError(t, err)
+	require.Equal(t, 5, movement.Id)
+	reservation, err := reopened.SaveReservation(ctx, internal.Reservation{LocationId: 1, Items: []internal.ReservationItem{{ProductId: 2, Quantity: 1}}, CreatedAt: at, ExpiresAt: at.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, deleted.Id+1, reservation.Id)
+}
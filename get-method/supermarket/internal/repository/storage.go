@@ -2,59 +2,136 @@ package repository
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 	"supermarket/internal"
 )
 
-type Storage struct{}
+// Storage keeps products as a JSON array in a single file.
+// Saves are atomic: the new content is written to a temporary file in the
+// same directory, flushed to disk and renamed over the previous file, so a
+// crash mid-write leaves either the old or the new content, never a mix.
+type Storage struct {
+	path string
+}
+
+func NewStorage(path string) *Storage {
+	return &Storage{path: path}
+}
 
-func (s *Storage) GetAll() (map[int]internal.Product, int, error) {
+func (s *Storage) GetAll() (map[int]internal.Product, error) {
 	var products map[int]internal.Product = map[int]internal.Product{}
 
-	file, err := os.Open(os.Getenv("DB_PATH"))
+	file, err := os.Open(s.path)
 	if err != nil {
-		return map[int]internal.Product{}, 0, err
+		// a store that was never saved is empty
+		if errors.Is(err, fs.ErrNotExist) {
+			return products, nil
+		}
+		return map[int]internal.Product{}, err
 	}
+	defer file.Close()
 
 	jsonData, err := io.ReadAll(file)
 	if err != nil {
-		return map[int]internal.Product{}, 0, err
+		return map[int]internal.Product{}, err
 	}
 
 	var readProducts []internal.Product
 	if err := json.Unmarshal(jsonData, &readProducts); err != nil {
-		return map[int]internal.Product{}, 0, err
+		return map[int]internal.Product{}, err
 	}
 
-	var lastId int = 0
 	for _, product := range readProducts {
 		products[product.Id] = product
-		lastId = max(lastId, product.Id)
 	}
 
-	return map[int]internal.Product{}, lastId, nil
+	return products, nil
 }
 
 func (s *Storage) Save(products map[int]internal.Product) error {
-	file, err := os.Create(os.Getenv("DB_PATH"))
-	if err != nil {
-		return err
-	}
-
 	var productsArray []internal.Product = make([]internal.Product, 0, len(products))
 	for _, product := range products {
 		productsArray = append(productsArray, product)
 	}
+	sort.Slice(productsArray, func(i, j int) bool {
+		return productsArray[i].Id < productsArray[j].Id
+	})
 
 	jsonData, err := json.Marshal(productsArray)
 	if err != nil {
 		return err
 	}
 
-	if _, err := file.Write(jsonData); err != nil {
+	return writeFileAtomic(s.path, jsonData)
+}
+
+// readJSONFile decodes the JSON file at path into v. It reports false,
+// leaving v alone, when there is no such file: a store that was never saved
+// is empty.
+func readJSONFile(path string, v any) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// writeJSONFile replaces the file at path with v encoded as JSON, as
+// writeFileAtomic does.
+func writeJSONFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces the file at path with data using a temporary
+// file, fsync and rename.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// removing fails once the rename succeeded, which is fine
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir flushes the directory entry so a completed rename survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
 		return err
 	}
+	defer d.Close()
 
-	return nil
+	return d.Sync()
 }
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"supermarket/internal"
+	"sync"
+)
+
+// SupplierMapDB is an in-memory supplier repository safe for concurrent
+// use. When it has a file, every write is logged to it before being
+// acknowledged.
+type SupplierMapDB struct {
+	mu        sync.RWMutex
+	suppliers *table[supplierRow]
+}
+
+// supplierRow is a supplier with its product links by product id, kept
+// together so a supplier goes away with its links.
+type supplierRow struct {
+	Supplier internal.Supplier                `json:"supplier"`
+	Products map[int]internal.SupplierProduct `json:"products"`
+}
+
+func NewSupplierMapDB() *SupplierMapDB {
+	return &SupplierMapDB{suppliers: newTable[supplierRow]()}
+}
+
+// NewSupplierFileDB keeps the suppliers and their product links in the file
+// at path, loading the ones already saved there.
+func NewSupplierFileDB(path string) (*SupplierMapDB, error) {
+	suppliers, err := openTable[supplierRow](path)
+	if err != nil {
+		return nil, err
+	}
+	return &SupplierMapDB{suppliers: suppliers}, nil
+}
+
+func (sdb *SupplierMapDB) GetAll(ctx context.Context) ([]internal.Supplier, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sdb.mu.RLock()
+	defer sdb.mu.RUnlock()
+
+	suppliers := make([]internal.Supplier, 0, len(sdb.suppliers.rows))
+	for _, row := range sdb.suppliers.rows {
+		suppliers = append(suppliers, row.Supplier)
+	}
+	slices.SortFunc(suppliers, func(a, b internal.Supplier) int { return a.Id - b.Id })
+	return suppliers, nil
+}
+
+func (sdb *SupplierMapDB) GetById(ctx context.Context, id int) (internal.Supplier, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Supplier{}, err
+	}
+
+	sdb.mu.RLock()
+	defer sdb.mu.RUnlock()
+
+	row, ok := sdb.suppliers.rows[id]
+	if !ok {
+		return internal.Supplier{}, internal.NewSupplierNotFoundError()
+	}
+	return row.Supplier, nil
+}
+
+func (sdb *SupplierMapDB) Save(ctx context.Context, supplier internal.Supplier) (internal.Supplier, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Supplier{}, err
+	}
+
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	supplier.Id = sdb.suppliers.nextId()
+	row := supplierRow{Supplier: supplier, Products: map[int]internal.SupplierProduct{}}
+	if err := sdb.suppliers.write(putRow(supplier.Id, row)); err != nil {
+		return internal.Supplier{}, err
+	}
+	return supplier, nil
+}
+
+func (sdb *SupplierMapDB) Update(ctx context.Context, supplier internal.Supplier) (internal.Supplier, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.Supplier{}, err
+	}
+
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	row, ok := sdb.suppliers.rows[supplier.Id]
+	if !ok {
+		return internal.Supplier{}, internal.NewSupplierNotFoundError()
+	}
+	row.Supplier = supplier
+	if err := sdb.suppliers.write(putRow(supplier.Id, row)); err != nil {
+		return internal.Supplier{}, err
+	}
+	return supplier, nil
+}
+
+func (sdb *SupplierMapDB) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	if _, ok := sdb.suppliers.rows[id]; !ok {
+		return internal.NewSupplierNotFoundError()
+	}
+	return sdb.suppliers.write(deleteRow[supplierRow](id))
+}
+
+func (sdb *SupplierMapDB) Products(ctx context.Context, supplierId int) ([]internal.SupplierProduct, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sdb.mu.RLock()
+	defer sdb.mu.RUnlock()
+
+	row, ok := sdb.suppliers.rows[supplierId]
+	if !ok {
+		return nil, internal.NewSupplierNotFoundError()
+	}
+	links := make([]internal.SupplierProduct, 0, len(row.Products))
+	for _, link := range row.Products {
+		links = append(links, link)
+	}
+	slices.SortFunc(links, func(a, b internal.SupplierProduct) int { return a.ProductId - b.ProductId })
+	return links, nil
+}
+
+func (sdb *SupplierMapDB) SaveProduct(ctx context.Context, link internal.SupplierProduct) (internal.SupplierProduct, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.SupplierProduct{}, err
+	}
+
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	row, ok := sdb.suppliers.rows[link.SupplierId]
+	if !ok {
+		return internal.SupplierProduct{}, internal.NewSupplierNotFoundError()
+	}
+	row.Products = cloneSupplierProducts(row.Products)
+	row.Products[link.ProductId] = link
+	if err := sdb.suppliers.write(putRow(link.SupplierId, row)); err != nil {
+		return internal.SupplierProduct{}, err
+	}
+	return link, nil
+}
+
+func (sdb *SupplierMapDB) DeleteProduct(ctx context.Context, supplierId int, productId int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sdb.mu.Lock()
+	defer sdb.mu.Unlock()
+
+	row, ok := sdb.suppliers.rows[supplierId]
+	if !ok {
+		return internal.NewSupplierNotFoundError()
+	}
+	if _, ok := row.Products[productId]; !ok {
+		return internal.NewSupplierProductNotFoundError()
+	}
+	row.Products = cloneSupplierProducts(row.Products)
+	delete(row.Products, productId)
+	return sdb.suppliers.write(putRow(supplierId, row))
+}
+
+// cloneSupplierProducts returns a copy of the links of a supplier by
+// product id, so writes do not change the stored ones in place.
+func cloneSupplierProducts(links map[int]internal.SupplierProduct) map[int]internal.SupplierProduct {
+	copied := make(map[int]internal.SupplierProduct, len(links)+1)
+	for productId, link := range links {
+		copied[productId] = link
+	}
+	return copied
+}
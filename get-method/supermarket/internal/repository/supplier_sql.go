@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"supermarket/internal"
+)
+
+func NewSupplierSQL(db *sql.DB) *SupplierSQL {
+	return &SupplierSQL{db: db}
+}
+
+// SupplierSQL is a SupplierRepository backed by the SQLite or MySQL
+// database of the products. The product links of a supplier go when it
+// does.
+type SupplierSQL struct {
+	db *sql.DB
+}
+
+// Queries, the same in both dialects.
+const (
+	sqlGetAllSuppliers     = "SELECT id, name, email, phone, lead_time_days FROM suppliers ORDER BY id"
+	sqlGetSupplierById     = "SELECT id, name, email, phone, lead_time_days FROM suppliers WHERE id = ?"
+	sqlCreateSupplier      = "INSERT INTO suppliers (name, email, phone, lead_time_days) VALUES (?, ?, ?, ?)"
+	sqlUpdateSupplier      = "UPDATE suppliers SET name = ?, email = ?, phone = ?, lead_time_days = ? WHERE id = ?"
+	sqlDeleteSupplier      = "DELETE FROM suppliers WHERE id = ?"
+	sqlGetSupplierId       = "SELECT id FROM suppliers WHERE id = ?"
+	sqlGetSupplierProducts = "SELECT supplier_id, product_id, supplier_code, cost_minor, currency FROM supplier_products WHERE supplier_id = ? ORDER BY product_id"
+	sqlCreateSupplierLink  = "INSERT INTO supplier_products (supplier_id, product_id, supplier_code, cost_minor, currency) VALUES (?, ?, ?, ?, ?)"
+	sqlDeleteSupplierLink  = "DELETE FROM supplier_products WHERE supplier_id = ? AND product_id = ?"
+)
+
+func (sdb *SupplierSQL) GetAll(ctx context.Context) ([]internal.Supplier, error) {
+	rows, err := sdb.db.QueryContext(ctx, sqlGetAllSuppliers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := []internal.Supplier{}
+	for rows.Next() {
+		var supplier internal.Supplier
+		if err := rows.Scan(&supplier.Id, &supplier.Name, &supplier.Email, &supplier.Phone, &supplier.LeadTimeDays); err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, supplier)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return suppliers, nil
+}
+
+func (sdb *SupplierSQL) GetById(ctx context.Context, id int) (internal.Supplier, error) {
+	var supplier internal.Supplier
+	if err := sdb.db.QueryRowContext(ctx, sqlGetSupplierById, id).Scan(&supplier.Id, &supplier.Name, &supplier.Email, &supplier.Phone, &supplier.LeadTimeDays); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.Supplier{}, internal.NewSupplierNotFoundError()
+		}
+		return internal.Supplier{}, err
+	}
+	return supplier, nil
+}
+
+func (sdb *SupplierSQL) Save(ctx context.Context, supplier internal.Supplier) (internal.Supplier, error) {
+	result, err := sdb.db.ExecContext(ctx, sqlCreateSupplier, supplier.Name, supplier.Email, supplier.Phone, supplier.LeadTimeDays)
+	if err != nil {
+		return internal.Supplier{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return internal.Supplier{}, err
+	}
+	supplier.Id = int(id)
+	return supplier, nil
+}
+
+func (sdb *SupplierSQL) Update(ctx context.Context, supplier internal.Supplier) (internal.Supplier, error) {
+	result, err := sdb.db.ExecContext(ctx, sqlUpdateSupplier, supplier.Name, supplier.Email, supplier.Phone, supplier.LeadTimeDays, supplier.Id)
+	if err != nil {
+		return internal.Supplier{}, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return internal.Supplier{}, err
+	}
+	if rowsAffected == 0 {
+		return internal.Supplier{}, internal.NewSupplierNotFoundError()
+	}
+	return supplier, nil
+}
+
+func (sdb *SupplierSQL) Delete(ctx context.Context, id int) error {
+	result, err := sdb.db.ExecContext(ctx, sqlDeleteSupplier, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return internal.NewSupplierNotFoundError()
+	}
+	return nil
+}
+
+func (sdb *SupplierSQL) Products(ctx context.Context, supplierId int) ([]internal.SupplierProduct, error) {
+	if err := checkSupplier(ctx, sdb.db, supplierId); err != nil {
+		return nil, err
+	}
+
+	rows, err := sdb.db.QueryContext(ctx, sqlGetSupplierProducts, supplierId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []internal.SupplierProduct{}
+	for rows.Next() {
+		var link internal.SupplierProduct
+		if err := rows.Scan(&link.SupplierId, &link.ProductId, &link.SupplierCode, &link.Cost.Amount, &link.Cost.Currency); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (sdb *SupplierSQL) SaveProduct(ctx context.Context, link internal.SupplierProduct) (internal.SupplierProduct, error) {
+	err := withinSQLTransaction(ctx, sdb.db, func(tx *sql.Tx) error {
+		if err := checkSupplier(ctx, tx, link.SupplierId); err != nil {
+			return err
+		}
+		// replacing the link the same way in both dialects
+		if _, err := tx.ExecContext(ctx, sqlDeleteSupplierLink, link.SupplierId, link.ProductId); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, sqlCreateSupplierLink, link.SupplierId, link.ProductId, link.SupplierCode, link.Cost.Amount, link.Cost.Currency)
+		return err
+	})
+	if err != nil {
+		return internal.SupplierProduct{}, err
+	}
+	return link, nil
+}
+
+func (sdb *SupplierSQL) DeleteProduct(ctx context.Context, supplierId int, productId int) error {
+	return withinSQLTransaction(ctx, sdb.db, func(tx *sql.Tx) error {
+		if err := checkSupplier(ctx, tx, supplierId); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, sqlDeleteSupplierLink, supplierId, productId)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return internal.NewSupplierProductNotFoundError()
+		}
+		return nil
+	})
+}
+
+// checkSupplier fails with SupplierNotFoundError when supplier id does not
+// exist.
+func checkSupplier(ctx context.Context, db sqlConn, id int) error {
+	if err := db.QueryRowContext(ctx, sqlGetSupplierId, id).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.NewSupplierNotFoundError()
+		}
+		return err
+	}
+	return nil
+}
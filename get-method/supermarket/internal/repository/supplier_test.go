@@ -0,0 +1,100 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testSupplierRepository runs the checks every supplier repository passes,
+// on an empty one.
+func testSupplierRepository(t *testing.T, sdb internal.SupplierRepository) {
+	ctx := context.Background()
+
+	first, err := sdb.Save(ctx, internal.Supplier{Name: "Dairy Co", Email: "orders@dairy.example", LeadTimeDays: 2})
+	require.NoError(t, err)
+	second, err := sdb.Save(ctx, internal.Supplier{Name: "Bakery", Phone: "555-0100"})
+	require.NoError(t, err)
+	require.Greater(t, second.Id, first.Id)
+
+	first.LeadTimeDays = 3
+	_, err = sdb.Update(ctx, first)
+	require.NoError(t, err)
+	found, err := sdb.GetById(ctx, first.Id)
+	require.NoError(t, err)
+	require.Equal(t, first, found)
+
+	milk := internal.SupplierProduct{SupplierId: first.Id, ProductId: 2, SupplierCode: "M-2", Cost: usd(80)}
+	_, err = sdb.SaveProduct(ctx, milk)
+	require.NoError(t, err)
+	_, err = sdb.SaveProduct(ctx, internal.SupplierProduct{SupplierId: first.Id, ProductId: 1, Cost: usd(50)})
+	require.NoError(t, err)
+	// saving a link again replaces it
+	milk.Cost = usd(90)
+	_, err = sdb.SaveProduct(ctx, milk)
+	require.NoError(t, err)
+	require.NoError(t, sdb.DeleteProduct(ctx, first.Id, 1))
+
+	links, err := sdb.Products(ctx, first.Id)
+	require.NoError(t, err)
+	require.Equal(t, []internal.SupplierProduct{milk}, links)
+	links, err = sdb.Products(ctx, second.Id)
+	require.NoError(t, err)
+	require.Empty(t, links)
+
+	// deleting a supplier deletes its links
+	require.NoError(t, sdb.Delete(ctx, first.Id))
+	suppliers, err := sdb.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Supplier{second}, suppliers)
+	_, err = sdb.Products(ctx, first.Id)
+	require.ErrorAs(t, err, &internal.SupplierNotFoundError{})
+
+	_, err = sdb.GetById(ctx, 99)
+	require.ErrorAs(t, err, &internal.SupplierNotFoundError{})
+	_, err = sdb.Update(ctx, internal.Supplier{Id: 99, Name: "Nobody"})
+	require.ErrorAs(t, err, &internal.SupplierNotFoundError{})
+	require.ErrorAs(t, sdb.Delete(ctx, 99), &internal.SupplierNotFoundError{})
+	_, err = sdb.SaveProduct(ctx, internal.SupplierProduct{SupplierId: 99, ProductId: 1, Cost: usd(50)})
+	require.ErrorAs(t, err, &internal.SupplierNotFoundError{})
+	require.ErrorAs(t, sdb.DeleteProduct(ctx, second.Id, 1), &internal.SupplierProductNotFoundError{})
+}
+
+func TestSupplierMapDB(t *testing.T) {
+	testSupplierRepository(t, repository.NewSupplierMapDB())
+}
+
+func TestSupplierSQLite(t *testing.T) {
+	testSupplierRepository(t, repository.NewSupplierSQL(openMigratedSQLite(t)))
+}
+
+func TestSupplierFileDBReloadsWhatWasSaved(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.json.suppliers")
+	sdb, err := repository.NewSupplierFileDB(path)
+	require.NoError(t, err)
+	testSupplierRepository(t, sdb)
+	suppliers, err := sdb.GetAll(ctx)
+	require.NoError(t, err)
+	link, err := sdb.SaveProduct(ctx, internal.SupplierProduct{SupplierId: suppliers[0].Id, ProductId: 3, Cost: usd(120)})
+	require.NoError(t, err)
+
+	reopened, err := repository.NewSupplierFileDB(path)
+	require.NoError(t, err)
+	reloaded, err := reopened.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, suppliers, reloaded)
+	links, err := reopened.Products(ctx, suppliers[0].Id)
+	require.NoError(t, err)
+	require.Equal(t, []internal.SupplierProduct{link}, links)
+
+	// ids keep growing after a restart
+	saved, err := reopened.Save(ctx, internal.Supplier{Name: "Greengrocer"})
+	require.NoError(t, err)
+	require.Equal(t, suppliers[0].Id+1, saved.Id)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// table holds the rows of a repository by id. A table opened on a file
+// appends every write to a write-ahead log before applying it, and folds
+// the log into a snapshot of the rows once it grows past compactAfter
+// records. Callers synchronize access and must not change the rows they
+// read in place.
+type table[T any] struct {
+	rows   map[int]T
+	lastId int
+
+	path         string
+	wal          *WAL
+	compactAfter int
+}
+
+// tableSnapshot is the content of the file of a table. The last id is kept
+// as rows can be deleted, and their ids must not be given again.
+type tableSnapshot[T any] struct {
+	LastId int       `json:"last_id"`
+	Rows   map[int]T `json:"rows"`
+}
+
+// tableChange puts Row at Id, or deletes Id when Row is nil.
+type tableChange[T any] struct {
+	Id  int `json:"id"`
+	Row *T  `json:"row,omitempty"`
+}
+
+func putRow[T any](id int, row T) tableChange[T] {
+	return tableChange[T]{Id: id, Row: &row}
+}
+
+func deleteRow[T any](id int) tableChange[T] {
+	return tableChange[T]{Id: id}
+}
+
+func newTable[T any]() *table[T] {
+	return &table[T]{rows: map[int]T{}}
+}
+
+// openTable loads the table kept in the file at path and in its write-ahead
+// log at path plus ".wal".
+func openTable[T any](path string) (*table[T], error) {
+	t := &table[T]{rows: map[int]T{}, path: path, compactAfter: DefaultCompactAfter}
+
+	var snapshot tableSnapshot[T]
+	if _, err := readJSONFile(path, &snapshot); err != nil {
+		return nil, err
+	}
+	for id, row := range snapshot.Rows {
+		t.rows[id] = row
+	}
+	t.lastId = snapshot.LastId
+
+	wal, err := OpenWAL(path + ".wal")
+	if err != nil {
+		return nil, err
+	}
+	if err := wal.replay(func(data []byte) bool {
+		var changes []tableChange[T]
+		if err := json.Unmarshal(data, &changes); err != nil || len(changes) == 0 {
+			return false
+		}
+		t.apply(changes)
+		return true
+	}); err != nil {
+		wal.Close()
+		return nil, err
+	}
+	t.wal = wal
+	return t, nil
+}
+
+// nextId returns the id the next inserted row gets.
+func (t *table[T]) nextId() int {
+	return t.lastId + 1
+}
+
+// write logs changes as one record, when the table has a file, then applies
+// them together.
+func (t *table[T]) write(changes ...tableChange[T]) error {
+	if t.wal == nil {
+		t.apply(changes)
+		return nil
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	if err := t.wal.appendRecord(data); err != nil {
+		return err
+	}
+	t.apply(changes)
+
+	if t.wal.Records() >= t.compactAfter {
+		// the changes are safe in the log already, a failed compaction is
+		// retried on the next write
+		if err := t.compact(); err != nil {
+			log.Printf("compacting %s: %v", t.path, err)
+		}
+	}
+	return nil
+}
+
+func (t *table[T]) apply(changes []tableChange[T]) {
+	for _, change := range changes {
+		if change.Row == nil {
+			delete(t.rows, change.Id)
+			continue
+		}
+		t.rows[change.Id] = *change.Row
+		t.lastId = max(t.lastId, change.Id)
+	}
+}
+
+// compact folds the log into a new snapshot. The log is rotated first so a
+// crash at any step leaves every change in the snapshot or in the log.
+func (t *table[T]) compact() error {
+	if err := t.wal.Rotate(); err != nil {
+		return err
+	}
+	if err := writeJSONFile(t.path, tableSnapshot[T]{LastId: t.lastId, Rows: t.rows}); err != nil {
+		return err
+	}
+	return t.wal.Discard()
+}
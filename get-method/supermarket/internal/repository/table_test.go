@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"supermarket/internal"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableReloadsFromSnapshotAndLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json.locations")
+	locations, err := openTable[internal.Location](path)
+	require.NoError(t, err)
+	locations.compactAfter = 3
+
+	for id := 1; id <= 4; id++ {
+		require.NoError(t, locations.write(putRow(id, internal.Location{Id: id, Name: "Store", Kind: internal.LocationStore})))
+	}
+	// the first three writes were folded into the snapshot, the last one is
+	// still in the log
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, 1, locations.wal.Records())
+
+	require.NoError(t, locations.write(deleteRow[internal.Location](4), deleteRow[internal.Location](3)))
+
+	reopened, err := openTable[internal.Location](path)
+	require.NoError(t, err)
+	require.Equal(t, locations.rows, reopened.rows)
+	// the ids of deleted rows are not given again
+	require.Equal(t, 5, reopened.nextId())
+
+	// nor after they are folded into the snapshot
+	require.NoError(t, reopened.compact())
+	reopened, err = openTable[internal.Location](path)
+	require.NoError(t, err)
+	require.Equal(t, locations.rows, reopened.rows)
+	require.Equal(t, 5, reopened.nextId())
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"supermarket/internal"
+	"sync"
+)
+
+// TaxMapDB is an in-memory tax jurisdiction repository safe for concurrent
+// use. When it has a file, every write is logged to it before being
+// acknowledged.
+type TaxMapDB struct {
+	mu            sync.RWMutex
+	jurisdictions *table[internal.TaxJurisdiction]
+	// ids holds the row of each jurisdiction by code
+	ids map[string]int
+}
+
+func NewTaxMapDB() *TaxMapDB {
+	return newTaxMapDB(newTable[internal.TaxJurisdiction]())
+}
+
+// NewTaxFileDB keeps the jurisdictions in the file at path, loading the ones
+// already saved there.
+func NewTaxFileDB(path string) (*TaxMapDB, error) {
+	jurisdictions, err := openTable[internal.TaxJurisdiction](path)
+	if err != nil {
+		return nil, err
+	}
+	return newTaxMapDB(jurisdictions), nil
+}
+
+func newTaxMapDB(jurisdictions *table[internal.TaxJurisdiction]) *TaxMapDB {
+	tdb := &TaxMapDB{jurisdictions: jurisdictions, ids: map[string]int{}}
+	for id, jurisdiction := range jurisdictions.rows {
+		tdb.ids[jurisdiction.Code] = id
+	}
+	return tdb
+}
+
+// GetAll returns the jurisdictions sorted by code.
+func (tdb *TaxMapDB) GetAll(ctx context.Context) ([]internal.TaxJurisdiction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tdb.mu.RLock()
+	defer tdb.mu.RUnlock()
+
+	jurisdictions := make([]internal.TaxJurisdiction, 0, len(tdb.jurisdictions.rows))
+	for _, jurisdiction := range tdb.jurisdictions.rows {
+		jurisdictions = append(jurisdictions, cloneJurisdiction(jurisdiction))
+	}
+	slices.SortFunc(jurisdictions, func(a, b internal.TaxJurisdiction) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	return jurisdictions, nil
+}
+
+func (tdb *TaxMapDB) GetByCode(ctx context.Context, code string) (internal.TaxJurisdiction, error) {
+	if err := ctx.Err(); err != nil {
+		return internal.TaxJurisdiction{}, err
+	}
+
+	tdb.mu.RLock()
+	defer tdb.mu.RUnlock()
+
+	jurisdiction, ok := tdb.jurisdictions.rows[tdb.ids[code]]
+	if !ok {
+		return internal.TaxJurisdiction{}, internal.NewTaxJurisdictionNotFoundError(code)
+	}
+	return cloneJurisdiction(jurisdiction), nil
+}
+
+func (tdb *TaxMapDB) Put(ctx context.Context, jurisdiction internal.TaxJurisdiction) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tdb.mu.Lock()
+	defer tdb.mu.Unlock()
+
+	id, ok := tdb.ids[jurisdiction.Code]
+	if !ok {
+		id = tdb.jurisdictions.nextId()
+	}
+	if err := tdb.jurisdictions.write(putRow(id, cloneJurisdiction(jurisdiction))); err != nil {
+		return err
+	}
+	tdb.ids[jurisdiction.Code] = id
+	return nil
+}
+
+func (tdb *TaxMapDB) Delete(ctx context.Context, code string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tdb.mu.Lock()
+	defer tdb.mu.Unlock()
+
+	id, ok := tdb.ids[code]
+	if !ok {
+		return internal.NewTaxJurisdictionNotFoundError(code)
+	}
+	if err := tdb.jurisdictions.write(deleteRow[internal.TaxJurisdiction](id)); err != nil {
+		return err
+	}
+	delete(tdb.ids, code)
+	return nil
+}
+
+// cloneJurisdiction copies the rates of jurisdiction, so callers can not
+// change the stored ones.
+func cloneJurisdiction(jurisdiction internal.TaxJurisdiction) internal.TaxJurisdiction {
+	jurisdiction.Rates = slices.Clone(jurisdiction.Rates)
+	return jurisdiction
+}
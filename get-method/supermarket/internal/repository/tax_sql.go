@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"supermarket/internal"
+)
+
+func NewTaxSQL(db *sql.DB) *TaxSQL {
+	return &TaxSQL{db: db}
+}
+
+// TaxSQL is a TaxRepository backed by the SQLite or MySQL database of the
+// products.
+type TaxSQL struct {
+	db *sql.DB
+}
+
+// Queries, the same in both dialects.
+const (
+	sqlGetAllJurisdictions   = "SELECT code, mode, rates FROM tax_jurisdictions ORDER BY code"
+	sqlGetJurisdictionByCode = "SELECT code, mode, rates FROM tax_jurisdictions WHERE code = ?"
+	sqlCreateJurisdiction    = "INSERT INTO tax_jurisdictions (code, mode, rates) VALUES (?, ?, ?)"
+	sqlDeleteJurisdiction    = "DELETE FROM tax_jurisdictions WHERE code = ?"
+)
+
+func (tdb *TaxSQL) GetAll(ctx context.Context) ([]internal.TaxJurisdiction, error) {
+	rows, err := tdb.db.QueryContext(ctx, sqlGetAllJurisdictions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jurisdictions := []internal.TaxJurisdiction{}
+	for rows.Next() {
+		jurisdiction, err := scanJurisdiction(rows)
+		if err != nil {
+			return nil, err
+		}
+		jurisdictions = append(jurisdictions, jurisdiction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return jurisdictions, nil
+}
+
+func (tdb *TaxSQL) GetByCode(ctx context.Context, code string) (internal.TaxJurisdiction, error) {
+	jurisdiction, err := scanJurisdiction(tdb.db.QueryRowContext(ctx, sqlGetJurisdictionByCode, code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return internal.TaxJurisdiction{}, internal.NewTaxJurisdictionNotFoundError(code)
+		}
+		return internal.TaxJurisdiction{}, err
+	}
+	return jurisdiction, nil
+}
+
+func (tdb *TaxSQL) Put(ctx context.Context, jurisdiction internal.TaxJurisdiction) error {
+	rates, err := json.Marshal(jurisdiction.Rates)
+	if err != nil {
+		return err
+	}
+
+	return withinSQLTransaction(ctx, tdb.db, func(tx *sql.Tx) error {
+		// replacing the jurisdiction the same way in both dialects
+		if _, err := tx.ExecContext(ctx, sqlDeleteJurisdiction, jurisdiction.Code); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, sqlCreateJurisdiction, jurisdiction.Code, jurisdiction.Mode, string(rates))
+		return err
+	})
+}
+
+func (tdb *TaxSQL) Delete(ctx context.Context, code string) error {
+	result, err := tdb.db.ExecContext(ctx, sqlDeleteJurisdiction, code)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return internal.NewTaxJurisdictionNotFoundError(code)
+	}
+	return nil
+}
+
+func scanJurisdiction(row rowScanner) (internal.TaxJurisdiction, error) {
+	var (
+		jurisdiction internal.TaxJurisdiction
+		rates        string
+	)
+	if err := row.Scan(&jurisdiction.Code, &jurisdiction.Mode, &rates); err != nil {
+		return internal.TaxJurisdiction{}, err
+	}
+
+	if err := json.Unmarshal([]byte(rates), &jurisdiction.Rates); err != nil {
+		return internal.TaxJurisdiction{}, err
+	}
+	return jurisdiction, nil
+}
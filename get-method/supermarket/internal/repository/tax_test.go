@@ -0,0 +1,69 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testTaxRepository runs the checks every tax jurisdiction repository
+// passes, on an empty one.
+func testTaxRepository(t *testing.T, tdb internal.TaxRepository) {
+	ctx := context.Background()
+	germany := internal.TaxJurisdiction{Code: "DE", Mode: internal.TaxInclusive, Rates: []internal.TaxRate{{Category: "food", Name: "reduced", Percent: 7}, {Category: "books", Name: "reduced", Percent: 7}}}
+	california := internal.TaxJurisdiction{Code: "US-CA", Mode: internal.TaxExclusive, Rates: []internal.TaxRate{{Category: "food", Name: "sales tax", Percent: 7.25}}}
+
+	require.NoError(t, tdb.Put(ctx, california))
+	require.NoError(t, tdb.Put(ctx, germany))
+	found, err := tdb.GetByCode(ctx, "DE")
+	require.NoError(t, err)
+	require.Equal(t, germany, found)
+
+	// putting a jurisdiction again replaces it
+	germany.Rates = germany.Rates[:1]
+	require.NoError(t, tdb.Put(ctx, germany))
+	jurisdictions, err := tdb.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []internal.TaxJurisdiction{germany, california}, jurisdictions)
+
+	require.NoError(t, tdb.Delete(ctx, "US-CA"))
+	_, err = tdb.GetByCode(ctx, "US-CA")
+	require.ErrorAs(t, err, &internal.TaxJurisdictionNotFoundError{})
+	require.ErrorAs(t, tdb.Delete(ctx, "US-CA"), &internal.TaxJurisdictionNotFoundError{})
+}
+
+func TestTaxMapDB(t *testing.T) {
+	testTaxRepository(t, repository.NewTaxMapDB())
+}
+
+func TestTaxSQLite(t *testing.T) {
+	testTaxRepository(t, repository.NewTaxSQL(openMigratedSQLite(t)))
+}
+
+func TestTaxFileDBReloadsWhatWasSaved(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "products.json.taxes")
+	tdb, err := repository.NewTaxFileDB(path)
+	require.NoError(t, err)
+	testTaxRepository(t, tdb)
+
+	reopened, err := repository.NewTaxFileDB(path)
+	require.NoError(t, err)
+	jurisdictions, err := tdb.GetAll(ctx)
+	require.NoError(t, err)
+	reloaded, err := reopened.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, jurisdictions, reloaded)
+
+	// the reloaded jurisdictions are replaced, not added again
+	jurisdictions[0].Mode = internal.TaxExclusive
+	require.NoError(t, reopened.Put(ctx, jurisdictions[0]))
+	reloaded, err = reopened.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, jurisdictions, reloaded)
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"supermarket/internal"
+	"sync"
+)
+
+const (
+	opPut    = "put"
+	opDelete = "delete"
+	opBatch  = "batch"
+
+	// compactingSuffix names the log segment being folded into a snapshot.
+	compactingSuffix = ".compacting"
+)
+
+// productChange is a single write applied to a products map, or a batch of
+// writes applied together. Changes carry the full product so replaying them
+// is idempotent.
+type productChange struct {
+	Op      string            `json:"op"`
+	Id      int               `json:"id,omitempty"`
+	Product *internal.Product `json:"product,omitempty"`
+	Changes []productChange   `json:"changes,omitempty"`
+}
+
+func putChange(product internal.Product) productChange {
+	// the stored product must not share its categories with the caller
+	product.CategoryIds = slices.Clone(product.CategoryIds)
+	return productChange{Op: opPut, Id: product.Id, Product: &product}
+}
+
+func deleteChange(id int) productChange {
+	return productChange{Op: opDelete, Id: id}
+}
+
+func batchChange(changes []productChange) productChange {
+	return productChange{Op: opBatch, Changes: changes}
+}
+
+func (c productChange) apply(products map[int]internal.Product) {
+	switch c.Op {
+	case opPut:
+		products[c.Id] = *c.Product
+	case opDelete:
+		delete(products, c.Id)
+	case opBatch:
+		for _, change := range c.Changes {
+			change.apply(products)
+		}
+	}
+}
+
+func (c productChange) valid() bool {
+	switch c.Op {
+	case opPut:
+		return c.Product != nil
+	case opDelete:
+		return true
+	case opBatch:
+		for _, change := range c.Changes {
+			if change.Op == opBatch || !change.valid() {
+				return false
+			}
+		}
+		return len(c.Changes) > 0
+	}
+	return false
+}
+
+// WALCorruptedError is returned when a log record other than the last one
+// can not be decoded, which a crash alone can not explain.
+type WALCorruptedError struct {
+	Path   string
+	Offset int64
+}
+
+func (e WALCorruptedError) Error() string {
+	return fmt.Sprintf("write-ahead log %s corrupted at offset %d", e.Path, e.Offset)
+}
+
+// WAL is an append-only log of changes, to products or to a table.
+// Each record is a line holding the CRC-32 of its JSON payload followed by
+// the payload, and is fsynced before Append returns.
+type WAL struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	records int
+}
+
+func OpenWAL(path string) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &WAL{path: path, file: file}, nil
+}
+
+// Records returns the number of records in the live log.
+func (w *WAL) Records() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.records
+}
+
+func (w *WAL) Append(change productChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return w.appendRecord(data)
+}
+
+func (w *WAL) appendRecord(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.file.Write(encodeRecord(data)); err != nil {
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.records++
+	return nil
+}
+
+// Replay applies every logged change, oldest first: the segment left by an
+// unfinished compaction and then the live log. A torn final record, left by
+// a crash mid-append, is dropped and truncated away.
+func (w *WAL) Replay(apply func(productChange)) error {
+	return w.replay(func(data []byte) bool {
+		var change productChange
+		if err := json.Unmarshal(data, &change); err != nil || !change.valid() {
+			return false
+		}
+		apply(change)
+		return true
+	})
+}
+
+// replay hands the payload of every logged record to apply, which reports
+// whether it could decode it, in the order Replay does.
+func (w *WAL) replay(apply func(data []byte) bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	compacting, err := os.OpenFile(w.path+compactingSuffix, os.O_RDWR, 0)
+	switch {
+	case err == nil:
+		_, err = replayFile(compacting, apply)
+		compacting.Close()
+		if err != nil {
+			return err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	records, err := replayFile(w.file, apply)
+	if err != nil {
+		return err
+	}
+	w.records = records
+	return nil
+}
+
+// Rotate moves the live log aside so it can be folded into a snapshot while
+// new changes go to an empty log. Callers must make sure no change is
+// appended concurrently.
+func (w *WAL) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	compactingPath := w.path + compactingSuffix
+
+	if _, err := os.Stat(compactingPath); err == nil {
+		// an earlier compaction did not finish, keep its records and add
+		// the live ones after them
+		if err := appendFile(compactingPath, w.file); err != nil {
+			return err
+		}
+		if err := w.file.Truncate(0); err != nil {
+			return err
+		}
+		if err := w.file.Sync(); err != nil {
+			return err
+		}
+		w.records = 0
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Rename(w.path, compactingPath); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(w.path)); err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file.Close()
+	w.file = file
+	w.records = 0
+	return nil
+}
+
+// Discard removes the rotated segment once its changes are in a snapshot.
+func (w *WAL) Discard() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := os.Remove(w.path + compactingSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return syncDir(filepath.Dir(w.path))
+}
+
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
+
+func encodeRecord(data []byte) []byte {
+	record := make([]byte, 0, len(data)+10)
+	record = fmt.Appendf(record, "%08x ", crc32.ChecksumIEEE(data))
+	record = append(record, data...)
+	return append(record, '\n')
+}
+
+func decodeRecord(line []byte) ([]byte, bool) {
+	sum, data, ok := bytes.Cut(line, []byte(" "))
+	if !ok {
+		return nil, false
+	}
+	want, err := strconv.ParseUint(string(sum), 16, 32)
+	if err != nil || uint32(want) != crc32.ChecksumIEEE(data) {
+		return nil, false
+	}
+	return data, true
+}
+
+// replayFile applies the records of file and returns how many it read.
+// An unreadable last record is truncated, any other one is an error.
+func replayFile(file *os.File, apply func(data []byte) bool) (int, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		offset  int64
+		records int
+	)
+	for len(data) > 0 {
+		line, rest, complete := bytes.Cut(data, []byte("\n"))
+
+		payload, ok := decodeRecord(line)
+		if !complete || !ok || !apply(payload) {
+			if complete && len(rest) > 0 {
+				return records, WALCorruptedError{Path: file.Name(), Offset: offset}
+			}
+			// torn final record
+			if err := file.Truncate(offset); err != nil {
+				return records, err
+			}
+			return records, file.Sync()
+		}
+
+		records++
+		offset += int64(len(line)) + 1
+		data = rest
+	}
+	return records, nil
+}
+
+// appendFile appends the whole content of src to the file at path.
+func appendFile(path string, src *os.File) error {
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	return dst.Sync()
+}
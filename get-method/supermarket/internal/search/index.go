@@ -0,0 +1,368 @@
+// Package search implements full-text search over product names.
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"supermarket/internal"
+	"sync"
+	"unicode/utf8"
+)
+
+// Match weights, relative to an exact match of a term.
+const (
+	exactWeight  = 1.0
+	prefixWeight = 0.5
+	typoWeight   = 0.6
+)
+
+// document is an indexed product with its name tokenized.
+type document struct {
+	product internal.Product
+	tokens  []token
+}
+
+// Index is an inverted index over product names safe for concurrent use.
+// Query words match the terms equal to them, starting with them, or at a
+// small edit distance of them, accents and case aside.
+type Index struct {
+	mu        sync.RWMutex
+	documents map[int]document
+	// postings maps each term to the ids of the documents holding it.
+	postings map[string]map[int]struct{}
+	// terms holds the keys of postings sorted, for prefix lookups. It is
+	// kept in step with postings by every write, under the same lock.
+	terms []string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		documents: map[int]document{},
+		postings:  map[string]map[int]struct{}{},
+	}
+}
+
+// Put indexes product, replacing any earlier version of it.
+func (ix *Index) Put(product internal.Product) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(product.Id)
+
+	doc := document{product: product, tokens: tokenize(product.Name)}
+	ix.documents[product.Id] = doc
+	for _, t := range doc.tokens {
+		ids, ok := ix.postings[t.term]
+		if !ok {
+			ids = map[int]struct{}{}
+			ix.postings[t.term] = ids
+			ix.addTerm(t.term)
+		}
+		ids[product.Id] = struct{}{}
+	}
+}
+
+// Remove drops the product id from the index.
+func (ix *Index) Remove(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+}
+
+// remove drops a document, the caller must hold mu.
+func (ix *Index) remove(id int) {
+	doc, ok := ix.documents[id]
+	if !ok {
+		return
+	}
+	delete(ix.documents, id)
+
+	for _, t := range doc.tokens {
+		ids := ix.postings[t.term]
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(ix.postings, t.term)
+			ix.removeTerm(t.term)
+		}
+	}
+}
+
+// addTerm inserts a new term into terms, the caller must hold mu.
+func (ix *Index) addTerm(term string) {
+	i := sort.SearchStrings(ix.terms, term)
+	ix.terms = append(ix.terms, "")
+	copy(ix.terms[i+1:], ix.terms[i:])
+	ix.terms[i] = term
+}
+
+// removeTerm drops a term from terms, the caller must hold mu.
+func (ix *Index) removeTerm(term string) {
+	i := sort.SearchStrings(ix.terms, term)
+	if i < len(ix.terms) && ix.terms[i] == term {
+		ix.terms = append(ix.terms[:i], ix.terms[i+1:]...)
+	}
+}
+
+// Len returns the number of indexed products.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.documents)
+}
+
+// termMatch is how well an index term matches a query word.
+type termMatch struct {
+	term   string
+	weight float64
+	// highlight is the number of leading runes of the term that matched,
+	// zero for all of them.
+	highlight int
+}
+
+// tokenMatch is the best match of a query word within a document.
+type tokenMatch struct {
+	score     float64
+	term      string
+	highlight int
+}
+
+// Search returns the products whose names match text best, at most limit of
+// them, and how many products matched in total. Products matching more of
+// the words of text rank first, and rarer words weigh more.
+func (ix *Index) Search(text string, limit int) ([]internal.ProductSearchResult, int) {
+	words := uniqueTerms(tokenize(text))
+	if len(words) == 0 {
+		return []internal.ProductSearchResult{}, 0
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	// matches[id][i] is the best match of the ith word in document id
+	matches := map[int][]tokenMatch{}
+	for i, word := range words {
+		for _, m := range ix.matchTerm(word) {
+			idf := 1 + math.Log(float64(len(ix.documents))/float64(len(ix.postings[m.term])))
+			score := m.weight * idf
+
+			for id := range ix.postings[m.term] {
+				docMatches, ok := matches[id]
+				if !ok {
+					docMatches = make([]tokenMatch, len(words))
+					matches[id] = docMatches
+				}
+				if score > docMatches[i].score {
+					docMatches[i] = tokenMatch{score: score, term: m.term, highlight: m.highlight}
+				}
+			}
+		}
+	}
+
+	results := make([]internal.ProductSearchResult, 0, len(matches))
+	for id, docMatches := range matches {
+		doc := ix.documents[id]
+
+		var (
+			score   float64
+			matched int
+		)
+		for _, m := range docMatches {
+			if m.score > 0 {
+				score += m.score
+				matched++
+			}
+		}
+		// favour documents matching every word, then shorter names
+		score *= float64(matched) / float64(len(words))
+		score /= 1 + 0.1*float64(len(doc.tokens))
+
+		results = append(results, internal.ProductSearchResult{
+			Product:     doc.product,
+			Score:       math.Round(score*1000) / 1000,
+			Highlighted: highlight(doc, docMatches),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Id < results[j].Id
+	})
+
+	total := len(results)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, total
+}
+
+func uniqueTerms(tokens []token) []string {
+	seen := map[string]bool{}
+	terms := []string{}
+	for _, t := range tokens {
+		if !seen[t.term] {
+			seen[t.term] = true
+			terms = append(terms, t.term)
+		}
+	}
+	return terms
+}
+
+// matchTerm returns the index terms matching word. The caller must hold mu.
+func (ix *Index) matchTerm(word string) []termMatch {
+	best := map[string]termMatch{}
+	add := func(m termMatch) {
+		if current, ok := best[m.term]; !ok || m.weight > current.weight {
+			best[m.term] = m
+		}
+	}
+
+	wordLen := utf8.RuneCountInString(word)
+
+	// terms starting with word, the longer the rest the weaker the match
+	from := sort.SearchStrings(ix.terms, word)
+	for _, term := range ix.terms[from:] {
+		if !strings.HasPrefix(term, word) {
+			break
+		}
+		if term == word {
+			add(termMatch{term: term, weight: exactWeight})
+			continue
+		}
+		termLen := utf8.RuneCountInString(term)
+		add(termMatch{term: term, weight: prefixWeight + prefixWeight*float64(wordLen)/float64(termLen), highlight: wordLen})
+	}
+
+	// short words are too ambiguous to correct
+	edits := maxEdits(wordLen)
+	if edits == 0 {
+		return matchList(best)
+	}
+
+	wordRunes := []rune(word)
+	for _, term := range ix.terms {
+		termRunes := []rune(term)
+		if abs(len(termRunes)-len(wordRunes)) <= edits {
+			if d := editDistance(wordRunes, termRunes, edits); d > 0 && d <= edits {
+				add(termMatch{term: term, weight: typoWeight / float64(d)})
+			}
+		}
+		// a misspelled prefix
+		if len(termRunes) > len(wordRunes) {
+			if d := editDistance(wordRunes, termRunes[:len(wordRunes)], 1); d == 1 {
+				add(termMatch{term: term, weight: prefixWeight * typoWeight, highlight: wordLen})
+			}
+		}
+	}
+	return matchList(best)
+}
+
+func matchList(best map[string]termMatch) []termMatch {
+	matches := make([]termMatch, 0, len(best))
+	for _, m := range best {
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// maxEdits is the number of typos tolerated in a word of n runes.
+func maxEdits(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b, counting insertions, deletions, substitutions and transpositions of
+// adjacent runes, or max+1 once it is known to exceed max.
+func editDistance(a, b []rune, max int) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// highlight returns the name of doc, HTML escaped, with the parts matching
+// the query wrapped in <mark> elements.
+func highlight(doc document, matches []tokenMatch) string {
+	// highlights[term] is how many runes of the tokens holding term to mark
+	highlights := map[string]int{}
+	for _, m := range matches {
+		if m.score == 0 {
+			continue
+		}
+		// a whole word, zero, beats any prefix
+		if current, ok := highlights[m.term]; ok && (current == 0 || (m.highlight != 0 && m.highlight <= current)) {
+			continue
+		}
+		highlights[m.term] = m.highlight
+	}
+
+	name := doc.product.Name
+	var b strings.Builder
+	last := 0
+	for _, t := range doc.tokens {
+		runes, ok := highlights[t.term]
+		if !ok {
+			continue
+		}
+
+		end := t.end
+		if runes > 0 {
+			// count runes in the original word, accents are one rune once
+			// composed
+			end = t.start
+			for i := 0; i < runes && end < t.end; i++ {
+				_, size := utf8.DecodeRuneInString(name[end:])
+				end += size
+			}
+		}
+
+		b.WriteString(html.EscapeString(name[last:t.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(name[t.start:end]))
+		b.WriteString("</mark>")
+		last = end
+	}
+	b.WriteString(html.EscapeString(name[last:]))
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package search_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/search"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newCatalogIndex() *search.Index {
+	index := search.NewIndex()
+	for id, name := range []string{
+		"Dark Chocolate Bar",
+		"Chocolate Chip Cookies",
+		"Hot Cocoa",
+		"Angel Hair Pasta",
+		"Pasta Sauce",
+		"Angel Food Cake",
+		"Crème Brûlée",
+		"Spaghetti",
+		"Choc <Ice>",
+	} {
+		index.Put(internal.Product{Id: id + 1, Name: name})
+	}
+	return index
+}
+
+func resultIds(results []internal.ProductSearchResult) []int {
+	ids := []int{}
+	for _, result := range results {
+		ids = append(ids, result.Id)
+	}
+	return ids
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := newCatalogIndex()
+
+	for text, expected := range map[string][]int{
+		// prefixes, the exact word first
+		"choc": {9, 1, 2},
+		// every word beats some of them
+		"pasta angel": {4, 5, 6},
+		// accents and case
+		"CREME brulee": {7},
+		"crème":        {7},
+		// typos
+		"choclate":  {1, 2},
+		"spagheti":  {8},
+		"chcolate":  {1, 2},
+		"cocoa hto": {3},
+		// no match
+		"milk": {},
+		"":     {},
+		"  ,;": {},
+	} {
+		results, total := index.Search(text, 10)
+		require.Equal(t, expected, resultIds(results), text)
+		require.Equal(t, len(expected), total, text)
+	}
+}
+
+func TestIndexSearchLimit(t *testing.T) {
+	index := newCatalogIndex()
+
+	results, total := index.Search("choc", 2)
+	require.Equal(t, []int{9, 1}, resultIds(results))
+	require.Equal(t, 3, total)
+	require.Greater(t, results[0].Score, results[1].Score)
+}
+
+func TestIndexSearchHighlights(t *testing.T) {
+	index := newCatalogIndex()
+
+	for text, expected := range map[string]string{
+		"choc":        "<mark>Choc</mark> &lt;Ice&gt;",
+		"chocolate":   "Dark <mark>Chocolate</mark> Bar",
+		"pasta angel": "<mark>Angel</mark> Hair <mark>Pasta</mark>",
+		"brul":        "Crème <mark>Brûl</mark>ée",
+		"spagheti":    "<mark>Spaghetti</mark>",
+	} {
+		results, _ := index.Search(text, 1)
+		require.Len(t, results, 1, text)
+		require.Equal(t, expected, results[0].Highlighted, text)
+	}
+}
+
+func TestIndexPutAndRemove(t *testing.T) {
+	index := newCatalogIndex()
+
+	index.Put(internal.Product{Id: 1, Name: "Milk Chocolate Bar"})
+	results, _ := index.Search("dark", 10)
+	require.Empty(t, results)
+	results, _ = index.Search("milk", 10)
+	require.Equal(t, []int{1}, resultIds(results))
+	require.Equal(t, "Milk Chocolate Bar", results[0].Name)
+
+	index.Remove(1)
+	index.Remove(42)
+	results, _ = index.Search("milk", 10)
+	require.Empty(t, results)
+	require.Equal(t, 8, index.Len())
+}
+
+func TestIndexSearchWhileWriting(t *testing.T) {
+	index := newCatalogIndex()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			index.Put(internal.Product{Id: 100, Name: fmt.Sprintf("Chocolate Truffle %d", i)})
+			index.Remove(100)
+		}
+	}()
+
+	// every term searched has postings, so every score is finite
+	for {
+		select {
+		case <-done:
+			results, _ := index.Search("truffle", 0)
+			require.Empty(t, results)
+			return
+		default:
+		}
+		results, _ := index.Search("chocolate truffle", 0)
+		for _, result := range results {
+			require.False(t, math.IsInf(result.Score, 0) || math.IsNaN(result.Score), result.Highlighted)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log"
+	"supermarket/internal"
+	"sync"
+)
+
+// IndexedRepository is a ProductRepository keeping an Index of its product
+// names in sync with the writes going through it. Writes made to the wrapped
+// repository directly are not seen.
+type IndexedRepository struct {
+	internal.ProductRepository
+	index *Index
+
+	// locks holds a *sync.Mutex per product id, so the index refreshes of a
+	// product happen in the order of its writes.
+	locks sync.Map
+}
+
+// NewIndexedRepository indexes every product of repo.
+func NewIndexedRepository(ctx context.Context, repo internal.ProductRepository) (*IndexedRepository, error) {
+	products, err := repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := NewIndex()
+	for _, product := range products {
+		index.Put(product)
+	}
+	return &IndexedRepository{ProductRepository: repo, index: index}, nil
+}
+
+// Index returns the index of the repository products.
+func (r *IndexedRepository) Index() *Index {
+	return r.index
+}
+
+func (r *IndexedRepository) SearchNames(ctx context.Context, text string, limit int) ([]internal.ProductSearchResult, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	results, total := r.index.Search(text, limit)
+	return results, total, nil
+}
+
+// refresh indexes what the repository holds for product id once a write to
+// it committed. Reading it back, rather than indexing what was written, keeps
+// the index right when writes to a product race.
+func (r *IndexedRepository) refresh(ctx context.Context, id int) {
+	l, _ := r.locks.LoadOrStore(id, &sync.Mutex{})
+	mu := l.(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+
+	// the write went through even if its caller gave up since
+	product, err := r.ProductRepository.GetById(context.WithoutCancel(ctx), id)
+	switch {
+	case err == nil:
+		r.index.Put(product)
+	case errors.As(err, &internal.ProductNotFoundError{}):
+		r.index.Remove(id)
+	default:
+		log.Printf("refreshing search index of product %d: %v", id, err)
+	}
+}
+
+func (r *IndexedRepository) Save(ctx context.Context, product internal.Product) (internal.Product, error) {
+	product, err := r.ProductRepository.Save(ctx, product)
+	if err != nil {
+		return internal.Product{}, err
+	}
+	r.refresh(ctx, product.Id)
+	return product, nil
+}
+
+func (r *IndexedRepository) UpdateOrCreate(ctx context.Context, product internal.Product) (internal.Product, error) {
+	product, err := r.ProductRepository.UpdateOrCreate(ctx, product)
+	if err != nil {
+		return internal.Product{}, err
+	}
+	r.refresh(ctx, product.Id)
+	return product, nil
+}
+
+func (r *IndexedRepository) PartialUpdate(ctx context.Context, id int, product internal.Product) (internal.Product, error) {
+	product, err := r.ProductRepository.PartialUpdate(ctx, id, product)
+	if err != nil {
+		return internal.Product{}, err
+	}
+	r.refresh(ctx, id)
+	return product, nil
+}
+
+func (r *IndexedRepository) Delete(ctx context.Context, id int) error {
+	if err := r.ProductRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.refresh(ctx, id)
+	return nil
+}
+
+// WithinTransaction runs fn in a transaction of the wrapped repository and
+// refreshes the products it wrote once it committed. Without transaction
+// support fn runs against the repository itself.
+func (r *IndexedRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repo internal.ProductRepository) error) error {
+	uow, ok := r.ProductRepository.(internal.ProductUnitOfWork)
+	if !ok {
+		return fn(ctx, r)
+	}
+
+	var written []int
+	err := uow.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+		written = written[:0]
+		return fn(ctx, &recordingRepository{ProductRepository: repo, written: &written})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range written {
+		r.refresh(ctx, id)
+	}
+	return nil
+}
+
+// recordingRepository records the ids of the products written through it.
+type recordingRepository struct {
+	internal.ProductRepository
+	written *[]int
+}
+
+func (r *recordingRepository) Save(ctx context.Context, product internal.Product) (internal.Product, error) {
+	product, err := r.ProductRepository.Save(ctx, product)
+	if err == nil {
+		*r.written = append(*r.written, product.Id)
+	}
+	return product, err
+}
+
+func (r *recordingRepository) UpdateOrCreate(ctx context.Context, product internal.Product) (internal.Product, error) {
+	product, err := r.ProductRepository.UpdateOrCreate(ctx, product)
+	if err == nil {
+		*r.written = append(*r.written, product.Id)
+	}
+	return product, err
+}
+
+func (r *recordingRepository) PartialUpdate(ctx context.Context, id int, product internal.Product) (internal.Product, error) {
+	product, err := r.ProductRepository.PartialUpdate(ctx, id, product)
+	if err == nil {
+		*r.written = append(*r.written, id)
+	}
+	return product, err
+}
+
+func (r *recordingRepository) Delete(ctx context.Context, id int) error {
+	err := r.ProductRepository.Delete(ctx, id)
+	if err == nil {
+		*r.written = append(*r.written, id)
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package search_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/search"
+
+	"github.com/stretchr/testify/require"
+)
+
+// usd returns an amount of cents of the default currency.
+func usd(cents int64) internal.Money {
+	return internal.NewMoney(cents, internal.DefaultCurrency)
+}
+
+func newIndexedRepository(t *testing.T) *search.IndexedRepository {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Dark Chocolate", Quantity: 1, Code: "c1", Price: usd(100), Version: 1},
+	}, LastID: 1}
+
+	repo, err := search.NewIndexedRepository(context.Background(), db)
+	require.NoError(t, err)
+	return repo
+}
+
+func searchIds(t *testing.T, repo *search.IndexedRepository, text string) []int {
+	results, _, err := repo.SearchNames(context.Background(), text, 10)
+	require.NoError(t, err)
+	return resultIds(results)
+}
+
+func TestIndexedRepositoryFollowsWrites(t *testing.T) {
+	ctx := context.Background()
+	repo := newIndexedRepository(t)
+	require.Equal(t, []int{1}, searchIds(t, repo, "chocolate"))
+
+	saved, err := repo.Save(ctx, internal.Product{Name: "Milk Chocolate", Quantity: 1, Code: "c2", Price: usd(100)})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, saved.Id}, searchIds(t, repo, "chocolate"))
+
+	_, err = repo.PartialUpdate(ctx, 1, internal.Product{Name: "Dark Cocoa", Quantity: 1, Code: "c1", Price: usd(100)})
+	require.NoError(t, err)
+	require.Equal(t, []int{saved.Id}, searchIds(t, repo, "chocolate"))
+	require.Equal(t, []int{1}, searchIds(t, repo, "cocoa"))
+
+	_, err = repo.UpdateOrCreate(ctx, internal.Product{Id: 7, Name: "White Chocolate", Quantity: 1, Code: "c7", Price: usd(100)})
+	require.NoError(t, err)
+	require.Equal(t, []int{saved.Id, 7}, searchIds(t, repo, "chocolate"))
+
+	require.NoError(t, repo.Delete(ctx, saved.Id))
+	require.Equal(t, []int{7}, searchIds(t, repo, "chocolate"))
+
+	// failed writes leave the index alone
+	require.Error(t, repo.Delete(ctx, saved.Id))
+	require.Equal(t, []int{7}, searchIds(t, repo, "chocolate"))
+}
+
+func TestIndexedRepositoryFollowsTransactions(t *testing.T) {
+	ctx := context.Background()
+	repo := newIndexedRepository(t)
+
+	errRollback := errors.New("rollback")
+	err := repo.WithinTransaction(ctx, func(ctx context.Context, tx internal.ProductRepository) error {
+		if _, err := tx.Save(ctx, internal.Product{Name: "Milk Chocolate", Quantity: 1, Code: "c2", Price: usd(100)}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	require.Equal(t, []int{1}, searchIds(t, repo, "chocolate"))
+
+	var saved internal.Product
+	err = repo.WithinTransaction(ctx, func(ctx context.Context, tx internal.ProductRepository) error {
+		var err error
+		if saved, err = tx.Save(ctx, internal.Product{Name: "Milk Chocolate", Quantity: 1, Code: "c2", Price: usd(100)}); err != nil {
+			return err
+		}
+		return tx.Delete(ctx, 1)
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{saved.Id}, searchIds(t, repo, "chocolate"))
+}
+
+func TestIndexedRepositoryConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	repo := newIndexedRepository(t)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				repo.PartialUpdate(ctx, 1, internal.Product{Name: fmt.Sprintf("Chocolate w%di%d", worker, i), Quantity: 1, Code: "c1", Price: usd(100)})
+				repo.SearchNames(ctx, "chocolate", 10)
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	// the index holds the name the repository ended with
+	product, err := repo.GetById(ctx, 1)
+	require.NoError(t, err)
+	results, _, err := repo.SearchNames(ctx, "chocolate", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, product, results[0].Product)
+}
@@ -0,0 +1,52 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// token is a word of a text. term is its normalized form, start and end its
+// byte offsets in the text.
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// tokenize splits text into words, runs of letters and digits.
+func tokenize(text string) []token {
+	var (
+		tokens []token
+		start  = -1
+	)
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{term: normalize(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{term: normalize(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// normalize folds the case of word and strips its accents, so "Crème" and
+// "creme" are the same term.
+func normalize(word string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), word)
+	if err != nil {
+		stripped = word
+	}
+	return strings.ToLower(stripped)
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"supermarket/internal"
+	"time"
+)
+
+type CartDefault struct {
+	repo       internal.ProductRepository
+	promotions internal.PromotionRepository
+	exchange   internal.ExchangeService
+	taxes      internal.TaxService
+	stock      internal.StockService
+	locations  internal.LocationRepository
+	prices     internal.PriceHistoryService
+	now        func() time.Time
+}
+
+// CartOptions are the optional dependencies of a CartDefault.
+type CartOptions struct {
+	// Promotions are applied to the carts, none without them.
+	Promotions internal.PromotionRepository
+	// Exchange converts prices to the currency of the cart, only carts in
+	// the currency of the products can be quoted without it.
+	Exchange internal.ExchangeService
+	// Taxes taxes the carts, untaxed without it.
+	Taxes internal.TaxService
+	// Stock checks the stock at the store of the cart. Without it only the
+	// default location sells, and the quantity of the products is in stock.
+	Stock internal.StockService
+	// Locations tells the stores, which sell, from the warehouses, which
+	// only keep stock. Without it any location of the stock sells.
+	Locations internal.LocationRepository
+	// Prices prices carts as of a time, which can not be without it: at
+	// the unit prices, promotions and expiration of the products then, but
+	// the current exchange and tax rates.
+	Prices internal.PriceHistoryService
+	// Now is the clock, time.Now when it is nil.
+	Now func() time.Time
+}
+
+func NewCartDefault(pdb internal.ProductRepository, opts CartOptions) *CartDefault {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &CartDefault{repo: pdb, promotions: opts.Promotions, exchange: opts.Exchange, taxes: opts.Taxes, stock: opts.Stock, locations: opts.Locations, prices: opts.Prices, now: now}
+}
+
+// Quote prices each line of cart at the unit price of its product, within
+// the stock of the store, then applies the active promotions, converts the
+// prices to the currency of the cart and taxes the discounted prices.
+func (cd *CartDefault) Quote(ctx context.Context, cart internal.Cart) (internal.Receipt, error) {
+	if err := cart.Validate(); err != nil {
+		return internal.Receipt{}, err
+	}
+	if err := cd.store(ctx, cart.StoreId); err != nil {
+		return internal.Receipt{}, err
+	}
+	if cart.At != nil && cd.prices == nil {
+		return internal.Receipt{}, internal.NewInvalidCartError("price history unavailable")
+	}
+
+	currency := cart.Currency
+	if currency == "" {
+		currency = internal.DefaultCurrency
+	}
+	now := cd.now()
+	if cart.At != nil {
+		now = *cart.At
+	}
+
+	receipt := internal.Receipt{
+		StoreId:   cart.StoreId,
+		At:        cart.At,
+		Lines:     []internal.ReceiptLine{},
+		Rejected:  []internal.RejectedLine{},
+		Discounts: []internal.AppliedDiscount{},
+		Subtotal:  internal.NewMoney(0, currency),
+	}
+	// products[i] is the product of receipt.Lines[i]
+	var products []internal.Product
+	// taken[id] is the quantity of product id priced by the lines so far
+	taken := map[int]int{}
+	for i, item := range cart.Items {
+		product, reason, err := cd.lookup(ctx, item)
+		if err != nil {
+			return internal.Receipt{}, err
+		}
+
+		rejected := internal.RejectedLine{Line: i, ProductId: item.ProductId, Code: item.Code, Quantity: item.Quantity, Reason: reason}
+		if reason != "" {
+			receipt.Rejected = append(receipt.Rejected, rejected)
+			continue
+		}
+
+		rejected.ProductId, rejected.Code = product.Id, product.Code
+		if !product.IsPublished {
+			rejected.Reason = internal.RejectNotPublished
+			receipt.Rejected = append(receipt.Rejected, rejected)
+			continue
+		}
+		if product.ExpiredAt(now) {
+			rejected.Reason = internal.RejectExpired
+			receipt.Rejected = append(receipt.Rejected, rejected)
+			continue
+		}
+		price := product.Price
+		if cart.At != nil {
+			if price, err = cd.prices.PriceAt(ctx, product, now); err != nil {
+				return internal.Receipt{}, err
+			}
+		}
+		unitPrice, conversion, err := cd.convert(ctx, price, currency)
+		if err != nil {
+			return internal.Receipt{}, err
+		}
+		if unitPrice.Currency != currency {
+			rejected.Reason = internal.RejectCurrencyMismatch
+			receipt.Rejected = append(receipt.Rejected, rejected)
+			continue
+		}
+		inStock, err := cd.inStock(ctx, product, cart)
+		if err != nil {
+			return internal.Receipt{}, err
+		}
+		if available := inStock - taken[product.Id]; item.Quantity > available {
+			available = max(available, 0)
+			rejected.Reason = internal.RejectInsufficientStock
+			rejected.Available = &available
+			receipt.Rejected = append(receipt.Rejected, rejected)
+			continue
+		}
+
+		taken[product.Id] += item.Quantity
+		line := internal.ReceiptLine{
+			Line:       i,
+			ProductId:  product.Id,
+			Code:       product.Code,
+			Name:       product.Name,
+			Quantity:   item.Quantity,
+			UnitPrice:  unitPrice,
+			Conversion: conversion,
+			Subtotal:   unitPrice.Mul(int64(item.Quantity)),
+			Discount:   internal.NewMoney(0, currency),
+		}
+		line.Total = line.Subtotal
+		receipt.Lines = append(receipt.Lines, line)
+		products = append(products, product)
+		receipt.ItemCount += line.Quantity
+		receipt.Subtotal = receipt.Subtotal.Add(line.Subtotal)
+	}
+
+	var promotions []internal.Promotion
+	if cd.promotions != nil {
+		var err error
+		if promotions, err = cd.promotions.GetAll(ctx); err != nil {
+			return internal.Receipt{}, err
+		}
+	}
+	applyPromotions(&receipt, products, promotions, now)
+
+	receipt.Discount = internal.NewMoney(0, currency)
+	for _, discount := range receipt.Discounts {
+		receipt.Discount = receipt.Discount.Add(discount.Amount)
+	}
+	receipt.Total = receipt.Subtotal.Sub(receipt.Discount)
+
+	jurisdiction, taxed, err := cd.jurisdiction(ctx, cart.Jurisdiction)
+	if err != nil {
+		return internal.Receipt{}, err
+	}
+	if taxed {
+		categories := make([]string, len(products))
+		for i, product := range products {
+			categories[i] = product.TaxCategory
+		}
+		applyTaxes(&receipt, jurisdiction, categories)
+	}
+	return receipt, nil
+}
+
+// store checks the store of a cart, the default location for zero, sells:
+// it must exist and not be a warehouse.
+func (cd *CartDefault) store(ctx context.Context, storeId int) error {
+	if storeId == 0 {
+		storeId = internal.DefaultLocationId
+	}
+	if cd.stock == nil && storeId != internal.DefaultLocationId {
+		return internal.NewInvalidCartError("unknown store")
+	}
+	if cd.locations == nil {
+		return nil
+	}
+
+	location, err := cd.locations.GetById(ctx, storeId)
+	if err != nil {
+		if errors.As(err, &internal.LocationNotFoundError{}) {
+			return internal.NewInvalidCartError("unknown store")
+		}
+		return err
+	}
+	if location.Kind != internal.LocationStore {
+		return internal.NewInvalidCartError("not a store")
+	}
+	return nil
+}
+
+// inStock returns the quantity of product available at the store of cart,
+// all of it being taken for carts priced as of a time.
+func (cd *CartDefault) inStock(ctx context.Context, product internal.Product, cart internal.Cart) (int, error) {
+	if cart.At != nil {
+		return math.MaxInt, nil
+	}
+	if cd.stock == nil {
+		return product.Quantity, nil
+	}
+	level, err := cd.stock.LevelAt(ctx, product.Id, cart.StoreId)
+	if err != nil {
+		if errors.As(err, &internal.LocationNotFoundError{}) {
+			return 0, internal.NewInvalidCartError("unknown store")
+		}
+		return 0, err
+	}
+	return level.Available, nil
+}
+
+// jurisdiction returns the tax jurisdiction of code, the one of the store
+// when it is empty. It reports false when the cart is not taxed: there is no
+// jurisdiction for the store, or taxes are not configured at all.
+func (cd *CartDefault) jurisdiction(ctx context.Context, code string) (internal.TaxJurisdiction, bool, error) {
+	if cd.taxes == nil {
+		if code != "" {
+			return internal.TaxJurisdiction{}, false, internal.NewInvalidCartError("unknown jurisdiction")
+		}
+		return internal.TaxJurisdiction{}, false, nil
+	}
+
+	jurisdiction, err := cd.taxes.GetByCode(ctx, code)
+	if errors.As(err, &internal.TaxJurisdictionNotFoundError{}) {
+		if code != "" {
+			return internal.TaxJurisdiction{}, false, internal.NewInvalidCartError("unknown jurisdiction")
+		}
+		return internal.TaxJurisdiction{}, false, nil
+	}
+	if err != nil {
+		return internal.TaxJurisdiction{}, false, err
+	}
+	return jurisdiction, true, nil
+}
+
+// convert returns price in currency and how it was converted, nil when it
+// already was in currency. price is returned as is when no rate converts it.
+func (cd *CartDefault) convert(ctx context.Context, price internal.Money, currency string) (internal.Money, *internal.PriceConversion, error) {
+	if price.Currency == currency || cd.exchange == nil {
+		return price, nil, nil
+	}
+
+	conversion, err := cd.exchange.Convert(ctx, price, currency)
+	if err != nil {
+		if errors.As(err, &internal.ExchangeRateNotFoundError{}) {
+			return price, nil, nil
+		}
+		return internal.Money{}, nil, err
+	}
+	return conversion.Converted, &conversion, nil
+}
+
+// lookup finds the product of item, or the reason item can not be priced.
+func (cd *CartDefault) lookup(ctx context.Context, item internal.CartItem) (internal.Product, internal.CartRejectReason, error) {
+	if item.Quantity <= 0 {
+		return internal.Product{}, internal.RejectInvalidQuantity, nil
+	}
+
+	var (
+		product internal.Product
+		err     error
+	)
+	switch {
+	case item.ProductId != 0:
+		product, err = cd.repo.GetById(ctx, item.ProductId)
+	case item.Code != "":
+		var found *internal.Product
+		if found, err = cd.repo.GetByCode(ctx, item.Code); err == nil {
+			product = *found
+		}
+	default:
+		return internal.Product{}, internal.RejectMissingProduct, nil
+	}
+	if err != nil {
+		if errors.As(err, &internal.ProductNotFoundError{}) {
+			return internal.Product{}, internal.RejectProductNotFound, nil
+		}
+		return internal.Product{}, "", err
+	}
+
+	// an id and a code must name the same product
+	if item.Code != "" && item.Code != product.Code {
+		return internal.Product{}, internal.RejectProductMismatch, nil
+	}
+	return product, "", nil
+}
@@ -0,0 +1,87 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newCartService() *service.CartDefault {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Price: usd(110), IsPublished: true},
+		2: {Id: 2, Name: "Bread", Quantity: 2, Code: "c2", Price: usd(235), IsPublished: true},
+		3: {Id: 3, Name: "Hidden", Quantity: 9, Code: "c3", Price: usd(300)},
+	}, LastID: 3}
+	return service.NewCartDefault(db, service.CartOptions{})
+}
+
+func TestQuote(t *testing.T) {
+	receipt, err := newCartService().Quote(context.Background(), internal.Cart{Items: []internal.CartItem{
+		{ProductId: 1, Quantity: 3},
+		{Code: "c2", Quantity: 2},
+		{ProductId: 1, Code: "c1", Quantity: 2},
+	}})
+	require.NoError(t, err)
+
+	require.Equal(t, []internal.ReceiptLine{
+		{Line: 0, ProductId: 1, Code: "c1", Name: "Milk", Quantity: 3, UnitPrice: usd(110), Subtotal: usd(330), Discount: usd(0), Total: usd(330)},
+		{Line: 1, ProductId: 2, Code: "c2", Name: "Bread", Quantity: 2, UnitPrice: usd(235), Subtotal: usd(470), Discount: usd(0), Total: usd(470)},
+		{Line: 2, ProductId: 1, Code: "c1", Name: "Milk", Quantity: 2, UnitPrice: usd(110), Subtotal: usd(220), Discount: usd(0), Total: usd(220)},
+	}, receipt.Lines)
+	require.Empty(t, receipt.Rejected)
+	require.Empty(t, receipt.Discounts)
+	require.Equal(t, 7, receipt.ItemCount)
+	require.Equal(t, usd(1020), receipt.Subtotal)
+	require.Equal(t, usd(1020), receipt.Total)
+}
+
+func TestQuoteRejectsLines(t *testing.T) {
+	available := func(n int) *int { return &n }
+
+	receipt, err := newCartService().Quote(context.Background(), internal.Cart{Items: []internal.CartItem{
+		{ProductId: 1, Quantity: 0},
+		{Quantity: 1},
+		{ProductId: 9, Quantity: 1},
+		{Code: "c9", Quantity: 1},
+		{ProductId: 1, Code: "c2", Quantity: 1},
+		{ProductId: 3, Quantity: 1},
+		{ProductId: 2, Quantity: 3},
+		{ProductId: 1, Quantity: 4},
+		// the stock left once the line above was priced
+		{ProductId: 1, Quantity: 2},
+	}})
+	require.NoError(t, err)
+
+	require.Equal(t, []internal.RejectedLine{
+		{Line: 0, ProductId: 1, Quantity: 0, Reason: internal.RejectInvalidQuantity},
+		{Line: 1, Quantity: 1, Reason: internal.RejectMissingProduct},
+		{Line: 2, ProductId: 9, Quantity: 1, Reason: internal.RejectProductNotFound},
+		{Line: 3, Code: "c9", Quantity: 1, Reason: internal.RejectProductNotFound},
+		{Line: 4, ProductId: 1, Code: "c2", Quantity: 1, Reason: internal.RejectProductMismatch},
+		{Line: 5, ProductId: 3, Code: "c3", Quantity: 1, Reason: internal.RejectNotPublished},
+		{Line: 6, ProductId: 2, Code: "c2", Quantity: 3, Reason: internal.RejectInsufficientStock, Available: available(2)},
+		{Line: 8, ProductId: 1, Code: "c1", Quantity: 2, Reason: internal.RejectInsufficientStock, Available: available(1)},
+	}, receipt.Rejected)
+	require.Len(t, receipt.Lines, 1)
+	require.Equal(t, 7, receipt.Lines[0].Line)
+	require.Equal(t, 4, receipt.ItemCount)
+	require.Equal(t, usd(440), receipt.Total)
+}
+
+func TestQuoteInvalidCart(t *testing.T) {
+	items := make([]internal.CartItem, internal.MaxCartItems+1)
+	for i := range items {
+		items[i] = internal.CartItem{ProductId: 1, Quantity: 1}
+	}
+
+	for _, cart := range []internal.Cart{{}, {Items: items}} {
+		_, err := newCartService().Quote(context.Background(), cart)
+		require.True(t, errors.As(err, &internal.InvalidCartError{}), err)
+	}
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"supermarket/internal"
+	"sync"
+)
+
+type CategoryDefault struct {
+	categories internal.CategoryRepository
+	products   internal.ProductRepository
+
+	// mu serializes changes to the tree, each of them reading the siblings
+	// it renumbers.
+	mu sync.Mutex
+	// deleting is held by deletions and shared by the product writes, so a
+	// category can not go away between the check of a product and its write.
+	deleting sync.RWMutex
+}
+
+// NewCategoryDefault keeps the category tree in crp and lists the products
+// of pdb in it.
+func NewCategoryDefault(crp internal.CategoryRepository, pdb internal.ProductRepository) *CategoryDefault {
+	return &CategoryDefault{categories: crp, products: pdb}
+}
+
+// Tree orders siblings by position.
+func (cd *CategoryDefault) Tree(ctx context.Context) ([]internal.CategoryNode, error) {
+	categories, err := cd.categories.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	children := childrenOf(categories)
+	var build func(parentId int) []internal.CategoryNode
+	build = func(parentId int) []internal.CategoryNode {
+		nodes := make([]internal.CategoryNode, 0, len(children[parentId]))
+		for _, child := range children[parentId] {
+			nodes = append(nodes, internal.CategoryNode{Category: child, Children: build(child.Id)})
+		}
+		return nodes
+	}
+	return build(0), nil
+}
+
+func (cd *CategoryDefault) GetById(ctx context.Context, id int) (internal.Category, error) {
+	return cd.categories.GetById(ctx, id)
+}
+
+func (cd *CategoryDefault) Save(ctx context.Context, category internal.Category) (internal.Category, error) {
+	category.Id, category.Position = 0, 0
+	category.Name = strings.TrimSpace(category.Name)
+	if err := category.Validate(); err != nil {
+		return internal.Category{}, err
+	}
+
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	categories, err := cd.categories.GetAll(ctx)
+	if err != nil {
+		return internal.Category{}, err
+	}
+	children := childrenOf(categories)
+	if err := checkParent(categories, category); err != nil {
+		return internal.Category{}, err
+	}
+	if err := checkSiblingName(children[category.ParentId], category); err != nil {
+		return internal.Category{}, err
+	}
+
+	category.Position = len(children[category.ParentId])
+	return cd.categories.Save(ctx, category)
+}
+
+func (cd *CategoryDefault) Rename(ctx context.Context, id int, name string) (internal.Category, error) {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	category, err := cd.categories.GetById(ctx, id)
+	if err != nil {
+		return internal.Category{}, err
+	}
+	category.Name = strings.TrimSpace(name)
+	if err := category.Validate(); err != nil {
+		return internal.Category{}, err
+	}
+
+	categories, err := cd.categories.GetAll(ctx)
+	if err != nil {
+		return internal.Category{}, err
+	}
+	if err := checkSiblingName(childrenOf(categories)[category.ParentId], category); err != nil {
+		return internal.Category{}, err
+	}
+
+	if err := cd.categories.Update(ctx, []internal.Category{category}); err != nil {
+		return internal.Category{}, err
+	}
+	return category, nil
+}
+
+// Move renumbers the siblings the category leaves and the ones it joins, so
+// positions stay 0 to the number of siblings minus one.
+func (cd *CategoryDefault) Move(ctx context.Context, id int, parentId int, position int) (internal.Category, error) {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	category, err := cd.categories.GetById(ctx, id)
+	if err != nil {
+		return internal.Category{}, err
+	}
+	categories, err := cd.categories.GetAll(ctx)
+	if err != nil {
+		return internal.Category{}, err
+	}
+	children := childrenOf(categories)
+
+	moved := category
+	moved.ParentId = parentId
+	if err := moved.Validate(); err != nil {
+		return internal.Category{}, err
+	}
+	if err := checkParent(categories, moved); err != nil {
+		return internal.Category{}, err
+	}
+	if parentId != category.ParentId {
+		if err := checkSiblingName(children[parentId], moved); err != nil {
+			return internal.Category{}, err
+		}
+	}
+
+	// the category can not become a descendant of itself
+	ancestor := parentId
+	for range categories {
+		if ancestor == 0 {
+			break
+		}
+		if ancestor == id {
+			return internal.Category{}, internal.NewInvalidCategoryError("parent")
+		}
+		ancestor = parentOf(categories, ancestor)
+	}
+
+	left := removeCategory(children[category.ParentId], id)
+	joined := left
+	if parentId != category.ParentId {
+		joined = children[parentId]
+	}
+	if position < 0 || position > len(joined) {
+		position = len(joined)
+	}
+	joined = append(joined[:position:position], append([]internal.Category{moved}, joined[position:]...)...)
+
+	// the category itself is stored even if its position did not change,
+	// its parent did
+	moved.Position = position
+	changed := append(removeCategory(renumber(joined), id), moved)
+	if parentId != category.ParentId {
+		changed = append(changed, renumber(left)...)
+	}
+	if err := cd.categories.Update(ctx, changed); err != nil {
+		return internal.Category{}, err
+	}
+	return moved, nil
+}
+
+// Delete renumbers the siblings left.
+func (cd *CategoryDefault) Delete(ctx context.Context, id int) error {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	cd.deleting.Lock()
+	defer cd.deleting.Unlock()
+
+	category, err := cd.categories.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	categories, err := cd.categories.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	children := childrenOf(categories)
+	if len(children[id]) > 0 {
+		return internal.NewCategoryNotEmptyError()
+	}
+
+	page, err := cd.products.GetPage(ctx, internal.ProductPageQuery{Filter: internal.CategoryFilter{Ids: []int{id}}, Limit: 1}.WithDefaults())
+	if err != nil {
+		return err
+	}
+	if page.Total > 0 {
+		return internal.NewCategoryNotEmptyError()
+	}
+
+	if err := cd.categories.Delete(ctx, id); err != nil {
+		return err
+	}
+	// a failure here leaves a gap in the positions, which sort the same
+	return cd.categories.Update(ctx, renumber(removeCategory(children[category.ParentId], id)))
+}
+
+// hold keeps the categories from being deleted until the returned func is
+// called.
+func (cd *CategoryDefault) hold() func() {
+	cd.deleting.RLock()
+	return cd.deleting.RUnlock
+}
+
+// Products filters the page query further with the category and its
+// descendants.
+func (cd *CategoryDefault) Products(ctx context.Context, id int, query internal.ProductPageQuery) (internal.ProductPage, error) {
+	if _, err := cd.categories.GetById(ctx, id); err != nil {
+		return internal.ProductPage{}, err
+	}
+	categories, err := cd.categories.GetAll(ctx)
+	if err != nil {
+		return internal.ProductPage{}, err
+	}
+
+	children := childrenOf(categories)
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			ids = append(ids, child.Id)
+		}
+	}
+
+	filter := internal.CategoryFilter{Ids: ids}
+	if query.Filter != nil {
+		query.Filter = internal.AndFilter{query.Filter, filter}
+	} else {
+		query.Filter = filter
+	}
+	query = query.WithDefaults()
+	if err := query.Validate(); err != nil {
+		return internal.ProductPage{}, err
+	}
+	return cd.products.GetPage(ctx, query)
+}
+
+// childrenOf groups categories, sorted by parent then position, by parent.
+func childrenOf(categories []internal.Category) map[int][]internal.Category {
+	children := map[int][]internal.Category{}
+	for _, category := range categories {
+		children[category.ParentId] = append(children[category.ParentId], category)
+	}
+	return children
+}
+
+// parentOf returns the parent of category id, 0 when it is not found.
+func parentOf(categories []internal.Category, id int) int {
+	for _, category := range categories {
+		if category.Id == id {
+			return category.ParentId
+		}
+	}
+	return 0
+}
+
+// checkParent fails when the parent of category is not one of categories.
+func checkParent(categories []internal.Category, category internal.Category) error {
+	if category.ParentId == 0 {
+		return nil
+	}
+	for _, c := range categories {
+		if c.Id == category.ParentId {
+			return nil
+		}
+	}
+	return internal.NewInvalidCategoryError("parent")
+}
+
+// checkSiblingName fails when one of siblings other than category has its
+// name, whatever the case.
+func checkSiblingName(siblings []internal.Category, category internal.Category) error {
+	for _, sibling := range siblings {
+		if sibling.Id != category.Id && strings.EqualFold(sibling.Name, category.Name) {
+			return internal.NewInvalidCategoryError("name is taken")
+		}
+	}
+	return nil
+}
+
+// removeCategory returns a copy of siblings without category id.
+func removeCategory(siblings []internal.Category, id int) []internal.Category {
+	left := make([]internal.Category, 0, len(siblings))
+	for _, sibling := range siblings {
+		if sibling.Id != id {
+			left = append(left, sibling)
+		}
+	}
+	return left
+}
+
+// renumber gives siblings their index as position and returns the ones
+// whose position changed.
+func renumber(siblings []internal.Category) []internal.Category {
+	var changed []internal.Category
+	for i, sibling := range siblings {
+		if sibling.Position != i {
+			sibling.Position = i
+			changed = append(changed, sibling)
+		}
+	}
+	return changed
+}
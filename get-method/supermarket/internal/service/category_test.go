@@ -0,0 +1,230 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// catalogs are the product and category repositories each backend pairs.
+var catalogs = map[string]func(t *testing.T) (internal.ProductRepository, internal.CategoryRepository){
+	"memory": func(t *testing.T) (internal.ProductRepository, internal.CategoryRepository) {
+		return &repository.ProductMapDB{Products: map[int]internal.Product{}}, repository.NewCategoryMapDB()
+	},
+	"file": func(t *testing.T) (internal.ProductRepository, internal.CategoryRepository) {
+		path := filepath.Join(t.TempDir(), "products.json")
+		db, err := repository.NewProductFileRepository(repository.NewStorage(path), path+".wal", 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		cdb, err := repository.NewCategoryFileDB(path + ".categories")
+		require.NoError(t, err)
+		return db, cdb
+	},
+	"sqlite": func(t *testing.T) (internal.ProductRepository, internal.CategoryRepository) {
+		db := openMigratedSQLite(t)
+		return repository.NewProductSQLite(db), repository.NewCategorySQL(db)
+	},
+}
+
+// names returns the names of nodes, each followed by the names of its
+// children in brackets.
+func names(nodes []internal.CategoryNode) []any {
+	var tree []any
+	for _, node := range nodes {
+		tree = append(tree, node.Name)
+		if len(node.Children) > 0 {
+			tree = append(tree, names(node.Children))
+		}
+	}
+	return tree
+}
+
+func saveCategory(t *testing.T, cs *service.CategoryDefault, name string, parentId int) internal.Category {
+	category, err := cs.Save(context.Background(), internal.Category{Name: name, ParentId: parentId})
+	require.NoError(t, err)
+	return category
+}
+
+func TestCategoryDefaultTree(t *testing.T) {
+	for name, newCatalog := range catalogs {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			pdb, crp := newCatalog(t)
+			cs := service.NewCategoryDefault(crp, pdb)
+
+			dairy := saveCategory(t, cs, "Dairy", 0)
+			cheese := saveCategory(t, cs, "Cheese", dairy.Id)
+			milk := saveCategory(t, cs, "Milk", dairy.Id)
+			bakery := saveCategory(t, cs, "Bakery", 0)
+			require.Equal(t, 1, milk.Position)
+			require.Equal(t, 1, bakery.Position)
+
+			_, err := cs.Save(ctx, internal.Category{Name: " cheese ", ParentId: dairy.Id})
+			require.ErrorAs(t, err, &internal.InvalidCategoryError{})
+			_, err = cs.Save(ctx, internal.Category{Name: "Bread", ParentId: 99})
+			require.ErrorAs(t, err, &internal.InvalidCategoryError{})
+
+			tree, err := cs.Tree(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []any{"Dairy", []any{"Cheese", "Milk"}, "Bakery"}, names(tree))
+
+			// reorder among siblings
+			_, err = cs.Move(ctx, milk.Id, dairy.Id, 0)
+			require.NoError(t, err)
+			tree, err = cs.Tree(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []any{"Dairy", []any{"Milk", "Cheese"}, "Bakery"}, names(tree))
+
+			// reparent, last by default
+			moved, err := cs.Move(ctx, milk.Id, bakery.Id, -1)
+			require.NoError(t, err)
+			require.Equal(t, internal.Category{Id: milk.Id, Name: "Milk", ParentId: bakery.Id}, moved)
+			tree, err = cs.Tree(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []any{"Dairy", []any{"Cheese"}, "Bakery", []any{"Milk"}}, names(tree))
+			require.Equal(t, 0, tree[0].Children[0].Position)
+
+			// to the top level, first
+			_, err = cs.Move(ctx, cheese.Id, 0, 0)
+			require.NoError(t, err)
+			tree, err = cs.Tree(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []any{"Cheese", "Dairy", "Bakery", []any{"Milk"}}, names(tree))
+			for i, node := range tree {
+				require.Equal(t, i, node.Position)
+			}
+
+			renamed, err := cs.Rename(ctx, cheese.Id, "Cheeses")
+			require.NoError(t, err)
+			require.Equal(t, "Cheeses", renamed.Name)
+			_, err = cs.Rename(ctx, cheese.Id, "dairy")
+			require.ErrorAs(t, err, &internal.InvalidCategoryError{})
+			_, err = cs.Rename(ctx, 99, "Other")
+			require.ErrorAs(t, err, &internal.CategoryNotFoundError{})
+		})
+	}
+}
+
+func TestCategoryDefaultMoveRejectsCycles(t *testing.T) {
+	for name, newCatalog := range catalogs {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			pdb, crp := newCatalog(t)
+			cs := service.NewCategoryDefault(crp, pdb)
+
+			dairy := saveCategory(t, cs, "Dairy", 0)
+			cheese := saveCategory(t, cs, "Cheese", dairy.Id)
+			blue := saveCategory(t, cs, "Blue", cheese.Id)
+
+			for _, parentId := range []int{dairy.Id, cheese.Id, blue.Id} {
+				_, err := cs.Move(ctx, dairy.Id, parentId, -1)
+				require.ErrorAs(t, err, &internal.InvalidCategoryError{}, parentId)
+			}
+			_, err := cs.Move(ctx, dairy.Id, 99, -1)
+			require.ErrorAs(t, err, &internal.InvalidCategoryError{})
+
+			tree, err := cs.Tree(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []any{"Dairy", []any{"Cheese", []any{"Blue"}}}, names(tree))
+		})
+	}
+}
+
+func TestCategoryDefaultDelete(t *testing.T) {
+	for name, newCatalog := range catalogs {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			pdb, crp := newCatalog(t)
+			cs := service.NewCategoryDefault(crp, pdb)
+			sv := service.NewProductDefault(pdb, service.ProductOptions{Categories: cs})
+
+			dairy := saveCategory(t, cs, "Dairy", 0)
+			cheese := saveCategory(t, cs, "Cheese", dairy.Id)
+			bakery := saveCategory(t, cs, "Bakery", 0)
+			drinks := saveCategory(t, cs, "Drinks", 0)
+
+			require.ErrorAs(t, cs.Delete(ctx, dairy.Id), &internal.CategoryNotEmptyError{})
+
+			product, err := sv.Save(ctx, internal.Product{Name: "brie", Quantity: 1, Code: "c1", Price: usd(100), CategoryIds: []int{cheese.Id}})
+			require.NoError(t, err)
+			require.ErrorAs(t, cs.Delete(ctx, cheese.Id), &internal.CategoryNotEmptyError{})
+
+			_, err = sv.PartialUpdate(ctx, product.Id, internal.Product{CategoryIds: []int{}})
+			require.NoError(t, err)
+			require.NoError(t, cs.Delete(ctx, cheese.Id))
+			require.NoError(t, cs.Delete(ctx, bakery.Id))
+			require.ErrorAs(t, cs.Delete(ctx, bakery.Id), &internal.CategoryNotFoundError{})
+
+			// the siblings left are renumbered
+			moved, err := cs.GetById(ctx, drinks.Id)
+			require.NoError(t, err)
+			require.Equal(t, 1, moved.Position)
+		})
+	}
+}
+
+func TestCategoryDefaultProducts(t *testing.T) {
+	for name, newCatalog := range catalogs {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			pdb, crp := newCatalog(t)
+			cs := service.NewCategoryDefault(crp, pdb)
+			sv := service.NewProductDefault(pdb, service.ProductOptions{Categories: cs})
+
+			dairy := saveCategory(t, cs, "Dairy", 0)
+			cheese := saveCategory(t, cs, "Cheese", dairy.Id)
+			blue := saveCategory(t, cs, "Blue", cheese.Id)
+			bakery := saveCategory(t, cs, "Bakery", 0)
+
+			save := func(name string, price int64, categoryIds ...int) internal.Product {
+				product, err := sv.Save(ctx, internal.Product{Name: name, Quantity: 1, Code: name, Price: usd(price), CategoryIds: categoryIds})
+				require.NoError(t, err)
+				return product
+			}
+			milk := save("milk", 100, dairy.Id)
+			roquefort := save("roquefort", 900, blue.Id)
+			bread := save("bread", 200, bakery.Id)
+			cake := save("cheesecake", 500, bakery.Id, cheese.Id, cheese.Id)
+			require.Equal(t, []int{cheese.Id, bakery.Id}, cake.CategoryIds)
+
+			_, err := sv.Save(ctx, internal.Product{Name: "other", Quantity: 1, Code: "other", Price: usd(100), CategoryIds: []int{99}})
+			require.ErrorAs(t, err, &internal.InvalidProductError{})
+
+			ids := func(page internal.ProductPage) []int {
+				var ids []int
+				for _, product := range page.Items {
+					ids = append(ids, product.Id)
+				}
+				return ids
+			}
+
+			page, err := cs.Products(ctx, dairy.Id, internal.ProductPageQuery{})
+			require.NoError(t, err)
+			require.Equal(t, []int{milk.Id, roquefort.Id, cake.Id}, ids(page))
+
+			page, err = cs.Products(ctx, cheese.Id, internal.ProductPageQuery{})
+			require.NoError(t, err)
+			require.Equal(t, []int{roquefort.Id, cake.Id}, ids(page))
+
+			page, err = cs.Products(ctx, dairy.Id, internal.ProductPageQuery{Filter: internal.PriceFilter{Op: internal.OpGte, Value: usd(500)}})
+			require.NoError(t, err)
+			require.Equal(t, []int{roquefort.Id, cake.Id}, ids(page))
+
+			_, err = cs.Products(ctx, 99, internal.ProductPageQuery{})
+			require.ErrorAs(t, err, &internal.CategoryNotFoundError{})
+
+			// a moved subtree takes its products along
+			_, err = cs.Move(ctx, blue.Id, bakery.Id, -1)
+			require.NoError(t, err)
+			page, err = cs.Products(ctx, bakery.Id, internal.ProductPageQuery{})
+			require.NoError(t, err)
+			require.ElementsMatch(t, []int{roquefort.Id, bread.Id, cake.Id}, ids(page))
+		})
+	}
+}
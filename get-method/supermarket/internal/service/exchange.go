@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"supermarket/internal"
+	"time"
+)
+
+type ExchangeDefault struct {
+	repo internal.ExchangeRateRepository
+	now  func() time.Time
+}
+
+// NewExchangeDefault converts amounts with the rates of rp.
+func NewExchangeDefault(rp internal.ExchangeRateRepository, now func() time.Time) *ExchangeDefault {
+	if now == nil {
+		now = time.Now
+	}
+	return &ExchangeDefault{repo: rp, now: now}
+}
+
+func (ed *ExchangeDefault) GetAll(ctx context.Context) ([]internal.ExchangeRate, error) {
+	return ed.repo.GetAll(ctx)
+}
+
+func (ed *ExchangeDefault) Put(ctx context.Context, base, quote, rate string) (internal.ExchangeRate, error) {
+	exchangeRate := internal.ExchangeRate{Base: base, Quote: quote, Rate: rate, UpdatedAt: ed.now().UTC()}
+	if err := exchangeRate.Validate(); err != nil {
+		return internal.ExchangeRate{}, err
+	}
+	if err := ed.repo.Put(ctx, exchangeRate); err != nil {
+		return internal.ExchangeRate{}, err
+	}
+	return exchangeRate, nil
+}
+
+func (ed *ExchangeDefault) Delete(ctx context.Context, base, quote string) error {
+	return ed.repo.Delete(ctx, base, quote)
+}
+
+// Convert uses the rate of the currency of m in currency, else the inverse
+// of the opposite rate, else goes through DefaultCurrency. m is returned as
+// is, at a rate of 1, when it already is in currency.
+func (ed *ExchangeDefault) Convert(ctx context.Context, m internal.Money, currency string) (internal.PriceConversion, error) {
+	if m.Currency == currency {
+		return internal.PriceConversion{Original: m, Converted: m, Rate: "1"}, nil
+	}
+
+	rate, err := ed.rate(ctx, m.Currency, currency)
+	if errors.As(err, &internal.ExchangeRateNotFoundError{}) && m.Currency != internal.DefaultCurrency && currency != internal.DefaultCurrency {
+		var first, second internal.ExchangeRate
+		if first, err = ed.rate(ctx, m.Currency, internal.DefaultCurrency); err == nil {
+			if second, err = ed.rate(ctx, internal.DefaultCurrency, currency); err == nil {
+				rate = first.Through(second)
+			}
+		}
+		if errors.As(err, &internal.ExchangeRateNotFoundError{}) {
+			err = internal.NewExchangeRateNotFoundError(m.Currency, currency)
+		}
+	}
+	if err != nil {
+		return internal.PriceConversion{}, err
+	}
+
+	return internal.PriceConversion{
+		Original:  m,
+		Converted: rate.Convert(m),
+		Rate:      rate.Rate,
+		RateTime:  rate.UpdatedAt,
+	}, nil
+}
+
+// rate returns the stored rate of base in quote, or the inverse of the one
+// of quote in base.
+func (ed *ExchangeDefault) rate(ctx context.Context, base, quote string) (internal.ExchangeRate, error) {
+	rate, err := ed.repo.Get(ctx, base, quote)
+	if !errors.As(err, &internal.ExchangeRateNotFoundError{}) {
+		return rate, err
+	}
+
+	inverse, err := ed.repo.Get(ctx, quote, base)
+	if err != nil {
+		return internal.ExchangeRate{}, err
+	}
+	return inverse.Inverse(), nil
+}
@@ -0,0 +1,110 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newExchangeService(t *testing.T, now time.Time, rates map[[2]string]string) *service.ExchangeDefault {
+	es := service.NewExchangeDefault(repository.NewExchangeRateMapDB(), func() time.Time { return now })
+	for pair, rate := range rates {
+		_, err := es.Put(context.Background(), pair[0], pair[1], rate)
+		require.NoError(t, err)
+	}
+	return es
+}
+
+func TestExchangeConvert(t *testing.T) {
+	now := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	es := newExchangeService(t, now, map[[2]string]string{
+		{"USD", "EUR"}: "0.8",
+		{"GBP", "USD"}: "1.25",
+	})
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		money     internal.Money
+		currency  string
+		converted internal.Money
+		rate      string
+	}{
+		{money: usd(1000), currency: "EUR", converted: internal.NewMoney(800, "EUR"), rate: "0.8"},
+		// the inverse of the stored rate
+		{money: internal.NewMoney(800, "EUR"), currency: "USD", converted: usd(1000), rate: "1.25"},
+		// through the default currency
+		{money: internal.NewMoney(1000, "GBP"), currency: "EUR", converted: internal.NewMoney(1000, "EUR"), rate: "1"},
+	} {
+		conversion, err := es.Convert(ctx, tc.money, tc.currency)
+		require.NoError(t, err, tc.currency)
+		require.Equal(t, internal.PriceConversion{Original: tc.money, Converted: tc.converted, Rate: tc.rate, RateTime: now}, conversion)
+	}
+
+	conversion, err := es.Convert(ctx, usd(150), "USD")
+	require.NoError(t, err)
+	require.Equal(t, internal.PriceConversion{Original: usd(150), Converted: usd(150), Rate: "1"}, conversion)
+
+	_, err = es.Convert(ctx, usd(150), "JPY")
+	require.True(t, errors.As(err, &internal.ExchangeRateNotFoundError{}))
+	_, err = es.Convert(ctx, internal.NewMoney(150, "EUR"), "JPY")
+	require.Equal(t, internal.NewExchangeRateNotFoundError("EUR", "JPY"), err)
+}
+
+func TestExchangePutAndDelete(t *testing.T) {
+	now := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	es := newExchangeService(t, now, nil)
+	ctx := context.Background()
+
+	rate, err := es.Put(ctx, "USD", "EUR", "0.92")
+	require.NoError(t, err)
+	require.Equal(t, internal.ExchangeRate{Base: "USD", Quote: "EUR", Rate: "0.92", UpdatedAt: now}, rate)
+
+	_, err = es.Put(ctx, "USD", "EUR", "-1")
+	require.True(t, errors.As(err, &internal.InvalidExchangeRateError{}))
+
+	rates, err := es.GetAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []internal.ExchangeRate{rate}, rates)
+
+	require.NoError(t, es.Delete(ctx, "USD", "EUR"))
+	require.True(t, errors.As(es.Delete(ctx, "USD", "EUR"), &internal.ExchangeRateNotFoundError{}))
+}
+
+func TestQuoteConvertsPrices(t *testing.T) {
+	now := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	es := newExchangeService(t, now, map[[2]string]string{{"USD", "EUR"}: "0.9"})
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Price: usd(125), IsPublished: true},
+		2: {Id: 2, Name: "Cheese", Quantity: 5, Code: "c2", Price: internal.NewMoney(400, "EUR"), IsPublished: true},
+		3: {Id: 3, Name: "Sake", Quantity: 5, Code: "c3", Price: internal.NewMoney(1500, "JPY"), IsPublished: true},
+	}, LastID: 3}
+	cs := service.NewCartDefault(db, service.CartOptions{Exchange: es})
+
+	receipt, err := cs.Quote(context.Background(), internal.Cart{Currency: "EUR", Items: []internal.CartItem{
+		{ProductId: 1, Quantity: 2},
+		{ProductId: 2, Quantity: 1},
+		{ProductId: 3, Quantity: 1},
+	}})
+	require.NoError(t, err)
+
+	eur := func(cents int64) internal.Money { return internal.NewMoney(cents, "EUR") }
+	require.Equal(t, []internal.ReceiptLine{
+		{
+			Line: 0, ProductId: 1, Code: "c1", Name: "Milk", Quantity: 2, UnitPrice: eur(112),
+			Conversion: &internal.PriceConversion{Original: usd(125), Converted: eur(112), Rate: "0.9", RateTime: now},
+			Subtotal:   eur(224), Discount: eur(0), Total: eur(224),
+		},
+		{Line: 1, ProductId: 2, Code: "c2", Name: "Cheese", Quantity: 1, UnitPrice: eur(400), Subtotal: eur(400), Discount: eur(0), Total: eur(400)},
+	}, receipt.Lines)
+	require.Equal(t, []internal.RejectedLine{
+		{Line: 2, ProductId: 3, Code: "c3", Quantity: 1, Reason: internal.RejectCurrencyMismatch},
+	}, receipt.Rejected)
+	require.Equal(t, eur(624), receipt.Total)
+}
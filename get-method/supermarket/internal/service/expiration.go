@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"supermarket/internal"
+	"time"
+)
+
+type ExpirationDefault struct {
+	repo internal.ProductRepository
+	uow  internal.ProductUnitOfWork
+	now  func() time.Time
+}
+
+// NewExpirationDefault watches the expiration of the products of pdb.
+func NewExpirationDefault(pdb internal.ProductRepository, now func() time.Time) *ExpirationDefault {
+	if now == nil {
+		now = time.Now
+	}
+	uow, ok := pdb.(internal.ProductUnitOfWork)
+	if !ok {
+		uow = directUnitOfWork{repo: pdb}
+	}
+	return &ExpirationDefault{repo: pdb, uow: uow, now: now}
+}
+
+// Expiring returns the products expiring before now plus within, counting
+// whole days: within 24h includes the products expiring tomorrow.
+func (ed *ExpirationDefault) Expiring(ctx context.Context, within time.Duration) ([]internal.ExpiringProduct, error) {
+	products, err := ed.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := ed.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	until := now.Add(within)
+
+	expiring := []internal.ExpiringProduct{}
+	for _, product := range products {
+		expiresOn, err := product.ExpiresOn(now.Location())
+		if err != nil || expiresOn.After(until) {
+			continue
+		}
+		expiring = append(expiring, internal.ExpiringProduct{
+			Product:  product,
+			Expired:  product.ExpiredAt(now),
+			DaysLeft: daysBetween(today, expiresOn),
+		})
+	}
+	slices.SortFunc(expiring, func(a, b internal.ExpiringProduct) int {
+		if a.DaysLeft != b.DaysLeft {
+			return a.DaysLeft - b.DaysLeft
+		}
+		return a.Id - b.Id
+	})
+	return expiring, nil
+}
+
+// UnpublishExpired skips the products changed or deleted while it runs,
+// the next run catches up with them.
+func (ed *ExpirationDefault) UnpublishExpired(ctx context.Context) ([]int, error) {
+	products, err := ed.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := ed.now()
+	unpublished := []int{}
+	for id, product := range products {
+		if !product.IsPublished || !product.ExpiredAt(now) {
+			continue
+		}
+		err := ed.uow.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+			current, err := repo.GetById(ctx, id)
+			if err != nil {
+				return err
+			}
+			if current.Version != product.Version {
+				return internal.NewProductVersionMismatchError(product.Version, current.Version)
+			}
+			current.IsPublished = false
+			_, err = repo.PartialUpdate(ctx, id, current)
+			return err
+		})
+		switch {
+		case err == nil:
+			unpublished = append(unpublished, id)
+		case errors.As(err, &internal.ProductNotFoundError{}), errors.As(err, &internal.ProductVersionMismatchError{}):
+		default:
+			return unpublished, err
+		}
+	}
+	slices.Sort(unpublished)
+	return unpublished, nil
+}
+
+// daysBetween returns the number of calendar days from from to to, both at
+// midnight.
+func daysBetween(from, to time.Time) int {
+	// daylight saving changes make some days 23 or 25 hours long
+	return int(to.Sub(from).Round(24*time.Hour) / (24 * time.Hour))
+}
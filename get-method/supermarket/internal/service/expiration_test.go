@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newExpiringProducts returns products expiring in the days around March
+// 10th 2024.
+func newExpiringProducts() *repository.ProductMapDB {
+	return &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Expiration: "09/03/2024", Price: usd(110), IsPublished: true, Version: 1},
+		2: {Id: 2, Name: "Bread", Quantity: 2, Code: "c2", Expiration: "10/03/2024", Price: usd(235), IsPublished: true, Version: 1},
+		3: {Id: 3, Name: "Cheese", Quantity: 9, Code: "c3", Expiration: "15/03/2024", Price: usd(300), IsPublished: true, Version: 1},
+		4: {Id: 4, Name: "Rice", Quantity: 9, Code: "c4", Expiration: "01/01/2025", Price: usd(300), IsPublished: true, Version: 1},
+		5: {Id: 5, Name: "Yogurt", Quantity: 9, Code: "c5", Expiration: "01/03/2024", Price: usd(90), Version: 1},
+	}, LastID: 5}
+}
+
+func TestExpiring(t *testing.T) {
+	now := time.Date(2024, 3, 10, 18, 0, 0, 0, time.UTC)
+	es := service.NewExpirationDefault(newExpiringProducts(), func() time.Time { return now })
+
+	expiring, err := es.Expiring(context.Background(), 7*24*time.Hour)
+	require.NoError(t, err)
+	var ids, daysLeft []int
+	var expired []bool
+	for _, p := range expiring {
+		ids = append(ids, p.Id)
+		daysLeft = append(daysLeft, p.DaysLeft)
+		expired = append(expired, p.Expired)
+	}
+	require.Equal(t, []int{5, 1, 2, 3}, ids)
+	require.Equal(t, []int{-9, -1, 0, 5}, daysLeft)
+	require.Equal(t, []bool{true, true, false, false}, expired)
+
+	expiring, err = es.Expiring(context.Background(), 0)
+	require.NoError(t, err)
+	require.Len(t, expiring, 3)
+}
+
+func TestUnpublishExpired(t *testing.T) {
+	db := newExpiringProducts()
+	now := time.Date(2024, 3, 10, 18, 0, 0, 0, time.UTC)
+	es := service.NewExpirationDefault(db, func() time.Time { return now })
+	ctx := context.Background()
+
+	ids, err := es.UnpublishExpired(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, ids)
+	require.False(t, db.Products[1].IsPublished)
+	require.Equal(t, 2, db.Products[1].Version)
+
+	// bread expires once its expiration date is over
+	now = time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	ids, err = es.UnpublishExpired(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, ids)
+
+	ids, err = es.UnpublishExpired(ctx)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+}
+
+func TestQuoteRejectsExpired(t *testing.T) {
+	now := time.Date(2024, 3, 10, 18, 0, 0, 0, time.UTC)
+	cs := service.NewCartDefault(newExpiringProducts(), service.CartOptions{Now: func() time.Time { return now }})
+
+	receipt, err := cs.Quote(context.Background(), internal.Cart{Items: []internal.CartItem{
+		{ProductId: 1, Quantity: 1},
+		{ProductId: 2, Quantity: 1},
+	}})
+	require.NoError(t, err)
+	require.Len(t, receipt.Lines, 1)
+	require.Equal(t, 2, receipt.Lines[0].ProductId)
+	require.Equal(t, []internal.RejectedLine{
+		{Line: 0, ProductId: 1, Code: "c1", Quantity: 1, Reason: internal.RejectExpired},
+	}, receipt.Rejected)
+}
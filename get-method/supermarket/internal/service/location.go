@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"supermarket/internal"
+)
+
+type LocationDefault struct {
+	locations internal.LocationRepository
+	stock     internal.StockRepository
+}
+
+// NewLocationDefault keeps the locations in lrp, refusing to delete the
+// ones the ledger of srp still has stock at.
+func NewLocationDefault(lrp internal.LocationRepository, srp internal.StockRepository) *LocationDefault {
+	return &LocationDefault{locations: lrp, stock: srp}
+}
+
+func (ld *LocationDefault) GetAll(ctx context.Context) ([]internal.Location, error) {
+	return ld.locations.GetAll(ctx)
+}
+
+func (ld *LocationDefault) GetById(ctx context.Context, id int) (internal.Location, error) {
+	return ld.locations.GetById(ctx, id)
+}
+
+func (ld *LocationDefault) Save(ctx context.Context, location internal.Location) (internal.Location, error) {
+	location.Id = 0
+	location.Name = strings.TrimSpace(location.Name)
+	if err := location.Validate(); err != nil {
+		return internal.Location{}, err
+	}
+	return ld.locations.Save(ctx, location)
+}
+
+func (ld *LocationDefault) Update(ctx context.Context, location internal.Location) (internal.Location, error) {
+	location.Name = strings.TrimSpace(location.Name)
+	if err := location.Validate(); err != nil {
+		return internal.Location{}, err
+	}
+	return ld.locations.Update(ctx, location)
+}
+
+// Delete keeps the movements of the location in the ledger, they record
+// where the stock was.
+func (ld *LocationDefault) Delete(ctx context.Context, id int) error {
+	if id == internal.DefaultLocationId {
+		return internal.NewLocationInUseError("default location")
+	}
+	if _, err := ld.locations.GetById(ctx, id); err != nil {
+		return err
+	}
+	stocked, err := ld.stock.Stocked(ctx, id)
+	if err != nil {
+		return err
+	}
+	if stocked {
+		return internal.NewLocationInUseError("location has stock")
+	}
+	return ld.locations.Delete(ctx, id)
+}
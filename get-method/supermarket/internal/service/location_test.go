@@ -0,0 +1,163 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStockLocations(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Price: usd(110), IsPublished: true, Version: 1},
+	}, LastID: 1}
+	ctx := context.Background()
+
+	ledger, locations := repository.NewStockMapDB(), repository.NewLocationMapDB()
+	ss := service.NewStockDefault(db, ledger, locations, nil)
+	ls := service.NewLocationDefault(locations, ledger)
+	warehouse, err := ls.Save(ctx, internal.Location{Name: "North warehouse", Kind: internal.LocationWarehouse})
+	require.NoError(t, err)
+	store, err := ls.Save(ctx, internal.Location{Name: " Corner shop ", Kind: internal.LocationStore})
+	require.NoError(t, err)
+	require.Equal(t, "Corner shop", store.Name)
+
+	// a transfer is a pair of movements leaving the quantity unchanged
+	movements, err := ss.Transfer(ctx, 1, internal.DefaultLocationId, warehouse.Id, 6, "restock")
+	require.NoError(t, err)
+	require.Len(t, movements, 2)
+	require.Equal(t, movements[0].TransferId, movements[1].TransferId)
+	require.Equal(t, -6, movements[0].Quantity)
+	require.Equal(t, warehouse.Id, movements[1].LocationId)
+	_, err = ss.Transfer(ctx, 1, warehouse.Id, store.Id, 3, "")
+	require.NoError(t, err)
+
+	level, err := ss.Level(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, internal.StockLevel{ProductId: 1, OnHand: 10, Available: 10, Locations: []internal.StockLevel{
+		{ProductId: 1, LocationId: internal.DefaultLocationId, OnHand: 4, Available: 4},
+		{ProductId: 1, LocationId: warehouse.Id, OnHand: 3, Available: 3},
+		{ProductId: 1, LocationId: store.Id, OnHand: 3, Available: 3},
+	}}, level)
+	product, err := db.GetById(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 10, product.Quantity)
+
+	// stock is only taken off where it is
+	_, err = ss.Transfer(ctx, 1, store.Id, warehouse.Id, 4, "")
+	require.Equal(t, internal.NewInsufficientStockError(1, 4, 3), err)
+	sell := movement(t, 1, internal.StockSell, 4)
+	sell.LocationId = store.Id
+	_, err = ss.Record(ctx, sell)
+	require.Equal(t, internal.NewInsufficientStockError(1, 4, 3), err)
+	reservation, err := ss.Reserve(ctx, store.Id, []internal.ReservationItem{{ProductId: 1, Quantity: 2}}, 0)
+	require.NoError(t, err)
+	_, err = ss.Reserve(ctx, store.Id, []internal.ReservationItem{{ProductId: 1, Quantity: 2}}, 0)
+	require.Equal(t, internal.NewInsufficientStockError(1, 2, 1), err)
+	sold, err := ss.Commit(ctx, reservation.Id)
+	require.NoError(t, err)
+	require.Equal(t, store.Id, sold[0].LocationId)
+
+	at, err := ss.LevelAt(ctx, 1, store.Id)
+	require.NoError(t, err)
+	require.Equal(t, internal.StockLevel{ProductId: 1, LocationId: store.Id, OnHand: 1, Available: 1}, at)
+	product, err = db.GetById(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 8, product.Quantity)
+
+	for _, tc := range []struct {
+		from, to, quantity int
+		err                error
+	}{
+		{from: store.Id, to: store.Id, quantity: 1, err: internal.NewInvalidStockMovementError("location_id")},
+		{from: 0, to: internal.DefaultLocationId, quantity: 1, err: internal.NewInvalidStockMovementError("location_id")},
+		{from: store.Id, to: warehouse.Id, quantity: 0, err: internal.NewInvalidStockMovementError("quantity")},
+		{from: store.Id, to: 9, quantity: 1, err: internal.NewLocationNotFoundError()},
+	} {
+		_, err := ss.Transfer(ctx, 1, tc.from, tc.to, tc.quantity, "")
+		require.Equal(t, tc.err, err)
+	}
+	_, err = ss.LevelAt(ctx, 1, 9)
+	require.Equal(t, internal.NewLocationNotFoundError(), err)
+
+	// locations are only deleted once empty
+	require.Equal(t, internal.NewLocationInUseError("default location"), ls.Delete(ctx, internal.DefaultLocationId))
+	require.Equal(t, internal.NewLocationInUseError("location has stock"), ls.Delete(ctx, warehouse.Id))
+	_, err = ss.Transfer(ctx, 1, warehouse.Id, store.Id, 3, "")
+	require.NoError(t, err)
+	require.NoError(t, ls.Delete(ctx, warehouse.Id))
+	require.Equal(t, internal.NewLocationNotFoundError(), ls.Delete(ctx, warehouse.Id))
+	_, err = ls.Save(ctx, internal.Location{Name: "Depot", Kind: "depot"})
+	require.Equal(t, internal.NewInvalidLocationError("kind"), err)
+}
+
+func TestOrdersAreScopedToAStore(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Price: usd(110), IsPublished: true, Version: 1},
+	}, LastID: 1}
+	ctx := context.Background()
+	now := func() time.Time { return time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC) }
+
+	ledger, locations := repository.NewStockMapDB(), repository.NewLocationMapDB()
+	ss := service.NewStockDefault(db, ledger, locations, now)
+	ls := service.NewLocationDefault(locations, ledger)
+	store, err := ls.Save(ctx, internal.Location{Name: "Corner shop", Kind: internal.LocationStore})
+	require.NoError(t, err)
+	warehouse, err := ls.Save(ctx, internal.Location{Name: "North warehouse", Kind: internal.LocationWarehouse})
+	require.NoError(t, err)
+	_, err = ss.Transfer(ctx, 1, internal.DefaultLocationId, store.Id, 3, "")
+	require.NoError(t, err)
+	_, err = ss.Transfer(ctx, 1, store.Id, warehouse.Id, 1, "")
+	require.NoError(t, err)
+	_, err = ss.Transfer(ctx, 1, internal.DefaultLocationId, store.Id, 1, "")
+	require.NoError(t, err)
+
+	carts := service.NewCartDefault(db, service.CartOptions{Stock: ss, Locations: locations, Now: now})
+	os := service.NewOrderDefault(carts, ss, repository.NewOrderMapDB(), now)
+
+	// the store only has 3 of the 10 units
+	receipt, err := carts.Quote(ctx, internal.Cart{StoreId: store.Id, Items: []internal.CartItem{{ProductId: 1, Quantity: 5}}})
+	require.NoError(t, err)
+	require.Len(t, receipt.Rejected, 1)
+	require.Equal(t, 3, *receipt.Rejected[0].Available)
+	receipt, err = carts.Quote(ctx, internal.Cart{Items: []internal.CartItem{{ProductId: 1, Quantity: 5}}})
+	require.NoError(t, err)
+	require.Empty(t, receipt.Rejected)
+
+	_, err = carts.Quote(ctx, internal.Cart{StoreId: 9, Items: []internal.CartItem{{ProductId: 1, Quantity: 1}}})
+	require.Equal(t, internal.NewInvalidCartError("unknown store"), err)
+	_, err = service.NewCartDefault(db, service.CartOptions{Now: now}).Quote(ctx, internal.Cart{StoreId: store.Id, Items: []internal.CartItem{{ProductId: 1, Quantity: 1}}})
+	require.Equal(t, internal.NewInvalidCartError("unknown store"), err)
+
+	// warehouses only keep stock, nothing is sold from them
+	warehouseCart := internal.Cart{StoreId: warehouse.Id, Items: []internal.CartItem{{ProductId: 1, Quantity: 1}}}
+	_, err = carts.Quote(ctx, warehouseCart)
+	require.Equal(t, internal.NewInvalidCartError("not a store"), err)
+	_, err = os.Checkout(ctx, warehouseCart)
+	require.Equal(t, internal.NewInvalidCartError("not a store"), err)
+	at, err := ss.LevelAt(ctx, 1, warehouse.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1, at.OnHand)
+
+	order, err := os.Checkout(ctx, internal.Cart{StoreId: store.Id, Items: []internal.CartItem{{ProductId: 1, Quantity: 2}}})
+	require.NoError(t, err)
+	require.Equal(t, store.Id, order.Receipt.StoreId)
+	at, err = ss.LevelAt(ctx, 1, store.Id)
+	require.NoError(t, err)
+	require.Equal(t, 1, at.OnHand)
+
+	// cancelling restocks the store
+	_, err = os.Transition(ctx, order.Id, internal.OrderCancelled)
+	require.NoError(t, err)
+	at, err = ss.LevelAt(ctx, 1, store.Id)
+	require.NoError(t, err)
+	require.Equal(t, 3, at.OnHand)
+	at, err = ss.LevelAt(ctx, 1, internal.DefaultLocationId)
+	require.NoError(t, err)
+	require.Equal(t, 6, at.OnHand)
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"supermarket/internal"
+	"sync"
+	"time"
+)
+
+type OrderDefault struct {
+	carts  internal.CartService
+	stock  internal.StockService
+	orders internal.OrderRepository
+	now    func() time.Time
+
+	// mu serializes transitions, so an order can not be cancelled twice and
+	// have its stock restored twice.
+	mu sync.Mutex
+}
+
+// NewOrderDefault orders the carts cs quotes from the stock of ss, keeping
+// the orders in orp.
+func NewOrderDefault(cs internal.CartService, ss internal.StockService, orp internal.OrderRepository, now func() time.Time) *OrderDefault {
+	if now == nil {
+		now = time.Now
+	}
+	return &OrderDefault{carts: cs, stock: ss, orders: orp, now: now}
+}
+
+// Checkout only orders carts every line of which can be bought. The items
+// are reserved at the store of the cart then sold in one go, so either all
+// of them are taken off its stock or none is.
+func (od *OrderDefault) Checkout(ctx context.Context, cart internal.Cart) (internal.Order, error) {
+	if cart.At != nil {
+		return internal.Order{}, internal.NewInvalidCartError("priced as of a time")
+	}
+	receipt, err := od.carts.Quote(ctx, cart)
+	if err != nil {
+		return internal.Order{}, err
+	}
+	if len(receipt.Rejected) > 0 || len(receipt.Lines) == 0 {
+		return internal.Order{}, internal.NewCheckoutRejectedError(receipt.Rejected)
+	}
+
+	reservation, err := od.stock.Reserve(ctx, receipt.StoreId, orderItems(receipt), 0)
+	if err != nil {
+		return internal.Order{}, err
+	}
+	if _, err := od.stock.Commit(ctx, reservation.Id); err != nil {
+		// the reservation expires anyway, releasing it only frees the stock
+		// sooner
+		_ = od.stock.Release(ctx, reservation.Id)
+		return internal.Order{}, err
+	}
+
+	now := od.now().UTC()
+	order, err := od.orders.Save(ctx, internal.Order{
+		Status:    internal.OrderPending,
+		Receipt:   receipt,
+		History:   []internal.OrderEvent{{Status: internal.OrderPending, At: now}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		if _, rerr := od.restock(ctx, receipt, "checkout failed"); rerr != nil {
+			return internal.Order{}, errors.Join(err, rerr)
+		}
+		return internal.Order{}, err
+	}
+	return order, nil
+}
+
+func (od *OrderDefault) GetAll(ctx context.Context, status internal.OrderStatus) ([]internal.Order, error) {
+	return od.orders.GetAll(ctx, status)
+}
+
+func (od *OrderDefault) GetById(ctx context.Context, id int) (internal.Order, error) {
+	return od.orders.GetById(ctx, id)
+}
+
+// Transition stores the new status before putting any stock back, so a
+// failed update can be retried without restocking twice. When restocking
+// fails the order and the stock are put back as they were.
+func (od *OrderDefault) Transition(ctx context.Context, id int, status internal.OrderStatus) (internal.Order, error) {
+	od.mu.Lock()
+	defer od.mu.Unlock()
+
+	order, err := od.orders.GetById(ctx, id)
+	if err != nil {
+		return internal.Order{}, err
+	}
+	if !order.Status.CanMoveTo(status) {
+		return internal.Order{}, internal.NewInvalidOrderTransitionError(order.Status, status)
+	}
+
+	previous := order
+	previous.History = slices.Clone(order.History)
+	now := od.now().UTC()
+	order.Status, order.UpdatedAt = status, now
+	order.History = append(order.History, internal.OrderEvent{Status: status, At: now})
+	updated, err := od.orders.Update(ctx, order)
+	if err != nil {
+		return internal.Order{}, err
+	}
+
+	if previous.Status.RestoresStock(status) {
+		returned, err := od.restock(ctx, order.Receipt, fmt.Sprintf("order %d %s", order.Id, status))
+		if err != nil {
+			return internal.Order{}, od.undoTransition(ctx, previous, returned, err)
+		}
+	}
+	return updated, nil
+}
+
+// undoTransition puts back previous, the order before a transition that
+// failed with err, and takes off the stock returned meanwhile.
+func (od *OrderDefault) undoTransition(ctx context.Context, previous internal.Order, returned []internal.StockMovement, err error) error {
+	ctx = context.WithoutCancel(ctx)
+
+	undoErrs := []error{err}
+	for _, movement := range returned {
+		undo := movement
+		undo.Id, undo.Kind, undo.Quantity, undo.Reason = 0, internal.StockAdjust, -movement.Quantity, "return rolled back"
+		if _, err := od.stock.Record(ctx, undo); err != nil {
+			undoErrs = append(undoErrs, err)
+		}
+	}
+	if _, err := od.orders.Update(ctx, previous); err != nil {
+		undoErrs = append(undoErrs, err)
+	}
+	return errors.Join(undoErrs...)
+}
+
+// restock puts the items of receipt back in the stock of its store, or of
+// the default location when the store was deleted since, recording why,
+// and returns the movements recorded, failing or not. Products deleted
+// since they were ordered are skipped.
+func (od *OrderDefault) restock(ctx context.Context, receipt internal.Receipt, reason string) ([]internal.StockMovement, error) {
+	var returned []internal.StockMovement
+	for _, item := range orderItems(receipt) {
+		movement, err := internal.NewStockMovement(item.ProductId, internal.StockReturn, item.Quantity, reason)
+		if err != nil {
+			return returned, err
+		}
+		movement.LocationId = receipt.StoreId
+		recorded, err := od.stock.Record(ctx, movement)
+		if errors.As(err, &internal.LocationNotFoundError{}) {
+			movement.LocationId = internal.DefaultLocationId
+			recorded, err = od.stock.Record(ctx, movement)
+		}
+		switch {
+		case err == nil:
+			returned = append(returned, recorded)
+		case !errors.As(err, &internal.ProductNotFoundError{}):
+			return returned, err
+		}
+	}
+	return returned, nil
+}
+
+// orderItems returns the quantity of each product the lines of receipt buy,
+// in the order the products first appear.
+func orderItems(receipt internal.Receipt) []internal.ReservationItem {
+	var items []internal.ReservationItem
+	index := map[int]int{}
+	for _, line := range receipt.Lines {
+		if i, ok := index[line.ProductId]; ok {
+			items[i].Quantity += line.Quantity
+			continue
+		}
+		index[line.ProductId] = len(items)
+		items = append(items, internal.ReservationItem{ProductId: line.ProductId, Quantity: line.Quantity})
+	}
+	return items
+}
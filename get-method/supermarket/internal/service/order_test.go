@@ -0,0 +1,185 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newOrderService returns an order service over products 1 and 2, with 10
+// and 3 units, and the product repository.
+func newOrderService(t *testing.T) (*service.OrderDefault, *repository.ProductMapDB) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Price: usd(110), IsPublished: true, Version: 1},
+		2: {Id: 2, Name: "Bread", Quantity: 3, Code: "c2", Price: usd(235), IsPublished: true, Version: 1},
+	}, LastID: 2}
+
+	now := func() time.Time { return time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC) }
+	stock := service.NewStockDefault(db, repository.NewStockMapDB(), nil, now)
+	carts := service.NewCartDefault(db, service.CartOptions{Now: now})
+	return service.NewOrderDefault(carts, stock, repository.NewOrderMapDB(), now), db
+}
+
+func quantity(t *testing.T, db *repository.ProductMapDB, id int) int {
+	product, err := db.GetById(context.Background(), id)
+	require.NoError(t, err)
+	return product.Quantity
+}
+
+func TestOrderCheckout(t *testing.T) {
+	os, db := newOrderService(t)
+	ctx := context.Background()
+
+	order, err := os.Checkout(ctx, internal.Cart{Items: []internal.CartItem{
+		{ProductId: 1, Quantity: 2},
+		{Code: "c2", Quantity: 3},
+		{ProductId: 1, Quantity: 1},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, 1, order.Id)
+	require.Equal(t, internal.OrderPending, order.Status)
+	require.Equal(t, usd(3*110+3*235), order.Receipt.Total)
+	require.Equal(t, []internal.OrderEvent{{Status: internal.OrderPending, At: order.CreatedAt}}, order.History)
+	require.Equal(t, 7, quantity(t, db, 1))
+	require.Equal(t, 0, quantity(t, db, 2))
+
+	// nothing is taken off the stock of carts with lines that can not be bought
+	_, err = os.Checkout(ctx, internal.Cart{Items: []internal.CartItem{
+		{ProductId: 1, Quantity: 1},
+		{ProductId: 2, Quantity: 1},
+	}})
+	var rejected internal.CheckoutRejectedError
+	require.True(t, errors.As(err, &rejected))
+	require.Len(t, rejected.Rejected, 1)
+	require.Equal(t, internal.RejectInsufficientStock, rejected.Rejected[0].Reason)
+	require.Equal(t, 7, quantity(t, db, 1))
+
+	_, err = os.Checkout(ctx, internal.Cart{})
+	require.True(t, errors.As(err, &internal.InvalidCartError{}))
+
+	orders, err := os.GetAll(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+}
+
+func TestOrderTransitions(t *testing.T) {
+	os, db := newOrderService(t)
+	ctx := context.Background()
+	checkout := func() internal.Order {
+		order, err := os.Checkout(ctx, internal.Cart{Items: []internal.CartItem{{ProductId: 1, Quantity: 4}}})
+		require.NoError(t, err)
+		return order
+	}
+
+	// cancelled orders give their stock back
+	cancelled := checkout()
+	require.Equal(t, 6, quantity(t, db, 1))
+	cancelled, err := os.Transition(ctx, cancelled.Id, internal.OrderCancelled)
+	require.NoError(t, err)
+	require.Equal(t, internal.OrderCancelled, cancelled.Status)
+	require.Len(t, cancelled.History, 2)
+	require.Equal(t, 10, quantity(t, db, 1))
+
+	_, err = os.Transition(ctx, cancelled.Id, internal.OrderCancelled)
+	require.Equal(t, internal.NewInvalidOrderTransitionError(internal.OrderCancelled, internal.OrderCancelled), err)
+	require.Equal(t, 10, quantity(t, db, 1))
+
+	// refunds give the stock back unless the order was fulfilled
+	refunded := checkout()
+	for _, status := range []internal.OrderStatus{internal.OrderPaid, internal.OrderRefunded} {
+		_, err = os.Transition(ctx, refunded.Id, status)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 10, quantity(t, db, 1))
+
+	fulfilled := checkout()
+	_, err = os.Transition(ctx, fulfilled.Id, internal.OrderFulfilled)
+	require.Equal(t, internal.NewInvalidOrderTransitionError(internal.OrderPending, internal.OrderFulfilled), err)
+	for _, status := range []internal.OrderStatus{internal.OrderPaid, internal.OrderFulfilled, internal.OrderRefunded} {
+		_, err = os.Transition(ctx, fulfilled.Id, status)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 6, quantity(t, db, 1))
+
+	orders, err := os.GetAll(ctx, internal.OrderRefunded)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+
+	_, err = os.Transition(ctx, 9, internal.OrderPaid)
+	require.True(t, errors.As(err, &internal.OrderNotFoundError{}))
+}
+
+// failingOrders fails Update while fail is set.
+type failingOrders struct {
+	internal.OrderRepository
+	fail bool
+}
+
+func (o *failingOrders) Update(ctx context.Context, order internal.Order) (internal.Order, error) {
+	if o.fail {
+		return internal.Order{}, errors.New("orders unavailable")
+	}
+	return o.OrderRepository.Update(ctx, order)
+}
+
+// failingReturns fails recording returns of product failOn.
+type failingReturns struct {
+	internal.StockService
+	failOn int
+}
+
+func (s *failingReturns) Record(ctx context.Context, movement internal.StockMovement) (internal.StockMovement, error) {
+	if movement.Kind == internal.StockReturn && movement.ProductId == s.failOn {
+		return internal.StockMovement{}, errors.New("ledger unavailable")
+	}
+	return s.StockService.Record(ctx, movement)
+}
+
+func TestOrderTransitionFailures(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Price: usd(110), IsPublished: true, Version: 1},
+		2: {Id: 2, Name: "Bread", Quantity: 3, Code: "c2", Price: usd(235), IsPublished: true, Version: 1},
+	}, LastID: 2}
+	now := func() time.Time { return time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC) }
+	stock := &failingReturns{StockService: service.NewStockDefault(db, repository.NewStockMapDB(), nil, now)}
+	orders := &failingOrders{OrderRepository: repository.NewOrderMapDB()}
+	os := service.NewOrderDefault(service.NewCartDefault(db, service.CartOptions{Now: now}), stock, orders, now)
+	ctx := context.Background()
+
+	order, err := os.Checkout(ctx, internal.Cart{Items: []internal.CartItem{{ProductId: 1, Quantity: 4}, {ProductId: 2, Quantity: 1}}})
+	require.NoError(t, err)
+
+	// the status is stored first, no stock comes back when it can not be
+	orders.fail = true
+	_, err = os.Transition(ctx, order.Id, internal.OrderCancelled)
+	require.EqualError(t, err, "orders unavailable")
+	require.Equal(t, 6, quantity(t, db, 1))
+
+	// when the stock can not come back, neither does what came back already
+	orders.fail = false
+	stock.failOn = 2
+	_, err = os.Transition(ctx, order.Id, internal.OrderCancelled)
+	require.EqualError(t, err, "ledger unavailable")
+	require.Equal(t, 6, quantity(t, db, 1))
+	stored, err := os.GetById(ctx, order.Id)
+	require.NoError(t, err)
+	require.Equal(t, internal.OrderPending, stored.Status)
+	require.Len(t, stored.History, 1)
+
+	// the retry gives back the stock once, as returns
+	stock.failOn = 0
+	_, err = os.Transition(ctx, order.Id, internal.OrderCancelled)
+	require.NoError(t, err)
+	require.Equal(t, 10, quantity(t, db, 1))
+	require.Equal(t, 3, quantity(t, db, 2))
+	movements, err := stock.Movements(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, internal.StockReturn, movements[len(movements)-1].Kind)
+}
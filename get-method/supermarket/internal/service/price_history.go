@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"supermarket/internal"
+	"sync"
+	"time"
+)
+
+type PriceHistoryDefault struct {
+	products internal.ProductRepository
+	uow      internal.ProductUnitOfWork
+	history  internal.PriceHistoryRepository
+	now      func() time.Time
+
+	// mu serializes applying the scheduled changes with cancelling them, so
+	// a change is never cancelled while it is being applied.
+	mu sync.Mutex
+}
+
+// NewPriceHistoryDefault keeps the price history of the products of pdb in
+// hrp.
+func NewPriceHistoryDefault(pdb internal.ProductRepository, hrp internal.PriceHistoryRepository, now func() time.Time) *PriceHistoryDefault {
+	if now == nil {
+		now = time.Now
+	}
+	uow, ok := pdb.(internal.ProductUnitOfWork)
+	if !ok {
+		uow = directUnitOfWork{repo: pdb}
+	}
+	return &PriceHistoryDefault{products: pdb, uow: uow, history: hrp, now: now}
+}
+
+func (hd *PriceHistoryDefault) History(ctx context.Context, productId int) ([]internal.PriceChange, error) {
+	if _, err := hd.products.GetById(ctx, productId); err != nil {
+		return nil, err
+	}
+	return hd.history.History(ctx, productId)
+}
+
+func (hd *PriceHistoryDefault) Schedule(ctx context.Context, change internal.PriceChange) (internal.PriceChange, error) {
+	now := hd.now().UTC()
+	change.Id, change.Previous, change.Pending, change.CreatedAt = 0, nil, true, now
+	if err := change.Validate(); err != nil {
+		return internal.PriceChange{}, err
+	}
+	if !change.EffectiveFrom.After(now) {
+		return internal.PriceChange{}, internal.NewInvalidPriceChangeError("effective_from")
+	}
+	change.EffectiveFrom = change.EffectiveFrom.UTC()
+	if _, err := hd.products.GetById(ctx, change.ProductId); err != nil {
+		return internal.PriceChange{}, err
+	}
+	return hd.history.Save(ctx, change)
+}
+
+func (hd *PriceHistoryDefault) Cancel(ctx context.Context, productId int, id int) error {
+	hd.mu.Lock()
+	defer hd.mu.Unlock()
+
+	change, err := hd.history.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if change.ProductId != productId {
+		return internal.NewPriceChangeNotFoundError()
+	}
+	if !change.Pending {
+		return internal.NewPriceChangeNotPendingError()
+	}
+	return hd.history.Delete(ctx, id)
+}
+
+func (hd *PriceHistoryDefault) PriceAt(ctx context.Context, product internal.Product, t time.Time) (internal.Money, error) {
+	history, err := hd.history.History(ctx, product.Id)
+	if err != nil {
+		return internal.Money{}, err
+	}
+	return internal.PriceAt(history, product.Price, t), nil
+}
+
+// ApplyDue sets the price of each product to its latest change effective
+// now, when that is a scheduled one. Scheduled changes superseded by a
+// later one, or by a price set on the product since they took effect, are
+// only marked as no longer pending. The pending changes of deleted products
+// are dropped.
+func (hd *PriceHistoryDefault) ApplyDue(ctx context.Context) ([]int, error) {
+	hd.mu.Lock()
+	defer hd.mu.Unlock()
+
+	now := hd.now().UTC()
+	pending, err := hd.history.Pending(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	// due holds the pending changes of each product, oldest effective first
+	due := map[int][]internal.PriceChange{}
+	var productIds []int
+	for _, change := range pending {
+		if _, ok := due[change.ProductId]; !ok {
+			productIds = append(productIds, change.ProductId)
+		}
+		due[change.ProductId] = append(due[change.ProductId], change)
+	}
+
+	repriced := []int{}
+	for _, productId := range productIds {
+		ok, err := hd.apply(ctx, productId, due[productId], now)
+		if err != nil {
+			return repriced, err
+		}
+		if ok {
+			repriced = append(repriced, productId)
+		}
+	}
+	return repriced, nil
+}
+
+// apply applies the pending changes of product id due at now and reports
+// whether its price changed.
+func (hd *PriceHistoryDefault) apply(ctx context.Context, id int, due []internal.PriceChange, now time.Time) (bool, error) {
+	history, err := hd.history.History(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	var latest internal.PriceChange
+	for _, change := range history {
+		if change.EffectiveFrom.After(now) {
+			break
+		}
+		latest = change
+	}
+
+	var previous internal.Money
+	repriced := false
+	if latest.Pending {
+		err = hd.uow.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+			product, err := repo.GetById(ctx, id)
+			if err != nil {
+				return err
+			}
+			previous = product.Price
+			if product.Price == latest.Price {
+				return nil
+			}
+			product.Price = latest.Price
+			_, err = repo.PartialUpdate(ctx, id, product)
+			repriced = err == nil
+			return err
+		})
+	} else {
+		_, err = hd.products.GetById(ctx, id)
+	}
+	if errors.As(err, &internal.ProductNotFoundError{}) {
+		for _, change := range due {
+			if err := hd.history.Delete(ctx, change.Id); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, change := range due {
+		change.Pending = false
+		if change.Id == latest.Id {
+			change.Previous = &previous
+		}
+		if _, err := hd.history.Update(ctx, change); err != nil {
+			return repriced, err
+		}
+	}
+	return repriced, nil
+}
+
+// recordPrice adds the price set on product id at now to hrp, previous
+// being the price it replaced, if any.
+func recordPrice(ctx context.Context, hrp internal.PriceHistoryRepository, id int, previous *internal.Money, price internal.Money, now time.Time) (internal.PriceChange, error) {
+	return hrp.Save(ctx, internal.PriceChange{
+		ProductId:     id,
+		Price:         price,
+		Previous:      previous,
+		EffectiveFrom: now,
+		CreatedAt:     now,
+	})
+}
@@ -0,0 +1,266 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// prices returns the price of each of changes.
+func prices(changes []internal.PriceChange) []internal.Money {
+	prices := make([]internal.Money, len(changes))
+	for i, change := range changes {
+		prices[i] = change.Price
+	}
+	return prices
+}
+
+func TestPriceHistory(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{}}
+	history := repository.NewPriceHistoryMapDB()
+	ctx := context.Background()
+
+	now := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	ps := service.NewProductDefault(db, service.ProductOptions{History: history, Now: clock})
+	hs := service.NewPriceHistoryDefault(db, history, clock)
+
+	product, err := ps.Save(ctx, internal.Product{Name: "Milk", Quantity: 5, Code: "c1", Expiration: "10/03/2030", Price: usd(110)})
+	require.NoError(t, err)
+
+	// only the updates changing the price are recorded
+	now = now.Add(time.Hour)
+	_, err = ps.PartialUpdate(ctx, product.Id, internal.Product{Price: usd(120)})
+	require.NoError(t, err)
+	_, err = ps.PartialUpdate(ctx, product.Id, internal.Product{Name: "Whole milk"})
+	require.NoError(t, err)
+
+	changes, err := hs.History(ctx, product.Id)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Money{usd(110), usd(120)}, prices(changes))
+	require.Nil(t, changes[0].Previous)
+	require.Equal(t, usd(110), *changes[1].Previous)
+	require.Equal(t, now, changes[1].EffectiveFrom)
+
+	// scheduled changes take effect once applied
+	monday := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	scheduled, err := hs.Schedule(ctx, internal.PriceChange{ProductId: product.Id, Price: usd(99), EffectiveFrom: monday})
+	require.NoError(t, err)
+	require.True(t, scheduled.Pending)
+	later, err := hs.Schedule(ctx, internal.PriceChange{ProductId: product.Id, Price: usd(130), EffectiveFrom: monday.AddDate(0, 0, 7)})
+	require.NoError(t, err)
+
+	repriced, err := hs.ApplyDue(ctx)
+	require.NoError(t, err)
+	require.Empty(t, repriced)
+
+	now = monday.Add(time.Minute)
+	repriced, err = hs.ApplyDue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int{product.Id}, repriced)
+	product, err = db.GetById(ctx, product.Id)
+	require.NoError(t, err)
+	require.Equal(t, usd(99), product.Price)
+
+	applied, err := history.GetById(ctx, scheduled.Id)
+	require.NoError(t, err)
+	require.False(t, applied.Pending)
+	require.Equal(t, usd(120), *applied.Previous)
+	require.Equal(t, internal.NewPriceChangeNotPendingError(), hs.Cancel(ctx, product.Id, scheduled.Id))
+
+	// the price at any time, scheduled changes included
+	for at, price := range map[time.Time]internal.Money{
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC): usd(110),
+		monday.Add(-time.Minute):                    usd(120),
+		monday:                                      usd(99),
+		monday.AddDate(0, 0, 8):                     usd(130),
+	} {
+		got, err := hs.PriceAt(ctx, product, at)
+		require.NoError(t, err)
+		require.Equal(t, price, got, at)
+	}
+
+	require.Equal(t, internal.NewPriceChangeNotFoundError(), hs.Cancel(ctx, 9, later.Id))
+	require.NoError(t, hs.Cancel(ctx, product.Id, later.Id))
+	changes, err = hs.History(ctx, product.Id)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Money{usd(110), usd(120), usd(99)}, prices(changes))
+
+	for _, change := range []internal.PriceChange{
+		{ProductId: product.Id, Price: usd(100), EffectiveFrom: now},
+		{ProductId: product.Id, Price: usd(0), EffectiveFrom: now.Add(time.Hour)},
+		{ProductId: product.Id, Price: internal.NewMoney(100, "usd"), EffectiveFrom: now.Add(time.Hour)},
+	} {
+		_, err := hs.Schedule(ctx, change)
+		require.ErrorAs(t, err, &internal.InvalidPriceChangeError{})
+	}
+	_, err = hs.Schedule(ctx, internal.PriceChange{ProductId: 9, Price: usd(100), EffectiveFrom: now.Add(time.Hour)})
+	require.ErrorAs(t, err, &internal.ProductNotFoundError{})
+	_, err = hs.History(ctx, 9)
+	require.ErrorAs(t, err, &internal.ProductNotFoundError{})
+}
+
+func TestPriceHistoryKeepsLaterPrices(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Price: usd(110), Version: 1},
+		2: {Id: 2, Name: "Bread", Quantity: 5, Code: "c2", Price: usd(235), Version: 1},
+	}, LastID: 2}
+	history := repository.NewPriceHistoryMapDB()
+	ctx := context.Background()
+
+	now := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	ps := service.NewProductDefault(db, service.ProductOptions{History: history, Now: clock})
+	hs := service.NewPriceHistoryDefault(db, history, clock)
+
+	for _, change := range []internal.PriceChange{
+		{ProductId: 1, Price: usd(100), EffectiveFrom: now.Add(time.Hour)},
+		{ProductId: 2, Price: usd(200), EffectiveFrom: now.Add(time.Hour)},
+	} {
+		_, err := hs.Schedule(ctx, change)
+		require.NoError(t, err)
+	}
+
+	// a price set once the change took effect, but before it was applied,
+	// wins over it
+	now = now.Add(2 * time.Hour)
+	_, err := ps.PartialUpdate(ctx, 1, internal.Product{Price: usd(150)})
+	require.NoError(t, err)
+	require.NoError(t, db.Delete(ctx, 2))
+
+	repriced, err := hs.ApplyDue(ctx)
+	require.NoError(t, err)
+	require.Empty(t, repriced)
+	product, err := db.GetById(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, usd(150), product.Price)
+
+	pending, err := history.Pending(ctx, now)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+	changes, err := history.History(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Money{usd(100), usd(150)}, prices(changes))
+
+	// before its history the product had the price the update replaced
+	got, err := hs.PriceAt(ctx, product, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, usd(110), got)
+}
+
+// failingHistory fails saving price changes while fail is set.
+type failingHistory struct {
+	internal.PriceHistoryRepository
+	fail bool
+}
+
+func (h *failingHistory) Save(ctx context.Context, change internal.PriceChange) (internal.PriceChange, error) {
+	if h.fail {
+		return internal.PriceChange{}, errors.New("history unavailable")
+	}
+	return h.PriceHistoryRepository.Save(ctx, change)
+}
+
+// failingCommit runs units of work whose commit fails while fail is set.
+type failingCommit struct {
+	*repository.ProductMapDB
+	fail bool
+}
+
+func (c *failingCommit) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repo internal.ProductRepository) error) error {
+	if !c.fail {
+		return c.ProductMapDB.WithinTransaction(ctx, fn)
+	}
+	return c.ProductMapDB.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+		if err := fn(ctx, repo); err != nil {
+			return err
+		}
+		return errors.New("commit failed")
+	})
+}
+
+func TestPriceHistoryWithinUnitOfWork(t *testing.T) {
+	db := &failingCommit{ProductMapDB: &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Expiration: "10/03/2030", Price: usd(110), Version: 1},
+	}, LastID: 1}}
+	history := &failingHistory{PriceHistoryRepository: repository.NewPriceHistoryMapDB()}
+	ctx := context.Background()
+	ps := service.NewProductDefault(db, service.ProductOptions{History: history})
+
+	// a price that can not be recorded is not set
+	history.fail = true
+	_, err := ps.PartialUpdate(ctx, 1, internal.Product{Price: usd(120)})
+	require.EqualError(t, err, "history unavailable")
+	_, err = ps.Save(ctx, internal.Product{Name: "Bread", Quantity: 5, Code: "c2", Expiration: "10/03/2030", Price: usd(235)})
+	require.EqualError(t, err, "history unavailable")
+
+	product, err := db.GetById(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, usd(110), product.Price)
+	_, err = db.GetByCode(ctx, "c2")
+	require.ErrorAs(t, err, &internal.ProductNotFoundError{})
+
+	// nor is a price recorded when the product can not be saved
+	history.fail = false
+	db.fail = true
+	product.Price = usd(130)
+	_, err = ps.UpdateOrCreate(ctx, product)
+	require.EqualError(t, err, "commit failed")
+
+	changes, err := history.History(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	db.fail = false
+	_, err = ps.UpdateOrCreate(ctx, product)
+	require.NoError(t, err)
+	changes, err = history.History(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []internal.Money{usd(130)}, prices(changes))
+}
+
+func TestCartQuotesAsOf(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 1, Code: "c1", Expiration: "10/03/2030", Price: usd(110), IsPublished: true, Version: 1},
+	}, LastID: 1}
+	history := repository.NewPriceHistoryMapDB()
+	ctx := context.Background()
+
+	now := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	_, err := service.NewProductDefault(db, service.ProductOptions{History: history, Now: clock}).PartialUpdate(ctx, 1, internal.Product{Price: usd(150)})
+	require.NoError(t, err)
+	hs := service.NewPriceHistoryDefault(db, history, clock)
+	_, err = hs.Schedule(ctx, internal.PriceChange{ProductId: 1, Price: usd(90), EffectiveFrom: now.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+
+	cs := service.NewCartDefault(db, service.CartOptions{Prices: hs, Now: clock})
+	for at, total := range map[time.Time]int64{
+		now.AddDate(0, 0, -1): 220,
+		now:                   300,
+		now.AddDate(0, 0, 2):  180,
+	} {
+		at := at
+		// past quotes are not checked against the stock
+		receipt, err := cs.Quote(ctx, internal.Cart{At: &at, Items: []internal.CartItem{{ProductId: 1, Quantity: 2}}})
+		require.NoError(t, err)
+		require.Empty(t, receipt.Rejected)
+		require.Equal(t, usd(total), receipt.Total, at)
+		require.Equal(t, at, *receipt.At)
+	}
+
+	at := now.AddDate(0, 0, -1)
+	os := service.NewOrderDefault(cs, service.NewStockDefault(db, repository.NewStockMapDB(), nil, clock), repository.NewOrderMapDB(), clock)
+	_, err = os.Checkout(ctx, internal.Cart{At: &at, Items: []internal.CartItem{{ProductId: 1, Quantity: 1}}})
+	require.ErrorAs(t, err, &internal.InvalidCartError{})
+
+	_, err = service.NewCartDefault(db, service.CartOptions{Now: clock}).Quote(ctx, internal.Cart{At: &at, Items: []internal.CartItem{{ProductId: 1, Quantity: 1}}})
+	require.Equal(t, internal.NewInvalidCartError("price history unavailable"), err)
+}
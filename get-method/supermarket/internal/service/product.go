@@ -1,123 +1,431 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"slices"
+	"strings"
 	"supermarket/internal"
+	"supermarket/internal/search"
 	"time"
 )
 
 type ProductDefault struct {
+	repo       internal.ProductRepository
+	uow        internal.ProductUnitOfWork
+	searcher   internal.ProductSearcher
+	categories *CategoryDefault
+	history    internal.PriceHistoryRepository
+	ledger     internal.StockRepository
+	now        func() time.Time
+}
+
+// ProductOptions are the optional dependencies of a ProductDefault.
+type ProductOptions struct {
+	// Categories checks the categories of the products, which can have none
+	// without it, and keeps them while the products are written.
+	Categories *CategoryDefault
+	// History keeps the prices set on the products, none is kept without it.
+	History internal.PriceHistoryRepository
+	// Ledger is the stock ledger. The quantity of a product with a ledger
+	// can only change through stock movements.
+	Ledger internal.StockRepository
+	// Now is the clock, time.Now when it is nil.
+	Now func() time.Time
+}
+
+// NewProductDefault uses pdb as its unit of work and its searcher when it
+// implements them.
+func NewProductDefault(pdb internal.ProductRepository, opts ProductOptions) *ProductDefault {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	uow, ok := pdb.(internal.ProductUnitOfWork)
+	if !ok {
+		uow = directUnitOfWork{repo: pdb}
+	}
+	searcher, ok := pdb.(internal.ProductSearcher)
+	if !ok {
+		searcher = scanSearcher{repo: pdb}
+	}
+	return &ProductDefault{repo: pdb, uow: uow, searcher: searcher, categories: opts.Categories, history: opts.History, ledger: opts.Ledger, now: now}
+}
+
+// directUnitOfWork runs the operations straight against repo.
+type directUnitOfWork struct {
 	repo internal.ProductRepository
 }
 
-func NewProductDefault(pdb internal.ProductRepository) *ProductDefault {
-	return &ProductDefault{repo: pdb}
+func (u directUnitOfWork) WithinTransaction(ctx context.Context, fn func(ctx context.Context, repo internal.ProductRepository) error) error {
+	return fn(ctx, u.repo)
 }
 
-func (pd *ProductDefault) CheckUniqueCode(code string) (bool, error) {
-	_, err := pd.repo.GetByCode(code)
+// scanSearcher searches a throwaway index of every product of repo.
+type scanSearcher struct {
+	repo internal.ProductRepository
+}
+
+func (s scanSearcher) SearchNames(ctx context.Context, text string, limit int) ([]internal.ProductSearchResult, int, error) {
+	products, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	index := search.NewIndex()
+	for _, product := range products {
+		index.Put(product)
+	}
+	results, total := index.Search(text, limit)
+	return results, total, nil
+}
+
+func (pd *ProductDefault) CheckUniqueCode(ctx context.Context, code string) (bool, error) {
+	return checkUniqueCode(ctx, pd.repo, code, 0)
+}
+
+// checkUniqueCode reports whether no product other than id uses code.
+func checkUniqueCode(ctx context.Context, repo internal.ProductRepository, code string, id int) (bool, error) {
+	product, err := repo.GetByCode(ctx, code)
 	if err != nil {
 		if errors.As(err, &internal.ProductNotFoundError{}) {
 			return true, nil
 		}
 		return false, err
 	}
-	return false, nil
+	return product.Id == id, nil
 }
 
-func (pd *ProductDefault) Save(product internal.Product) internal.Product {
-	return pd.repo.Save(product)
+// checkVersion fails when an expected version is set and differs from the
+// version of stored.
+func checkVersion(stored internal.Product, expected int) error {
+	if expected != 0 && stored.Version != expected {
+		return internal.NewProductVersionMismatchError(expected, stored.Version)
+	}
+	return nil
 }
 
-func (pd *ProductDefault) GetAll() (map[int]internal.Product, error) {
-	return pd.repo.GetAll()
+// holdCategories keeps the categories from being deleted while a product is
+// checked and written, until the returned func is called.
+func (pd *ProductDefault) holdCategories() func() {
+	if pd.categories == nil {
+		return func() {}
+	}
+	return pd.categories.hold()
 }
 
-func (pd *ProductDefault) GetById(id int) (internal.Product, error) {
-	return pd.repo.GetById(id)
+// checkCategories sorts the category ids of product and fails with
+// internal.InvalidProductError when one of them is not a category. The
+// caller must hold the categories.
+func (pd *ProductDefault) checkCategories(ctx context.Context, product *internal.Product) error {
+	if len(product.CategoryIds) == 0 {
+		return nil
+	}
+	if pd.categories == nil {
+		return internal.NewInvalidProductError("category_ids")
+	}
+
+	ids := slices.Clone(product.CategoryIds)
+	slices.Sort(ids)
+	product.CategoryIds = slices.Compact(ids)
+	for _, id := range product.CategoryIds {
+		if _, err := pd.categories.GetById(ctx, id); err != nil {
+			if errors.As(err, &internal.CategoryNotFoundError{}) {
+				return internal.NewInvalidProductError("category_ids")
+			}
+			return err
+		}
+	}
+	return nil
 }
 
-func (pd *ProductDefault) GetByGreaterPrice(price float64) ([]internal.Product, error) {
-	return pd.repo.GetByGreaterPrice(price)
+// checkQuantity fails with internal.InvalidProductError when quantity
+// differs from the quantity of stored and stored has a stock ledger, which
+// sets it, or when it is none and stored has no ledger. stored is the zero
+// product for a new one.
+func (pd *ProductDefault) checkQuantity(ctx context.Context, stored internal.Product, quantity int) error {
+	opened := false
+	if pd.ledger != nil && stored.Id != 0 {
+		var err error
+		if _, opened, err = pd.ledger.OnHand(ctx, stored.Id); err != nil {
+			return err
+		}
+	}
+	switch {
+	case opened && quantity != stored.Quantity:
+		return internal.NewInvalidProductError("quantity is kept by the stock ledger")
+	case !opened && quantity == 0:
+		return internal.NewInvalidProductError("quantity")
+	}
+	return nil
 }
 
-func (pd *ProductDefault) UpdateOrCreate(product internal.Product) (internal.Product, error) {
-	if err := product.Validate(); err != nil {
+// Save stores a new product, failing with internal.ProductAlreadyExistsError
+// when its code is taken.
+func (pd *ProductDefault) Save(ctx context.Context, product internal.Product) (internal.Product, error) {
+	defer pd.holdCategories()()
+
+	if err := pd.checkCategories(ctx, &product); err != nil {
 		return internal.Product{}, err
 	}
-	return pd.repo.UpdateOrCreate(product)
-}
 
-func (pd *ProductDefault) PartialUpdate(id int, product internal.Product) (internal.Product, error) {
-	dbProduct, err := pd.repo.GetById(id)
+	var (
+		saved    internal.Product
+		recorded internal.PriceChange
+	)
+	err := pd.uow.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+		unique, err := checkUniqueCode(ctx, repo, product.Code, 0)
+		if err != nil {
+			return err
+		}
+		if !unique {
+			return internal.NewProductAlreadyExistsError()
+		}
+
+		if saved, err = repo.Save(ctx, product); err != nil {
+			return err
+		}
+		recorded, err = pd.recordPrice(ctx, saved, nil)
+		return err
+	})
 	if err != nil {
-		return internal.Product{}, internal.NewProductNotFoundError()
+		return internal.Product{}, pd.dropPrice(ctx, recorded, err)
 	}
+	return saved, nil
+}
 
-	if product.Name == "" {
-		product.Name = dbProduct.Name
+// recordPrice adds the price of product to its history when it is not
+// previous, the price it had before, if any. It is the last step of the unit
+// of work saving product, so a history that can not be written fails the
+// save, and returns the change recorded, the zero change when there is none.
+func (pd *ProductDefault) recordPrice(ctx context.Context, product internal.Product, previous *internal.Money) (internal.PriceChange, error) {
+	if pd.history == nil || (previous != nil && *previous == product.Price) {
+		return internal.PriceChange{}, nil
 	}
+	return recordPrice(ctx, pd.history, product.Id, previous, product.Price, pd.now().UTC())
+}
 
-	if product.Quantity == 0 {
-		product.Quantity = dbProduct.Quantity
+// dropPrice deletes recorded from the history after the unit of work that
+// recorded it failed with err, and returns err.
+func (pd *ProductDefault) dropPrice(ctx context.Context, recorded internal.PriceChange, err error) error {
+	if recorded.Id == 0 {
+		return err
+	}
+	if derr := pd.history.Delete(context.WithoutCancel(ctx), recorded.Id); derr != nil {
+		return errors.Join(err, derr)
 	}
+	return err
+}
 
-	if product.Code == "" {
-		product.Code = dbProduct.Code
-	} else {
-		p, err := pd.repo.GetByCode(product.Code)
-		if err == nil && p.Id != id {
-			return internal.Product{}, internal.NewInvalidProductError("code is not unique")
-		}
+func (pd *ProductDefault) GetAll(ctx context.Context) (map[int]internal.Product, error) {
+	return pd.repo.GetAll(ctx)
+}
+
+func (pd *ProductDefault) GetPage(ctx context.Context, query internal.ProductPageQuery) (internal.ProductPage, error) {
+	query = query.WithDefaults()
+	if err := query.Validate(); err != nil {
+		return internal.ProductPage{}, err
 	}
+	return pd.repo.GetPage(ctx, query)
+}
 
-	if product.Price == 0 {
-		product.Price = dbProduct.Price
+func (pd *ProductDefault) GetById(ctx context.Context, id int) (internal.Product, error) {
+	return pd.repo.GetById(ctx, id)
+}
+
+func (pd *ProductDefault) GetByGreaterPrice(ctx context.Context, price internal.Money) ([]internal.Product, error) {
+	return pd.repo.GetByGreaterPrice(ctx, price)
+}
+
+func (pd *ProductDefault) UpdateOrCreate(ctx context.Context, product internal.Product) (internal.Product, error) {
+	if err := product.ValidateDetails(); err != nil {
+		return internal.Product{}, err
 	}
+	defer pd.holdCategories()()
 
-	if product.IsPublished == false {
-		product.IsPublished = dbProduct.IsPublished
+	if err := pd.checkCategories(ctx, &product); err != nil {
+		return internal.Product{}, err
 	}
 
-	if product.Expiration == "" {
-		product.Expiration = dbProduct.Expiration
-	} else {
-		_, err := time.Parse("02/01/2006", product.Expiration)
+	var (
+		saved internal.Product
+		// previous is the price of the product replaced, if any
+		previous *internal.Money
+		recorded internal.PriceChange
+	)
+	err := pd.uow.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+		// a product that does not exist yet matches no version
+		var stored internal.Product
+		if product.Id != 0 {
+			var err error
+			if stored, err = repo.GetById(ctx, product.Id); err != nil && !errors.As(err, &internal.ProductNotFoundError{}) {
+				return err
+			}
+			if err == nil {
+				previous = &stored.Price
+			}
+		}
+		if err := pd.checkQuantity(ctx, stored, product.Quantity); err != nil {
+			return err
+		}
+		if product.Version != 0 {
+			if err := checkVersion(stored, product.Version); err != nil {
+				return err
+			}
+		}
+
+		unique, err := checkUniqueCode(ctx, repo, product.Code, product.Id)
 		if err != nil {
-			return internal.Product{}, internal.NewInvalidProductError("expiration")
+			return err
 		}
+		if !unique {
+			return internal.NewInvalidProductError("code is not unique")
+		}
+
+		if saved, err = repo.UpdateOrCreate(ctx, product); err != nil {
+			return err
+		}
+		recorded, err = pd.recordPrice(ctx, saved, previous)
+		return err
+	})
+	if err != nil {
+		return internal.Product{}, pd.dropPrice(ctx, recorded, err)
 	}
-	return pd.repo.PartialUpdate(id, product)
+	return saved, nil
 }
 
-func (pd *ProductDefault) Delete(id int) error {
-	return pd.repo.Delete(id)
-}
+// PartialUpdate fills the zero fields of product from the stored one and
+// saves the result. Reading, checking and writing happen in one unit of work
+// so a concurrent write can not invalidate the checks.
+func (pd *ProductDefault) PartialUpdate(ctx context.Context, id int, product internal.Product) (internal.Product, error) {
+	defer pd.holdCategories()()
 
-func (pd *ProductDefault) GetTotalPrice(productIds []int) (float64, error) {
-	var products []internal.Product
-	if len(productIds) == 0 {
-		productsMap, err := pd.repo.GetAll()
+	var (
+		updated  internal.Product
+		previous internal.Money
+		recorded internal.PriceChange
+	)
+	err := pd.uow.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+		dbProduct, err := repo.GetById(ctx, id)
 		if err != nil {
-			return 0, err
+			return err
+		}
+		previous = dbProduct.Price
+		if err := checkVersion(dbProduct, product.Version); err != nil {
+			return err
 		}
 
-		for _, product := range productsMap {
-			products = append(products, product)
+		if product.Name == "" {
+			product.Name = dbProduct.Name
 		}
-	} else {
-		for _, id := range productIds {
-			product, err := pd.repo.GetById(id)
+
+		if product.Quantity == 0 {
+			product.Quantity = dbProduct.Quantity
+		} else if err := pd.checkQuantity(ctx, dbProduct, product.Quantity); err != nil {
+			return err
+		}
+
+		if product.Code == "" {
+			product.Code = dbProduct.Code
+		} else {
+			unique, err := checkUniqueCode(ctx, repo, product.Code, id)
 			if err != nil {
-				return 0, err
+				return err
+			}
+			if !unique {
+				return internal.NewInvalidProductError("code is not unique")
 			}
-			products = append(products, product)
 		}
+
+		if product.Price == (internal.Money{}) {
+			product.Price = dbProduct.Price
+		}
+
+		if product.TaxCategory == "" {
+			product.TaxCategory = dbProduct.TaxCategory
+		}
+
+		// an empty list removes the product from its categories
+		if product.CategoryIds == nil {
+			product.CategoryIds = dbProduct.CategoryIds
+		} else if err := pd.checkCategories(ctx, &product); err != nil {
+			return err
+		}
+
+		if product.IsPublished == false {
+			product.IsPublished = dbProduct.IsPublished
+		}
+
+		if product.Expiration == "" {
+			product.Expiration = dbProduct.Expiration
+		} else {
+			_, err := time.Parse("02/01/2006", product.Expiration)
+			if err != nil {
+				return internal.NewInvalidProductError("expiration")
+			}
+		}
+
+		if updated, err = repo.PartialUpdate(ctx, id, product); err != nil {
+			return err
+		}
+		recorded, err = pd.recordPrice(ctx, updated, &previous)
+		return err
+	})
+	if err != nil {
+		return internal.Product{}, pd.dropPrice(ctx, recorded, err)
 	}
+	return updated, nil
+}
 
-	totalPrice := 0.0
-	for _, product := range products {
-		totalPrice += product.Price
+func (pd *ProductDefault) Delete(ctx context.Context, id int, version int) error {
+	if version == 0 {
+		return pd.repo.Delete(ctx, id)
+	}
+
+	return pd.uow.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+		product, err := repo.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(product, version); err != nil {
+			return err
+		}
+		return repo.Delete(ctx, id)
+	})
+}
+
+func (pd *ProductDefault) Search(ctx context.Context, text string, limit int) ([]internal.ProductSearchResult, int, error) {
+	if limit == 0 {
+		limit = internal.DefaultSearchLimit
+	}
+	if limit < 1 || limit > internal.MaxSearchLimit {
+		return nil, 0, internal.NewInvalidPageQueryError("limit")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, 0, internal.NewInvalidPageQueryError("q")
+	}
+	return pd.searcher.SearchNames(ctx, text, limit)
+}
+
+// GetTotalPrice sums the unit prices of productIds, an id listed twice
+// counting twice. It predates CartDefault.Quote and checks neither stock nor
+// publication. Prices in several currencies make it fail with
+// internal.InvalidCartError.
+func (pd *ProductDefault) GetTotalPrice(ctx context.Context, productIds []int) (internal.Money, error) {
+	if len(productIds) == 0 {
+		return internal.Money{}, internal.NewInvalidCartError("cart is empty")
+	}
+
+	var totalPrice internal.Money
+	for _, id := range productIds {
+		product, err := pd.repo.GetById(ctx, id)
+		if err != nil {
+			return internal.Money{}, err
+		}
+		if totalPrice != (internal.Money{}) && product.Price.Currency != totalPrice.Currency {
+			return internal.Money{}, internal.NewInvalidCartError("prices in several currencies")
+		}
+		totalPrice = totalPrice.Add(product.Price)
 	}
 
 	return totalPrice, nil
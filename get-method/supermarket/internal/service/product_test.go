@@ -0,0 +1,193 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"supermarket/platform/database/migration"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usd returns an amount of cents of the default currency.
+func usd(cents int64) internal.Money {
+	return internal.NewMoney(cents, internal.DefaultCurrency)
+}
+
+const workers = 16
+
+func openMigratedSQLite(t *testing.T) *sql.DB {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "supermarket.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := repository.Migrations(repository.DialectSQLite)
+	require.NoError(t, err)
+	migrator, err := migration.NewMigrator(db, migrations)
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	return db
+}
+
+func newSQLiteRepository(t *testing.T) internal.ProductRepository {
+	return repository.NewProductSQLite(openMigratedSQLite(t))
+}
+
+var repositories = map[string]func(t *testing.T) internal.ProductRepository{
+	"memory": func(t *testing.T) internal.ProductRepository {
+		return &repository.ProductMapDB{Products: map[int]internal.Product{}}
+	},
+	"file": func(t *testing.T) internal.ProductRepository {
+		path := filepath.Join(t.TempDir(), "products.json")
+		db, err := repository.NewProductFileRepository(repository.NewStorage(path), path+".wal", 0)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return db
+	},
+	"sqlite": newSQLiteRepository,
+}
+
+// race runs fn from several goroutines at once and returns how many calls
+// succeeded.
+func race(t *testing.T, fn func(worker int) error) int {
+	var (
+		wg        sync.WaitGroup
+		start     = make(chan struct{})
+		succeeded atomic.Int32
+	)
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			<-start
+
+			err := fn(worker)
+			if err == nil {
+				succeeded.Add(1)
+				return
+			}
+			assert.True(t, errors.As(err, &internal.ProductAlreadyExistsError{}) || errors.As(err, &internal.InvalidProductError{}), err)
+		}(worker)
+	}
+	close(start)
+	wg.Wait()
+	return int(succeeded.Load())
+}
+
+func requireUniqueCodes(t *testing.T, repo internal.ProductRepository) {
+	products, err := repo.GetAll(context.Background())
+	require.NoError(t, err)
+
+	codes := map[string]int{}
+	for _, product := range products {
+		codes[product.Code]++
+		require.Equal(t, 1, codes[product.Code], "code %s is used twice", product.Code)
+	}
+}
+
+func TestProductDefaultConcurrentSaveKeepsCodesUnique(t *testing.T) {
+	for name, newRepository := range repositories {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepository(t)
+			sv := service.NewProductDefault(repo, service.ProductOptions{})
+
+			succeeded := race(t, func(worker int) error {
+				_, err := sv.Save(context.Background(), internal.Product{Name: fmt.Sprintf("p%d", worker), Quantity: 1, Code: "same", Price: usd(100)})
+				return err
+			})
+			require.Equal(t, 1, succeeded)
+			requireUniqueCodes(t, repo)
+		})
+	}
+}
+
+func TestProductDefaultConcurrentPartialUpdateKeepsCodesUnique(t *testing.T) {
+	for name, newRepository := range repositories {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepository(t)
+			sv := service.NewProductDefault(repo, service.ProductOptions{})
+
+			ids := make([]int, workers)
+			for worker := range ids {
+				product, err := sv.Save(context.Background(), internal.Product{Name: fmt.Sprintf("p%d", worker), Quantity: 1, Code: fmt.Sprintf("c%d", worker), Price: usd(100)})
+				require.NoError(t, err)
+				ids[worker] = product.Id
+			}
+
+			succeeded := race(t, func(worker int) error {
+				_, err := sv.PartialUpdate(context.Background(), ids[worker], internal.Product{Code: "same"})
+				return err
+			})
+			require.Equal(t, 1, succeeded)
+			requireUniqueCodes(t, repo)
+		})
+	}
+}
+
+func TestProductDefaultPartialUpdateKeepsUnsetFields(t *testing.T) {
+	repo := &repository.ProductMapDB{Products: map[int]internal.Product{}}
+	sv := service.NewProductDefault(repo, service.ProductOptions{})
+
+	product, err := sv.Save(context.Background(), internal.Product{Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), Expiration: "01/02/2065"})
+	require.NoError(t, err)
+
+	updated, err := sv.PartialUpdate(context.Background(), product.Id, internal.Product{Price: usd(500)})
+	require.NoError(t, err)
+	product.Price = usd(500)
+	product.Version = 2
+	require.Equal(t, product, updated)
+
+	_, err = sv.PartialUpdate(context.Background(), product.Id+1, internal.Product{Price: usd(500)})
+	require.ErrorAs(t, err, &internal.ProductNotFoundError{})
+}
+
+func TestProductDefaultRejectsStaleVersion(t *testing.T) {
+	for name, newRepository := range repositories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			sv := service.NewProductDefault(newRepository(t), service.ProductOptions{})
+
+			product, err := sv.Save(ctx, internal.Product{Name: "p1", Quantity: 1, Code: "c1", Price: usd(100), Expiration: "01/02/2065"})
+			require.NoError(t, err)
+			require.Equal(t, 1, product.Version)
+
+			patched, err := sv.PartialUpdate(ctx, product.Id, internal.Product{Name: "patched", Version: 1})
+			require.NoError(t, err)
+			require.Equal(t, 2, patched.Version)
+
+			_, err = sv.PartialUpdate(ctx, product.Id, internal.Product{Name: "stale", Version: 1})
+			require.ErrorAs(t, err, &internal.ProductVersionMismatchError{})
+
+			product.Name = "stale"
+			product.Version = 1
+			_, err = sv.UpdateOrCreate(ctx, product)
+			require.ErrorAs(t, err, &internal.ProductVersionMismatchError{})
+
+			product.Version = 2
+			updated, err := sv.UpdateOrCreate(ctx, product)
+			require.NoError(t, err)
+			require.Equal(t, 3, updated.Version)
+
+			require.ErrorAs(t, sv.Delete(ctx, product.Id, 2), &internal.ProductVersionMismatchError{})
+			require.NoError(t, sv.Delete(ctx, product.Id, 3))
+
+			// an unconditional write ignores versions
+			product.Version = 0
+			created, err := sv.UpdateOrCreate(ctx, product)
+			require.NoError(t, err)
+			require.Equal(t, 1, created.Version)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"supermarket/internal"
+)
+
+type PromotionDefault struct {
+	repo internal.PromotionRepository
+}
+
+func NewPromotionDefault(rp internal.PromotionRepository) *PromotionDefault {
+	return &PromotionDefault{repo: rp}
+}
+
+func (pd *PromotionDefault) GetAll(ctx context.Context) ([]internal.Promotion, error) {
+	promotions, err := pd.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sortPromotions(promotions)
+	return promotions, nil
+}
+
+func (pd *PromotionDefault) GetById(ctx context.Context, id int) (internal.Promotion, error) {
+	return pd.repo.GetById(ctx, id)
+}
+
+func (pd *PromotionDefault) Save(ctx context.Context, promotion internal.Promotion) (internal.Promotion, error) {
+	if err := promotion.Validate(); err != nil {
+		return internal.Promotion{}, err
+	}
+	return pd.repo.Save(ctx, promotion)
+}
+
+func (pd *PromotionDefault) Update(ctx context.Context, promotion internal.Promotion) (internal.Promotion, error) {
+	if err := promotion.Validate(); err != nil {
+		return internal.Promotion{}, err
+	}
+	return pd.repo.Update(ctx, promotion)
+}
+
+func (pd *PromotionDefault) Delete(ctx context.Context, id int) error {
+	return pd.repo.Delete(ctx, id)
+}
+
+// sortPromotions sorts promotions in the order they apply: by decreasing
+// priority, then by id.
+func sortPromotions(promotions []internal.Promotion) {
+	slices.SortFunc(promotions, func(a, b internal.Promotion) int {
+		if c := cmp.Compare(b.Priority, a.Priority); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Id, b.Id)
+	})
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"supermarket/internal"
+	"time"
+)
+
+// pricedLine is a receipt line going through the promotions.
+type pricedLine struct {
+	*internal.ReceiptLine
+	product internal.Product
+	// discounted is set once a promotion discounted the line and locked once
+	// a promotion that does not stack did.
+	discounted bool
+	locked     bool
+}
+
+// remaining is what is left to pay for the line.
+func (l *pricedLine) remaining() internal.Money {
+	return l.Subtotal.Sub(l.Discount)
+}
+
+// applyPromotions discounts the lines of receipt with the promotions active
+// at now, then the basket, recording each discount granted. products holds
+// the product of each line. Discounts are rounded to the minor unit half to
+// even, each line on its own.
+func applyPromotions(receipt *internal.Receipt, products []internal.Product, promotions []internal.Promotion, now time.Time) {
+	lines := make([]*pricedLine, len(receipt.Lines))
+	for i := range receipt.Lines {
+		lines[i] = &pricedLine{ReceiptLine: &receipt.Lines[i], product: products[i]}
+	}
+
+	active := make([]internal.Promotion, 0, len(promotions))
+	for _, promotion := range promotions {
+		if promotion.ActiveOn(now) {
+			active = append(active, promotion)
+		}
+	}
+	sortPromotions(active)
+
+	for _, promotion := range active {
+		if promotion.Basket() {
+			continue
+		}
+
+		var eligible []*pricedLine
+		for _, line := range lines {
+			if !line.locked && (promotion.Stackable || !line.discounted) {
+				eligible = append(eligible, line)
+			}
+		}
+
+		discounts := lineDiscounts(promotion, eligible, now)
+		applied := internal.AppliedDiscount{
+			PromotionId: promotion.Id,
+			Name:        promotion.Name,
+			Kind:        promotion.Kind,
+			Amount:      internal.NewMoney(0, receipt.Subtotal.Currency),
+			Description: describePromotion(promotion),
+		}
+		for _, line := range eligible {
+			ratio, ok := discounts[line]
+			if !ok {
+				continue
+			}
+			discount := line.remaining().MulRat(ratio, internal.RoundHalfEven)
+			if !discount.IsPositive() {
+				continue
+			}
+			line.Discount = line.Discount.Add(discount)
+			line.discounted = true
+			line.locked = !promotion.Stackable
+			applied.Lines = append(applied.Lines, line.Line)
+			applied.Amount = applied.Amount.Add(discount)
+		}
+		if len(applied.Lines) > 0 {
+			receipt.Discounts = append(receipt.Discounts, applied)
+		}
+	}
+
+	total := internal.NewMoney(0, receipt.Subtotal.Currency)
+	for _, line := range lines {
+		line.Total = line.remaining()
+		total = total.Add(line.Total)
+	}
+
+	basketDiscounted := false
+	for _, promotion := range active {
+		if !promotion.Basket() || (basketDiscounted && !promotion.Stackable) {
+			continue
+		}
+		// thresholds only make sense in their own currency
+		if promotion.Threshold.Currency != total.Currency || total.Cmp(promotion.Threshold) < 0 {
+			continue
+		}
+
+		discount := promotion.Amount
+		if promotion.Percent > 0 {
+			discount = total.Percent(promotion.Percent, internal.RoundHalfEven)
+		}
+		if discount.Cmp(total) > 0 {
+			discount = total
+		}
+		if !discount.IsPositive() {
+			continue
+		}
+		total = total.Sub(discount)
+		receipt.Discounts = append(receipt.Discounts, internal.AppliedDiscount{
+			PromotionId: promotion.Id,
+			Name:        promotion.Name,
+			Kind:        promotion.Kind,
+			Amount:      discount,
+			Description: describePromotion(promotion),
+		})
+
+		basketDiscounted = true
+		if !promotion.Stackable {
+			break
+		}
+	}
+}
+
+// lineDiscounts returns the part of what is left to pay for each of lines
+// that promotion takes off, at most all of it.
+func lineDiscounts(promotion internal.Promotion, lines []*pricedLine, now time.Time) map[*pricedLine]*big.Rat {
+	discounts := map[*pricedLine]*big.Rat{}
+
+	var targeted []*pricedLine
+	for _, line := range lines {
+		if targets(promotion, line.product, now) {
+			targeted = append(targeted, line)
+		}
+	}
+
+	switch promotion.Kind {
+	case internal.PromotionPercentOff:
+		for _, line := range targeted {
+			discounts[line] = internal.PercentRat(promotion.Percent)
+		}
+	case internal.PromotionMultiBuy:
+		// lines of the same product add up towards the offer
+		var (
+			ids   []int
+			byId  = map[int][]*pricedLine{}
+			units = map[int]int{}
+		)
+		for _, line := range targeted {
+			if _, ok := byId[line.ProductId]; !ok {
+				ids = append(ids, line.ProductId)
+			}
+			byId[line.ProductId] = append(byId[line.ProductId], line)
+			units[line.ProductId] += line.Quantity
+		}
+		for _, id := range ids {
+			free := units[id] / promotion.Buy * (promotion.Buy - promotion.Pay)
+			discountUnits(discounts, byId[id], free, big.NewRat(1, 1))
+		}
+	case internal.PromotionBuyXGetY:
+		var (
+			rewards        []*pricedLine
+			triggerUnits   int
+			rewardUnits    int
+			rewardTargeted bool
+		)
+		for _, line := range lines {
+			if line.ProductId == promotion.GetProductId {
+				rewards = append(rewards, line)
+				rewardUnits += line.Quantity
+			}
+		}
+		for _, line := range targeted {
+			if line.ProductId == promotion.GetProductId {
+				rewardTargeted = true
+				continue
+			}
+			triggerUnits += line.Quantity
+		}
+
+		part := big.NewRat(1, 1)
+		if promotion.Percent != 0 {
+			part = internal.PercentRat(promotion.Percent)
+		}
+		discountUnits(discounts, rewards, min(rewardUnits, givenUnits(promotion, triggerUnits, rewardUnits, rewardTargeted)), part)
+	}
+	return discounts
+}
+
+// givenUnits is the number of units a buy_x_get_y promotion gives for the
+// trigger units bought. When the given product also triggers the promotion
+// its units either trigger it or are given, each group of Buy+Get of them
+// giving Get.
+func givenUnits(promotion internal.Promotion, triggerUnits, rewardUnits int, rewardTargeted bool) int {
+	if !rewardTargeted {
+		return triggerUnits / promotion.Buy * promotion.Get
+	}
+
+	pool := triggerUnits + rewardUnits
+	group := promotion.Buy + promotion.Get
+	given := pool / group * promotion.Get
+	// a last group short of some of its given units
+	if left := pool % group; left > promotion.Buy {
+		given += left - promotion.Buy
+	}
+	return given
+}
+
+// discountUnits takes part of the price of units units of lines off, from
+// the last line backwards.
+func discountUnits(discounts map[*pricedLine]*big.Rat, lines []*pricedLine, units int, part *big.Rat) {
+	for i := len(lines) - 1; i >= 0 && units > 0; i-- {
+		line := lines[i]
+		n := min(units, line.Quantity)
+		ratio := new(big.Rat).Mul(big.NewRat(int64(n), int64(line.Quantity)), part)
+		if current, ok := discounts[line]; ok {
+			ratio.Add(ratio, current)
+		}
+		discounts[line] = ratio
+		units -= n
+	}
+}
+
+// targets reports whether the line promotion applies to product at now.
+func targets(promotion internal.Promotion, product internal.Product, now time.Time) bool {
+	if len(promotion.ProductIds) > 0 && !slices.Contains(promotion.ProductIds, product.Id) {
+		return false
+	}
+	if promotion.ExpiringWithinDays == 0 {
+		return true
+	}
+
+	expiration, err := time.Parse("02/01/2006", product.Expiration)
+	if err != nil {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(expiration.Sub(today).Hours() / 24)
+	return !expiration.Before(today) && days <= promotion.ExpiringWithinDays
+}
+
+func describePromotion(promotion internal.Promotion) string {
+	var description string
+	switch promotion.Kind {
+	case internal.PromotionMultiBuy:
+		description = fmt.Sprintf("%d for %d", promotion.Buy, promotion.Pay)
+	case internal.PromotionPercentOff:
+		description = fmt.Sprintf("%g%% off", promotion.Percent)
+	case internal.PromotionBuyXGetY:
+		if promotion.Percent == 0 || promotion.Percent == 100 {
+			description = fmt.Sprintf("buy %d get %d of product %d free", promotion.Buy, promotion.Get, promotion.GetProductId)
+		} else {
+			description = fmt.Sprintf("buy %d get %d of product %d at %g%% off", promotion.Buy, promotion.Get, promotion.GetProductId, promotion.Percent)
+		}
+	case internal.PromotionBasketThreshold:
+		if promotion.Percent > 0 {
+			return fmt.Sprintf("%g%% off baskets of %s %s or more", promotion.Percent, promotion.Threshold, promotion.Threshold.Currency)
+		}
+		return fmt.Sprintf("%s %s off baskets of %s %s or more", promotion.Amount, promotion.Amount.Currency, promotion.Threshold, promotion.Threshold.Currency)
+	}
+
+	if promotion.ExpiringWithinDays > 0 {
+		description += fmt.Sprintf(" on items expiring within %d days", promotion.ExpiringWithinDays)
+	}
+	return description
+}
@@ -0,0 +1,203 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// today is the day the promotion tests quote carts on.
+var today = time.Date(2024, time.March, 10, 15, 0, 0, 0, time.UTC)
+
+func quoteWithPromotions(t *testing.T, items []internal.CartItem, promotions ...internal.Promotion) internal.Receipt {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 20, Code: "c1", Price: usd(100), IsPublished: true, Expiration: "14/03/2024"},
+		2: {Id: 2, Name: "Bread", Quantity: 20, Code: "c2", Price: usd(200), IsPublished: true, Expiration: "30/03/2024"},
+		3: {Id: 3, Name: "Jam", Quantity: 20, Code: "c3", Price: usd(400), IsPublished: true, Expiration: "01/01/2025"},
+	}, LastID: 3}
+
+	prp := repository.NewPromotionMapDB()
+	ps := service.NewPromotionDefault(prp)
+	for _, promotion := range promotions {
+		_, err := ps.Save(context.Background(), promotion)
+		require.NoError(t, err)
+	}
+
+	cs := service.NewCartDefault(db, service.CartOptions{Promotions: prp, Now: func() time.Time { return today }})
+	receipt, err := cs.Quote(context.Background(), internal.Cart{Items: items})
+	require.NoError(t, err)
+	return receipt
+}
+
+func TestQuoteMultiBuy(t *testing.T) {
+	// the two milk lines add up to six units, two of them free
+	receipt := quoteWithPromotions(t, []internal.CartItem{
+		{ProductId: 1, Quantity: 4},
+		{ProductId: 2, Quantity: 2},
+		{ProductId: 1, Quantity: 2},
+	}, internal.Promotion{Name: "3 for 2", Kind: internal.PromotionMultiBuy, ProductIds: []int{1}, Buy: 3, Pay: 2})
+
+	require.Equal(t, []internal.AppliedDiscount{
+		{PromotionId: 1, Name: "3 for 2", Kind: internal.PromotionMultiBuy, Lines: []int{2}, Amount: usd(200), Description: "3 for 2"},
+	}, receipt.Discounts)
+	require.Equal(t, usd(200), receipt.Lines[2].Discount)
+	require.Equal(t, usd(0), receipt.Lines[2].Total)
+	require.Equal(t, usd(1000), receipt.Subtotal)
+	require.Equal(t, usd(200), receipt.Discount)
+	require.Equal(t, usd(800), receipt.Total)
+}
+
+func TestQuotePercentOffExpiring(t *testing.T) {
+	// only the milk expires within a week
+	receipt := quoteWithPromotions(t, []internal.CartItem{
+		{ProductId: 1, Quantity: 3},
+		{ProductId: 2, Quantity: 1},
+	}, internal.Promotion{Name: "Expiring", Kind: internal.PromotionPercentOff, ExpiringWithinDays: 7, Percent: 10})
+
+	require.Equal(t, []internal.AppliedDiscount{
+		{PromotionId: 1, Name: "Expiring", Kind: internal.PromotionPercentOff, Lines: []int{0}, Amount: usd(30), Description: "10% off on items expiring within 7 days"},
+	}, receipt.Discounts)
+	require.Equal(t, usd(470), receipt.Total)
+}
+
+func TestQuoteBuyXGetY(t *testing.T) {
+	// two breads give a free jar of jam, the second jar is paid
+	receipt := quoteWithPromotions(t, []internal.CartItem{
+		{ProductId: 2, Quantity: 3},
+		{ProductId: 3, Quantity: 2},
+	}, internal.Promotion{Name: "Breakfast", Kind: internal.PromotionBuyXGetY, ProductIds: []int{2}, Buy: 2, Get: 1, GetProductId: 3})
+
+	require.Len(t, receipt.Discounts, 1)
+	require.Equal(t, []int{1}, receipt.Discounts[0].Lines)
+	require.Equal(t, usd(400), receipt.Discount)
+	require.Equal(t, usd(1000), receipt.Total)
+
+	// buy two get one of the same product: five units hold one full group
+	// and a group short of its given unit
+	for units, free := range map[int]internal.Money{2: usd(0), 3: usd(100), 5: usd(100), 6: usd(200)} {
+		receipt := quoteWithPromotions(t, []internal.CartItem{
+			{ProductId: 1, Quantity: units},
+		}, internal.Promotion{Name: "Milk", Kind: internal.PromotionBuyXGetY, ProductIds: []int{1}, Buy: 2, Get: 1, GetProductId: 1})
+		require.Equal(t, free, receipt.Discount, units)
+	}
+}
+
+func TestQuoteBasketThreshold(t *testing.T) {
+	items := []internal.CartItem{{ProductId: 3, Quantity: 3}}
+
+	// the threshold applies to the total once the line promotions applied
+	receipt := quoteWithPromotions(t, items,
+		internal.Promotion{Name: "Jam", Kind: internal.PromotionPercentOff, ProductIds: []int{3}, Percent: 25},
+		internal.Promotion{Name: "Big basket", Kind: internal.PromotionBasketThreshold, Threshold: usd(1000), Amount: usd(500)},
+	)
+	require.Equal(t, usd(300), receipt.Discount)
+	require.Equal(t, usd(900), receipt.Total)
+
+	receipt = quoteWithPromotions(t, items,
+		internal.Promotion{Name: "Big basket", Kind: internal.PromotionBasketThreshold, Threshold: usd(1000), Percent: 50},
+	)
+	require.Equal(t, []internal.AppliedDiscount{
+		{PromotionId: 1, Name: "Big basket", Kind: internal.PromotionBasketThreshold, Amount: usd(600), Description: "50% off baskets of 10.00 USD or more"},
+	}, receipt.Discounts)
+	require.Equal(t, usd(600), receipt.Total)
+}
+
+func TestQuoteStacking(t *testing.T) {
+	items := []internal.CartItem{{ProductId: 3, Quantity: 1}}
+
+	for _, tc := range []struct {
+		name       string
+		promotions []internal.Promotion
+		applied    []string
+		total      internal.Money
+	}{
+		{
+			name: "exclusive promotions give way to the highest priority",
+			promotions: []internal.Promotion{
+				{Name: "low", Kind: internal.PromotionPercentOff, Percent: 50, Priority: 1},
+				{Name: "high", Kind: internal.PromotionPercentOff, Percent: 25, Priority: 2},
+			},
+			applied: []string{"high"},
+			total:   usd(300),
+		},
+		{
+			name: "stackable promotions discount what is left",
+			promotions: []internal.Promotion{
+				{Name: "first", Kind: internal.PromotionPercentOff, Percent: 50, Priority: 2, Stackable: true},
+				{Name: "second", Kind: internal.PromotionPercentOff, Percent: 50, Priority: 1, Stackable: true},
+			},
+			applied: []string{"first", "second"},
+			total:   usd(100),
+		},
+		{
+			name: "an exclusive promotion locks the lines it discounted",
+			promotions: []internal.Promotion{
+				{Name: "exclusive", Kind: internal.PromotionPercentOff, Percent: 25, Priority: 2},
+				{Name: "stackable", Kind: internal.PromotionPercentOff, Percent: 50, Priority: 1, Stackable: true},
+			},
+			applied: []string{"exclusive"},
+			total:   usd(300),
+		},
+		{
+			name: "an exclusive promotion skips discounted lines",
+			promotions: []internal.Promotion{
+				{Name: "stackable", Kind: internal.PromotionPercentOff, Percent: 50, Priority: 2, Stackable: true},
+				{Name: "exclusive", Kind: internal.PromotionPercentOff, Percent: 25, Priority: 1},
+			},
+			applied: []string{"stackable"},
+			total:   usd(200),
+		},
+		{
+			name: "promotions out of their dates do not apply",
+			promotions: []internal.Promotion{
+				{Name: "past", Kind: internal.PromotionPercentOff, Percent: 50, EndsAt: "09/03/2024"},
+				{Name: "future", Kind: internal.PromotionPercentOff, Percent: 50, StartsAt: "11/03/2024"},
+				{Name: "today", Kind: internal.PromotionPercentOff, Percent: 25, StartsAt: "10/03/2024", EndsAt: "10/03/2024"},
+			},
+			applied: []string{"today"},
+			total:   usd(300),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			receipt := quoteWithPromotions(t, items, tc.promotions...)
+
+			var applied []string
+			for _, discount := range receipt.Discounts {
+				applied = append(applied, discount.Name)
+			}
+			require.Equal(t, tc.applied, applied)
+			require.Equal(t, tc.total, receipt.Total)
+		})
+	}
+}
+
+func TestPromotionValidation(t *testing.T) {
+	ps := service.NewPromotionDefault(repository.NewPromotionMapDB())
+
+	for field, promotion := range map[string]internal.Promotion{
+		"name":      {Kind: internal.PromotionPercentOff, Percent: 10},
+		"kind":      {Name: "p", Kind: "free_lunch"},
+		"pay":       {Name: "p", Kind: internal.PromotionMultiBuy, Buy: 3, Pay: 3},
+		"percent":   {Name: "p", Kind: internal.PromotionPercentOff, Percent: 120},
+		"get":       {Name: "p", Kind: internal.PromotionBuyXGetY, Buy: 1, GetProductId: 1},
+		"threshold": {Name: "p", Kind: internal.PromotionBasketThreshold, Amount: usd(100)},
+		"amount":    {Name: "p", Kind: internal.PromotionBasketThreshold, Threshold: usd(1000), Amount: usd(100), Percent: 10},
+		"ends_at":   {Name: "p", Kind: internal.PromotionPercentOff, Percent: 10, StartsAt: "02/01/2024", EndsAt: "01/01/2024"},
+		"starts_at": {Name: "p", Kind: internal.PromotionPercentOff, Percent: 10, StartsAt: "2024-01-01"},
+	} {
+		_, err := ps.Save(context.Background(), promotion)
+		var invalid internal.InvalidPromotionError
+		require.True(t, errors.As(err, &invalid), field)
+		require.Equal(t, field, invalid.Field)
+	}
+
+	_, err := ps.Update(context.Background(), internal.Promotion{Id: 9, Name: "p", Kind: internal.PromotionPercentOff, Percent: 10})
+	require.True(t, errors.As(err, &internal.PromotionNotFoundError{}))
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"supermarket/internal"
+	"sync"
+	"time"
+)
+
+type PurchaseOrderDefault struct {
+	suppliers internal.SupplierRepository
+	stock     internal.StockService
+	orders    internal.PurchaseOrderRepository
+	now       func() time.Time
+
+	// mu serializes changes to purchase orders, so an order can not be
+	// received twice and have its lines added to the stock twice.
+	mu sync.Mutex
+}
+
+// NewPurchaseOrderDefault orders from the suppliers of srp, adding what is
+// received to the stock of ss.
+func NewPurchaseOrderDefault(srp internal.SupplierRepository, ss internal.StockService, porp internal.PurchaseOrderRepository, now func() time.Time) *PurchaseOrderDefault {
+	if now == nil {
+		now = time.Now
+	}
+	return &PurchaseOrderDefault{suppliers: srp, stock: ss, orders: porp, now: now}
+}
+
+func (pd *PurchaseOrderDefault) Create(ctx context.Context, supplierId int, lines []internal.PurchaseOrderLine) (internal.PurchaseOrder, error) {
+	now := pd.now().UTC()
+	order := internal.PurchaseOrder{
+		SupplierId: supplierId,
+		Status:     internal.PurchaseOrderDraft,
+		Lines:      lines,
+		History:    []internal.PurchaseOrderEvent{{Status: internal.PurchaseOrderDraft, At: now}},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := pd.price(ctx, &order); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	return pd.orders.Save(ctx, order)
+}
+
+func (pd *PurchaseOrderDefault) GetAll(ctx context.Context, status internal.PurchaseOrderStatus) ([]internal.PurchaseOrder, error) {
+	return pd.orders.GetAll(ctx, status)
+}
+
+func (pd *PurchaseOrderDefault) GetById(ctx context.Context, id int) (internal.PurchaseOrder, error) {
+	return pd.orders.GetById(ctx, id)
+}
+
+func (pd *PurchaseOrderDefault) UpdateLines(ctx context.Context, id int, lines []internal.PurchaseOrderLine) (internal.PurchaseOrder, error) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	order, err := pd.orders.GetById(ctx, id)
+	if err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	if order.Status != internal.PurchaseOrderDraft {
+		return internal.PurchaseOrder{}, internal.NewPurchaseOrderNotDraftError(order.Status)
+	}
+
+	order.Lines, order.UpdatedAt = lines, pd.now().UTC()
+	if err := pd.price(ctx, &order); err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	return pd.orders.Update(ctx, order)
+}
+
+// Transition sets when the delivery is due as the order is sent, from the
+// lead time the supplier has then.
+func (pd *PurchaseOrderDefault) Transition(ctx context.Context, id int, status internal.PurchaseOrderStatus) (internal.PurchaseOrder, error) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	order, err := pd.orders.GetById(ctx, id)
+	if err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+	if !order.Status.CanMoveTo(status) {
+		return internal.PurchaseOrder{}, internal.NewInvalidPurchaseOrderTransitionError(order.Status, status)
+	}
+
+	previous := order
+	previous.History = slices.Clone(order.History)
+	now := pd.now().UTC()
+	if status == internal.PurchaseOrderSent {
+		supplier, err := pd.suppliers.GetById(ctx, order.SupplierId)
+		if err != nil {
+			return internal.PurchaseOrder{}, err
+		}
+		expectedAt := now.AddDate(0, 0, supplier.LeadTimeDays)
+		order.ExpectedAt = &expectedAt
+	}
+	order.Status, order.UpdatedAt = status, now
+	order.History = append(order.History, internal.PurchaseOrderEvent{Status: status, At: now})
+	updated, err := pd.orders.Update(ctx, order)
+	if err != nil {
+		return internal.PurchaseOrder{}, err
+	}
+
+	if status == internal.PurchaseOrderReceived {
+		received, err := pd.receive(ctx, order)
+		if err != nil {
+			return internal.PurchaseOrder{}, pd.undoReceipt(ctx, previous, received, err)
+		}
+	}
+	return updated, nil
+}
+
+// undoReceipt puts back previous, the order before a receipt that failed
+// with err, and takes the lines received meanwhile back off the stock.
+func (pd *PurchaseOrderDefault) undoReceipt(ctx context.Context, previous internal.PurchaseOrder, received []internal.PurchaseOrderLine, err error) error {
+	ctx = context.WithoutCancel(ctx)
+
+	undoErrs := []error{err}
+	if err := pd.unreceive(ctx, received, fmt.Sprintf("purchase order %d", previous.Id)); err != nil {
+		undoErrs = append(undoErrs, err)
+	}
+	if _, err := pd.orders.Update(ctx, previous); err != nil {
+		undoErrs = append(undoErrs, err)
+	}
+	return errors.Join(undoErrs...)
+}
+
+// price checks the lines of order against the products of its supplier,
+// pricing those without a unit cost at the cost of the supplier, and sets
+// the totals.
+func (pd *PurchaseOrderDefault) price(ctx context.Context, order *internal.PurchaseOrder) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+	links, err := pd.suppliers.Products(ctx, order.SupplierId)
+	if err != nil {
+		if errors.As(err, &internal.SupplierNotFoundError{}) {
+			return internal.NewInvalidPurchaseOrderError("supplier_id")
+		}
+		return err
+	}
+	costs := map[int]internal.Money{}
+	for _, link := range links {
+		costs[link.ProductId] = link.Cost
+	}
+
+	lines := make([]internal.PurchaseOrderLine, len(order.Lines))
+	var total internal.Money
+	for i, line := range order.Lines {
+		cost, ok := costs[line.ProductId]
+		if !ok {
+			return internal.NewInvalidPurchaseOrderError("product_id")
+		}
+		if line.UnitCost.IsZero() {
+			line.UnitCost = cost
+		}
+		if i > 0 && line.UnitCost.Currency != lines[0].UnitCost.Currency {
+			return internal.NewInvalidPurchaseOrderError("currency")
+		}
+		line.Total = line.UnitCost.Mul(int64(line.Quantity))
+		total = total.Add(line.Total)
+		lines[i] = line
+	}
+	order.Lines, order.Total = lines, total
+	return nil
+}
+
+// receive adds the lines of order to the stock, each as a receive movement,
+// and returns the lines received, failing or not. Products deleted since
+// they were ordered are skipped.
+func (pd *PurchaseOrderDefault) receive(ctx context.Context, order internal.PurchaseOrder) ([]internal.PurchaseOrderLine, error) {
+	reason := fmt.Sprintf("purchase order %d", order.Id)
+	var received []internal.PurchaseOrderLine
+	for _, line := range order.Lines {
+		movement, err := internal.NewStockMovement(line.ProductId, internal.StockReceive, line.Quantity, reason)
+		if err != nil {
+			return received, err
+		}
+		_, err = pd.stock.Record(ctx, movement)
+		if errors.As(err, &internal.ProductNotFoundError{}) {
+			continue
+		}
+		if err != nil {
+			return received, err
+		}
+		received = append(received, line)
+	}
+	return received, nil
+}
+
+// unreceive takes lines back off the stock after a receipt failed, even
+// when ctx was cancelled meanwhile.
+func (pd *PurchaseOrderDefault) unreceive(ctx context.Context, lines []internal.PurchaseOrderLine, reason string) error {
+	ctx = context.WithoutCancel(ctx)
+
+	var errs []error
+	for _, line := range lines {
+		movement, err := internal.NewStockMovement(line.ProductId, internal.StockAdjust, -line.Quantity, reason+" receipt failed")
+		if err != nil {
+			return err
+		}
+		if _, err := pd.stock.Record(ctx, movement); err != nil && !errors.As(err, &internal.ProductNotFoundError{}) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,263 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failingStock fails to record the movements of product failId.
+type failingStock struct {
+	internal.StockService
+	failId int
+}
+
+func (fs failingStock) Record(ctx context.Context, movement internal.StockMovement) (internal.StockMovement, error) {
+	if movement.ProductId == fs.failId {
+		return internal.StockMovement{}, errors.New("ledger unavailable")
+	}
+	return fs.StockService.Record(ctx, movement)
+}
+
+// newPurchasing returns the supplier and purchase order services over
+// products 1 and 2, with 10 and 3 units, both sold by supplier 1, and the
+// stock service.
+func newPurchasing(t *testing.T) (*service.SupplierDefault, *service.PurchaseOrderDefault, internal.StockService, *repository.ProductMapDB) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Price: usd(110), IsPublished: true, Version: 1},
+		2: {Id: 2, Name: "Bread", Quantity: 3, Code: "c2", Price: usd(235), IsPublished: true, Version: 1},
+	}, LastID: 2}
+
+	now := func() time.Time { return time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC) }
+	suppliers := repository.NewSupplierMapDB()
+	stock := service.NewStockDefault(db, repository.NewStockMapDB(), nil, now)
+	ss := service.NewSupplierDefault(suppliers, db)
+	ps := service.NewPurchaseOrderDefault(suppliers, stock, repository.NewPurchaseOrderMapDB(), now)
+
+	ctx := context.Background()
+	supplier, err := ss.Save(ctx, internal.Supplier{Name: " Farm ", Email: "orders@farm.example", LeadTimeDays: 3})
+	require.NoError(t, err)
+	require.Equal(t, "Farm", supplier.Name)
+	_, err = ss.LinkProduct(ctx, internal.SupplierProduct{SupplierId: supplier.Id, ProductId: 1, Cost: usd(60)})
+	require.NoError(t, err)
+	_, err = ss.LinkProduct(ctx, internal.SupplierProduct{SupplierId: supplier.Id, ProductId: 2, Cost: usd(120)})
+	require.NoError(t, err)
+	return ss, ps, stock, db
+}
+
+func TestSupplierDefault(t *testing.T) {
+	ss, _, _, _ := newPurchasing(t)
+	ctx := context.Background()
+
+	_, err := ss.Save(ctx, internal.Supplier{Name: " "})
+	require.ErrorAs(t, err, &internal.InvalidSupplierError{})
+	_, err = ss.Save(ctx, internal.Supplier{Name: "Mill", Email: "mill"})
+	require.ErrorAs(t, err, &internal.InvalidSupplierError{})
+	_, err = ss.Update(ctx, internal.Supplier{Id: 9, Name: "Mill"})
+	require.ErrorAs(t, err, &internal.SupplierNotFoundError{})
+
+	_, err = ss.LinkProduct(ctx, internal.SupplierProduct{SupplierId: 1, ProductId: 9, Cost: usd(10)})
+	require.ErrorAs(t, err, &internal.ProductNotFoundError{})
+	_, err = ss.LinkProduct(ctx, internal.SupplierProduct{SupplierId: 9, ProductId: 1, Cost: usd(10)})
+	require.ErrorAs(t, err, &internal.SupplierNotFoundError{})
+	_, err = ss.LinkProduct(ctx, internal.SupplierProduct{SupplierId: 1, ProductId: 1})
+	require.ErrorAs(t, err, &internal.InvalidSupplierError{})
+
+	// linking again replaces the cost
+	_, err = ss.LinkProduct(ctx, internal.SupplierProduct{SupplierId: 1, ProductId: 1, SupplierCode: "M-1", Cost: usd(65)})
+	require.NoError(t, err)
+	links, err := ss.Products(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []internal.SupplierProduct{
+		{SupplierId: 1, ProductId: 1, SupplierCode: "M-1", Cost: usd(65)},
+		{SupplierId: 1, ProductId: 2, Cost: usd(120)},
+	}, links)
+
+	require.NoError(t, ss.UnlinkProduct(ctx, 1, 2))
+	require.ErrorAs(t, ss.UnlinkProduct(ctx, 1, 2), &internal.SupplierProductNotFoundError{})
+
+	require.NoError(t, ss.Delete(ctx, 1))
+	_, err = ss.Products(ctx, 1)
+	require.ErrorAs(t, err, &internal.SupplierNotFoundError{})
+}
+
+func TestPurchaseOrderLifecycle(t *testing.T) {
+	_, ps, stock, db := newPurchasing(t)
+	ctx := context.Background()
+
+	order, err := ps.Create(ctx, 1, []internal.PurchaseOrderLine{
+		{ProductId: 1, Quantity: 20},
+		{ProductId: 2, Quantity: 5, UnitCost: usd(100)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, internal.PurchaseOrderDraft, order.Status)
+	require.Equal(t, []internal.PurchaseOrderLine{
+		{ProductId: 1, Quantity: 20, UnitCost: usd(60), Total: usd(1200)},
+		{ProductId: 2, Quantity: 5, UnitCost: usd(100), Total: usd(500)},
+	}, order.Lines)
+	require.Equal(t, usd(1700), order.Total)
+
+	order, err = ps.UpdateLines(ctx, order.Id, []internal.PurchaseOrderLine{{ProductId: 2, Quantity: 6}})
+	require.NoError(t, err)
+	require.Equal(t, usd(720), order.Total)
+
+	_, err = ps.Transition(ctx, order.Id, internal.PurchaseOrderReceived)
+	require.ErrorAs(t, err, &internal.InvalidPurchaseOrderTransitionError{})
+
+	sent, err := ps.Transition(ctx, order.Id, internal.PurchaseOrderSent)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 3, 13, 9, 30, 0, 0, time.UTC), *sent.ExpectedAt)
+	_, err = ps.UpdateLines(ctx, order.Id, []internal.PurchaseOrderLine{{ProductId: 2, Quantity: 1}})
+	require.ErrorAs(t, err, &internal.PurchaseOrderNotDraftError{})
+
+	received, err := ps.Transition(ctx, order.Id, internal.PurchaseOrderReceived)
+	require.NoError(t, err)
+	require.Equal(t, []internal.PurchaseOrderStatus{internal.PurchaseOrderDraft, internal.PurchaseOrderSent, internal.PurchaseOrderReceived}, statuses(received.History))
+	require.Equal(t, 9, quantity(t, db, 2))
+	require.Equal(t, 10, quantity(t, db, 1))
+
+	movements, err := stock.Movements(ctx, 2)
+	require.NoError(t, err)
+	last := movements[len(movements)-1]
+	require.Equal(t, internal.StockReceive, last.Kind)
+	require.Equal(t, 6, last.Quantity)
+	require.Equal(t, "purchase order 1", last.Reason)
+
+	// a received order is not received twice
+	_, err = ps.Transition(ctx, order.Id, internal.PurchaseOrderReceived)
+	require.ErrorAs(t, err, &internal.InvalidPurchaseOrderTransitionError{})
+	require.Equal(t, 9, quantity(t, db, 2))
+
+	orders, err := ps.GetAll(ctx, internal.PurchaseOrderReceived)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+}
+
+func statuses(history []internal.PurchaseOrderEvent) []internal.PurchaseOrderStatus {
+	var statuses []internal.PurchaseOrderStatus
+	for _, event := range history {
+		statuses = append(statuses, event.Status)
+	}
+	return statuses
+}
+
+func TestPurchaseOrderRejectsInvalidLines(t *testing.T) {
+	ss, ps, _, _ := newPurchasing(t)
+	ctx := context.Background()
+
+	other, err := ss.Save(ctx, internal.Supplier{Name: "Mill"})
+	require.NoError(t, err)
+
+	for name, lines := range map[string][]internal.PurchaseOrderLine{
+		"no lines":       nil,
+		"not linked":     {{ProductId: 3, Quantity: 1}},
+		"twice":          {{ProductId: 1, Quantity: 1}, {ProductId: 1, Quantity: 2}},
+		"no quantity":    {{ProductId: 1}},
+		"mixed currency": {{ProductId: 1, Quantity: 1}, {ProductId: 2, Quantity: 1, UnitCost: internal.NewMoney(100, "EUR")}},
+	} {
+		_, err := ps.Create(ctx, 1, lines)
+		require.ErrorAs(t, err, &internal.InvalidPurchaseOrderError{}, name)
+	}
+
+	_, err = ps.Create(ctx, other.Id, []internal.PurchaseOrderLine{{ProductId: 1, Quantity: 1}})
+	require.ErrorAs(t, err, &internal.InvalidPurchaseOrderError{})
+	_, err = ps.Create(ctx, 9, []internal.PurchaseOrderLine{{ProductId: 1, Quantity: 1}})
+	require.ErrorAs(t, err, &internal.InvalidPurchaseOrderError{})
+}
+
+func TestPurchaseOrderReceiptIsAllOrNothing(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Price: usd(110), Version: 1},
+		2: {Id: 2, Name: "Bread", Quantity: 3, Code: "c2", Price: usd(235), Version: 1},
+		3: {Id: 3, Name: "Rice", Quantity: 1, Code: "c3", Price: usd(300), Version: 1},
+	}, LastID: 3}
+	ctx := context.Background()
+	suppliers := repository.NewSupplierMapDB()
+	stock := service.NewStockDefault(db, repository.NewStockMapDB(), nil, nil)
+	ss := service.NewSupplierDefault(suppliers, db)
+	ps := service.NewPurchaseOrderDefault(suppliers, failingStock{StockService: stock, failId: 2}, repository.NewPurchaseOrderMapDB(), nil)
+
+	_, err := ss.Save(ctx, internal.Supplier{Name: "Farm"})
+	require.NoError(t, err)
+	for id := 1; id <= 3; id++ {
+		_, err := ss.LinkProduct(ctx, internal.SupplierProduct{SupplierId: 1, ProductId: id, Cost: usd(50)})
+		require.NoError(t, err)
+	}
+
+	order, err := ps.Create(ctx, 1, []internal.PurchaseOrderLine{
+		{ProductId: 3, Quantity: 4},
+		{ProductId: 1, Quantity: 5},
+		{ProductId: 2, Quantity: 6},
+	})
+	require.NoError(t, err)
+	_, err = ps.Transition(ctx, order.Id, internal.PurchaseOrderSent)
+	require.NoError(t, err)
+
+	// products deleted since they were ordered are skipped
+	require.NoError(t, db.Delete(ctx, 3))
+
+	_, err = ps.Transition(ctx, order.Id, internal.PurchaseOrderReceived)
+	require.Error(t, err)
+	require.Equal(t, 10, quantity(t, db, 1))
+	require.Equal(t, 3, quantity(t, db, 2))
+
+	order, err = ps.GetById(ctx, order.Id)
+	require.NoError(t, err)
+	require.Equal(t, internal.PurchaseOrderSent, order.Status)
+	require.Len(t, order.History, 2)
+}
+
+// failingPurchaseOrders fails Update while fail is set.
+type failingPurchaseOrders struct {
+	internal.PurchaseOrderRepository
+	fail bool
+}
+
+func (o *failingPurchaseOrders) Update(ctx context.Context, order internal.PurchaseOrder) (internal.PurchaseOrder, error) {
+	if o.fail {
+		return internal.PurchaseOrder{}, errors.New("purchase orders unavailable")
+	}
+	return o.PurchaseOrderRepository.Update(ctx, order)
+}
+
+func TestPurchaseOrderReceiptStoresTheStatusFirst(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Price: usd(110), Version: 1},
+	}, LastID: 1}
+	ctx := context.Background()
+	suppliers := repository.NewSupplierMapDB()
+	orders := &failingPurchaseOrders{PurchaseOrderRepository: repository.NewPurchaseOrderMapDB()}
+	ss := service.NewSupplierDefault(suppliers, db)
+	ps := service.NewPurchaseOrderDefault(suppliers, service.NewStockDefault(db, repository.NewStockMapDB(), nil, nil), orders, nil)
+
+	_, err := ss.Save(ctx, internal.Supplier{Name: "Farm"})
+	require.NoError(t, err)
+	_, err = ss.LinkProduct(ctx, internal.SupplierProduct{SupplierId: 1, ProductId: 1, Cost: usd(50)})
+	require.NoError(t, err)
+	order, err := ps.Create(ctx, 1, []internal.PurchaseOrderLine{{ProductId: 1, Quantity: 5}})
+	require.NoError(t, err)
+	_, err = ps.Transition(ctx, order.Id, internal.PurchaseOrderSent)
+	require.NoError(t, err)
+
+	// no stock comes in when the status can not be stored
+	orders.fail = true
+	_, err = ps.Transition(ctx, order.Id, internal.PurchaseOrderReceived)
+	require.EqualError(t, err, "purchase orders unavailable")
+	require.Equal(t, 10, quantity(t, db, 1))
+
+	// the retry receives the lines once
+	orders.fail = false
+	_, err = ps.Transition(ctx, order.Id, internal.PurchaseOrderReceived)
+	require.NoError(t, err)
+	require.Equal(t, 15, quantity(t, db, 1))
+	_, err = ps.Transition(ctx, order.Id, internal.PurchaseOrderReceived)
+	require.ErrorAs(t, err, &internal.InvalidPurchaseOrderTransitionError{})
+	require.Equal(t, 15, quantity(t, db, 1))
+}
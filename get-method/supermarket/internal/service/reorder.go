@@ -0,0 +1,181 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"supermarket/internal"
+	"time"
+)
+
+type ReorderDefault struct {
+	products  internal.ProductRepository
+	ledger    internal.StockRepository
+	suppliers internal.SupplierRepository
+	orders    internal.PurchaseOrderRepository
+	now       func() time.Time
+}
+
+// NewReorderDefault suggests reordering the products of pdb from their sales
+// in ledger, which it only reads. srp and porp can be nil.
+func NewReorderDefault(pdb internal.ProductRepository, ledger internal.StockRepository, srp internal.SupplierRepository, porp internal.PurchaseOrderRepository, now func() time.Time) *ReorderDefault {
+	if now == nil {
+		now = time.Now
+	}
+	return &ReorderDefault{products: pdb, ledger: ledger, suppliers: srp, orders: porp, now: now}
+}
+
+// Suggestions plans for the stock to last the lead time plus the safety
+// days at the fastest velocity measured, rounding quantities up.
+func (rd *ReorderDefault) Suggestions(ctx context.Context, policy internal.ReorderPolicy) ([]internal.ReorderSuggestion, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	products, err := rd.products.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	leadTimes, err := rd.leadTimes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	onOrder, err := rd.onOrder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := rd.now()
+	suggestions := []internal.ReorderSuggestion{}
+	for _, product := range products {
+		// products whose ledger is not open yet have no sales to go by
+		movements, err := rd.ledger.Movements(ctx, product.Id)
+		if err != nil {
+			return nil, err
+		}
+		velocities, perDay := salesVelocities(movements, policy.Windows, now)
+		if perDay == 0 {
+			continue
+		}
+
+		level, err := ledgerLevel(ctx, rd.ledger, product.Id, now)
+		if err != nil {
+			return nil, err
+		}
+
+		supplier, ok := leadTimes[product.Id]
+		if !ok {
+			supplier.LeadTimeDays = policy.DefaultLeadTimeDays
+		}
+		suggestion := internal.ReorderSuggestion{
+			ProductId:    product.Id,
+			Name:         product.Name,
+			Code:         product.Code,
+			Available:    level.Available,
+			OnOrder:      onOrder[product.Id],
+			Velocities:   velocities,
+			UnitsPerDay:  round(perDay, 2),
+			DaysOfStock:  round(float64(level.Available)/perDay, 1),
+			SupplierId:   supplier.Id,
+			LeadTimeDays: supplier.LeadTimeDays,
+			SafetyStock:  int(math.Ceil(perDay * float64(policy.SafetyDays))),
+			ReorderPoint: int(math.Ceil(perDay * float64(supplier.LeadTimeDays+policy.SafetyDays))),
+		}
+		position := suggestion.Available + suggestion.OnOrder
+		if position > suggestion.ReorderPoint {
+			continue
+		}
+		target := suggestion.ReorderPoint + int(math.Ceil(perDay*float64(policy.CoverDays)))
+		suggestion.SuggestedQuantity = max(target-position, 1)
+		suggestions = append(suggestions, suggestion)
+	}
+
+	slices.SortFunc(suggestions, func(a, b internal.ReorderSuggestion) int {
+		if a.DaysOfStock != b.DaysOfStock {
+			return cmp.Compare(a.DaysOfStock, b.DaysOfStock)
+		}
+		return cmp.Compare(a.ProductId, b.ProductId)
+	})
+	return suggestions, nil
+}
+
+// leadTimes returns the supplier with the shortest lead time of each product
+// sold by one, the lowest id among those as fast.
+func (rd *ReorderDefault) leadTimes(ctx context.Context) (map[int]internal.Supplier, error) {
+	fastest := map[int]internal.Supplier{}
+	if rd.suppliers == nil {
+		return fastest, nil
+	}
+	suppliers, err := rd.suppliers.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, supplier := range suppliers {
+		links, err := rd.suppliers.Products(ctx, supplier.Id)
+		if err != nil {
+			// deleted since the suppliers were listed
+			if errors.As(err, &internal.SupplierNotFoundError{}) {
+				continue
+			}
+			return nil, err
+		}
+		for _, link := range links {
+			current, ok := fastest[link.ProductId]
+			if !ok || supplier.LeadTimeDays < current.LeadTimeDays {
+				fastest[link.ProductId] = supplier
+			}
+		}
+	}
+	return fastest, nil
+}
+
+// onOrder returns the quantity of each product the purchase orders sent
+// still bring.
+func (rd *ReorderDefault) onOrder(ctx context.Context) (map[int]int, error) {
+	quantities := map[int]int{}
+	if rd.orders == nil {
+		return quantities, nil
+	}
+	orders, err := rd.orders.GetAll(ctx, internal.PurchaseOrderSent)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		for _, line := range order.Lines {
+			quantities[line.ProductId] += line.Quantity
+		}
+	}
+	return quantities, nil
+}
+
+// salesVelocities returns the velocity of the sales among movements over
+// each of windows up to now, and the fastest of them in units per day.
+func salesVelocities(movements []internal.StockMovement, windows []time.Duration, now time.Time) ([]internal.SalesVelocity, float64) {
+	velocities := make([]internal.SalesVelocity, 0, len(windows))
+	var fastest float64
+	for _, window := range windows {
+		since := now.Add(-window)
+		sold := 0
+		for _, movement := range movements {
+			// returns take back the sales they undo
+			if (movement.Kind == internal.StockSell || movement.Kind == internal.StockReturn) && movement.CreatedAt.After(since) && !movement.CreatedAt.After(now) {
+				sold -= movement.Quantity
+			}
+		}
+		perDay := float64(sold) * 24 / window.Hours()
+		fastest = max(fastest, perDay)
+		velocities = append(velocities, internal.SalesVelocity{
+			Window:      internal.FormatPeriod(window),
+			UnitsSold:   sold,
+			UnitsPerDay: round(perDay, 2),
+		})
+	}
+	return velocities, fastest
+}
+
+// round rounds x to decimals decimal places.
+func round(x float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(x*scale) / scale
+}
@@ -0,0 +1,113 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReorderSuggestions(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 60, Code: "c1", Price: usd(110), Version: 1},
+		2: {Id: 2, Name: "Bread", Quantity: 10, Code: "c2", Price: usd(235), Version: 1},
+		3: {Id: 3, Name: "Rice", Quantity: 5, Code: "c3", Price: usd(300), Version: 1},
+		4: {Id: 4, Name: "Salt", Quantity: 90, Code: "c4", Price: usd(80), Version: 1},
+	}, LastID: 4}
+	ctx := context.Background()
+
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	clock := now
+	ledger := repository.NewStockMapDB()
+	stock := service.NewStockDefault(db, ledger, nil, func() time.Time { return clock })
+	sell := func(daysAgo int, productId, quantity int) {
+		clock = now.AddDate(0, 0, -daysAgo)
+		movement, err := internal.NewStockMovement(productId, internal.StockSell, quantity, "")
+		require.NoError(t, err)
+		_, err = stock.Record(ctx, movement)
+		require.NoError(t, err)
+	}
+	sell(40, 1, 5)
+	sell(20, 1, 30)
+	sell(2, 1, 14)
+	sell(1, 2, 7)
+	sell(3, 4, 7)
+	clock = now
+
+	suppliers := repository.NewSupplierMapDB()
+	ss := service.NewSupplierDefault(suppliers, db)
+	fast, err := ss.Save(ctx, internal.Supplier{Name: "Farm", LeadTimeDays: 5})
+	require.NoError(t, err)
+	slow, err := ss.Save(ctx, internal.Supplier{Name: "Mill", LeadTimeDays: 10})
+	require.NoError(t, err)
+	for _, link := range []internal.SupplierProduct{
+		{SupplierId: slow.Id, ProductId: 1, Cost: usd(50)},
+		{SupplierId: fast.Id, ProductId: 1, Cost: usd(60)},
+		{SupplierId: slow.Id, ProductId: 2, Cost: usd(100)},
+	} {
+		_, err := ss.LinkProduct(ctx, link)
+		require.NoError(t, err)
+	}
+
+	orders := repository.NewPurchaseOrderMapDB()
+	ps := service.NewPurchaseOrderDefault(suppliers, stock, orders, func() time.Time { return now })
+	order, err := ps.Create(ctx, slow.Id, []internal.PurchaseOrderLine{{ProductId: 2, Quantity: 5}})
+	require.NoError(t, err)
+	_, err = ps.Transition(ctx, order.Id, internal.PurchaseOrderSent)
+	require.NoError(t, err)
+	// drafts are not on order
+	_, err = ps.Create(ctx, slow.Id, []internal.PurchaseOrderLine{{ProductId: 1, Quantity: 100}})
+	require.NoError(t, err)
+
+	rs := service.NewReorderDefault(db, ledger, suppliers, orders, func() time.Time { return now })
+	suggestions, err := rs.Suggestions(ctx, internal.DefaultReorderPolicy())
+	require.NoError(t, err)
+	require.Equal(t, []internal.ReorderSuggestion{
+		{
+			ProductId: 2, Name: "Bread", Code: "c2",
+			Available: 3, OnOrder: 5,
+			Velocities: []internal.SalesVelocity{
+				{Window: "7d", UnitsSold: 7, UnitsPerDay: 1},
+				{Window: "30d", UnitsSold: 7, UnitsPerDay: 0.23},
+			},
+			UnitsPerDay: 1, DaysOfStock: 3,
+			SupplierId: slow.Id, LeadTimeDays: 10,
+			SafetyStock: 3, ReorderPoint: 13, SuggestedQuantity: 19,
+		},
+		{
+			ProductId: 1, Name: "Milk", Code: "c1",
+			Available: 11,
+			Velocities: []internal.SalesVelocity{
+				{Window: "7d", UnitsSold: 14, UnitsPerDay: 2},
+				{Window: "30d", UnitsSold: 44, UnitsPerDay: 1.47},
+			},
+			UnitsPerDay: 2, DaysOfStock: 5.5,
+			SupplierId: fast.Id, LeadTimeDays: 5,
+			SafetyStock: 6, ReorderPoint: 16, SuggestedQuantity: 33,
+		},
+	}, suggestions)
+
+	// without suppliers products take the default lead time
+	rs = service.NewReorderDefault(db, ledger, nil, nil, func() time.Time { return now })
+	suggestions, err = rs.Suggestions(ctx, internal.ReorderPolicy{Windows: []time.Duration{30 * 24 * time.Hour}, DefaultLeadTimeDays: 400})
+	require.NoError(t, err)
+	ids := []int{}
+	for _, suggestion := range suggestions {
+		require.Zero(t, suggestion.SupplierId)
+		ids = append(ids, suggestion.ProductId)
+	}
+	require.Equal(t, []int{1, 2, 4}, ids)
+
+	// the ledger of the product never sold is left unopened
+	_, opened, err := ledger.OnHand(ctx, 3)
+	require.NoError(t, err)
+	require.False(t, opened)
+
+	_, err = rs.Suggestions(ctx, internal.ReorderPolicy{})
+	require.ErrorAs(t, err, &internal.InvalidReorderPolicyError{})
+}
@@ -0,0 +1,458 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"supermarket/internal"
+	"sync"
+	"time"
+)
+
+type StockDefault struct {
+	products  internal.ProductRepository
+	uow       internal.ProductUnitOfWork
+	ledger    internal.StockRepository
+	locations internal.LocationRepository
+	now       func() time.Time
+
+	// locks holds a *sync.Mutex per product id. Every change to the stock of
+	// a product happens holding its lock, so checking the stock available
+	// and taking it can not race.
+	locks sync.Map
+}
+
+// NewStockDefault keeps the ledger of the products of pdb in srp, at the
+// locations of lrp.
+func NewStockDefault(pdb internal.ProductRepository, srp internal.StockRepository, lrp internal.LocationRepository, now func() time.Time) *StockDefault {
+	if now == nil {
+		now = time.Now
+	}
+	uow, ok := pdb.(internal.ProductUnitOfWork)
+	if !ok {
+		uow = directUnitOfWork{repo: pdb}
+	}
+	return &StockDefault{products: pdb, uow: uow, ledger: srp, locations: lrp, now: now}
+}
+
+// lock locks the stock of products ids, in increasing id order so that
+// concurrent callers can not deadlock, and returns the function unlocking
+// them.
+func (sd *StockDefault) lock(ids ...int) func() {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	mutexes := make([]*sync.Mutex, 0, len(ids))
+	for _, id := range ids {
+		l, _ := sd.locks.LoadOrStore(id, &sync.Mutex{})
+		mu := l.(*sync.Mutex)
+		mu.Lock()
+		mutexes = append(mutexes, mu)
+	}
+	return func() {
+		for i := len(mutexes) - 1; i >= 0; i-- {
+			mutexes[i].Unlock()
+		}
+	}
+}
+
+// open returns the quantity on hand of product id. A product without a
+// ledger yet gets one opening with an adjustment to its quantity, one with
+// a ledger gets its quantity set back to what the ledger adds up to if it
+// was changed behind the ledger. The caller must hold the lock of the
+// product.
+func (sd *StockDefault) open(ctx context.Context, id int) (int, error) {
+	product, err := sd.products.GetById(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	onHand, opened, err := sd.ledger.OnHand(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case opened && product.Quantity != onHand:
+		if err := sd.setQuantities(ctx, map[int]int{id: onHand}); err != nil {
+			return 0, err
+		}
+	case !opened && product.Quantity != 0:
+		if _, err := sd.ledger.Record(ctx, internal.StockMovement{
+			ProductId:  id,
+			LocationId: internal.DefaultLocationId,
+			Kind:       internal.StockAdjust,
+			Quantity:   product.Quantity,
+			Reason:     "opening balance",
+			CreatedAt:  sd.now().UTC(),
+		}); err != nil {
+			return 0, err
+		}
+		return product.Quantity, nil
+	}
+	return onHand, nil
+}
+
+// location returns locationId, the default location for zero, failing
+// with LocationNotFoundError when it does not exist.
+func (sd *StockDefault) location(ctx context.Context, locationId int) (int, error) {
+	if locationId == 0 || locationId == internal.DefaultLocationId {
+		return internal.DefaultLocationId, nil
+	}
+	if sd.locations == nil {
+		return 0, internal.NewLocationNotFoundError()
+	}
+	if _, err := sd.locations.GetById(ctx, locationId); err != nil {
+		return 0, err
+	}
+	return locationId, nil
+}
+
+// level returns the stock of product id with its breakdown by location. The
+// caller must hold the lock of the product, once its ledger is open.
+func (sd *StockDefault) level(ctx context.Context, id int) (internal.StockLevel, error) {
+	return ledgerLevel(ctx, sd.ledger, id, sd.now())
+}
+
+// ledgerLevel returns the stock of product id in ledger at now, with its
+// breakdown by location.
+func ledgerLevel(ctx context.Context, ledger internal.StockRepository, id int, now time.Time) (internal.StockLevel, error) {
+	onHand, err := ledger.OnHandByLocation(ctx, id)
+	if err != nil {
+		return internal.StockLevel{}, err
+	}
+	reserved, err := ledger.ReservedByLocation(ctx, id, now)
+	if err != nil {
+		return internal.StockLevel{}, err
+	}
+
+	locations := make([]int, 0, len(onHand))
+	for locationId := range onHand {
+		locations = append(locations, locationId)
+	}
+	for locationId := range reserved {
+		if _, ok := onHand[locationId]; !ok {
+			locations = append(locations, locationId)
+		}
+	}
+	slices.Sort(locations)
+
+	level := internal.StockLevel{ProductId: id, Locations: make([]internal.StockLevel, 0, len(locations))}
+	for _, locationId := range locations {
+		at := internal.StockLevel{
+			ProductId:  id,
+			LocationId: locationId,
+			OnHand:     onHand[locationId],
+			Reserved:   reserved[locationId],
+			Available:  max(onHand[locationId]-reserved[locationId], 0),
+		}
+		level.OnHand += at.OnHand
+		level.Reserved += at.Reserved
+		level.Available += at.Available
+		level.Locations = append(level.Locations, at)
+	}
+	return level, nil
+}
+
+// levelAt returns the stock of product id at locationId. The caller must
+// hold the lock of the product, once its ledger is open.
+func (sd *StockDefault) levelAt(ctx context.Context, id, locationId int) (internal.StockLevel, error) {
+	level, err := sd.level(ctx, id)
+	if err != nil {
+		return internal.StockLevel{}, err
+	}
+	for _, at := range level.Locations {
+		if at.LocationId == locationId {
+			return at, nil
+		}
+	}
+	return internal.StockLevel{ProductId: id, LocationId: locationId}, nil
+}
+
+// setQuantities stores the quantity of each product of quantities, all of
+// them or none.
+func (sd *StockDefault) setQuantities(ctx context.Context, quantities map[int]int) error {
+	return sd.uow.WithinTransaction(ctx, func(ctx context.Context, repo internal.ProductRepository) error {
+		for id, quantity := range quantities {
+			product, err := repo.GetById(ctx, id)
+			if err != nil {
+				return err
+			}
+			if product.Quantity == quantity {
+				continue
+			}
+			product.Quantity = quantity
+			if _, err := repo.PartialUpdate(ctx, id, product); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (sd *StockDefault) Level(ctx context.Context, productId int) (internal.StockLevel, error) {
+	unlock := sd.lock(productId)
+	defer unlock()
+
+	if _, err := sd.open(ctx, productId); err != nil {
+		return internal.StockLevel{}, err
+	}
+	return sd.level(ctx, productId)
+}
+
+func (sd *StockDefault) LevelAt(ctx context.Context, productId int, locationId int) (internal.StockLevel, error) {
+	locationId, err := sd.location(ctx, locationId)
+	if err != nil {
+		return internal.StockLevel{}, err
+	}
+
+	unlock := sd.lock(productId)
+	defer unlock()
+
+	if _, err := sd.open(ctx, productId); err != nil {
+		return internal.StockLevel{}, err
+	}
+	return sd.levelAt(ctx, productId, locationId)
+}
+
+func (sd *StockDefault) Movements(ctx context.Context, productId int) ([]internal.StockMovement, error) {
+	unlock := sd.lock(productId)
+	defer unlock()
+
+	if _, err := sd.open(ctx, productId); err != nil {
+		return nil, err
+	}
+	return sd.ledger.Movements(ctx, productId)
+}
+
+// Record takes stock off only as far as it is not reserved at the location
+// of the movement.
+func (sd *StockDefault) Record(ctx context.Context, movement internal.StockMovement) (internal.StockMovement, error) {
+	if movement.Kind == internal.StockTransfer {
+		return internal.StockMovement{}, internal.NewInvalidStockMovementError("kind")
+	}
+	locationId, err := sd.location(ctx, movement.LocationId)
+	if err != nil {
+		return internal.StockMovement{}, err
+	}
+	movement.LocationId = locationId
+
+	unlock := sd.lock(movement.ProductId)
+	defer unlock()
+
+	onHand, err := sd.open(ctx, movement.ProductId)
+	if err != nil {
+		return internal.StockMovement{}, err
+	}
+	if movement.Quantity < 0 {
+		level, err := sd.levelAt(ctx, movement.ProductId, movement.LocationId)
+		if err != nil {
+			return internal.StockMovement{}, err
+		}
+		if -movement.Quantity > level.Available {
+			return internal.StockMovement{}, internal.NewInsufficientStockError(movement.ProductId, -movement.Quantity, level.Available)
+		}
+	}
+
+	return sd.record(ctx, movement, onHand)
+}
+
+// record stores the quantity movement leaves on hand, then records it. The
+// caller must hold the lock of the product.
+func (sd *StockDefault) record(ctx context.Context, movement internal.StockMovement, onHand int) (internal.StockMovement, error) {
+	if err := sd.setQuantities(ctx, map[int]int{movement.ProductId: onHand + movement.Quantity}); err != nil {
+		return internal.StockMovement{}, err
+	}
+	movement.CreatedAt = sd.now().UTC()
+	return sd.ledger.Record(ctx, movement)
+}
+
+// Transfer moves the stock without changing the quantity of the product,
+// which adds up every location.
+func (sd *StockDefault) Transfer(ctx context.Context, productId int, from, to int, quantity int, reason string) ([]internal.StockMovement, error) {
+	if quantity <= 0 {
+		return nil, internal.NewInvalidStockMovementError("quantity")
+	}
+	from, err := sd.location(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if to, err = sd.location(ctx, to); err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, internal.NewInvalidStockMovementError("location_id")
+	}
+
+	unlock := sd.lock(productId)
+	defer unlock()
+
+	if _, err := sd.open(ctx, productId); err != nil {
+		return nil, err
+	}
+	level, err := sd.levelAt(ctx, productId, from)
+	if err != nil {
+		return nil, err
+	}
+	if quantity > level.Available {
+		return nil, internal.NewInsufficientStockError(productId, quantity, level.Available)
+	}
+
+	now := sd.now().UTC()
+	out := internal.StockMovement{ProductId: productId, LocationId: from, Kind: internal.StockTransfer, Quantity: -quantity, Reason: reason, CreatedAt: now}
+	in := internal.StockMovement{ProductId: productId, LocationId: to, Kind: internal.StockTransfer, Quantity: quantity, Reason: reason, CreatedAt: now}
+	return sd.ledger.RecordTransfer(ctx, out, in)
+}
+
+func (sd *StockDefault) Reserve(ctx context.Context, locationId int, items []internal.ReservationItem, ttl time.Duration) (internal.Reservation, error) {
+	if ttl == 0 {
+		ttl = internal.DefaultReservationTTL
+	}
+	if ttl < 0 || ttl > internal.MaxReservationTTL {
+		return internal.Reservation{}, internal.NewInvalidReservationError("ttl")
+	}
+	now := sd.now().UTC()
+	reservation := internal.Reservation{Items: items, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	if err := reservation.Validate(); err != nil {
+		return internal.Reservation{}, err
+	}
+	locationId, err := sd.location(ctx, locationId)
+	if err != nil {
+		return internal.Reservation{}, err
+	}
+	reservation.LocationId = locationId
+
+	unlock := sd.lock(reservationProducts(reservation)...)
+	defer unlock()
+
+	if err := sd.ledger.DeleteExpiredReservations(ctx, now); err != nil {
+		return internal.Reservation{}, err
+	}
+	for _, item := range items {
+		if _, err := sd.open(ctx, item.ProductId); err != nil {
+			return internal.Reservation{}, err
+		}
+		level, err := sd.levelAt(ctx, item.ProductId, locationId)
+		if err != nil {
+			return internal.Reservation{}, err
+		}
+		if item.Quantity > level.Available {
+			return internal.Reservation{}, internal.NewInsufficientStockError(item.ProductId, item.Quantity, level.Available)
+		}
+	}
+	return sd.ledger.SaveReservation(ctx, reservation)
+}
+
+func (sd *StockDefault) GetReservation(ctx context.Context, id int) (internal.Reservation, error) {
+	reservation, err := sd.ledger.GetReservation(ctx, id)
+	if err != nil {
+		return internal.Reservation{}, err
+	}
+	if !reservation.ActiveAt(sd.now()) {
+		return internal.Reservation{}, internal.NewReservationNotFoundError()
+	}
+	return reservation, nil
+}
+
+// Commit records a sale of each item of the reservation at its location,
+// all of them or none. The stock of an item can only be short if the
+// quantity of its product was lowered, or its stock moved away, since it
+// was reserved.
+func (sd *StockDefault) Commit(ctx context.Context, id int) ([]internal.StockMovement, error) {
+	reservation, err := sd.GetReservation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := sd.lock(reservationProducts(reservation)...)
+	defer unlock()
+
+	// committed or released while waiting for the locks
+	if reservation, err = sd.GetReservation(ctx, id); err != nil {
+		return nil, err
+	}
+
+	onHand := make(map[int]int, len(reservation.Items))
+	sold := make(map[int]int, len(reservation.Items))
+	for _, item := range reservation.Items {
+		if onHand[item.ProductId], err = sd.open(ctx, item.ProductId); err != nil {
+			return nil, err
+		}
+		level, err := sd.levelAt(ctx, item.ProductId, reservation.LocationId)
+		if err != nil {
+			return nil, err
+		}
+		// the reservation itself holds item.Quantity of the reserved stock
+		if available := level.OnHand - (level.Reserved - item.Quantity); item.Quantity > available {
+			return nil, internal.NewInsufficientStockError(item.ProductId, item.Quantity, max(available, 0))
+		}
+		sold[item.ProductId] = onHand[item.ProductId] - item.Quantity
+	}
+
+	if err := sd.setQuantities(ctx, sold); err != nil {
+		return nil, err
+	}
+	movements := make([]internal.StockMovement, 0, len(reservation.Items))
+	for _, item := range reservation.Items {
+		movement, err := sd.ledger.Record(ctx, internal.StockMovement{
+			ProductId:     item.ProductId,
+			LocationId:    reservation.LocationId,
+			Kind:          internal.StockSell,
+			Quantity:      -item.Quantity,
+			ReservationId: id,
+			CreatedAt:     sd.now().UTC(),
+		})
+		if err != nil {
+			return nil, sd.undoCommit(ctx, movements, onHand, err)
+		}
+		movements = append(movements, movement)
+	}
+	// the reservation goes last, a failed commit can be retried
+	if err := sd.ledger.DeleteReservation(ctx, id); err != nil {
+		return nil, sd.undoCommit(ctx, movements, onHand, err)
+	}
+	return movements, nil
+}
+
+// undoCommit puts back the stock of a commit that failed with err: the
+// quantities of its products go back to onHand and the sales recorded are
+// offset by adjustments. It carries on when ctx is done, the commit may have
+// failed for that. The caller must hold the locks of the products.
+func (sd *StockDefault) undoCommit(ctx context.Context, sales []internal.StockMovement, onHand map[int]int, err error) error {
+	ctx = context.WithoutCancel(ctx)
+
+	undoErrs := []error{err}
+	for _, sale := range sales {
+		if _, err := sd.ledger.Record(ctx, internal.StockMovement{
+			ProductId:     sale.ProductId,
+			LocationId:    sale.LocationId,
+			Kind:          internal.StockAdjust,
+			Quantity:      -sale.Quantity,
+			Reason:        "sale rolled back",
+			ReservationId: sale.ReservationId,
+			CreatedAt:     sd.now().UTC(),
+		}); err != nil {
+			undoErrs = append(undoErrs, err)
+		}
+	}
+	if err := sd.setQuantities(ctx, onHand); err != nil {
+		undoErrs = append(undoErrs, err)
+	}
+	return errors.Join(undoErrs...)
+}
+
+func (sd *StockDefault) Release(ctx context.Context, id int) error {
+	if _, err := sd.GetReservation(ctx, id); err != nil {
+		return err
+	}
+	return sd.ledger.DeleteReservation(ctx, id)
+}
+
+// reservationProducts returns the ids of the products reservation holds.
+func reservationProducts(reservation internal.Reservation) []int {
+	ids := make([]int, len(reservation.Items))
+	for i, item := range reservation.Items {
+		ids[i] = item.ProductId
+	}
+	return ids
+}
@@ -0,0 +1,318 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newStockService returns a stock service over products 1 and 2, with 10
+// and 3 units, and a clock tests can move.
+func newStockService(t *testing.T) (*service.StockDefault, *repository.ProductMapDB, *time.Time) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Price: usd(110), Version: 1},
+		2: {Id: 2, Name: "Bread", Quantity: 3, Code: "c2", Price: usd(235), Version: 1},
+	}, LastID: 2}
+
+	now := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	return service.NewStockDefault(db, repository.NewStockMapDB(), nil, func() time.Time { return now }), db, &now
+}
+
+// atDefault returns level broken down by location, all of it being at the
+// default location.
+func atDefault(level internal.StockLevel) internal.StockLevel {
+	at := level
+	at.LocationId = internal.DefaultLocationId
+	level.Locations = []internal.StockLevel{at}
+	return level
+}
+
+func movement(t *testing.T, productId int, kind internal.StockMovementKind, quantity int) internal.StockMovement {
+	m, err := internal.NewStockMovement(productId, kind, quantity, "")
+	require.NoError(t, err)
+	return m
+}
+
+func TestStockLedger(t *testing.T) {
+	ss, db, _ := newStockService(t)
+	ctx := context.Background()
+
+	for _, m := range []internal.StockMovement{
+		movement(t, 1, internal.StockReceive, 5),
+		movement(t, 1, internal.StockSell, 4),
+		movement(t, 1, internal.StockWriteOff, 1),
+		movement(t, 1, internal.StockAdjust, -2),
+	} {
+		_, err := ss.Record(ctx, m)
+		require.NoError(t, err)
+	}
+
+	level, err := ss.Level(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, atDefault(internal.StockLevel{ProductId: 1, OnHand: 8, Available: 8}), level)
+
+	// the quantity of the product is what the ledger adds up to
+	product, err := db.GetById(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 8, product.Quantity)
+
+	// a quantity set behind the ledger is set back to what it adds up to
+	product.Quantity = 20
+	_, err = db.UpdateOrCreate(ctx, product)
+	require.NoError(t, err)
+
+	movements, err := ss.Movements(ctx, 1)
+	require.NoError(t, err)
+	var changes []int
+	for _, m := range movements {
+		changes = append(changes, m.Quantity)
+	}
+	require.Equal(t, []int{10, 5, -4, -1, -2}, changes)
+	require.Equal(t, "opening balance", movements[0].Reason)
+	product, err = db.GetById(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 8, product.Quantity)
+
+	_, err = ss.Record(ctx, movement(t, 1, internal.StockSell, 9))
+	require.Equal(t, internal.NewInsufficientStockError(1, 9, 8), err)
+
+	_, err = ss.Level(ctx, 9)
+	require.True(t, errors.As(err, &internal.ProductNotFoundError{}))
+}
+
+func TestNewStockMovement(t *testing.T) {
+	for _, tc := range []struct {
+		kind     internal.StockMovementKind
+		quantity int
+		field    string
+	}{
+		{kind: internal.StockReceive, quantity: 0, field: "quantity"},
+		{kind: internal.StockSell, quantity: -1, field: "quantity"},
+		{kind: internal.StockReturn, quantity: -1, field: "quantity"},
+		{kind: internal.StockAdjust, quantity: 0, field: "quantity"},
+		{kind: "steal", quantity: 1, field: "kind"},
+	} {
+		_, err := internal.NewStockMovement(1, tc.kind, tc.quantity, "")
+		require.Equal(t, internal.NewInvalidStockMovementError(tc.field), err, tc.kind)
+	}
+}
+
+func TestStockReservations(t *testing.T) {
+	ss, _, now := newStockService(t)
+	ctx := context.Background()
+
+	reservation, err := ss.Reserve(ctx, 0, []internal.ReservationItem{{ProductId: 1, Quantity: 6}, {ProductId: 2, Quantity: 3}}, 0)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(internal.DefaultReservationTTL), reservation.ExpiresAt)
+
+	level, err := ss.Level(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, atDefault(internal.StockLevel{ProductId: 1, OnHand: 10, Reserved: 6, Available: 4}), level)
+
+	// reserved stock can not be sold nor reserved again, all or nothing
+	_, err = ss.Record(ctx, movement(t, 1, internal.StockSell, 5))
+	require.True(t, errors.As(err, &internal.InsufficientStockError{}))
+	_, err = ss.Reserve(ctx, 0, []internal.ReservationItem{{ProductId: 1, Quantity: 1}, {ProductId: 2, Quantity: 1}}, 0)
+	require.Equal(t, internal.NewInsufficientStockError(2, 1, 0), err)
+	level, err = ss.Level(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 6, level.Reserved)
+
+	movements, err := ss.Commit(ctx, reservation.Id)
+	require.NoError(t, err)
+	require.Len(t, movements, 2)
+	require.Equal(t, -6, movements[0].Quantity)
+	require.Equal(t, reservation.Id, movements[0].ReservationId)
+
+	level, err = ss.Level(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, atDefault(internal.StockLevel{ProductId: 1, OnHand: 4, Available: 4}), level)
+
+	_, err = ss.Commit(ctx, reservation.Id)
+	require.True(t, errors.As(err, &internal.ReservationNotFoundError{}))
+
+	// released and expired reservations no longer hold stock
+	released, err := ss.Reserve(ctx, 0, []internal.ReservationItem{{ProductId: 1, Quantity: 4}}, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, ss.Release(ctx, released.Id))
+
+	expired, err := ss.Reserve(ctx, 0, []internal.ReservationItem{{ProductId: 1, Quantity: 4}}, time.Minute)
+	require.NoError(t, err)
+	*now = now.Add(time.Minute)
+	level, err = ss.Level(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 4, level.Available)
+	_, err = ss.Commit(ctx, expired.Id)
+	require.True(t, errors.As(err, &internal.ReservationNotFoundError{}))
+
+	for _, items := range [][]internal.ReservationItem{
+		nil,
+		{{ProductId: 1, Quantity: 0}},
+		{{ProductId: 1, Quantity: 1}, {ProductId: 1, Quantity: 1}},
+	} {
+		_, err := ss.Reserve(ctx, 0, items, 0)
+		require.True(t, errors.As(err, &internal.InvalidReservationError{}))
+	}
+	_, err = ss.Reserve(ctx, 0, []internal.ReservationItem{{ProductId: 1, Quantity: 1}}, 48*time.Hour)
+	require.Equal(t, internal.NewInvalidReservationError("ttl"), err)
+}
+
+func TestStockDoesNotOversell(t *testing.T) {
+	ss, db, _ := newStockService(t)
+	ctx := context.Background()
+	sell := movement(t, 1, internal.StockSell, 1)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		sold   int
+		failed []error
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(reserve bool) {
+			defer wg.Done()
+
+			var err error
+			if reserve {
+				var reservation internal.Reservation
+				if reservation, err = ss.Reserve(ctx, 0, []internal.ReservationItem{{ProductId: 1, Quantity: 1}}, 0); err == nil {
+					_, err = ss.Commit(ctx, reservation.Id)
+				}
+			} else {
+				_, err = ss.Record(ctx, sell)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				sold++
+			} else if !errors.As(err, &internal.InsufficientStockError{}) {
+				failed = append(failed, err)
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+
+	require.Empty(t, failed)
+	require.Equal(t, 10, sold)
+	product, err := db.GetById(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 0, product.Quantity)
+}
+
+// failingLedger fails the Record call numbered failOn, counting from one,
+// while it is set.
+type failingLedger struct {
+	internal.StockRepository
+	records int
+	failOn  int
+}
+
+func (l *failingLedger) Record(ctx context.Context, movement internal.StockMovement) (internal.StockMovement, error) {
+	l.records++
+	if l.records == l.failOn {
+		return internal.StockMovement{}, errors.New("ledger unavailable")
+	}
+	return l.StockRepository.Record(ctx, movement)
+}
+
+func TestStockCommitIsAllOrNothing(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Price: usd(110), Version: 1},
+		2: {Id: 2, Name: "Bread", Quantity: 3, Code: "c2", Price: usd(235), Version: 1},
+	}, LastID: 2}
+	ledger := &failingLedger{StockRepository: repository.NewStockMapDB()}
+	ss := service.NewStockDefault(db, ledger, nil, nil)
+	ctx := context.Background()
+
+	reservation, err := ss.Reserve(ctx, 0, []internal.ReservationItem{{ProductId: 1, Quantity: 6}, {ProductId: 2, Quantity: 3}}, 0)
+	require.NoError(t, err)
+
+	// the sale of the second item fails, after the first one was recorded
+	ledger.failOn = ledger.records + 2
+	_, err = ss.Commit(ctx, reservation.Id)
+	require.EqualError(t, err, "ledger unavailable")
+
+	for id, quantity := range map[int]int{1: 10, 2: 3} {
+		product, err := db.GetById(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, quantity, product.Quantity)
+
+		level, err := ss.Level(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, quantity, level.OnHand)
+	}
+	movements, err := ss.Movements(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, internal.StockSell, movements[1].Kind)
+	require.Equal(t, internal.StockAdjust, movements[2].Kind)
+	require.Equal(t, 6, movements[2].Quantity)
+
+	// the reservation still holds the stock, committing again sells it once
+	_, err = ss.GetReservation(ctx, reservation.Id)
+	require.NoError(t, err)
+	sold, err := ss.Commit(ctx, reservation.Id)
+	require.NoError(t, err)
+	require.Len(t, sold, 2)
+	level, err := ss.Level(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, atDefault(internal.StockLevel{ProductId: 1, OnHand: 4, Available: 4}), level)
+}
+
+func TestProductQuantityFollowsTheLedger(t *testing.T) {
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 10, Code: "c1", Expiration: "10/03/2030", Price: usd(110), Version: 1},
+	}, LastID: 1}
+	ledger := repository.NewStockMapDB()
+	ss := service.NewStockDefault(db, ledger, nil, nil)
+	ps := service.NewProductDefault(db, service.ProductOptions{Ledger: ledger})
+	ctx := context.Background()
+
+	// the quantity is the product's own until its ledger opens
+	product, err := ps.PartialUpdate(ctx, 1, internal.Product{Quantity: 12})
+	require.NoError(t, err)
+	require.Equal(t, 12, product.Quantity)
+	level, err := ss.Level(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 12, level.OnHand)
+
+	_, err = ps.PartialUpdate(ctx, 1, internal.Product{Quantity: 15})
+	require.Equal(t, internal.NewInvalidProductError("quantity is kept by the stock ledger"), err)
+	product.Quantity = 15
+	_, err = ps.UpdateOrCreate(ctx, product)
+	require.Equal(t, internal.NewInvalidProductError("quantity is kept by the stock ledger"), err)
+
+	// updates leaving the quantity alone still go through
+	_, err = ss.Record(ctx, movement(t, 1, internal.StockReceive, 3))
+	require.NoError(t, err)
+	product, err = ps.PartialUpdate(ctx, 1, internal.Product{Name: "Whole milk"})
+	require.NoError(t, err)
+	require.Equal(t, 15, product.Quantity)
+	product.Version = 0
+	product, err = ps.UpdateOrCreate(ctx, product)
+	require.NoError(t, err)
+	require.Equal(t, 15, product.Quantity)
+
+	// a sold out product can still be replaced, a new one needs a quantity
+	_, err = ss.Record(ctx, movement(t, 1, internal.StockSell, 15))
+	require.NoError(t, err)
+	product, err = ps.GetById(ctx, 1)
+	require.NoError(t, err)
+	require.Zero(t, product.Quantity)
+	product.Name, product.Version = "Milk", 0
+	product, err = ps.UpdateOrCreate(ctx, product)
+	require.NoError(t, err)
+	require.Equal(t, "Milk", product.Name)
+	require.Zero(t, product.Quantity)
+	_, err = ps.UpdateOrCreate(ctx, internal.Product{Id: 2, Name: "Bread", Code: "c2", Expiration: "10/03/2030", Price: usd(235)})
+	require.Equal(t, internal.NewInvalidProductError("quantity"), err)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"supermarket/internal"
+)
+
+type SupplierDefault struct {
+	suppliers internal.SupplierRepository
+	products  internal.ProductRepository
+}
+
+// NewSupplierDefault keeps the suppliers in srp, linking them to the
+// products of pdb.
+func NewSupplierDefault(srp internal.SupplierRepository, pdb internal.ProductRepository) *SupplierDefault {
+	return &SupplierDefault{suppliers: srp, products: pdb}
+}
+
+func (sd *SupplierDefault) GetAll(ctx context.Context) ([]internal.Supplier, error) {
+	return sd.suppliers.GetAll(ctx)
+}
+
+func (sd *SupplierDefault) GetById(ctx context.Context, id int) (internal.Supplier, error) {
+	return sd.suppliers.GetById(ctx, id)
+}
+
+func (sd *SupplierDefault) Save(ctx context.Context, supplier internal.Supplier) (internal.Supplier, error) {
+	supplier.Id = 0
+	supplier.Name = strings.TrimSpace(supplier.Name)
+	if err := supplier.Validate(); err != nil {
+		return internal.Supplier{}, err
+	}
+	return sd.suppliers.Save(ctx, supplier)
+}
+
+func (sd *SupplierDefault) Update(ctx context.Context, supplier internal.Supplier) (internal.Supplier, error) {
+	supplier.Name = strings.TrimSpace(supplier.Name)
+	if err := supplier.Validate(); err != nil {
+		return internal.Supplier{}, err
+	}
+	return sd.suppliers.Update(ctx, supplier)
+}
+
+// Delete keeps the purchase orders of the supplier, they record what was
+// ordered from it.
+func (sd *SupplierDefault) Delete(ctx context.Context, id int) error {
+	return sd.suppliers.Delete(ctx, id)
+}
+
+func (sd *SupplierDefault) Products(ctx context.Context, supplierId int) ([]internal.SupplierProduct, error) {
+	return sd.suppliers.Products(ctx, supplierId)
+}
+
+func (sd *SupplierDefault) LinkProduct(ctx context.Context, link internal.SupplierProduct) (internal.SupplierProduct, error) {
+	if err := link.Validate(); err != nil {
+		return internal.SupplierProduct{}, err
+	}
+	if _, err := sd.suppliers.GetById(ctx, link.SupplierId); err != nil {
+		return internal.SupplierProduct{}, err
+	}
+	if _, err := sd.products.GetById(ctx, link.ProductId); err != nil {
+		return internal.SupplierProduct{}, err
+	}
+	return sd.suppliers.SaveProduct(ctx, link)
+}
+
+func (sd *SupplierDefault) UnlinkProduct(ctx context.Context, supplierId int, productId int) error {
+	return sd.suppliers.DeleteProduct(ctx, supplierId, productId)
+}
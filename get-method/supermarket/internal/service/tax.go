@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"supermarket/internal"
+)
+
+type TaxDefault struct {
+	repo internal.TaxRepository
+	// store is the code of the jurisdiction the store is in
+	store string
+}
+
+// NewTaxDefault serves the jurisdictions of rp, store being the code of the
+// one the store is in, none when it is empty.
+func NewTaxDefault(rp internal.TaxRepository, store string) *TaxDefault {
+	return &TaxDefault{repo: rp, store: store}
+}
+
+func (td *TaxDefault) GetAll(ctx context.Context) ([]internal.TaxJurisdiction, error) {
+	return td.repo.GetAll(ctx)
+}
+
+func (td *TaxDefault) GetByCode(ctx context.Context, code string) (internal.TaxJurisdiction, error) {
+	if code == "" {
+		if td.store == "" {
+			return internal.TaxJurisdiction{}, internal.NewTaxJurisdictionNotFoundError("")
+		}
+		code = td.store
+	}
+	return td.repo.GetByCode(ctx, code)
+}
+
+func (td *TaxDefault) Put(ctx context.Context, jurisdiction internal.TaxJurisdiction) (internal.TaxJurisdiction, error) {
+	if err := jurisdiction.Validate(); err != nil {
+		return internal.TaxJurisdiction{}, err
+	}
+	if jurisdiction.Rates == nil {
+		jurisdiction.Rates = []internal.TaxRate{}
+	}
+	if err := td.repo.Put(ctx, jurisdiction); err != nil {
+		return internal.TaxJurisdiction{}, err
+	}
+	return jurisdiction, nil
+}
+
+func (td *TaxDefault) Delete(ctx context.Context, code string) error {
+	return td.repo.Delete(ctx, code)
+}
+
+// taxGroup is the lines of a quote taxed at the same rate.
+type taxGroup struct {
+	rate   internal.TaxRate
+	taxed  bool
+	lines  []int
+	amount internal.Money
+}
+
+// applyTaxes breaks the taxes of receipt out as jurisdiction levies them,
+// categories holding the tax category of each line. Basket discounts are
+// spread over the rates in proportion to the amount taxed at each, and the
+// taxes are rounded half up once per rate.
+func applyTaxes(receipt *internal.Receipt, jurisdiction internal.TaxJurisdiction, categories []string) {
+	currency := receipt.Subtotal.Currency
+
+	var groups []*taxGroup
+	linesTotal := internal.NewMoney(0, currency)
+	for i, line := range receipt.Lines {
+		rate, taxed := jurisdiction.Rate(categories[i])
+		var group *taxGroup
+		for _, g := range groups {
+			if g.taxed == taxed && g.rate.Name == rate.Name && g.rate.Percent == rate.Percent {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			group = &taxGroup{rate: rate, taxed: taxed, amount: internal.NewMoney(0, currency)}
+			groups = append(groups, group)
+		}
+		group.lines = append(group.lines, line.Line)
+		group.amount = group.amount.Add(line.Total)
+		linesTotal = linesTotal.Add(line.Total)
+	}
+
+	basket := internal.NewMoney(0, currency)
+	for _, discount := range receipt.Discounts {
+		if len(discount.Lines) == 0 {
+			basket = basket.Add(discount.Amount)
+		}
+	}
+
+	summary := &internal.TaxSummary{
+		Jurisdiction: jurisdiction.Code,
+		Mode:         jurisdiction.Mode,
+		Rates:        []internal.TaxBreakdown{},
+		Net:          internal.NewMoney(0, currency),
+		Tax:          internal.NewMoney(0, currency),
+		Gross:        internal.NewMoney(0, currency),
+	}
+	spread := internal.NewMoney(0, currency)
+	for i, group := range groups {
+		var share internal.Money
+		switch {
+		case i == len(groups)-1:
+			// the last rate takes what rounding left of the basket discounts
+			share = basket.Sub(spread)
+		case linesTotal.IsPositive():
+			share = basket.MulRat(big.NewRat(group.amount.Amount, linesTotal.Amount), internal.RoundHalfEven)
+		default:
+			share = internal.NewMoney(0, currency)
+		}
+		spread = spread.Add(share)
+		amount := group.amount.Sub(share)
+
+		net, tax := amount, internal.NewMoney(0, currency)
+		if group.taxed {
+			if jurisdiction.Mode == internal.TaxInclusive {
+				// the tax is percent/(100+percent) of a gross price
+				part := internal.PercentRat(group.rate.Percent)
+				part.Quo(part, new(big.Rat).Add(big.NewRat(1, 1), part))
+				tax = amount.MulRat(part, internal.RoundHalfUp)
+				net = amount.Sub(tax)
+			} else {
+				tax = amount.Percent(group.rate.Percent, internal.RoundHalfUp)
+			}
+			summary.Rates = append(summary.Rates, internal.TaxBreakdown{
+				Name:    group.rate.Name,
+				Percent: group.rate.Percent,
+				Lines:   group.lines,
+				Net:     net,
+				Tax:     tax,
+				Gross:   net.Add(tax),
+			})
+		}
+		summary.Net = summary.Net.Add(net)
+		summary.Tax = summary.Tax.Add(tax)
+		summary.Gross = summary.Gross.Add(net.Add(tax))
+	}
+
+	receipt.Tax = summary
+	receipt.Total = summary.Gross
+}
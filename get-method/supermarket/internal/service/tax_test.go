@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"supermarket/internal"
+	"supermarket/internal/repository"
+	"supermarket/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTaxedCartService(t *testing.T, promotions ...internal.Promotion) *service.CartDefault {
+	ctx := context.Background()
+
+	ts := service.NewTaxDefault(repository.NewTaxMapDB(), "DE")
+	for _, jurisdiction := range []internal.TaxJurisdiction{
+		{Code: "DE", Mode: internal.TaxInclusive, Rates: []internal.TaxRate{
+			{Category: "standard", Name: "VAT 19%", Percent: 19},
+			{Category: "food", Name: "VAT 7%", Percent: 7},
+		}},
+		{Code: "US-CA", Mode: internal.TaxExclusive, Rates: []internal.TaxRate{
+			{Category: "standard", Name: "Sales tax", Percent: 7.25},
+			{Category: "food", Name: "Groceries", Percent: 0},
+		}},
+		{Code: "XX", Mode: internal.TaxExclusive, Rates: []internal.TaxRate{
+			{Category: "food", Name: "Food tax", Percent: 5},
+		}},
+	} {
+		_, err := ts.Put(ctx, jurisdiction)
+		require.NoError(t, err)
+	}
+
+	prp := repository.NewPromotionMapDB()
+	for _, promotion := range promotions {
+		_, err := prp.Save(ctx, promotion)
+		require.NoError(t, err)
+	}
+
+	db := &repository.ProductMapDB{Products: map[int]internal.Product{
+		1: {Id: 1, Name: "Milk", Quantity: 5, Code: "c1", Price: usd(107), TaxCategory: "food", IsPublished: true},
+		2: {Id: 2, Name: "Wine", Quantity: 5, Code: "c2", Price: usd(1190), IsPublished: true},
+	}, LastID: 2}
+	return service.NewCartDefault(db, service.CartOptions{Promotions: prp, Taxes: ts})
+}
+
+var taxedItems = []internal.CartItem{{ProductId: 1, Quantity: 2}, {ProductId: 2, Quantity: 1}}
+
+func TestQuoteTaxInclusive(t *testing.T) {
+	// carts naming no jurisdiction are taxed as in the one of the store
+	receipt, err := newTaxedCartService(t).Quote(context.Background(), internal.Cart{Items: taxedItems})
+	require.NoError(t, err)
+
+	require.Equal(t, &internal.TaxSummary{
+		Jurisdiction: "DE",
+		Mode:         internal.TaxInclusive,
+		Rates: []internal.TaxBreakdown{
+			{Name: "VAT 7%", Percent: 7, Lines: []int{0}, Net: usd(200), Tax: usd(14), Gross: usd(214)},
+			{Name: "VAT 19%", Percent: 19, Lines: []int{1}, Net: usd(1000), Tax: usd(190), Gross: usd(1190)},
+		},
+		Net:   usd(1200),
+		Tax:   usd(204),
+		Gross: usd(1404),
+	}, receipt.Tax)
+	require.Equal(t, usd(1404), receipt.Total)
+}
+
+func TestQuoteTaxExclusive(t *testing.T) {
+	receipt, err := newTaxedCartService(t).Quote(context.Background(), internal.Cart{Items: taxedItems, Jurisdiction: "US-CA"})
+	require.NoError(t, err)
+
+	require.Equal(t, []internal.TaxBreakdown{
+		{Name: "Groceries", Percent: 0, Lines: []int{0}, Net: usd(214), Tax: usd(0), Gross: usd(214)},
+		// 0.86275 rounds half up per rate
+		{Name: "Sales tax", Percent: 7.25, Lines: []int{1}, Net: usd(1190), Tax: usd(86), Gross: usd(1276)},
+	}, receipt.Tax.Rates)
+	require.Equal(t, usd(86), receipt.Tax.Tax)
+	require.Equal(t, usd(1404), receipt.Subtotal)
+	require.Equal(t, usd(1490), receipt.Total)
+
+	// products of categories with no rate, and no standard rate, are untaxed
+	receipt, err = newTaxedCartService(t).Quote(context.Background(), internal.Cart{Items: taxedItems, Jurisdiction: "XX"})
+	require.NoError(t, err)
+	require.Equal(t, []internal.TaxBreakdown{
+		{Name: "Food tax", Percent: 5, Lines: []int{0}, Net: usd(214), Tax: usd(11), Gross: usd(225)},
+	}, receipt.Tax.Rates)
+	require.Equal(t, usd(1404), receipt.Tax.Net)
+	require.Equal(t, usd(1415), receipt.Total)
+}
+
+func TestQuoteTaxSpreadsBasketDiscounts(t *testing.T) {
+	cs := newTaxedCartService(t, internal.Promotion{Name: "1 off 10", Kind: internal.PromotionBasketThreshold, Threshold: usd(1000), Amount: usd(100)})
+
+	receipt, err := cs.Quote(context.Background(), internal.Cart{Items: taxedItems, Jurisdiction: "US-CA"})
+	require.NoError(t, err)
+
+	// 214/1404 of the discount is taken off the groceries, the rest off the
+	// lines taxed at the standard rate
+	require.Equal(t, []internal.TaxBreakdown{
+		{Name: "Groceries", Percent: 0, Lines: []int{0}, Net: usd(199), Tax: usd(0), Gross: usd(199)},
+		{Name: "Sales tax", Percent: 7.25, Lines: []int{1}, Net: usd(1105), Tax: usd(80), Gross: usd(1185)},
+	}, receipt.Tax.Rates)
+	require.Equal(t, usd(100), receipt.Discount)
+	require.Equal(t, usd(1384), receipt.Total)
+}
+
+func TestQuoteTaxJurisdiction(t *testing.T) {
+	_, err := newTaxedCartService(t).Quote(context.Background(), internal.Cart{Items: taxedItems, Jurisdiction: "FR"})
+	require.True(t, errors.As(err, &internal.InvalidCartError{}))
+
+	// carts are untaxed without a jurisdiction for the store
+	receipt, err := newCartService().Quote(context.Background(), internal.Cart{Items: []internal.CartItem{{ProductId: 1, Quantity: 1}}})
+	require.NoError(t, err)
+	require.Nil(t, receipt.Tax)
+}
+
+func TestTaxJurisdictionValidate(t *testing.T) {
+	ts := service.NewTaxDefault(repository.NewTaxMapDB(), "")
+
+	for field, jurisdiction := range map[string]internal.TaxJurisdiction{
+		"code":     {Code: "de", Mode: internal.TaxInclusive},
+		"mode":     {Code: "DE", Mode: "gross"},
+		"category": {Code: "DE", Mode: internal.TaxInclusive, Rates: []internal.TaxRate{{Category: "food", Percent: 7}, {Category: "food", Percent: 19}}},
+		"percent":  {Code: "DE", Mode: internal.TaxInclusive, Rates: []internal.TaxRate{{Category: "food", Percent: -7}}},
+	} {
+		_, err := ts.Put(context.Background(), jurisdiction)
+		var invalid internal.InvalidTaxJurisdictionError
+		require.True(t, errors.As(err, &invalid), field)
+		require.Equal(t, field, invalid.Field)
+	}
+
+	_, err := ts.GetByCode(context.Background(), "")
+	require.True(t, errors.As(err, &internal.TaxJurisdictionNotFoundError{}))
+}
@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StockMovementKind is why the stock of a product changed.
+type StockMovementKind string
+
+const (
+	// StockReceive adds the units received from a supplier.
+	StockReceive StockMovementKind = "receive"
+	// StockSell takes the units sold off.
+	StockSell StockMovementKind = "sell"
+	// StockAdjust corrects the stock, up or down, after a count, and opens
+	// the ledger of a product with the quantity it had.
+	StockAdjust StockMovementKind = "adjust"
+	// StockReturn puts back the units of a sale that was undone, such as
+	// those of a cancelled or refunded order.
+	StockReturn StockMovementKind = "return"
+	// StockWriteOff takes off the units lost, damaged or expired.
+	StockWriteOff StockMovementKind = "write_off"
+	// StockTransfer moves units between locations. A transfer is recorded
+	// as a pair of movements sharing a TransferId, one taking the units off
+	// the location they leave and one adding them to the location they go
+	// to, so it does not change the stock of the product.
+	StockTransfer StockMovementKind = "transfer"
+)
+
+// StockMovement is an entry of the stock ledger of a product. Quantity is the
+// signed change of the stock at LocationId, DefaultLocationId when it is
+// zero, negative for sales and write-offs. ReservationId is the reservation
+// a sale committed, if any, and TransferId the transfer the movement is
+// half of.
+type StockMovement struct {
+	Id            int               `json:"id"`
+	ProductId     int               `json:"product_id"`
+	LocationId    int               `json:"location_id"`
+	Kind          StockMovementKind `json:"kind"`
+	Quantity      int               `json:"quantity"`
+	Reason        string            `json:"reason,omitempty"`
+	ReservationId int               `json:"reservation_id,omitempty"`
+	TransferId    int               `json:"transfer_id,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// NewStockMovement returns a movement of kind for quantity units of product
+// id at the default location, quantity being taken off the stock for sales
+// and write-offs. It fails with InvalidStockMovementError when quantity is
+// not positive, or zero for adjustments, which are signed, and for
+// transfers, which are made with StockService.Transfer.
+func NewStockMovement(productId int, kind StockMovementKind, quantity int, reason string) (StockMovement, error) {
+	movement := StockMovement{ProductId: productId, Kind: kind, Quantity: quantity, Reason: reason}
+	switch kind {
+	case StockReceive, StockReturn:
+	case StockSell, StockWriteOff:
+		movement.Quantity = -quantity
+	case StockAdjust:
+		if quantity == 0 {
+			return StockMovement{}, NewInvalidStockMovementError("quantity")
+		}
+		return movement, nil
+	default:
+		return StockMovement{}, NewInvalidStockMovementError("kind")
+	}
+	if quantity <= 0 {
+		return StockMovement{}, NewInvalidStockMovementError("quantity")
+	}
+	return movement, nil
+}
+
+// StockLevel is the stock of a product: OnHand is what the ledger adds up
+// to, Reserved what active reservations hold of it and Available what is
+// left to sell. The stock of a product as a whole breaks down into the
+// stock at each of Locations, the locations its ledger moved stock at, and
+// adds them up. The stock at a location has its LocationId set instead.
+type StockLevel struct {
+	ProductId  int          `json:"product_id"`
+	LocationId int          `json:"location_id,omitempty"`
+	OnHand     int          `json:"on_hand"`
+	Reserved   int          `json:"reserved"`
+	Available  int          `json:"available"`
+	Locations  []StockLevel `json:"locations,omitempty"`
+}
+
+// DefaultReservationTTL and MaxReservationTTL bound how long reservations
+// hold stock.
+const (
+	DefaultReservationTTL = 15 * time.Minute
+	MaxReservationTTL     = 24 * time.Hour
+)
+
+// ReservationItem is a quantity of a product held by a reservation.
+type ReservationItem struct {
+	ProductId int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+// Reservation holds stock at LocationId for an open cart until ExpiresAt,
+// when the stock goes back to being available. Committing it sells the
+// stock.
+type Reservation struct {
+	Id         int               `json:"id"`
+	LocationId int               `json:"location_id"`
+	Items      []ReservationItem `json:"items"`
+	CreatedAt  time.Time         `json:"created_at"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+}
+
+func (r Reservation) Validate() error {
+	if len(r.Items) == 0 || len(r.Items) > MaxCartItems {
+		return NewInvalidReservationError("items")
+	}
+	seen := map[int]bool{}
+	for _, item := range r.Items {
+		if item.ProductId <= 0 || seen[item.ProductId] {
+			return NewInvalidReservationError("product_id")
+		}
+		if item.Quantity <= 0 {
+			return NewInvalidReservationError("quantity")
+		}
+		seen[item.ProductId] = true
+	}
+	return nil
+}
+
+// ActiveAt reports whether the reservation still holds its stock at t.
+func (r Reservation) ActiveAt(t time.Time) bool {
+	return t.Before(r.ExpiresAt)
+}
+
+// StockRepository stores the stock ledger and the reservations. It does not
+// check the stock, the service serializes the writes to each product for
+// that.
+type StockRepository interface {
+	// Movements returns the movements of product id, oldest first.
+	Movements(ctx context.Context, productId int) ([]StockMovement, error)
+	// OnHand returns the sum of the movements of product id. It reports false
+	// when there are none.
+	OnHand(ctx context.Context, productId int) (int, bool, error)
+	// OnHandByLocation returns the sum of the movements of product id at
+	// each location it has any at.
+	OnHandByLocation(ctx context.Context, productId int) (map[int]int, error)
+	// Stocked reports whether any product has stock, or lacks some, at
+	// location id.
+	Stocked(ctx context.Context, locationId int) (bool, error)
+	// Record appends movement to the ledger.
+	Record(ctx context.Context, movement StockMovement) (StockMovement, error)
+	// RecordTransfer appends the two movements of a transfer to the ledger,
+	// setting their TransferId to the id of out.
+	RecordTransfer(ctx context.Context, out, in StockMovement) ([]StockMovement, error)
+
+	// ReservedByLocation returns the quantity of product id held by the
+	// reservations active at now at each location.
+	ReservedByLocation(ctx context.Context, productId int, now time.Time) (map[int]int, error)
+	SaveReservation(ctx context.Context, reservation Reservation) (Reservation, error)
+	GetReservation(ctx context.Context, id int) (Reservation, error)
+	DeleteReservation(ctx context.Context, id int) error
+	// DeleteExpiredReservations deletes the reservations no longer active at
+	// now.
+	DeleteExpiredReservations(ctx context.Context, now time.Time) error
+}
+
+// The methods of StockService taking a location fail with
+// LocationNotFoundError when it does not exist, and take the default
+// location for zero.
+type StockService interface {
+	// Level returns the stock of a product with its breakdown by location.
+	Level(ctx context.Context, productId int) (StockLevel, error)
+	// LevelAt returns the stock of a product at location id.
+	LevelAt(ctx context.Context, productId int, locationId int) (StockLevel, error)
+	Movements(ctx context.Context, productId int) ([]StockMovement, error)
+	// Record records movement, setting the quantity of its product. It fails
+	// with InsufficientStockError when it takes more than is available at
+	// its location.
+	Record(ctx context.Context, movement StockMovement) (StockMovement, error)
+	// Transfer moves quantity units of a product from a location to another
+	// and returns the pair of movements recorded. It fails with
+	// InsufficientStockError when they are not available at from.
+	Transfer(ctx context.Context, productId int, from, to int, quantity int, reason string) ([]StockMovement, error)
+	// Reserve holds the stock of every item at location id for ttl,
+	// DefaultReservationTTL when it is zero, or none of it. It fails with
+	// InsufficientStockError when an item is not available there.
+	Reserve(ctx context.Context, locationId int, items []ReservationItem, ttl time.Duration) (Reservation, error)
+	// GetReservation, Commit and Release fail with ReservationNotFoundError
+	// when the reservation does not exist or expired.
+	GetReservation(ctx context.Context, id int) (Reservation, error)
+	// Commit sells the stock held by a reservation.
+	Commit(ctx context.Context, id int) ([]StockMovement, error)
+	// Release makes the stock held by a reservation available again.
+	Release(ctx context.Context, id int) error
+}
+
+type InvalidStockMovementError struct {
+	Field string
+}
+
+func (e InvalidStockMovementError) Error() string {
+	return "invalid stock movement: " + e.Field
+}
+
+func NewInvalidStockMovementError(field string) error {
+	return InvalidStockMovementError{Field: field}
+}
+
+type InvalidReservationError struct {
+	Field string
+}
+
+func (e InvalidReservationError) Error() string {
+	return "invalid reservation: " + e.Field
+}
+
+func NewInvalidReservationError(field string) error {
+	return InvalidReservationError{Field: field}
+}
+
+type InsufficientStockError struct {
+	ProductId int
+	Requested int
+	Available int
+}
+
+func (e InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock of product %d: %d requested, %d available", e.ProductId, e.Requested, e.Available)
+}
+
+func NewInsufficientStockError(productId, requested, available int) error {
+	return InsufficientStockError{ProductId: productId, Requested: requested, Available: available}
+}
+
+type ReservationNotFoundError struct{}
+
+func (e ReservationNotFoundError) Error() string {
+	return "reservation not found"
+}
+
+func NewReservationNotFoundError() error {
+	return ReservationNotFoundError{}
+}
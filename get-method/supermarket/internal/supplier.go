@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"context"
+	"strings"
+)
+
+// Supplier is a company the supermarket restocks from. LeadTimeDays is how
+// many days its deliveries take once a purchase order is sent.
+type Supplier struct {
+	Id           int    `json:"id"`
+	Name         string `json:"name"`
+	Email        string `json:"email,omitempty"`
+	Phone        string `json:"phone,omitempty"`
+	LeadTimeDays int    `json:"lead_time_days"`
+}
+
+func (s Supplier) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return NewInvalidSupplierError("name")
+	}
+	if s.Email != "" && !strings.Contains(s.Email, "@") {
+		return NewInvalidSupplierError("email")
+	}
+	if s.LeadTimeDays < 0 {
+		return NewInvalidSupplierError("lead_time_days")
+	}
+	return nil
+}
+
+// SupplierProduct links a product to a supplier selling it at Cost, the
+// price the supermarket pays per unit. SupplierCode is the code the
+// supplier knows the product by, if any.
+type SupplierProduct struct {
+	SupplierId   int    `json:"supplier_id"`
+	ProductId    int    `json:"product_id"`
+	SupplierCode string `json:"supplier_code,omitempty"`
+	Cost         Money  `json:"cost"`
+}
+
+func (l SupplierProduct) Validate() error {
+	if l.ProductId <= 0 {
+		return NewInvalidSupplierError("product_id")
+	}
+	if !l.Cost.IsPositive() || !ValidCurrency(l.Cost.Currency) {
+		return NewInvalidSupplierError("cost")
+	}
+	return nil
+}
+
+type SupplierRepository interface {
+	// GetAll returns every supplier sorted by id.
+	GetAll(ctx context.Context) ([]Supplier, error)
+	GetById(ctx context.Context, id int) (Supplier, error)
+	Save(ctx context.Context, supplier Supplier) (Supplier, error)
+	Update(ctx context.Context, supplier Supplier) (Supplier, error)
+	// Delete deletes the supplier with its product links.
+	Delete(ctx context.Context, id int) error
+
+	// Products returns the product links of supplier id sorted by product
+	// id.
+	Products(ctx context.Context, supplierId int) ([]SupplierProduct, error)
+	// SaveProduct adds or replaces the link between a supplier and a
+	// product.
+	SaveProduct(ctx context.Context, link SupplierProduct) (SupplierProduct, error)
+	// DeleteProduct fails with SupplierProductNotFoundError when the
+	// product is not linked to the supplier.
+	DeleteProduct(ctx context.Context, supplierId int, productId int) error
+}
+
+type SupplierService interface {
+	GetAll(ctx context.Context) ([]Supplier, error)
+	GetById(ctx context.Context, id int) (Supplier, error)
+	Save(ctx context.Context, supplier Supplier) (Supplier, error)
+	Update(ctx context.Context, supplier Supplier) (Supplier, error)
+	Delete(ctx context.Context, id int) error
+
+	Products(ctx context.Context, supplierId int) ([]SupplierProduct, error)
+	// LinkProduct fails with SupplierNotFoundError or ProductNotFoundError
+	// when the supplier or the product of link does not exist.
+	LinkProduct(ctx context.Context, link SupplierProduct) (SupplierProduct, error)
+	UnlinkProduct(ctx context.Context, supplierId int, productId int) error
+}
+
+type InvalidSupplierError struct {
+	Field string
+}
+
+func (e InvalidSupplierError) Error() string {
+	return "invalid supplier: " + e.Field
+}
+
+func NewInvalidSupplierError(field string) error {
+	return InvalidSupplierError{Field: field}
+}
+
+type SupplierNotFoundError struct{}
+
+func (e SupplierNotFoundError) Error() string {
+	return "supplier not found"
+}
+
+func NewSupplierNotFoundError() error {
+	return SupplierNotFoundError{}
+}
+
+type SupplierProductNotFoundError struct{}
+
+func (e SupplierProductNotFoundError) Error() string {
+	return "product not linked to supplier"
+}
+
+func NewSupplierProductNotFoundError() error {
+	return SupplierProductNotFoundError{}
+}
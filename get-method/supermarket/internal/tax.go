@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"context"
+	"regexp"
+)
+
+// DefaultTaxCategory is the tax category of products without one. The rate
+// of a jurisdiction for it also applies to the categories the jurisdiction
+// does not list.
+const DefaultTaxCategory = "standard"
+
+// TaxMode tells whether the prices of a jurisdiction include its taxes.
+type TaxMode string
+
+const (
+	// TaxExclusive prices are net, taxes are added on top of them.
+	TaxExclusive TaxMode = "exclusive"
+	// TaxInclusive prices are gross, they already include the taxes, as VAT
+	// does in most of Europe.
+	TaxInclusive TaxMode = "inclusive"
+)
+
+// TaxRate is the rate, in percent, at which a jurisdiction taxes the
+// products of a tax category.
+type TaxRate struct {
+	Category string  `json:"category"`
+	Name     string  `json:"name"`
+	Percent  float64 `json:"percent"`
+}
+
+// TaxJurisdiction is a place the store sells in, with its tax rates, such
+// as "DE" or "US-CA".
+type TaxJurisdiction struct {
+	Code  string    `json:"code"`
+	Mode  TaxMode   `json:"mode"`
+	Rates []TaxRate `json:"rates"`
+}
+
+var jurisdictionCode = regexp.MustCompile(`^[A-Z0-9]+(-[A-Z0-9]+)*$`)
+
+func (j TaxJurisdiction) Validate() error {
+	if !jurisdictionCode.MatchString(j.Code) {
+		return NewInvalidTaxJurisdictionError("code")
+	}
+	if j.Mode != TaxExclusive && j.Mode != TaxInclusive {
+		return NewInvalidTaxJurisdictionError("mode")
+	}
+
+	categories := map[string]bool{}
+	for _, rate := range j.Rates {
+		if rate.Category == "" || categories[rate.Category] {
+			return NewInvalidTaxJurisdictionError("category")
+		}
+		if rate.Percent < 0 || rate.Percent > 100 {
+			return NewInvalidTaxJurisdictionError("percent")
+		}
+		categories[rate.Category] = true
+	}
+	return nil
+}
+
+// Rate returns the rate of category, falling back to the one of
+// DefaultTaxCategory. It reports false when neither is listed, the products
+// of category being untaxed.
+func (j TaxJurisdiction) Rate(category string) (TaxRate, bool) {
+	if category == "" {
+		category = DefaultTaxCategory
+	}
+
+	var fallback *TaxRate
+	for i, rate := range j.Rates {
+		switch rate.Category {
+		case category:
+			return rate, true
+		case DefaultTaxCategory:
+			fallback = &j.Rates[i]
+		}
+	}
+	if fallback == nil {
+		return TaxRate{}, false
+	}
+	return *fallback, true
+}
+
+type TaxRepository interface {
+	GetAll(ctx context.Context) ([]TaxJurisdiction, error)
+	GetByCode(ctx context.Context, code string) (TaxJurisdiction, error)
+	// Put stores jurisdiction, replacing the one of the same code.
+	Put(ctx context.Context, jurisdiction TaxJurisdiction) error
+	Delete(ctx context.Context, code string) error
+}
+
+type TaxService interface {
+	GetAll(ctx context.Context) ([]TaxJurisdiction, error)
+	// GetByCode returns the jurisdiction of code, the one the store is in
+	// when code is empty. It fails with TaxJurisdictionNotFoundError when
+	// there is none.
+	GetByCode(ctx context.Context, code string) (TaxJurisdiction, error)
+	// Put fails with InvalidTaxJurisdictionError when jurisdiction is not
+	// valid.
+	Put(ctx context.Context, jurisdiction TaxJurisdiction) (TaxJurisdiction, error)
+	Delete(ctx context.Context, code string) error
+}
+
+// TaxBreakdown is the tax due at a rate in a quote: Net is the price of the
+// Lines taxed at it without the tax, Tax the tax and Gross their sum.
+type TaxBreakdown struct {
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+	Lines   []int   `json:"lines"`
+	Net     Money   `json:"net"`
+	Tax     Money   `json:"tax"`
+	Gross   Money   `json:"gross"`
+}
+
+// TaxSummary is the taxes of a quote in Jurisdiction, by rate, and their
+// totals. Lines taxed at no rate are in Net and Gross only.
+type TaxSummary struct {
+	Jurisdiction string         `json:"jurisdiction"`
+	Mode         TaxMode        `json:"mode"`
+	Rates        []TaxBreakdown `json:"rates"`
+	Net          Money          `json:"net"`
+	Tax          Money          `json:"tax"`
+	Gross        Money          `json:"gross"`
+}
+
+type InvalidTaxJurisdictionError struct {
+	Field string
+}
+
+func (e InvalidTaxJurisdictionError) Error() string {
+	return "invalid tax jurisdiction: " + e.Field
+}
+
+func NewInvalidTaxJurisdictionError(field string) error {
+	return InvalidTaxJurisdictionError{Field: field}
+}
+
+type TaxJurisdictionNotFoundError struct {
+	Code string
+}
+
+func (e TaxJurisdictionNotFoundError) Error() string {
+	if e.Code == "" {
+		return "no tax jurisdiction configured"
+	}
+	return "tax jurisdiction not found: " + e.Code
+}
+
+func NewTaxJurisdictionNotFoundError(code string) error {
+	return TaxJurisdictionNotFoundError{Code: code}
+}
@@ -0,0 +1,287 @@
+package migration
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Queries
+const (
+	CreateMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at VARCHAR(64) NOT NULL
+	)`
+	GetAppliedMigrations = "SELECT version, name, applied_at FROM schema_migrations ORDER BY version"
+	InsertMigration      = "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"
+	DeleteMigration      = "DELETE FROM schema_migrations WHERE version = ?"
+)
+
+// fileName matches <version>_<name>.up.sql and <version>_<name>.down.sql.
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change read from a pair of
+// <version>_<name>.up.sql and <version>_<name>.down.sql files.
+// Statements in a file are separated by a semicolon ending a line.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status tells whether a migration was applied and when.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Missing is set for applied versions no migration file exists for.
+	Missing bool
+}
+
+// NoMigrationError is returned by Down when nothing is applied.
+type NoMigrationError struct{}
+
+func (e NoMigrationError) Error() string {
+	return "no migration to revert"
+}
+
+// IrreversibleMigrationError is returned by Down for migrations without a
+// down file.
+type IrreversibleMigrationError struct {
+	Version int64
+}
+
+func (e IrreversibleMigrationError) Error() string {
+	return fmt.Sprintf("migration %d can not be reverted", e.Version)
+}
+
+// Load reads the migrations found at the root of fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations, recording applied versions in
+// the schema_migrations table.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in version order, each one in its own
+// transaction, and returns the ones it applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err := m.run(migration.Up, InsertMigration, migration.Version, migration.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			return done, fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the most recently applied migration.
+func (m *Migrator) Down() (Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return Migration{}, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return Migration{}, IrreversibleMigrationError{Version: migration.Version}
+		}
+
+		if err := m.run(migration.Down, DeleteMigration, migration.Version); err != nil {
+			return Migration{}, fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return migration, nil
+	}
+	return Migration{}, NoMigrationError{}
+}
+
+// Status lists every known migration and every applied version, in order.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status, ok := applied[migration.Version]
+		if !ok {
+			status = Status{Version: migration.Version, Name: migration.Name}
+		}
+		delete(applied, migration.Version)
+		statuses = append(statuses, status)
+	}
+	for _, status := range applied {
+		status.Missing = true
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+func (m *Migrator) applied() (map[int64]Status, error) {
+	if _, err := m.db.Exec(CreateMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(GetAppliedMigrations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]Status{}
+	for rows.Next() {
+		var (
+			status    Status
+			appliedAt string
+		)
+		if err := rows.Scan(&status.Version, &status.Name, &appliedAt); err != nil {
+			return nil, err
+		}
+		status.Applied = true
+		if status.AppliedAt, err = time.Parse(time.RFC3339, appliedAt); err != nil {
+			return nil, err
+		}
+		applied[status.Version] = status
+	}
+	return applied, rows.Err()
+}
+
+// run executes script and the bookkeeping statement in one transaction.
+// Engines committing DDL implicitly, like MySQL, only make the bookkeeping
+// atomic.
+func (m *Migrator) run(script string, bookkeeping string, args ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range splitStatements(script) {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(bookkeeping, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// splitStatements splits script on semicolons ending a line, dropping
+// statements made of comments only.
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		hasCode    bool
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			hasCode = true
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			if hasCode {
+				statements = append(statements, strings.TrimSpace(current.String()))
+			}
+			current.Reset()
+			hasCode = false
+		}
+	}
+	if hasCode {
+		statements = append(statements, strings.TrimSpace(current.String()))
+	}
+	return statements
+}
@@ -0,0 +1,136 @@
+package migration_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"supermarket/platform/database/migration"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+var testMigrations = fstest.MapFS{
+	"0001_create_items.up.sql": {Data: []byte(`
+-- items to sell
+CREATE TABLE items (
+  id INTEGER PRIMARY KEY,
+  name TEXT NOT NULL
+);
+INSERT INTO items (id, name) VALUES (1, 'first');
+`)},
+	"0001_create_items.down.sql": {Data: []byte("DROP TABLE items;\n")},
+	"0002_add_price.up.sql":      {Data: []byte("ALTER TABLE items ADD COLUMN price REAL NOT NULL DEFAULT 0;\n")},
+	"0002_add_price.down.sql":    {Data: []byte("ALTER TABLE items DROP COLUMN price;\n")},
+	"0003_index_names.up.sql":    {Data: []byte("CREATE UNIQUE INDEX items_name ON items (name);\n")},
+	"0003_index_names.down.sql":  {Data: []byte("DROP INDEX items_name;\n")},
+}
+
+func openDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigratorUpDownStatus(t *testing.T) {
+	db := openDB(t)
+	migrator, err := migration.NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	for _, status := range statuses {
+		require.False(t, status.Applied)
+	}
+
+	applied, err := migrator.Up()
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+
+	_, err = db.Exec("INSERT INTO items (id, name, price) VALUES (2, 'second', 1.5)")
+	require.NoError(t, err)
+
+	// running again is a no-op
+	applied, err = migrator.Up()
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	reverted, err := migrator.Down()
+	require.NoError(t, err)
+	require.EqualValues(t, 3, reverted.Version)
+
+	statuses, err = migrator.Status()
+	require.NoError(t, err)
+	require.True(t, statuses[0].Applied)
+	require.True(t, statuses[1].Applied)
+	require.False(t, statuses[2].Applied)
+
+	_, err = migrator.Down()
+	require.NoError(t, err)
+	_, err = migrator.Down()
+	require.NoError(t, err)
+	_, err = migrator.Down()
+	require.ErrorAs(t, err, &migration.NoMigrationError{})
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'items'").Scan(&count))
+	require.Zero(t, count)
+}
+
+func TestMigratorRollsBackFailedMigration(t *testing.T) {
+	db := openDB(t)
+	broken := fstest.MapFS{
+		"0001_create_items.up.sql": testMigrations["0001_create_items.up.sql"],
+		"0002_broken.up.sql":       {Data: []byte("CREATE TABLE other (id INTEGER);\nNOT SQL;\n")},
+	}
+	migrator, err := migration.NewMigrator(db, broken)
+	require.NoError(t, err)
+
+	applied, err := migrator.Up()
+	require.Error(t, err)
+	require.Len(t, applied, 1)
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	require.True(t, statuses[0].Applied)
+	require.False(t, statuses[1].Applied)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'other'").Scan(&count))
+	require.Zero(t, count)
+
+	_, err = migrator.Down()
+	require.ErrorAs(t, err, &migration.IrreversibleMigrationError{})
+}
+
+func TestMigratorReportsAppliedVersionsWithoutFile(t *testing.T) {
+	db := openDB(t)
+	migrator, err := migration.NewMigrator(db, testMigrations)
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+
+	older, err := migration.NewMigrator(db, fstest.MapFS{
+		"0001_create_items.up.sql": testMigrations["0001_create_items.up.sql"],
+	})
+	require.NoError(t, err)
+
+	statuses, err := older.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	require.False(t, statuses[0].Missing)
+	require.True(t, statuses[1].Missing)
+	require.True(t, statuses[2].Missing)
+}
+
+func TestLoadRejectsUnexpectedFiles(t *testing.T) {
+	_, err := migration.Load(fstest.MapFS{"create_items.sql": {Data: []byte("SELECT 1;")}})
+	require.Error(t, err)
+
+	_, err = migration.Load(fstest.MapFS{"0001_create_items.down.sql": {Data: []byte("SELECT 1;")}})
+	require.Error(t, err)
+}
@@ -10,6 +10,7 @@ func ResponseData(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		handler.ServeHTTP(w, r)
 
+		fmt.Printf("Request ID: %s\n", RequestIDFrom(r.Context()))
 		fmt.Printf("Method: %s\n", r.Method)
 		fmt.Printf("Time: %s\n", time.Now())
 		fmt.Printf("Path: %s%s\n", r.Host, r.URL.Path)